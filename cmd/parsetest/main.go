@@ -3,22 +3,42 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"homebooks/internal/parser"
 )
 
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Println("Usage: parsetest <path-to-pdf>")
+		fmt.Println("Usage: parsetest <path-to-pdf|path-to-csv|path-to-ofx|path-to-extracted-txt> [fallback-month YYYY-MM]")
 		os.Exit(1)
 	}
 
-	pdfPath := os.Args[1]
+	path := os.Args[1]
+	var opts parser.Options
+	if len(os.Args) > 2 {
+		opts.FallbackMonth = os.Args[2]
+	}
 
-	p := parser.NewTDBankParser()
-	result, err := p.Parse(pdfPath)
+	var result *parser.ParsedStatement
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".txt") {
+		// Text already extracted with pdftotext -layout, e.g. a fixture
+		var text []byte
+		text, err = os.ReadFile(path)
+		if err == nil {
+			result, err = parser.NewTDBankParser(opts).ParseText(string(text))
+		}
+	} else {
+		var p parser.StatementParser
+		p, err = parser.ForFile(path, opts)
+		if err == nil {
+			result, err = p.Parse(path)
+		}
+	}
 	if err != nil {
-		fmt.Printf("Error parsing PDF: %v\n", err)
+		fmt.Printf("Error parsing statement: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -27,6 +47,15 @@ func main() {
 	fmt.Printf("Ending Balance: $%.2f\n", result.EndingBalance)
 	fmt.Printf("Transactions: %d\n\n", len(result.Transactions))
 
+	if len(result.Warnings) > 0 {
+		fmt.Println("Warnings:")
+		fmt.Println("---------")
+		for _, w := range result.Warnings {
+			fmt.Printf("  %s\n", w)
+		}
+		fmt.Println()
+	}
+
 	// Summary by type
 	typeCounts := make(map[string]int)
 	typeAmounts := make(map[string]float64)
@@ -80,6 +109,10 @@ func main() {
 	fmt.Printf("  Total Credits:      $%10.2f\n", totalCredits)
 	fmt.Printf("  Total Debits:       $%10.2f\n", totalDebits)
 	fmt.Printf("  Calculated Ending:  $%10.2f\n", calculatedEnding)
+	if !result.HasBalances() {
+		fmt.Println("  No statement balances (CSV export), skipping difference")
+		return
+	}
 	fmt.Printf("  Statement Ending:   $%10.2f\n", result.EndingBalance)
 	diff := calculatedEnding - result.EndingBalance
 	if diff != 0 {
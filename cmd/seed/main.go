@@ -0,0 +1,431 @@
+// Command seed loads demo data for evaluating HomeBooks and for manual QA of the reports.
+// Everything it inserts is recorded so it can be removed again with -remove.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"time"
+
+	"homebooks/internal/database"
+	"homebooks/internal/models"
+)
+
+func main() {
+	dbPath := flag.String("db", os.Getenv("HOMEBOOKS_DB_PATH"), "database path (default ./data/homebooks.db)")
+	force := flag.Bool("force", false, "seed even though the database already has data")
+	remove := flag.Bool("remove", false, "remove previously seeded demo data instead of adding it")
+	flag.Parse()
+
+	if *dbPath == "" {
+		*dbPath = "./data/homebooks.db"
+	}
+
+	db, err := database.Open(*dbPath)
+	if err != nil {
+		fmt.Printf("Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	if err := db.Init(); err != nil {
+		fmt.Printf("Error initializing database: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *remove {
+		n, err := db.RemoveDemoData()
+		if err != nil {
+			fmt.Printf("Error removing demo data: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Removed %d demo records\n", n)
+		return
+	}
+
+	fresh, err := db.IsFreshInstall()
+	if err != nil {
+		fmt.Printf("Error checking database: %v\n", err)
+		os.Exit(1)
+	}
+	if !fresh && !*force {
+		fmt.Println("Database already has data; refusing to mix in demo data. Use -force to seed anyway.")
+		os.Exit(1)
+	}
+
+	s := &seeder{db: db, rng: rand.New(rand.NewSource(1)), today: time.Now()}
+	if err := s.run(); err != nil {
+		fmt.Printf("Error seeding demo data: %v\n", err)
+		fmt.Println("Partially seeded data can be cleaned up with -remove")
+		os.Exit(1)
+	}
+	fmt.Printf("Seeded demo data into %s (remove it with: seed -remove)\n", *dbPath)
+}
+
+type seeder struct {
+	db    *database.DB
+	rng   *rand.Rand
+	today time.Time
+
+	vendors   map[string]int64
+	employees []models.Employee
+}
+
+// demoVendor is a vendor with the range of a typical invoice
+type demoVendor struct {
+	name, category, paymentType string
+	low, high                   float64
+}
+
+var demoVendors = []demoVendor{
+	{"Demo Foodservice", "Food,Paper", "check", 600, 1400},
+	{"Demo Meats", "Meat", "check", 300, 700},
+	{"Demo Beverages", "Beverages", "debit", 120, 260},
+	{"Demo Power & Light", "Utilities", "debit", 280, 420},
+	{"Demo Properties", "Rent", "check", 3200, 3200},
+	{"Demo Pest Control", "Services", "debit", 85, 85},
+}
+
+var demoEmployees = []models.Employee{
+	{Name: "Demo Cook", HourlyRate: 18.00, PaymentMethod: "check"},
+	{Name: "Demo Server", HourlyRate: 12.50, PaymentMethod: "cash"},
+	{Name: "Demo Dishwasher", HourlyRate: 15.00, PaymentMethod: "cash"},
+	{Name: "Demo Manager", HourlyRate: 22.00, PaymentMethod: "check"},
+}
+
+func (s *seeder) run() error {
+	steps := []func() error{s.seedVendors, s.seedEmployees, s.seedSales, s.seedExpenses, s.seedPayroll, s.seedReconciliation}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *seeder) mark(table string, id int64) error {
+	return s.db.MarkDemo(table, id)
+}
+
+// between returns a random amount in [low, high] rounded to cents
+func (s *seeder) between(low, high float64) float64 {
+	return math.Round((low+s.rng.Float64()*(high-low))*100) / 100
+}
+
+func (s *seeder) seedVendors() error {
+	s.vendors = make(map[string]int64)
+	for _, v := range demoVendors {
+		id, err := s.db.CreateVendor(v.name, v.category, database.DemoNote, v.paymentType)
+		if err != nil {
+			return err
+		}
+		if err := s.mark("vendors", id); err != nil {
+			return err
+		}
+		s.vendors[v.name] = id
+	}
+	return nil
+}
+
+func (s *seeder) seedEmployees() error {
+	for _, e := range demoEmployees {
+		id, err := s.db.CreateEmployee(e.Name, e.HourlyRate, e.PaymentMethod)
+		if err != nil {
+			return err
+		}
+		if err := s.mark("employees", id); err != nil {
+			return err
+		}
+		e.ID = id
+		s.employees = append(s.employees, e)
+	}
+	return nil
+}
+
+// seedSales enters three weeks of shifts and delivery, skipping anything already entered
+func (s *seeder) seedSales() error {
+	shifts := []struct {
+		name      string
+		low, high float64
+	}{
+		{"breakfast", 250, 450},
+		{"lunch", 800, 1200},
+		{"dinner", 1300, 2200},
+	}
+
+	for day := 21; day >= 0; day-- {
+		date := s.today.AddDate(0, 0, -day)
+		dateStr := date.Format("2006-01-02")
+		weekend := date.Weekday() == time.Friday || date.Weekday() == time.Saturday
+
+		existing, err := s.db.GetShiftsForDate(dateStr)
+		if err != nil {
+			return err
+		}
+		taken := make(map[string]bool)
+		for _, shift := range existing {
+			taken[shift] = true
+		}
+
+		for _, shift := range shifts {
+			if taken[shift.name] {
+				continue
+			}
+			net := s.between(shift.low, shift.high)
+			if weekend {
+				net = math.Round(net*125) / 100
+			}
+			taxes := math.Round(net*8.5) / 100
+			card := math.Round((net+taxes)*s.between(0.65, 0.8)*100) / 100
+			cash := math.Round((net+taxes-card)*100) / 100
+			id, err := s.db.UpsertSale(models.DailySale{
+				Date:        dateStr,
+				Shift:       shift.name,
+				NetSales:    net,
+				Taxes:       taxes,
+				CreditCard:  card,
+				CashReceipt: cash,
+				CashOnHand:  cash + s.between(-5, 5),
+				Notes:       database.DemoNote,
+			})
+			if err != nil {
+				return err
+			}
+			if err := s.mark("daily_sales", id); err != nil {
+				return err
+			}
+		}
+
+		delivery, err := s.db.GetDeliverySalesForDate(dateStr)
+		if err != nil {
+			return err
+		}
+		if delivery != nil {
+			continue
+		}
+		grubhub := s.between(80, 240)
+		doordash := s.between(120, 320)
+		uber := s.between(60, 200)
+		if err := s.db.UpsertDeliverySales(models.DeliverySales{
+			Date: dateStr,
+			Platforms: []models.DeliveryPlatformSales{
+				{Platform: "grubhub", Subtotal: grubhub, Net: math.Round(grubhub*80) / 100},
+				{Platform: "doordash", Subtotal: doordash, Net: math.Round(doordash*75) / 100},
+				{Platform: "ubereats", Subtotal: uber, Net: math.Round(uber*72) / 100},
+			},
+			Notes: database.DemoNote,
+		}); err != nil {
+			return err
+		}
+		delivery, err = s.db.GetDeliverySalesForDate(dateStr)
+		if err != nil {
+			return err
+		}
+		if err := s.mark("delivery_sales", delivery.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedExpenses enters this month's and last month's receipts; the most recent few are left unpaid
+func (s *seeder) seedExpenses() error {
+	checkNumber := 5001
+	for monthsAgo := 1; monthsAgo >= 0; monthsAgo-- {
+		monthStart := time.Date(s.today.Year(), s.today.Month(), 1, 0, 0, 0, 0, time.Local).AddDate(0, -monthsAgo, 0)
+		for _, v := range demoVendors {
+			// Rent and pest control come once a month, supplies weekly
+			visits := 1
+			if v.low != v.high {
+				visits = 4
+			}
+			for i := 0; i < visits; i++ {
+				date := monthStart.AddDate(0, 0, 2+i*7+s.rng.Intn(3))
+				if date.After(s.today) {
+					continue
+				}
+				expense := models.Expense{
+					Date:          date.Format("2006-01-02"),
+					VendorID:      s.vendors[v.name],
+					Amount:        s.between(v.low, v.high),
+					InvoiceNumber: fmt.Sprintf("DEMO-%04d", s.rng.Intn(10000)),
+					Status:        "paid",
+					PaymentType:   v.paymentType,
+					DatePaid:      date.AddDate(0, 0, 1).Format("2006-01-02"),
+					Notes:         database.DemoNote,
+				}
+				if s.today.Sub(date) < 5*24*time.Hour {
+					expense.Status = "not_paid"
+					expense.PaymentType = ""
+					expense.DatePaid = ""
+				} else if v.paymentType == "check" {
+					expense.CheckNumber = fmt.Sprintf("%d", checkNumber)
+					checkNumber++
+				}
+				id, err := s.db.CreateExpense(expense)
+				if err != nil {
+					return err
+				}
+				if err := s.mark("expenses", id); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// seedPayroll enters the last full Monday-Sunday week as paid
+func (s *seeder) seedPayroll() error {
+	daysSinceMonday := (int(s.today.Weekday()) + 6) % 7
+	weekStart := s.today.AddDate(0, 0, -daysSinceMonday-7)
+	weekEnd := weekStart.AddDate(0, 0, 6)
+
+	weekID, err := s.db.GetOrCreatePayrollWeek(weekStart.Format("2006-01-02"), weekEnd.Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+	if err := s.mark("payroll_weeks", weekID); err != nil {
+		return err
+	}
+
+	for _, e := range s.employees {
+		id, err := s.db.CreatePayroll(models.Payroll{
+			WeekID:        weekID,
+			EmployeeID:    e.ID,
+			TotalHours:    math.Round(s.between(24, 42)*2) / 2,
+			HourlyRate:    e.HourlyRate,
+			PaymentMethod: e.PaymentMethod,
+			Status:        "paid",
+			DatePaid:      weekEnd.AddDate(0, 0, 1).Format("2006-01-02"),
+			Notes:         database.DemoNote,
+		})
+		if err != nil {
+			return err
+		}
+		if err := s.mark("payroll", id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedReconciliation builds last month's statement for a demo account: card batches and
+// delivery payouts in, last month's paid receipts and a fee out, ready to review
+func (s *seeder) seedReconciliation() error {
+	accountID, err := s.db.CreateAccount(models.Account{Name: "Demo Checking", LastFour: "0000"})
+	if err != nil {
+		return err
+	}
+	if err := s.mark("accounts", accountID); err != nil {
+		return err
+	}
+
+	monthStart := time.Date(s.today.Year(), s.today.Month(), 1, 0, 0, 0, 0, time.Local).AddDate(0, -1, 0)
+	monthEnd := monthStart.AddDate(0, 1, -1)
+
+	reconID, err := s.db.CreateReconciliation(models.BankReconciliation{
+		StatementDate: monthEnd.Format("2006-01-02"),
+		Status:        "pending",
+		Notes:         database.DemoNote,
+		AccountID:     accountID,
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.mark("bank_reconciliations", reconID); err != nil {
+		return err
+	}
+
+	var txns []models.BankTransaction
+	var deposits, payments, checks, fees float64
+
+	for day := monthStart; !day.After(monthEnd); day = day.AddDate(0, 0, 7) {
+		amount := s.between(6000, 9000)
+		deposits += amount
+		txns = append(txns, models.BankTransaction{
+			PostingDate:     day.Format("2006-01-02"),
+			Description:     "DEMO MERCHANT SVCS DEPOSIT",
+			Amount:          amount,
+			TransactionType: "deposit",
+			Category:        "income_cards",
+		})
+		payout := s.between(700, 1100)
+		deposits += payout
+		txns = append(txns, models.BankTransaction{
+			PostingDate:     day.AddDate(0, 0, 1).Format("2006-01-02"),
+			Description:     "DOORDASH PAYOUT DEMO",
+			Amount:          payout,
+			TransactionType: "ach",
+			Category:        "income_delivery",
+			Platform:        "doordash",
+		})
+	}
+
+	expenses, err := s.db.ListExpensesDateRange(monthStart.Format("2006-01-02"), monthEnd.Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+	matches := make(map[int]int64) // index into txns -> expense ID
+	for _, e := range expenses {
+		if e.Status != "paid" || e.Notes != database.DemoNote {
+			continue
+		}
+		txn := models.BankTransaction{
+			PostingDate: e.DatePaid,
+			Description: fmt.Sprintf("%s DEMO", e.VendorName),
+			Amount:      -e.Amount,
+			VendorHint:  e.VendorName,
+			Category:    "expense",
+		}
+		if e.PaymentType == "check" {
+			txn.TransactionType = "check"
+			txn.CheckNumber = e.CheckNumber
+			txn.Category = "expense_check"
+			txn.Description = "CHECK " + e.CheckNumber
+			checks += e.Amount
+		} else {
+			txn.TransactionType = "debit"
+			payments += e.Amount
+		}
+		matches[len(txns)] = e.ID
+		txns = append(txns, txn)
+	}
+
+	fees = 24.95
+	txns = append(txns, models.BankTransaction{
+		PostingDate:     monthEnd.Format("2006-01-02"),
+		Description:     "MONTHLY MAINTENANCE FEE",
+		Amount:          -fees,
+		TransactionType: "fee",
+		Category:        "fee",
+	})
+
+	starting := 12000.00
+	ending := math.Round((starting+deposits-payments-checks-fees)*100) / 100
+	if err := s.db.UpdateReconciliationParsed(reconID, starting, ending, "0000", deposits, payments, checks, fees, ending, true); err != nil {
+		return err
+	}
+
+	for i, txn := range txns {
+		txn.ReconciliationID = reconID
+		txn.MatchStatus = "unmatched"
+		id, err := s.db.CreateBankTransaction(&txn)
+		if err != nil {
+			return err
+		}
+		if err := s.mark("bank_transactions", id); err != nil {
+			return err
+		}
+		// Leave a couple of receipts unmatched so there is something to review
+		if expenseID, ok := matches[i]; ok && i%5 != 0 {
+			if err := s.db.MatchBankTransaction(id, expenseID, "auto_exact"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"homebooks/internal/database"
+	"homebooks/internal/models"
+)
+
+// TestSeedAndRemove seeds a fresh database, adds real rows that refer to the demo ones,
+// and checks the demo data comes out again without taking the real rows with it
+func TestSeedAndRemove(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "seed.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	if err := db.Init(); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+
+	s := &seeder{db: db, rng: rand.New(rand.NewSource(1)), today: time.Date(2026, 10, 16, 0, 0, 0, 0, time.Local)}
+	if err := s.run(); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	if has, err := db.HasDemoData(); err != nil || !has {
+		t.Fatalf("HasDemoData after seeding = %v, %v", has, err)
+	}
+
+	// A real receipt from a demo vendor, real payroll for a demo employee, and a real
+	// statement on the demo account with a transaction matched to a demo receipt
+	demoVendorID := s.vendors["Demo Meats"]
+	realExpenseID, err := db.CreateExpense(models.Expense{Date: "2026-10-15", VendorID: demoVendorID, Amount: 410, Status: "not_paid"})
+	if err != nil {
+		t.Fatalf("CreateExpense: %v", err)
+	}
+	demoEmployee := s.employees[0]
+	weekID, err := db.GetOrCreatePayrollWeek("2026-10-12", "2026-10-18")
+	if err != nil {
+		t.Fatalf("GetOrCreatePayrollWeek: %v", err)
+	}
+	realPayrollID, err := db.CreatePayroll(models.Payroll{WeekID: weekID, EmployeeID: demoEmployee.ID, TotalHours: 30, HourlyRate: demoEmployee.HourlyRate, PaymentMethod: "cash", Status: "not_paid"})
+	if err != nil {
+		t.Fatalf("CreatePayroll: %v", err)
+	}
+	accounts, err := db.ListAccounts()
+	if err != nil || len(accounts) != 1 {
+		t.Fatalf("ListAccounts = %d accounts, %v; want the demo account", len(accounts), err)
+	}
+	reconID, err := db.CreateReconciliation(models.BankReconciliation{StatementDate: "2026-10-31", Status: "parsed", AccountID: accounts[0].ID})
+	if err != nil {
+		t.Fatalf("CreateReconciliation: %v", err)
+	}
+	demoExpenses, _, err := db.ListExpenses(models.ExpenseFilter{VendorID: demoVendorID, Status: "paid"})
+	if err != nil || len(demoExpenses) == 0 {
+		t.Fatalf("ListExpenses = %d demo receipts, %v", len(demoExpenses), err)
+	}
+	txnID, err := db.CreateBankTransaction(&models.BankTransaction{
+		ReconciliationID: reconID, PostingDate: "2026-10-03", Description: "CHECK", Amount: -demoExpenses[0].Amount,
+		TransactionType: "check", Category: "expense_check", MatchStatus: "unmatched",
+	})
+	if err != nil {
+		t.Fatalf("CreateBankTransaction: %v", err)
+	}
+	if err := db.MatchBankTransaction(txnID, demoExpenses[0].ID, "manual"); err != nil {
+		t.Fatalf("MatchBankTransaction: %v", err)
+	}
+
+	removed, err := db.RemoveDemoData()
+	if err != nil {
+		t.Fatalf("RemoveDemoData: %v", err)
+	}
+	if removed == 0 {
+		t.Errorf("RemoveDemoData removed nothing")
+	}
+	if has, err := db.HasDemoData(); err != nil || has {
+		t.Errorf("HasDemoData after removal = %v, %v", has, err)
+	}
+
+	if _, err := db.GetExpense(realExpenseID); err != nil {
+		t.Errorf("real receipt removed: %v", err)
+	}
+	if _, err := db.GetVendor(demoVendorID); err != nil {
+		t.Errorf("demo vendor with a real receipt removed: %v", err)
+	}
+	if _, err := db.GetPayroll(realPayrollID); err != nil {
+		t.Errorf("real payroll removed: %v", err)
+	}
+	if _, err := db.GetEmployee(demoEmployee.ID); err != nil {
+		t.Errorf("demo employee with real payroll removed: %v", err)
+	}
+	if _, err := db.GetAccount(accounts[0].ID); err != nil {
+		t.Errorf("demo account with a real statement removed: %v", err)
+	}
+	txn, err := db.GetBankTransaction(txnID)
+	if err != nil {
+		t.Fatalf("real bank transaction removed: %v", err)
+	}
+	if txn.MatchStatus != "unmatched" || txn.MatchedExpenseID != nil {
+		t.Errorf("real transaction still matched to a removed receipt: %s", txn.MatchStatus)
+	}
+	if _, err := db.GetVendor(s.vendors["Demo Pest Control"]); err == nil {
+		t.Errorf("unreferenced demo vendor kept")
+	}
+	if _, err := db.GetEmployee(s.employees[1].ID); err == nil {
+		t.Errorf("unreferenced demo employee kept")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"homebooks/internal/auth"
 	"homebooks/internal/database"
@@ -14,6 +15,7 @@ import (
 	"homebooks/internal/handlers"
 	"homebooks/internal/jobs"
 	"homebooks/internal/logger"
+	"homebooks/internal/reconciliation"
 	"homebooks/internal/version"
 	"homebooks/web"
 )
@@ -57,7 +59,7 @@ func main() {
 	}
 
 	// Parse templates
-	tmpl, err := template.ParseFS(web.TemplatesFS, "templates/*.html")
+	tmpl, err := template.New("").Funcs(handlers.TemplateFuncs).ParseFS(web.TemplatesFS, "templates/*.html")
 	if err != nil {
 		log.Error("template_parse_failed", "error", err.Error())
 		os.Exit(1)
@@ -69,17 +71,21 @@ func main() {
 	// Clean expired sessions on startup
 	a.CleanExpiredSessions()
 
-	// Initialize filestore (in data/uploads directory alongside database)
+	// Initialize filestore (in data/uploads directory alongside database, or in the
+	// S3 bucket named by HOMEBOOKS_S3_BUCKET)
 	uploadsPath := filepath.Join(filepath.Dir(dbPath), "uploads")
-	files, err := filestore.New(uploadsPath)
+	files, err := filestore.NewFromEnv(uploadsPath)
 	if err != nil {
 		log.Error("filestore_init_failed", "path", uploadsPath, "error", err.Error())
 		os.Exit(1)
 	}
+	log.Info("filestore_ready", "location", files.FullPath(""))
 
 	// Initialize and start job worker
 	worker := jobs.NewWorker(db, log)
-	worker.Register("parse_statement", jobs.ParseStatementHandler(uploadsPath))
+	worker.Register("parse_statement", jobs.ParseStatementHandler(files, reconciliation.MatchOptionsFromEnv()))
+	worker.Register("backup", jobs.BackupHandler(filepath.Join(filepath.Dir(dbPath), "backups"), jobs.BackupKeepFromEnv()))
+	worker.Schedule("backup", 24*time.Hour)
 	worker.Start()
 	defer worker.Stop()
 
@@ -97,87 +103,7 @@ func main() {
 	}
 	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(staticFS)))
 
-	// Auth routes (no auth required)
-	mux.HandleFunc("GET /login", h.LoginPage)
-	mux.HandleFunc("POST /login", h.LoginSubmit)
-	mux.HandleFunc("POST /logout", h.Logout)
-
-	// Protected routes
-	mux.HandleFunc("GET /{$}", h.Dashboard)
-
-	// Sales
-	mux.HandleFunc("GET /sales", h.SalesList)
-	mux.HandleFunc("GET /sales/new", h.SalesNew)
-	mux.HandleFunc("POST /sales", h.SalesCreate)
-	mux.HandleFunc("GET /sales/{id}/edit", h.SalesEdit)
-	mux.HandleFunc("POST /sales/{id}", h.SalesUpdate)
-	mux.HandleFunc("POST /sales/{id}/delete", h.SalesDelete)
-	mux.HandleFunc("GET /api/sales/shifts", h.SalesShiftsAPI)
-
-	// Delivery Sales
-	mux.HandleFunc("GET /sales/delivery/new", h.DeliveryNew)
-	mux.HandleFunc("GET /sales/delivery/{date}/edit", h.DeliveryEdit)
-	mux.HandleFunc("POST /sales/delivery", h.DeliverySave)
-
-	// Expenses
-	mux.HandleFunc("GET /expenses", h.ExpensesList)
-	mux.HandleFunc("GET /expenses/new", h.ExpensesNew)
-	mux.HandleFunc("POST /expenses", h.ExpensesCreate)
-	mux.HandleFunc("GET /expenses/{id}/edit", h.ExpensesEdit)
-	mux.HandleFunc("POST /expenses/{id}", h.ExpensesUpdate)
-	mux.HandleFunc("GET /expenses/{id}/pay", h.ExpensesPayForm)
-	mux.HandleFunc("POST /expenses/{id}/pay", h.ExpensesPay)
-	mux.HandleFunc("POST /expenses/{id}/delete", h.ExpensesDelete)
-	mux.HandleFunc("GET /expenses/{id}/receipt", h.ExpensesDownloadReceipt)
-	mux.HandleFunc("POST /expenses/{id}/receipt", h.ExpensesUploadReceipt)
-	mux.HandleFunc("POST /expenses/{id}/receipt/delete", h.ExpensesDeleteReceipt)
-
-	// Payroll
-	mux.HandleFunc("GET /payroll", h.PayrollList)
-	mux.HandleFunc("POST /payroll/save", h.PayrollSaveHours)
-	mux.HandleFunc("GET /payroll/weeks/new", h.PayrollWeekNew)
-	mux.HandleFunc("GET /payroll/weeks/{id}/edit", h.PayrollWeekEdit)
-	mux.HandleFunc("GET /payroll/history/{id}", h.PayrollWeekDetail)
-	mux.HandleFunc("GET /payroll/new", h.PayrollNew)
-	mux.HandleFunc("POST /payroll", h.PayrollCreate)
-	mux.HandleFunc("GET /payroll/entry/{id}/edit", h.PayrollEdit)
-	mux.HandleFunc("POST /payroll/entry/{id}", h.PayrollUpdate)
-	mux.HandleFunc("POST /payroll/entry/{id}/pay", h.PayrollPay)
-	mux.HandleFunc("POST /payroll/entry/{id}/delete", h.PayrollDelete)
-
-	// Bank Statements
-	mux.HandleFunc("GET /bank-statements", h.ReconciliationsList)
-	mux.HandleFunc("POST /bank-statements/upload", h.ReconciliationsUpload)
-	mux.HandleFunc("GET /bank-statements/{id}", h.ReconciliationsReview)
-	mux.HandleFunc("POST /bank-statements/{id}/reparse", h.ReconciliationsReparse)
-	mux.HandleFunc("POST /bank-statements/{id}/complete", h.ReconciliationsComplete)
-	mux.HandleFunc("POST /bank-statements/{id}/match", h.ReconciliationsMatch)
-	mux.HandleFunc("POST /bank-statements/{id}/unmatch", h.ReconciliationsUnmatch)
-	mux.HandleFunc("POST /bank-statements/{id}/ignore", h.ReconciliationsIgnore)
-	mux.HandleFunc("POST /bank-statements/{id}/create-expense", h.ReconciliationsCreateExpense)
-	mux.HandleFunc("POST /bank-statements/{id}/update-type", h.ReconciliationsUpdateType)
-	mux.HandleFunc("POST /bank-statements/{id}/delete", h.ReconciliationsDelete)
-
-	// Jobs API
-	mux.HandleFunc("GET /api/jobs/{id}", h.JobStatus)
-
-	// Version API
-	mux.HandleFunc("GET /api/version", h.APIVersion)
-
-	// Vendors
-	mux.HandleFunc("GET /vendors", h.VendorsList)
-	mux.HandleFunc("GET /vendors/new", h.VendorsNew)
-	mux.HandleFunc("GET /vendors/{id}", h.VendorsShow)
-	mux.HandleFunc("POST /vendors", h.VendorsCreate)
-	mux.HandleFunc("GET /vendors/{id}/edit", h.VendorsEdit)
-	mux.HandleFunc("POST /vendors/{id}", h.VendorsUpdate)
-	mux.HandleFunc("POST /vendors/{id}/delete", h.VendorsDelete)
-
-	// Employees
-	mux.HandleFunc("GET /employees", h.EmployeesList)
-	mux.HandleFunc("POST /employees", h.EmployeesCreate)
-	mux.HandleFunc("POST /employees/{id}/deactivate", h.EmployeesDeactivate)
-	mux.HandleFunc("POST /employees/{id}/reactivate", h.EmployeesReactivate)
+	h.RegisterRoutes(mux)
 
 	// Wrap with middleware: logging -> auth -> mux
 	handler := logger.HTTPMiddleware(a.Middleware(mux))
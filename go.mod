@@ -2,4 +2,8 @@ module homebooks
 
 go 1.22
 
-require github.com/mattn/go-sqlite3 v1.14.33
+require (
+	github.com/mattn/go-sqlite3 v1.14.33
+	golang.org/x/crypto v0.33.0
+	golang.org/x/image v0.18.0
+)
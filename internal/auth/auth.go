@@ -5,38 +5,127 @@ import (
 	"crypto/rand"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"golang.org/x/crypto/bcrypt"
+
+	"homebooks/internal/filestore"
 	"homebooks/internal/logger"
 )
 
 const (
 	SessionCookieName = "homebooks_session"
 	SessionDuration   = 30 * 24 * time.Hour // 30 days
+	// ShortSessionDuration is how long a login lasts without "keep me signed in", about a
+	// shift on a shared terminal
+	ShortSessionDuration = 12 * time.Hour
+	// SessionRefreshInterval is how often an active session's expiry is pushed back out
+	// to SessionDuration from now, so sessions in use don't lapse
+	SessionRefreshInterval = 24 * time.Hour
 )
 
+// ErrWrongPassword is returned by ChangePassword when the current password doesn't match
+var ErrWrongPassword = errors.New("current password is incorrect")
+
+// passwordHashKey is the settings row holding the bcrypt hash of the login password
+const passwordHashKey = "password_hash"
+
 type Auth struct {
-	db       *sql.DB
-	password string
+	db              *sql.DB
+	password        string
+	defaultPassword atomic.Bool
+	// secureCookies marks the session cookie Secure, for deployments served over HTTPS
+	secureCookies bool
+	sameSite      http.SameSite
+	// maxUploadSize caps multipart bodies read while looking for the CSRF token
+	maxUploadSize int64
 }
 
+// New sets up auth. HOMEBOOKS_PASSWORD only seeds the login password: once a hash
+// is stored in settings (on first login, or when changed in the app) it wins
 func New(db *sql.DB) *Auth {
 	password := os.Getenv("HOMEBOOKS_PASSWORD")
 	if password == "" {
 		password = "changeme" // Default for development
 	}
-	return &Auth{db: db, password: password}
+	a := &Auth{db: db, password: password, maxUploadSize: filestore.MaxUploadSizeFromEnv()}
+	a.secureCookies, a.sameSite = cookieSecurityFromEnv()
+
+	if hash, err := a.storedHash(); err == nil && hash != "" {
+		a.defaultPassword.Store(bcrypt.CompareHashAndPassword([]byte(hash), []byte("changeme")) == nil)
+	} else {
+		a.defaultPassword.Store(password == "changeme")
+	}
+	return a
+}
+
+// cookieSecurityFromEnv reads HOMEBOOKS_SECURE_COOKIES. Any true value marks the session
+// cookie Secure; "strict" also tightens SameSite from Lax to Strict. Off by default so
+// plain HTTP works in development
+func cookieSecurityFromEnv() (bool, http.SameSite) {
+	value := strings.ToLower(strings.TrimSpace(os.Getenv("HOMEBOOKS_SECURE_COOKIES")))
+	if value == "strict" {
+		return true, http.SameSiteStrictMode
+	}
+	secure, _ := strconv.ParseBool(value)
+	return secure, http.SameSiteLaxMode
+}
+
+// UsingDefaultPassword reports whether the shared password is still the development
+// default and in use, i.e. no named users have been added
+func (a *Auth) UsingDefaultPassword() bool {
+	return a.defaultPassword.Load() && !a.HasUsers()
+}
+
+// Login verifies a sign-in. Once named users exist the name must match one of them;
+// before that the shared password is checked and no user is returned
+func (a *Auth) Login(ctx context.Context, name, password string) (*User, bool) {
+	if !a.HasUsers() {
+		return nil, a.CheckPassword(ctx, password)
+	}
+
+	l := logger.FromContext(ctx)
+	u, ok := a.checkUser(name, password)
+	if !ok {
+		l.Warn("auth_login_failed", "reason", "invalid_credentials", "name", name)
+		return nil, false
+	}
+	l.Info("auth_login_success", "user", u.Name)
+	return u, true
 }
 
-// CheckPassword verifies the provided password
+// CheckPassword verifies the shared password against the stored hash. Before a
+// hash exists it falls back to HOMEBOOKS_PASSWORD and stores the hash on success
 func (a *Auth) CheckPassword(ctx context.Context, password string) bool {
-	success := password == a.password
 	l := logger.FromContext(ctx)
 
+	hash, err := a.storedHash()
+	if err != nil {
+		l.Error("auth_password_hash_error", "error", err.Error())
+		return false
+	}
+
+	var success bool
+	if hash != "" {
+		success = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	} else {
+		success = password == a.password
+		if success {
+			if err := a.storePassword(password); err != nil {
+				l.Error("auth_password_hash_error", "error", err.Error())
+			} else {
+				l.Info("auth_password_hash_created")
+			}
+		}
+	}
+
 	if success {
 		l.Info("auth_login_success")
 	} else {
@@ -45,8 +134,79 @@ func (a *Auth) CheckPassword(ctx context.Context, password string) bool {
 	return success
 }
 
-// CreateSession creates a new session and returns the token
-func (a *Auth) CreateSession(ctx context.Context) (string, error) {
+// ChangePassword replaces the signed-in user's password, or the shared password when
+// no user is signed in, after verifying the current one
+func (a *Auth) ChangePassword(ctx context.Context, current, password string) error {
+	l := logger.FromContext(ctx)
+
+	if u := CurrentUser(ctx); u != nil {
+		if _, ok := a.checkUser(u.Name, current); !ok {
+			l.Warn("auth_password_change_failed", "reason", "invalid_password")
+			return ErrWrongPassword
+		}
+		if err := a.setUserPassword(u.ID, password); err != nil {
+			l.Error("auth_password_change_error", "error", err.Error())
+			return err
+		}
+		l.Info("auth_password_changed")
+		return nil
+	}
+
+	hash, err := a.storedHash()
+	if err != nil {
+		return err
+	}
+	if hash != "" {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(current)) != nil {
+			l.Warn("auth_password_change_failed", "reason", "invalid_password")
+			return ErrWrongPassword
+		}
+	} else if current != a.password {
+		l.Warn("auth_password_change_failed", "reason", "invalid_password")
+		return ErrWrongPassword
+	}
+
+	if err := a.storePassword(password); err != nil {
+		l.Error("auth_password_change_error", "error", err.Error())
+		return err
+	}
+	l.Info("auth_password_changed")
+	return nil
+}
+
+// storedHash returns the stored password hash, or "" if none has been stored yet
+func (a *Auth) storedHash() (string, error) {
+	var hash string
+	err := a.db.QueryRow(`SELECT value FROM settings WHERE key = ?`, passwordHashKey).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get password hash: %w", err)
+	}
+	return hash, nil
+}
+
+// storePassword hashes password and saves it as the login password
+func (a *Auth) storePassword(password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+	_, err = a.db.Exec(`
+		INSERT INTO settings (key, value, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP
+	`, passwordHashKey, string(hash))
+	if err != nil {
+		return fmt.Errorf("save password hash: %w", err)
+	}
+	a.defaultPassword.Store(password == "changeme")
+	return nil
+}
+
+// CreateSession creates a new session for user (nil for the shared password) and returns
+// the token. Unless remember is set the session lasts ShortSessionDuration
+func (a *Auth) CreateSession(ctx context.Context, user *User, remember bool) (string, error) {
 	l := logger.FromContext(ctx)
 
 	token, err := generateToken()
@@ -54,38 +214,103 @@ func (a *Auth) CreateSession(ctx context.Context) (string, error) {
 		l.Error("auth_session_create_error", "error", err.Error())
 		return "", err
 	}
+	csrf, err := generateToken()
+	if err != nil {
+		l.Error("auth_session_create_error", "error", err.Error())
+		return "", err
+	}
+
+	var userID sql.NullInt64
+	if user != nil {
+		userID = sql.NullInt64{Int64: user.ID, Valid: true}
+	}
 
-	expiresAt := time.Now().Add(SessionDuration)
+	now := time.Now()
+	expiresAt := now.Add(ShortSessionDuration)
+	if remember {
+		expiresAt = now.Add(SessionDuration)
+	}
 	_, err = a.db.Exec(`
-		INSERT INTO sessions (token, expires_at) VALUES (?, ?)
-	`, token, expiresAt)
+		INSERT INTO sessions (token, user_id, expires_at, csrf_token, last_seen_at, remember) VALUES (?, ?, ?, ?, ?, ?)
+	`, token, userID, expiresAt, csrf, now, remember)
 	if err != nil {
 		l.Error("auth_session_create_error", "error", err.Error())
 		return "", fmt.Errorf("create session: %w", err)
 	}
 
-	l.Info("auth_session_created", "expires_at", expiresAt.Format(time.RFC3339))
+	l.Info("auth_session_created", "expires_at", expiresAt.Format(time.RFC3339), "remember", remember)
 	return token, nil
 }
 
-// ValidateSession checks if the token is valid and not expired
-func (a *Auth) ValidateSession(ctx context.Context, token string) bool {
+// ValidateSession checks if the token is valid and not expired, returning the
+// session's user (nil for a shared-password session)
+func (a *Auth) ValidateSession(ctx context.Context, token string) (*User, bool) {
+	s, ok := a.validateSession(ctx, token)
+	return s.user, ok
+}
+
+// session is what a valid session token resolves to
+type session struct {
+	user     *User
+	csrf     string
+	remember bool
+	// refreshed is set when this request pushed the expiry out, so the cookie should be re-sent
+	refreshed bool
+}
+
+// validateSession is ValidateSession returning the whole session. A remembered session
+// not refreshed within SessionRefreshInterval gets its expiry extended; short sessions
+// end on schedule
+func (a *Auth) validateSession(ctx context.Context, token string) (session, bool) {
 	l := logger.FromContext(ctx)
 
 	var expiresAt time.Time
+	var lastSeen sql.NullTime
+	var userID sql.NullInt64
+	var csrf string
+	var remember bool
 	err := a.db.QueryRow(`
-		SELECT expires_at FROM sessions WHERE token = ?
-	`, token).Scan(&expiresAt)
+		SELECT expires_at, last_seen_at, user_id, COALESCE(csrf_token, ''), COALESCE(remember, 1) FROM sessions WHERE token = ?
+	`, token).Scan(&expiresAt, &lastSeen, &userID, &csrf, &remember)
 	if err != nil {
 		l.Debug("auth_session_invalid", "reason", "not_found")
-		return false
+		return session{}, false
 	}
 
-	if time.Now().After(expiresAt) {
+	now := time.Now()
+	if now.After(expiresAt) {
 		l.Debug("auth_session_invalid", "reason", "expired")
-		return false
+		return session{}, false
+	}
+
+	user := a.sessionUser(userID)
+	// Shared-password sessions end once named users exist, so everyone signs in by name
+	if user == nil && a.HasUsers() {
+		l.Debug("auth_session_invalid", "reason", "shared_password_session")
+		return session{}, false
+	}
+
+	s := session{user: user, csrf: csrf, remember: remember}
+	if remember && (!lastSeen.Valid || now.Sub(lastSeen.Time) >= SessionRefreshInterval) {
+		if err := a.refreshSession(token, now); err != nil {
+			l.Error("auth_session_refresh_error", "error", err.Error())
+		} else {
+			l.Debug("auth_session_refreshed")
+			s.refreshed = true
+		}
+	}
+	return s, true
+}
+
+// refreshSession marks a session seen at now and moves its expiry to SessionDuration later
+func (a *Auth) refreshSession(token string, now time.Time) error {
+	_, err := a.db.Exec(`
+		UPDATE sessions SET expires_at = ?, last_seen_at = ? WHERE token = ?
+	`, now.Add(SessionDuration), now, token)
+	if err != nil {
+		return fmt.Errorf("refresh session: %w", err)
 	}
-	return true
+	return nil
 }
 
 // DeleteSession removes a session
@@ -101,21 +326,28 @@ func (a *Auth) DeleteSession(ctx context.Context, token string) error {
 	return nil
 }
 
-// CleanExpiredSessions removes expired sessions
+// CleanExpiredSessions removes expired sessions. The cutoff is bound as a Go time so it
+// is written the same way as the expiries it is compared against
 func (a *Auth) CleanExpiredSessions() error {
-	_, err := a.db.Exec(`DELETE FROM sessions WHERE expires_at < datetime('now')`)
+	_, err := a.db.Exec(`DELETE FROM sessions WHERE expires_at < ?`, time.Now())
 	return err
 }
 
-// SetSessionCookie sets the session cookie on the response
-func (a *Auth) SetSessionCookie(w http.ResponseWriter, token string) {
+// SetSessionCookie sets the session cookie on the response. Without remember it is a
+// browser-session cookie, gone when the browser closes
+func (a *Auth) SetSessionCookie(w http.ResponseWriter, token string, remember bool) {
+	maxAge := 0
+	if remember {
+		maxAge = int(SessionDuration.Seconds())
+	}
 	http.SetCookie(w, &http.Cookie{
 		Name:     SessionCookieName,
 		Value:    token,
 		Path:     "/",
-		MaxAge:   int(SessionDuration.Seconds()),
+		MaxAge:   maxAge,
 		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
+		Secure:   a.secureCookies,
+		SameSite: a.sameSite,
 	})
 }
 
@@ -127,6 +359,8 @@ func (a *Auth) ClearSessionCookie(w http.ResponseWriter) {
 		Path:     "/",
 		MaxAge:   -1,
 		HttpOnly: true,
+		Secure:   a.secureCookies,
+		SameSite: a.sameSite,
 	})
 }
 
@@ -139,7 +373,8 @@ func (a *Auth) GetSessionFromRequest(r *http.Request) string {
 	return cookie.Value
 }
 
-// Middleware checks for valid session, redirects to login if not authenticated
+// Middleware checks for valid session, redirects to login if not authenticated. Any
+// request that changes data must also carry the session's CSRF token or gets a 403
 func (a *Auth) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -158,13 +393,37 @@ func (a *Auth) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
-		if !a.ValidateSession(ctx, token) {
+		s, ok := a.validateSession(ctx, token)
+		if !ok {
 			l.Debug("auth_redirect_to_login", "path", r.URL.Path)
 			http.Redirect(w, r, "/login", http.StatusFound)
 			return
 		}
+		if s.refreshed {
+			a.SetSessionCookie(w, token, s.remember)
+		}
 
-		next.ServeHTTP(w, r)
+		csrf, err := a.ensureCSRFToken(token, s.csrf)
+		if err != nil {
+			l.Error("auth_csrf_token_error", "error", err.Error())
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if !a.parseMultipart(w, r) {
+			return
+		}
+		if !checkCSRF(r, csrf) {
+			l.Warn("auth_csrf_rejected", "method", r.Method, "path", r.URL.Path)
+			http.Error(w, "This form has expired. Go back, reload the page and try again.", http.StatusForbidden)
+			return
+		}
+
+		if s.user != nil {
+			ctx = logger.WithUser(withUser(ctx, s.user), s.user.Name)
+		}
+		s.csrf = csrf
+		ctx = withSession(ctx, s)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"homebooks/internal/logger"
+)
+
+const (
+	// CSRFFieldName is the hidden form field carrying the session's CSRF token
+	CSRFFieldName = "csrf_token"
+	// CSRFHeaderName carries the token on requests made from scripts
+	CSRFHeaderName = "X-CSRF-Token"
+)
+
+// CSRFToken returns the CSRF token of the session making this request, or "" when
+// nobody is signed in
+func CSRFToken(ctx context.Context) string {
+	return sessionFrom(ctx).csrf
+}
+
+// ensureCSRFToken returns csrf, first issuing one for sessions created before tokens were stored
+func (a *Auth) ensureCSRFToken(session, csrf string) (string, error) {
+	if csrf != "" {
+		return csrf, nil
+	}
+	csrf, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+	if _, err := a.db.Exec(`UPDATE sessions SET csrf_token = ? WHERE token = ?`, csrf, session); err != nil {
+		return "", fmt.Errorf("save csrf token: %w", err)
+	}
+	return csrf, nil
+}
+
+// checkCSRF reports whether r may go ahead: reads always can, anything else must carry
+// the session's token in the form or the X-CSRF-Token header
+func checkCSRF(r *http.Request, csrf string) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	sent := r.Header.Get(CSRFHeaderName)
+	if sent == "" {
+		sent = r.PostFormValue(CSRFFieldName)
+	}
+	return csrf != "" && subtle.ConstantTimeCompare([]byte(sent), []byte(csrf)) == 1
+}
+
+// parseMultipart reads an upload form whose CSRF token is not in the header before the
+// token is checked, capped at the upload limit. Otherwise the form would be parsed with
+// no limit while looking for the token, before any handler's own cap applies. It
+// answers the request itself and returns false when the body is too large or malformed
+func (a *Auth) parseMultipart(w http.ResponseWriter, r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	if r.Header.Get(CSRFHeaderName) != "" {
+		return true
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "multipart/form-data" {
+		return true
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, a.maxUploadSize)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			logger.FromContext(r.Context()).Warn("auth_upload_too_large", "path", r.URL.Path, "limit", a.maxUploadSize)
+			http.Error(w, fmt.Sprintf("Upload is too large (limit is %d MB)", a.maxUploadSize>>20), http.StatusRequestEntityTooLarge)
+			return false
+		}
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
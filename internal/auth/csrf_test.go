@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"homebooks/internal/database"
+)
+
+// newTestSession returns auth over a migrated temp database, with one signed-in
+// session's token and CSRF token
+func newTestSession(t *testing.T) (a *Auth, token, csrf string) {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Init(); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+
+	a = New(db.DB)
+	token, err = a.CreateSession(context.Background(), nil, false)
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if err := db.QueryRow(`SELECT csrf_token FROM sessions WHERE token = ?`, token).Scan(&csrf); err != nil {
+		t.Fatalf("query csrf token: %v", err)
+	}
+	return a, token, csrf
+}
+
+func TestMiddlewareCSRF(t *testing.T) {
+	t.Setenv("HOMEBOOKS_MAX_UPLOAD_MB", "1")
+	a, token, csrf := newTestSession(t)
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	upload := func(size int, csrfField string) (string, *bytes.Buffer) {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField(CSRFFieldName, csrfField)
+		fw, _ := mw.CreateFormFile("statement_file", "statement.csv")
+		fw.Write(bytes.Repeat([]byte("x"), size))
+		mw.Close()
+		return mw.FormDataContentType(), &body
+	}
+
+	tests := []struct {
+		name string
+		req  func() *http.Request
+		want int
+	}{
+		{"form with the session's token", func() *http.Request {
+			return formRequest(url.Values{CSRFFieldName: {csrf}})
+		}, http.StatusNoContent},
+		{"form with another token", func() *http.Request {
+			return formRequest(url.Values{CSRFFieldName: {"not-the-token"}})
+		}, http.StatusForbidden},
+		{"form without a token", func() *http.Request {
+			return formRequest(url.Values{"amount": {"12"}})
+		}, http.StatusForbidden},
+		{"header with the session's token", func() *http.Request {
+			req := formRequest(nil)
+			req.Header.Set(CSRFHeaderName, csrf)
+			return req
+		}, http.StatusNoContent},
+		{"upload within the limit", func() *http.Request {
+			contentType, body := upload(100<<10, csrf)
+			req := httptest.NewRequest(http.MethodPost, "/bank-statements/preview", body)
+			req.Header.Set("Content-Type", contentType)
+			return req
+		}, http.StatusNoContent},
+		{"upload over the limit with the token in the form", func() *http.Request {
+			contentType, body := upload(3<<20, csrf)
+			req := httptest.NewRequest(http.MethodPost, "/bank-statements/preview", body)
+			req.Header.Set("Content-Type", contentType)
+			return req
+		}, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req()
+			req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: token})
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func formRequest(form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
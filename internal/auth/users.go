@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"homebooks/internal/logger"
+)
+
+// ErrUserExists is returned by CreateUser when the name is already taken
+var ErrUserExists = errors.New("a user with that name already exists")
+
+// User is a named login
+type User struct {
+	ID        int64
+	Name      string
+	CreatedAt time.Time
+}
+
+type userKey struct{}
+
+// CurrentUser returns the user signed in for this request, or nil when the shared
+// password is in use
+func CurrentUser(ctx context.Context) *User {
+	u, _ := ctx.Value(userKey{}).(*User)
+	return u
+}
+
+func withUser(ctx context.Context, u *User) context.Context {
+	return context.WithValue(ctx, userKey{}, u)
+}
+
+type sessionKey struct{}
+
+// KeepSignedIn reports whether the session making this request was started with
+// "keep me signed in"
+func KeepSignedIn(ctx context.Context) bool {
+	return sessionFrom(ctx).remember
+}
+
+func sessionFrom(ctx context.Context) session {
+	s, _ := ctx.Value(sessionKey{}).(session)
+	return s
+}
+
+func withSession(ctx context.Context, s session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// HasUsers reports whether any named users exist. Until one does, everyone signs in
+// with the shared password
+func (a *Auth) HasUsers() bool {
+	var count int
+	if err := a.db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		return false
+	}
+	return count > 0
+}
+
+// ListUsers returns all users by name
+func (a *Auth) ListUsers() ([]User, error) {
+	rows, err := a.db.Query(`SELECT id, name, created_at FROM users ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Name, &u.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan user: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// CreateUser adds a named login with a bcrypt hash of password
+func (a *Auth) CreateUser(ctx context.Context, name, password string) (int64, error) {
+	name = strings.TrimSpace(name)
+
+	var count int
+	if err := a.db.QueryRow(`SELECT COUNT(*) FROM users WHERE name = ?`, name).Scan(&count); err != nil {
+		return 0, fmt.Errorf("check user name: %w", err)
+	}
+	if count > 0 {
+		return 0, ErrUserExists
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, fmt.Errorf("hash password: %w", err)
+	}
+	result, err := a.db.Exec(`INSERT INTO users (name, password_hash) VALUES (?, ?)`, name, string(hash))
+	if err != nil {
+		return 0, fmt.Errorf("create user: %w", err)
+	}
+
+	logger.FromContext(ctx).Info("auth_user_created", "name", name)
+	return result.LastInsertId()
+}
+
+// DeleteUser removes a user; their sessions go with them
+func (a *Auth) DeleteUser(ctx context.Context, id int64) error {
+	if _, err := a.db.Exec(`DELETE FROM users WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+	logger.FromContext(ctx).Info("auth_user_deleted", "user_id", id)
+	return nil
+}
+
+// checkUser verifies a named login, returning the user on success
+func (a *Auth) checkUser(name, password string) (*User, bool) {
+	var u User
+	var hash string
+	err := a.db.QueryRow(`
+		SELECT id, name, password_hash, created_at FROM users WHERE name = ?
+	`, strings.TrimSpace(name)).Scan(&u.ID, &u.Name, &hash, &u.CreatedAt)
+	if err != nil {
+		return nil, false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return nil, false
+	}
+	return &u, true
+}
+
+// setUserPassword replaces a user's password hash
+func (a *Auth) setUserPassword(id int64, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+	if _, err := a.db.Exec(`UPDATE users SET password_hash = ? WHERE id = ?`, string(hash), id); err != nil {
+		return fmt.Errorf("update user password: %w", err)
+	}
+	return nil
+}
+
+// sessionUser returns the user a session belongs to, or nil for a shared-password session
+func (a *Auth) sessionUser(userID sql.NullInt64) *User {
+	if !userID.Valid {
+		return nil
+	}
+	var u User
+	err := a.db.QueryRow(`SELECT id, name, created_at FROM users WHERE id = ?`, userID.Int64).
+		Scan(&u.ID, &u.Name, &u.CreatedAt)
+	if err != nil {
+		return nil
+	}
+	return &u
+}
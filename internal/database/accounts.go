@@ -0,0 +1,108 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"homebooks/internal/models"
+)
+
+// ListAccounts returns all bank accounts ordered by name
+func (db *DB) ListAccounts() ([]models.Account, error) {
+	rows, err := db.Query(`SELECT id, name, last_four, created_at FROM accounts ORDER BY name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("query accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []models.Account
+	for rows.Next() {
+		var a models.Account
+		if err := rows.Scan(&a.ID, &a.Name, &a.LastFour, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan account: %w", err)
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+// GetAccount returns a single bank account by ID
+func (db *DB) GetAccount(id int64) (models.Account, error) {
+	var a models.Account
+	err := db.QueryRow(`SELECT id, name, last_four, created_at FROM accounts WHERE id = ?`, id).
+		Scan(&a.ID, &a.Name, &a.LastFour, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return a, fmt.Errorf("account not found")
+	}
+	if err != nil {
+		return a, fmt.Errorf("query account: %w", err)
+	}
+	return a, nil
+}
+
+// CreateAccount inserts a new bank account
+func (db *DB) CreateAccount(a models.Account) (int64, error) {
+	result, err := db.Exec(`INSERT INTO accounts (name, last_four) VALUES (?, ?)`, a.Name, a.LastFour)
+	if err != nil {
+		return 0, fmt.Errorf("insert account: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// FillAccountLastFour records the last four digits parsed from a statement on its account
+// if the account doesn't have them yet
+func (db *DB) FillAccountLastFour(reconciliationID int64, lastFour string) error {
+	if lastFour == "" {
+		return nil
+	}
+	_, err := db.Exec(`
+		UPDATE accounts SET last_four = ?
+		WHERE last_four = '' AND id = (SELECT account_id FROM bank_reconciliations WHERE id = ?)
+	`, lastFour, reconciliationID)
+	if err != nil {
+		return fmt.Errorf("fill account last four: %w", err)
+	}
+	return nil
+}
+
+// assignReconciliationAccounts gives statements uploaded before accounts existed an account,
+// one per distinct last four digits
+func (db *DB) assignReconciliationAccounts() error {
+	rows, err := db.Query(`SELECT DISTINCT account_last_four FROM bank_reconciliations WHERE account_id IS NULL`)
+	if err != nil {
+		return fmt.Errorf("query unassigned reconciliations: %w", err)
+	}
+	var lastFours []string
+	for rows.Next() {
+		var lastFour string
+		if err := rows.Scan(&lastFour); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan account last four: %w", err)
+		}
+		lastFours = append(lastFours, lastFour)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("read unassigned reconciliations: %w", err)
+	}
+
+	for _, lastFour := range lastFours {
+		var accountID int64
+		err := db.QueryRow(`SELECT id FROM accounts WHERE last_four = ? ORDER BY id LIMIT 1`, lastFour).Scan(&accountID)
+		if err == sql.ErrNoRows {
+			name := "Checking"
+			if lastFour != "" {
+				name = "Account " + lastFour
+			}
+			accountID, err = db.CreateAccount(models.Account{Name: name, LastFour: lastFour})
+		}
+		if err != nil {
+			return fmt.Errorf("find account for %q: %w", lastFour, err)
+		}
+		if _, err := db.Exec(`UPDATE bank_reconciliations SET account_id = ? WHERE account_id IS NULL AND account_last_four = ?`,
+			accountID, lastFour); err != nil {
+			return fmt.Errorf("assign reconciliation account: %w", err)
+		}
+	}
+	return nil
+}
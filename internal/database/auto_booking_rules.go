@@ -0,0 +1,53 @@
+package database
+
+import (
+	"fmt"
+
+	"homebooks/internal/models"
+)
+
+// ListAutoBookingRules returns all auto-booking rules, optionally limited to one vendor (0 = all)
+func (db *DB) ListAutoBookingRules(vendorID int64) ([]models.AutoBookingRule, error) {
+	rows, err := db.Query(`
+		SELECT r.id, r.vendor_id, v.name, r.category, r.pattern, r.created_at
+		FROM auto_booking_rules r
+		JOIN vendors v ON r.vendor_id = v.id
+		WHERE ? = 0 OR r.vendor_id = ?
+		ORDER BY r.id
+	`, vendorID, vendorID)
+	if err != nil {
+		return nil, fmt.Errorf("query auto-booking rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []models.AutoBookingRule
+	for rows.Next() {
+		var rule models.AutoBookingRule
+		if err := rows.Scan(&rule.ID, &rule.VendorID, &rule.VendorName, &rule.Category,
+			&rule.Pattern, &rule.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan auto-booking rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// CreateAutoBookingRule inserts a new auto-booking rule
+func (db *DB) CreateAutoBookingRule(rule models.AutoBookingRule) (int64, error) {
+	result, err := db.Exec(`
+		INSERT INTO auto_booking_rules (vendor_id, category, pattern) VALUES (?, ?, ?)
+	`, rule.VendorID, rule.Category, rule.Pattern)
+	if err != nil {
+		return 0, fmt.Errorf("insert auto-booking rule: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// DeleteAutoBookingRule removes an auto-booking rule
+func (db *DB) DeleteAutoBookingRule(id int64) error {
+	_, err := db.Exec(`DELETE FROM auto_booking_rules WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete auto-booking rule: %w", err)
+	}
+	return nil
+}
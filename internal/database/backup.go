@@ -0,0 +1,18 @@
+package database
+
+import (
+	"fmt"
+	"os"
+)
+
+// BackupTo writes a consistent copy of the database to path with VACUUM INTO, which
+// is safe while the app is running. path must not exist or must be an empty file
+func (db *DB) BackupTo(path string) error {
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+		return fmt.Errorf("backup database: %s already exists", path)
+	}
+	if _, err := db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("backup database: %w", err)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"homebooks/internal/models"
 )
@@ -12,10 +13,10 @@ func (db *DB) CreateBankTransaction(txn *models.BankTransaction) (int64, error)
 	result, err := db.Exec(`
 		INSERT INTO bank_transactions (
 			reconciliation_id, posting_date, description, amount, transaction_type,
-			category, check_number, vendor_hint, reference_number
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			category, platform, check_number, vendor_hint, reference_number
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, txn.ReconciliationID, txn.PostingDate, txn.Description, txn.Amount, txn.TransactionType,
-		txn.Category, txn.CheckNumber, txn.VendorHint, txn.ReferenceNumber)
+		txn.Category, txn.Platform, txn.CheckNumber, txn.VendorHint, txn.ReferenceNumber)
 	if err != nil {
 		return 0, fmt.Errorf("insert bank transaction: %w", err)
 	}
@@ -26,10 +27,11 @@ func (db *DB) CreateBankTransaction(txn *models.BankTransaction) (int64, error)
 func (db *DB) GetBankTransactions(reconciliationID int64) ([]models.BankTransaction, error) {
 	rows, err := db.Query(`
 		SELECT bt.id, bt.reconciliation_id, date(bt.posting_date), bt.description, bt.amount,
-			   bt.transaction_type, bt.category, bt.check_number, bt.vendor_hint, bt.reference_number,
+			   bt.transaction_type, bt.category, bt.platform, bt.check_number, bt.vendor_hint, bt.reference_number,
 			   bt.matched_expense_id, bt.match_status, bt.match_confidence, bt.matched_at,
 			   bt.notes, bt.created_at,
-			   COALESCE(v.name, ''), COALESCE(date(e.date), '')
+			   COALESCE(v.name, e.payee_name, ''), COALESCE(date(e.date), ''),
+			   bt.matched_expense_id IS NOT NULL AND (e.id IS NULL OR e.deleted_at IS NOT NULL)
 		FROM bank_transactions bt
 		LEFT JOIN expenses e ON bt.matched_expense_id = e.id
 		LEFT JOIN vendors v ON e.vendor_id = v.id
@@ -47,10 +49,10 @@ func (db *DB) GetBankTransactions(reconciliationID int64) ([]models.BankTransact
 		var matchedExpenseID sql.NullInt64
 		var matchedAt sql.NullTime
 		if err := rows.Scan(&t.ID, &t.ReconciliationID, &t.PostingDate, &t.Description, &t.Amount,
-			&t.TransactionType, &t.Category, &t.CheckNumber, &t.VendorHint, &t.ReferenceNumber,
+			&t.TransactionType, &t.Category, &t.Platform, &t.CheckNumber, &t.VendorHint, &t.ReferenceNumber,
 			&matchedExpenseID, &t.MatchStatus, &t.MatchConfidence, &matchedAt,
 			&t.Notes, &t.CreatedAt,
-			&t.MatchedExpenseVendor, &t.MatchedExpenseDate); err != nil {
+			&t.MatchedExpenseVendor, &t.MatchedExpenseDate, &t.MatchedExpenseDeleted); err != nil {
 			return nil, fmt.Errorf("scan bank transaction: %w", err)
 		}
 		if matchedExpenseID.Valid {
@@ -64,6 +66,75 @@ func (db *DB) GetBankTransactions(reconciliationID int64) ([]models.BankTransact
 	return transactions, rows.Err()
 }
 
+// GetBankTransactionsPaged returns one page of a reconciliation's transactions matching the
+// filter, along with how many match in total
+func (db *DB) GetBankTransactionsPaged(reconciliationID int64, filter models.BankTransactionFilter) ([]models.BankTransaction, int, error) {
+	where := "bt.reconciliation_id = ?"
+	args := []interface{}{reconciliationID}
+	switch filter.Direction {
+	case "credits":
+		where += " AND bt.amount >= 0"
+	case "debits":
+		where += " AND bt.amount < 0"
+	}
+	if filter.MatchStatus != "" {
+		where += " AND bt.match_status = ?"
+		args = append(args, filter.MatchStatus)
+	}
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM bank_transactions bt WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count bank transactions: %w", err)
+	}
+
+	page := filter.Page
+	if page < 1 {
+		page = 1
+	}
+	args = append(args, filter.PerPage, (page-1)*filter.PerPage)
+
+	rows, err := db.Query(`
+		SELECT bt.id, bt.reconciliation_id, date(bt.posting_date), bt.description, bt.amount,
+			   bt.transaction_type, bt.category, bt.platform, bt.check_number, bt.vendor_hint, bt.reference_number,
+			   bt.matched_expense_id, bt.match_status, bt.match_confidence, bt.matched_at,
+			   bt.notes, bt.created_at,
+			   COALESCE(v.name, e.payee_name, ''), COALESCE(date(e.date), ''),
+			   bt.matched_expense_id IS NOT NULL AND (e.id IS NULL OR e.deleted_at IS NOT NULL)
+		FROM bank_transactions bt
+		LEFT JOIN expenses e ON bt.matched_expense_id = e.id
+		LEFT JOIN vendors v ON e.vendor_id = v.id
+		WHERE `+where+`
+		ORDER BY bt.posting_date, bt.id
+		LIMIT ? OFFSET ?
+	`, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query bank transactions page: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []models.BankTransaction
+	for rows.Next() {
+		var t models.BankTransaction
+		var matchedExpenseID sql.NullInt64
+		var matchedAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.ReconciliationID, &t.PostingDate, &t.Description, &t.Amount,
+			&t.TransactionType, &t.Category, &t.Platform, &t.CheckNumber, &t.VendorHint, &t.ReferenceNumber,
+			&matchedExpenseID, &t.MatchStatus, &t.MatchConfidence, &matchedAt,
+			&t.Notes, &t.CreatedAt,
+			&t.MatchedExpenseVendor, &t.MatchedExpenseDate, &t.MatchedExpenseDeleted); err != nil {
+			return nil, 0, fmt.Errorf("scan bank transaction: %w", err)
+		}
+		if matchedExpenseID.Valid {
+			t.MatchedExpenseID = &matchedExpenseID.Int64
+		}
+		if matchedAt.Valid {
+			t.MatchedAt = &matchedAt.Time
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions, total, rows.Err()
+}
+
 // GetBankTransaction returns a single transaction by ID
 func (db *DB) GetBankTransaction(id int64) (*models.BankTransaction, error) {
 	var t models.BankTransaction
@@ -71,19 +142,20 @@ func (db *DB) GetBankTransaction(id int64) (*models.BankTransaction, error) {
 	var matchedAt sql.NullTime
 	err := db.QueryRow(`
 		SELECT bt.id, bt.reconciliation_id, date(bt.posting_date), bt.description, bt.amount,
-			   bt.transaction_type, bt.category, bt.check_number, bt.vendor_hint, bt.reference_number,
+			   bt.transaction_type, bt.category, bt.platform, bt.check_number, bt.vendor_hint, bt.reference_number,
 			   bt.matched_expense_id, bt.match_status, bt.match_confidence, bt.matched_at,
 			   bt.notes, bt.created_at,
-			   COALESCE(v.name, ''), COALESCE(date(e.date), '')
+			   COALESCE(v.name, e.payee_name, ''), COALESCE(date(e.date), ''),
+			   bt.matched_expense_id IS NOT NULL AND (e.id IS NULL OR e.deleted_at IS NOT NULL)
 		FROM bank_transactions bt
 		LEFT JOIN expenses e ON bt.matched_expense_id = e.id
 		LEFT JOIN vendors v ON e.vendor_id = v.id
 		WHERE bt.id = ?
 	`, id).Scan(&t.ID, &t.ReconciliationID, &t.PostingDate, &t.Description, &t.Amount,
-		&t.TransactionType, &t.Category, &t.CheckNumber, &t.VendorHint, &t.ReferenceNumber,
+		&t.TransactionType, &t.Category, &t.Platform, &t.CheckNumber, &t.VendorHint, &t.ReferenceNumber,
 		&matchedExpenseID, &t.MatchStatus, &t.MatchConfidence, &matchedAt,
 		&t.Notes, &t.CreatedAt,
-		&t.MatchedExpenseVendor, &t.MatchedExpenseDate)
+		&t.MatchedExpenseVendor, &t.MatchedExpenseDate, &t.MatchedExpenseDeleted)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("bank transaction not found")
 	}
@@ -103,7 +175,7 @@ func (db *DB) GetBankTransaction(id int64) (*models.BankTransaction, error) {
 func (db *DB) GetUnmatchedBankTransactions(reconciliationID int64) ([]models.BankTransaction, error) {
 	rows, err := db.Query(`
 		SELECT id, reconciliation_id, date(posting_date), description, amount,
-			   transaction_type, category, check_number, vendor_hint, reference_number,
+			   transaction_type, category, platform, check_number, vendor_hint, reference_number,
 			   matched_expense_id, match_status, match_confidence, matched_at,
 			   notes, created_at
 		FROM bank_transactions
@@ -121,7 +193,7 @@ func (db *DB) GetUnmatchedBankTransactions(reconciliationID int64) ([]models.Ban
 		var matchedExpenseID sql.NullInt64
 		var matchedAt sql.NullTime
 		if err := rows.Scan(&t.ID, &t.ReconciliationID, &t.PostingDate, &t.Description, &t.Amount,
-			&t.TransactionType, &t.Category, &t.CheckNumber, &t.VendorHint, &t.ReferenceNumber,
+			&t.TransactionType, &t.Category, &t.Platform, &t.CheckNumber, &t.VendorHint, &t.ReferenceNumber,
 			&matchedExpenseID, &t.MatchStatus, &t.MatchConfidence, &matchedAt,
 			&t.Notes, &t.CreatedAt); err != nil {
 			return nil, fmt.Errorf("scan bank transaction: %w", err)
@@ -163,6 +235,19 @@ func (db *DB) IgnoreBankTransaction(txnID int64, reason string) error {
 	return nil
 }
 
+// MarkBankTransactionPersonal flags a transaction as a personal charge that stays out of business totals
+func (db *DB) MarkBankTransactionPersonal(txnID int64, note string) error {
+	_, err := db.Exec(`
+		UPDATE bank_transactions
+		SET match_status = 'personal', notes = ?, matched_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND match_status = 'unmatched'
+	`, note, txnID)
+	if err != nil {
+		return fmt.Errorf("mark bank transaction personal: %w", err)
+	}
+	return nil
+}
+
 // MarkBankTransactionCreated marks a transaction as having a created expense
 func (db *DB) MarkBankTransactionCreated(txnID, expenseID int64) error {
 	_, err := db.Exec(`
@@ -176,6 +261,21 @@ func (db *DB) MarkBankTransactionCreated(txnID, expenseID int64) error {
 	return nil
 }
 
+// IgnoreBankTransactionsByType ignores every unmatched transaction of a type on a
+// reconciliation, e.g. transfers between the owner's accounts. Matched, created and
+// personal transactions are left alone. Returns the number ignored
+func (db *DB) IgnoreBankTransactionsByType(reconciliationID int64, txnType, reason string) (int64, error) {
+	result, err := db.Exec(`
+		UPDATE bank_transactions
+		SET match_status = 'ignored', notes = ?, matched_at = CURRENT_TIMESTAMP
+		WHERE reconciliation_id = ? AND transaction_type = ? AND match_status = 'unmatched'
+	`, reason, reconciliationID, txnType)
+	if err != nil {
+		return 0, fmt.Errorf("ignore bank transactions by type: %w", err)
+	}
+	return result.RowsAffected()
+}
+
 // UnmatchBankTransaction removes the match from a transaction
 func (db *DB) UnmatchBankTransaction(txnID int64) error {
 	_, err := db.Exec(`
@@ -198,6 +298,16 @@ func (db *DB) UpdateBankTransactionType(txnID int64, txnType string) error {
 	return nil
 }
 
+// UpdateBankTransactionCategory corrects a transaction's category (and delivery platform)
+// without touching its type, amount or match status
+func (db *DB) UpdateBankTransactionCategory(txnID int64, category, platform string) error {
+	_, err := db.Exec(`UPDATE bank_transactions SET category = ?, platform = ? WHERE id = ?`, category, platform, txnID)
+	if err != nil {
+		return fmt.Errorf("update bank transaction category: %w", err)
+	}
+	return nil
+}
+
 // UpdateBankTransactionTypeAndSign updates the transaction type and adjusts amount sign
 // Also marks deposits as matched since they correspond to sales, not expenses
 func (db *DB) UpdateBankTransactionTypeAndSign(txnID int64, txnType string, shouldBePositive bool) error {
@@ -219,6 +329,18 @@ func (db *DB) UpdateBankTransactionTypeAndSign(txnID int64, txnType string, shou
 	return nil
 }
 
+// UpdateBankTransactionFields corrects a transaction's date, description and amount, e.g.
+// after a bad OCR read. Its type, category and match are left as they are
+func (db *DB) UpdateBankTransactionFields(txnID int64, postingDate, description string, amount float64) error {
+	_, err := db.Exec(`
+		UPDATE bank_transactions SET posting_date = ?, description = ?, amount = ? WHERE id = ?
+	`, postingDate, description, amount, txnID)
+	if err != nil {
+		return fmt.Errorf("update bank transaction fields: %w", err)
+	}
+	return nil
+}
+
 // DeleteBankTransactions deletes all transactions for a reconciliation
 func (db *DB) DeleteBankTransactions(reconciliationID int64) error {
 	_, err := db.Exec(`DELETE FROM bank_transactions WHERE reconciliation_id = ?`, reconciliationID)
@@ -237,10 +359,15 @@ type ReconciliationStats struct {
 	UnmatchedCount     int
 	IgnoredCount       int
 	CreatedCount       int
+	PersonalCount      int
+	PersonalDebits     float64 // included in TotalDebits so the balance check still ties out
 	ElectronicDeposits float64
 	ElectronicPayments float64
 	ChecksPaid         float64
 	ServiceFees        float64
+	// Review is complete once nothing is left unmatched
+	ReviewComplete  bool
+	PercentReviewed float64
 }
 
 func (db *DB) GetReconciliationStats(reconciliationID int64) (*ReconciliationStats, error) {
@@ -254,17 +381,152 @@ func (db *DB) GetReconciliationStats(reconciliationID int64) (*ReconciliationSta
 			COALESCE(SUM(CASE WHEN match_status = 'unmatched' THEN 1 ELSE 0 END), 0),
 			COALESCE(SUM(CASE WHEN match_status = 'ignored' THEN 1 ELSE 0 END), 0),
 			COALESCE(SUM(CASE WHEN match_status = 'created' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN match_status = 'personal' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN match_status = 'personal' AND amount < 0 THEN ABS(amount) ELSE 0 END), 0),
 			COALESCE(SUM(CASE WHEN transaction_type = 'deposit' AND amount > 0 THEN amount ELSE 0 END), 0),
-			COALESCE(SUM(CASE WHEN transaction_type IN ('ach', 'debit') AND amount < 0 THEN ABS(amount) ELSE 0 END), 0),
-			COALESCE(SUM(CASE WHEN transaction_type = 'check' AND amount < 0 THEN ABS(amount) ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN transaction_type IN ('ach', 'debit') AND amount < 0 AND match_status != 'personal' THEN ABS(amount) ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN transaction_type = 'check' AND amount < 0 AND match_status != 'personal' THEN ABS(amount) ELSE 0 END), 0),
 			COALESCE(SUM(CASE WHEN transaction_type = 'fee' THEN ABS(amount) ELSE 0 END), 0)
 		FROM bank_transactions
 		WHERE reconciliation_id = ?
 	`, reconciliationID).Scan(&stats.TotalTransactions, &stats.TotalCredits, &stats.TotalDebits,
 		&stats.MatchedCount, &stats.UnmatchedCount, &stats.IgnoredCount, &stats.CreatedCount,
+		&stats.PersonalCount, &stats.PersonalDebits,
 		&stats.ElectronicDeposits, &stats.ElectronicPayments, &stats.ChecksPaid, &stats.ServiceFees)
 	if err != nil {
 		return nil, fmt.Errorf("query reconciliation stats: %w", err)
 	}
+	stats.ReviewComplete = stats.UnmatchedCount == 0
+	stats.PercentReviewed = percentReviewed(stats.TotalTransactions, stats.UnmatchedCount)
 	return &stats, nil
 }
+
+// ReconciliationProgress is how much of a statement's review is done, for the list view
+type ReconciliationProgress struct {
+	TotalTransactions int
+	UnmatchedCount    int
+}
+
+// ReviewComplete reports whether nothing is left unmatched
+func (p ReconciliationProgress) ReviewComplete() bool {
+	return p.UnmatchedCount == 0
+}
+
+// PercentReviewed is the share of transactions no longer unmatched
+func (p ReconciliationProgress) PercentReviewed() float64 {
+	return percentReviewed(p.TotalTransactions, p.UnmatchedCount)
+}
+
+// GetReconciliationProgress counts total and unmatched transactions for each of the
+// given reconciliations in one query. Reconciliations without transactions are absent
+func (db *DB) GetReconciliationProgress(ids []int64) (map[int64]ReconciliationProgress, error) {
+	progress := make(map[int64]ReconciliationProgress)
+	if len(ids) == 0 {
+		return progress, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT reconciliation_id, COUNT(*),
+			   COALESCE(SUM(CASE WHEN match_status = 'unmatched' THEN 1 ELSE 0 END), 0)
+		FROM bank_transactions
+		WHERE reconciliation_id IN (%s)
+		GROUP BY reconciliation_id
+	`, strings.Join(placeholders, ",")), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query reconciliation progress: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var p ReconciliationProgress
+		if err := rows.Scan(&id, &p.TotalTransactions, &p.UnmatchedCount); err != nil {
+			return nil, fmt.Errorf("scan reconciliation progress: %w", err)
+		}
+		progress[id] = p
+	}
+	return progress, rows.Err()
+}
+
+func percentReviewed(total, unmatched int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(total-unmatched) / float64(total) * 100
+}
+
+// BusinessDebits returns total debits excluding personal charges
+func (s ReconciliationStats) BusinessDebits() float64 {
+	return s.TotalDebits - s.PersonalDebits
+}
+
+// VendorHintTotal is the count and total of debits sharing a vendor hint
+type VendorHintTotal struct {
+	VendorHint string
+	Count      int
+	Total      float64
+}
+
+// GetDebitsByVendorHint groups a reconciliation's debits with the given match status
+// by vendor hint, largest total first
+func (db *DB) GetDebitsByVendorHint(reconciliationID int64, matchStatus string) ([]VendorHintTotal, error) {
+	rows, err := db.Query(`
+		SELECT vendor_hint, COUNT(*), SUM(ABS(amount))
+		FROM bank_transactions
+		WHERE reconciliation_id = ? AND match_status = ? AND amount < 0
+		GROUP BY vendor_hint
+		ORDER BY SUM(ABS(amount)) DESC
+	`, reconciliationID, matchStatus)
+	if err != nil {
+		return nil, fmt.Errorf("query debits by vendor hint: %w", err)
+	}
+	defer rows.Close()
+
+	var totals []VendorHintTotal
+	for rows.Next() {
+		var t VendorHintTotal
+		if err := rows.Scan(&t.VendorHint, &t.Count, &t.Total); err != nil {
+			return nil, fmt.Errorf("scan vendor hint total: %w", err)
+		}
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
+
+// TransactionTypeCount is the number of transactions of one type
+type TransactionTypeCount struct {
+	TransactionType string
+	Count           int
+}
+
+// GetUnmatchedTransactionTypes counts a reconciliation's unmatched transactions by type
+func (db *DB) GetUnmatchedTransactionTypes(reconciliationID int64) ([]TransactionTypeCount, error) {
+	rows, err := db.Query(`
+		SELECT transaction_type, COUNT(*)
+		FROM bank_transactions
+		WHERE reconciliation_id = ? AND match_status = 'unmatched'
+		GROUP BY transaction_type
+		ORDER BY COUNT(*) DESC, transaction_type
+	`, reconciliationID)
+	if err != nil {
+		return nil, fmt.Errorf("query unmatched transaction types: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []TransactionTypeCount
+	for rows.Next() {
+		var c TransactionTypeCount
+		if err := rows.Scan(&c.TransactionType, &c.Count); err != nil {
+			return nil, fmt.Errorf("scan transaction type count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
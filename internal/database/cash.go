@@ -0,0 +1,129 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"homebooks/internal/models"
+)
+
+// ListCashDeposits returns cash deposits, most recent first
+func (db *DB) ListCashDeposits() ([]models.CashDeposit, error) {
+	rows, err := db.Query(`
+		SELECT id, date(date), amount, notes, created_at
+		FROM cash_deposits
+		ORDER BY date(date) DESC, id DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query cash deposits: %w", err)
+	}
+	defer rows.Close()
+
+	var deposits []models.CashDeposit
+	for rows.Next() {
+		var d models.CashDeposit
+		if err := rows.Scan(&d.ID, &d.Date, &d.Amount, &d.Notes, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan cash deposit: %w", err)
+		}
+		deposits = append(deposits, d)
+	}
+	return deposits, rows.Err()
+}
+
+func (db *DB) CreateCashDeposit(d models.CashDeposit) (int64, error) {
+	result, err := db.Exec(`
+		INSERT INTO cash_deposits (date, amount, notes) VALUES (?, ?, ?)
+	`, d.Date, d.Amount, d.Notes)
+	if err != nil {
+		return 0, fmt.Errorf("insert cash deposit: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+func (db *DB) DeleteCashDeposit(id int64) error {
+	_, err := db.Exec(`DELETE FROM cash_deposits WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete cash deposit: %w", err)
+	}
+	return nil
+}
+
+// GetCashLedger rolls undeposited cash forward day by day, starting from openingFloat
+// on the first day with sales or deposits. Days are returned oldest first.
+func (db *DB) GetCashLedger(openingFloat float64) ([]models.CashLedgerDay, error) {
+	days := make(map[string]*models.CashLedgerDay)
+	day := func(date string) *models.CashLedgerDay {
+		d, ok := days[date]
+		if !ok {
+			d = &models.CashLedgerDay{Date: date}
+			days[date] = d
+		}
+		return d
+	}
+
+	rows, err := db.Query(`
+		SELECT date(date), SUM(cash_receipt), SUM(cash_on_hand)
+		FROM daily_sales
+		WHERE deleted_at IS NULL
+		GROUP BY date(date)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query cash sales: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var date string
+		var receipts, counted float64
+		if err := rows.Scan(&date, &receipts, &counted); err != nil {
+			return nil, fmt.Errorf("scan cash sales: %w", err)
+		}
+		d := day(date)
+		d.CashReceipts = receipts
+		d.CashCounted = counted
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	depRows, err := db.Query(`
+		SELECT date(date), SUM(amount)
+		FROM cash_deposits
+		GROUP BY date(date)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query cash deposit totals: %w", err)
+	}
+	defer depRows.Close()
+	for depRows.Next() {
+		var date string
+		var amount float64
+		if err := depRows.Scan(&date, &amount); err != nil {
+			return nil, fmt.Errorf("scan cash deposit totals: %w", err)
+		}
+		day(date).Deposits = amount
+	}
+	if err := depRows.Err(); err != nil {
+		return nil, err
+	}
+
+	ledger := make([]models.CashLedgerDay, 0, len(days))
+	for _, d := range days {
+		ledger = append(ledger, *d)
+	}
+	sort.Slice(ledger, func(i, j int) bool { return ledger[i].Date < ledger[j].Date })
+
+	expected, actual := openingFloat, openingFloat
+	for i := range ledger {
+		d := &ledger[i]
+		d.Opening = expected
+		expected += d.CashReceipts - d.Deposits
+		actual += d.CashCounted - d.Deposits
+		d.ExpectedClosing = expected
+		d.ActualClosing = actual
+		if t, err := time.Parse("2006-01-02", d.Date); err == nil {
+			d.DateDisplay = t.Format("01-02-2006")
+		}
+	}
+	return ledger, nil
+}
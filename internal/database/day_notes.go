@@ -0,0 +1,57 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GetDayNotesForDates returns the day notes recorded for the given dates, keyed by date
+func (db *DB) GetDayNotesForDates(dates []string) (map[string]string, error) {
+	result := make(map[string]string)
+	if len(dates) == 0 {
+		return result, nil
+	}
+
+	placeholders := make([]string, len(dates))
+	args := make([]interface{}, len(dates))
+	for i, date := range dates {
+		placeholders[i] = "?"
+		args[i] = date
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT date(date), note FROM sales_day_notes WHERE date IN (%s)
+	`, strings.Join(placeholders, ",")), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query day notes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var date, note string
+		if err := rows.Scan(&date, &note); err != nil {
+			return nil, fmt.Errorf("scan day note: %w", err)
+		}
+		result[date] = note
+	}
+	return result, rows.Err()
+}
+
+// SaveDayNote sets the note for a date; an empty note removes it
+func (db *DB) SaveDayNote(date, note string) error {
+	if note == "" {
+		if _, err := db.Exec(`DELETE FROM sales_day_notes WHERE date = ?`, date); err != nil {
+			return fmt.Errorf("delete day note: %w", err)
+		}
+		return nil
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO sales_day_notes (date, note) VALUES (?, ?)
+		ON CONFLICT(date) DO UPDATE SET note = excluded.note, updated_at = CURRENT_TIMESTAMP
+	`, date, note)
+	if err != nil {
+		return fmt.Errorf("save day note: %w", err)
+	}
+	return nil
+}
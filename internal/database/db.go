@@ -1,11 +1,13 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	_ "embed"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -39,11 +41,237 @@ func Open(dbPath string) (*DB, error) {
 	return &DB{db}, nil
 }
 
-// Init creates tables if they don't exist
+// Init creates tables if they don't exist, then applies any pending migrations.
+//
+// The ensureColumn calls and table rebuilds below came before numbered migrations
+// and are kept as they are for databases created back then. This list must not grow:
+// a new column, index or table rebuild goes in a new file in migrations/ instead
 func (db *DB) Init() error {
 	_, err := db.Exec(schema)
 	if err != nil {
 		return fmt.Errorf("execute schema: %w", err)
 	}
+
+	// Columns added after the initial release, before migrations/. Frozen; see above
+	if err := db.ensureColumn("bank_reconciliations", "default_vendor_id", "INTEGER"); err != nil {
+		return err
+	}
+	if err := db.ensureColumn("expenses", "payee_name", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := db.relaxExpenseVendor(); err != nil {
+		return err
+	}
+	if err := db.ensureColumn("expenses", "category", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := db.allowPersonalTransactions(); err != nil {
+		return err
+	}
+	if err := db.ensureColumn("bank_transactions", "platform", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := db.ensureColumn("bank_reconciliations", "discrepancy_notes", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := db.ensureColumn("bank_reconciliations", "accepted_discrepancy_amount", "REAL DEFAULT 0"); err != nil {
+		return err
+	}
+	for _, table := range []string{"daily_sales", "expenses", "payroll"} {
+		if err := db.ensureColumn(table, "deleted_at", "DATETIME"); err != nil {
+			return err
+		}
+	}
+	if err := db.ensureColumn("bank_reconciliations", "account_id", "INTEGER REFERENCES accounts(id)"); err != nil {
+		return err
+	}
+	if err := db.assignReconciliationAccounts(); err != nil {
+		return err
+	}
+	if err := db.ensureColumn("bank_reconciliations", "calculated_ending_balance", "REAL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := db.ensureColumn("bank_reconciliations", "balance_matches", "INTEGER DEFAULT 1"); err != nil {
+		return err
+	}
+	if err := db.ensureColumn("sessions", "user_id", "INTEGER REFERENCES users(id) ON DELETE CASCADE"); err != nil {
+		return err
+	}
+	if err := db.ensureColumn("payroll", "withholding", "REAL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	// Schema changes from here on are numbered files in migrations/
+	return db.Migrate()
+}
+
+// ensureColumn adds a column to an existing table if it is missing
+func (db *DB) ensureColumn(table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("query table info %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			return fmt.Errorf("scan table info %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("read table info %s: %w", table, err)
+	}
+	rows.Close()
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, column, err)
+	}
 	return nil
 }
+
+// relaxExpenseVendor drops the NOT NULL constraint on expenses.vendor_id so
+// ad-hoc payees can be stored. SQLite can't alter a constraint, so the table is rebuilt.
+func (db *DB) relaxExpenseVendor() error {
+	var notNull int
+	err := db.QueryRow(`SELECT "notnull" FROM pragma_table_info('expenses') WHERE name = 'vendor_id'`).Scan(&notNull)
+	if err != nil {
+		return fmt.Errorf("query expenses vendor_id: %w", err)
+	}
+	if notNull == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get connection: %w", err)
+	}
+	defer conn.Close()
+
+	// Foreign keys must be off while the table is swapped out, and can't be toggled inside a transaction
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return fmt.Errorf("disable foreign keys: %w", err)
+	}
+	defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmts := []string{
+		`CREATE TABLE expenses_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			date DATE NOT NULL,
+			vendor_id INTEGER REFERENCES vendors(id),
+			payee_name TEXT DEFAULT '',
+			amount REAL NOT NULL,
+			invoice_number TEXT DEFAULT '',
+			status TEXT CHECK(status IN ('paid', 'not_paid')) DEFAULT 'not_paid',
+			payment_type TEXT CHECK(payment_type IN ('cash', 'check', 'debit', 'credit', '')) DEFAULT '',
+			check_number TEXT DEFAULT '',
+			date_opened DATE,
+			due_date DATE,
+			date_paid DATE,
+			notes TEXT DEFAULT '',
+			receipt_path TEXT DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT INTO expenses_new (id, date, vendor_id, payee_name, amount, invoice_number, status, payment_type,
+			check_number, date_opened, due_date, date_paid, notes, receipt_path, created_at, updated_at)
+		SELECT id, date, vendor_id, payee_name, amount, invoice_number, status, payment_type,
+			check_number, date_opened, due_date, date_paid, notes, receipt_path, created_at, updated_at
+		FROM expenses`,
+		`DROP TABLE expenses`,
+		`ALTER TABLE expenses_new RENAME TO expenses`,
+		`CREATE INDEX IF NOT EXISTS idx_expenses_date ON expenses(date)`,
+		`CREATE INDEX IF NOT EXISTS idx_expenses_status ON expenses(status)`,
+		`CREATE INDEX IF NOT EXISTS idx_expenses_vendor_id ON expenses(vendor_id)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("rebuild expenses: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// allowPersonalTransactions widens the bank_transactions.match_status CHECK constraint to
+// accept 'personal'. Like relaxExpenseVendor, this needs a table rebuild.
+func (db *DB) allowPersonalTransactions() error {
+	var tableSQL string
+	err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'bank_transactions'`).Scan(&tableSQL)
+	if err != nil {
+		return fmt.Errorf("query bank_transactions schema: %w", err)
+	}
+	if strings.Contains(tableSQL, "'personal'") {
+		return nil
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return fmt.Errorf("disable foreign keys: %w", err)
+	}
+	defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmts := []string{
+		`CREATE TABLE bank_transactions_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			reconciliation_id INTEGER NOT NULL,
+			posting_date DATE NOT NULL,
+			description TEXT NOT NULL,
+			amount REAL NOT NULL,
+			transaction_type TEXT NOT NULL,
+			category TEXT DEFAULT '',
+			check_number TEXT DEFAULT '',
+			vendor_hint TEXT DEFAULT '',
+			reference_number TEXT DEFAULT '',
+			matched_expense_id INTEGER,
+			match_status TEXT CHECK(match_status IN ('unmatched', 'matched', 'ignored', 'created', 'personal')) DEFAULT 'unmatched',
+			match_confidence TEXT DEFAULT '',
+			matched_at DATETIME,
+			notes TEXT DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (reconciliation_id) REFERENCES bank_reconciliations(id),
+			FOREIGN KEY (matched_expense_id) REFERENCES expenses(id)
+		)`,
+		`INSERT INTO bank_transactions_new (id, reconciliation_id, posting_date, description, amount, transaction_type,
+			category, check_number, vendor_hint, reference_number, matched_expense_id, match_status,
+			match_confidence, matched_at, notes, created_at)
+		SELECT id, reconciliation_id, posting_date, description, amount, transaction_type,
+			category, check_number, vendor_hint, reference_number, matched_expense_id, match_status,
+			match_confidence, matched_at, notes, created_at
+		FROM bank_transactions`,
+		`DROP TABLE bank_transactions`,
+		`ALTER TABLE bank_transactions_new RENAME TO bank_transactions`,
+		`CREATE INDEX IF NOT EXISTS idx_bank_txn_recon ON bank_transactions(reconciliation_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_bank_txn_status ON bank_transactions(match_status)`,
+		`CREATE INDEX IF NOT EXISTS idx_bank_txn_date ON bank_transactions(posting_date)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("rebuild bank_transactions: %w", err)
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,21 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// openTestDB returns a fully migrated database in a temp directory, closed when the
+// test ends
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Init(); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	return db
+}
@@ -12,42 +12,179 @@ import (
 func (db *DB) GetDeliverySalesForDate(date string) (*models.DeliverySales, error) {
 	var d models.DeliverySales
 	err := db.QueryRow(`
-		SELECT id, date(date), grubhub_subtotal, grubhub_net, doordash_subtotal, doordash_net,
-		       ubereats_earnings, ubereats_payout, notes
+		SELECT id, date(date), notes
 		FROM delivery_sales
 		WHERE date = ?
-	`, date).Scan(&d.ID, &d.Date, &d.GrubhubSubtotal, &d.GrubhubNet,
-		&d.DoordashSubtotal, &d.DoordashNet, &d.UberEatsEarnings, &d.UberEatsPayout,
-		&d.Notes)
+	`, date).Scan(&d.ID, &d.Date, &d.Notes)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("query delivery sales: %w", err)
 	}
+
+	d.Platforms, err = db.GetDeliveryForDate(date)
+	if err != nil {
+		return nil, err
+	}
 	return &d, nil
 }
 
-// UpsertDeliverySales creates or updates delivery sales for a date
+// GetDeliveryForDate returns each platform's sales for a date, in DeliveryPlatforms order.
+// Platforms with nothing entered are left out
+func (db *DB) GetDeliveryForDate(date string) ([]models.DeliveryPlatformSales, error) {
+	byDate, err := db.getDeliveryPlatformSales([]string{date})
+	if err != nil {
+		return nil, err
+	}
+	for _, platforms := range byDate {
+		return platforms, nil
+	}
+	return nil, nil
+}
+
+// getDeliveryPlatformSales fetches platform sales for a set of dates, keyed by YYYY-MM-DD
+func (db *DB) getDeliveryPlatformSales(dates []string) (map[string][]models.DeliveryPlatformSales, error) {
+	placeholders := make([]string, len(dates))
+	args := make([]interface{}, len(dates))
+	for i, date := range dates {
+		placeholders[i] = "?"
+		args[i] = date
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT date(date), platform, subtotal, net
+		FROM delivery_platform_sales
+		WHERE date IN (%s)
+	`, strings.Join(placeholders, ",")), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query delivery platform sales: %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[string]map[string]models.DeliveryPlatformSales)
+	for rows.Next() {
+		var date string
+		var p models.DeliveryPlatformSales
+		if err := rows.Scan(&date, &p.Platform, &p.Subtotal, &p.Net); err != nil {
+			return nil, fmt.Errorf("scan delivery platform sales: %w", err)
+		}
+		if found[date] == nil {
+			found[date] = make(map[string]models.DeliveryPlatformSales)
+		}
+		found[date][p.Platform] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]models.DeliveryPlatformSales, len(found))
+	for date, platforms := range found {
+		for _, platform := range models.DeliveryPlatforms {
+			if p, ok := platforms[platform.Key]; ok {
+				result[date] = append(result[date], p)
+			}
+		}
+	}
+	return result, nil
+}
+
+// SumDeliveryNet returns a platform's recorded net delivery sales between two dates (inclusive)
+func (db *DB) SumDeliveryNet(platform, startDate, endDate string) (float64, error) {
+	if !models.IsDeliveryPlatform(platform) {
+		return 0, fmt.Errorf("unknown delivery platform %q", platform)
+	}
+	var total float64
+	err := db.QueryRow(`
+		SELECT COALESCE(SUM(net), 0)
+		FROM delivery_platform_sales
+		WHERE platform = ? AND date BETWEEN ? AND ?
+	`, platform, startDate, endDate).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("sum delivery net: %w", err)
+	}
+	return total, nil
+}
+
+// UpsertDeliverySales creates or updates delivery sales for a date: the notes and each
+// platform in d.Platforms. Platforms not listed are left as they are, and a platform
+// saved with zero subtotal and net is removed
 func (db *DB) UpsertDeliverySales(d models.DeliverySales) error {
-	_, err := db.Exec(`
-		INSERT INTO delivery_sales (date, grubhub_subtotal, grubhub_net, doordash_subtotal, doordash_net,
-		                            ubereats_earnings, ubereats_payout, notes)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO delivery_sales (date, notes) VALUES (?, ?)
 		ON CONFLICT(date) DO UPDATE SET
-			grubhub_subtotal = excluded.grubhub_subtotal,
-			grubhub_net = excluded.grubhub_net,
-			doordash_subtotal = excluded.doordash_subtotal,
-			doordash_net = excluded.doordash_net,
-			ubereats_earnings = excluded.ubereats_earnings,
-			ubereats_payout = excluded.ubereats_payout,
 			notes = excluded.notes,
 			updated_at = CURRENT_TIMESTAMP
-	`, d.Date, d.GrubhubSubtotal, d.GrubhubNet, d.DoordashSubtotal, d.DoordashNet,
-		d.UberEatsEarnings, d.UberEatsPayout, d.Notes)
+	`, d.Date, d.Notes)
 	if err != nil {
 		return fmt.Errorf("upsert delivery sales: %w", err)
 	}
+	for _, p := range d.Platforms {
+		if err := upsertDeliveryPlatform(tx, d.Date, p); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit delivery sales: %w", err)
+	}
+	return nil
+}
+
+// UpsertDeliveryPlatform sets one platform's sales for a date without touching the
+// other platforms or the day's notes
+func (db *DB) UpsertDeliveryPlatform(date, platform string, subtotal, net float64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO delivery_sales (date) VALUES (?) ON CONFLICT(date) DO NOTHING`, date); err != nil {
+		return fmt.Errorf("insert delivery sales: %w", err)
+	}
+	p := models.DeliveryPlatformSales{Platform: platform, Subtotal: subtotal, Net: net}
+	if err := upsertDeliveryPlatform(tx, date, p); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit delivery sales: %w", err)
+	}
+	return nil
+}
+
+// upsertDeliveryPlatform writes one platform's row for a date whose delivery_sales row
+// already exists
+func upsertDeliveryPlatform(tx *sql.Tx, date string, p models.DeliveryPlatformSales) error {
+	if !models.IsDeliveryPlatform(p.Platform) {
+		return fmt.Errorf("unknown delivery platform %q", p.Platform)
+	}
+
+	if p.Subtotal == 0 && p.Net == 0 {
+		_, err := tx.Exec(`DELETE FROM delivery_platform_sales WHERE date = ? AND platform = ?`, date, p.Platform)
+		if err != nil {
+			return fmt.Errorf("delete delivery platform sales: %w", err)
+		}
+		return nil
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO delivery_platform_sales (date, platform, subtotal, net) VALUES (?, ?, ?, ?)
+		ON CONFLICT(date, platform) DO UPDATE SET
+			subtotal = excluded.subtotal,
+			net = excluded.net,
+			updated_at = CURRENT_TIMESTAMP
+	`, date, p.Platform, p.Subtotal, p.Net)
+	if err != nil {
+		return fmt.Errorf("upsert delivery platform sales: %w", err)
+	}
 	return nil
 }
 
@@ -66,8 +203,7 @@ func (db *DB) GetDeliverySalesForDates(dates []string) (map[string]*models.Deliv
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, date(date), grubhub_subtotal, grubhub_net, doordash_subtotal, doordash_net,
-		       ubereats_earnings, ubereats_payout, notes
+		SELECT id, date(date), notes
 		FROM delivery_sales
 		WHERE date IN (%s)
 	`, strings.Join(placeholders, ","))
@@ -81,13 +217,21 @@ func (db *DB) GetDeliverySalesForDates(dates []string) (map[string]*models.Deliv
 	result := make(map[string]*models.DeliverySales)
 	for rows.Next() {
 		var d models.DeliverySales
-		if err := rows.Scan(&d.ID, &d.Date, &d.GrubhubSubtotal, &d.GrubhubNet,
-			&d.DoordashSubtotal, &d.DoordashNet, &d.UberEatsEarnings, &d.UberEatsPayout,
-			&d.Notes); err != nil {
+		if err := rows.Scan(&d.ID, &d.Date, &d.Notes); err != nil {
 			return nil, fmt.Errorf("scan delivery sale: %w", err)
 		}
 		result[d.Date] = &d
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return result, rows.Err()
+	platforms, err := db.getDeliveryPlatformSales(dates)
+	if err != nil {
+		return nil, err
+	}
+	for date, d := range result {
+		d.Platforms = platforms[date]
+	}
+	return result, nil
 }
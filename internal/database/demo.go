@@ -0,0 +1,104 @@
+package database
+
+import "fmt"
+
+// DemoNote marks the notes of demo records so they're recognizable in the UI
+const DemoNote = "Demo data"
+
+// demoTables lists the tables demo data is written to, children before parents so
+// removal never trips a foreign key
+var demoTables = []string{
+	"bank_transactions",
+	"bank_reconciliations",
+	"accounts",
+	"payroll",
+	"payroll_weeks",
+	"expenses",
+	"daily_sales",
+	"delivery_sales",
+	"vendors",
+	"employees",
+}
+
+// MarkDemo records that a row was inserted as demo data
+func (db *DB) MarkDemo(table string, id int64) error {
+	_, err := db.Exec(`INSERT OR IGNORE INTO demo_records (table_name, record_id) VALUES (?, ?)`, table, id)
+	if err != nil {
+		return fmt.Errorf("mark demo record: %w", err)
+	}
+	return nil
+}
+
+// HasDemoData reports whether any demo records are present
+func (db *DB) HasDemoData() (bool, error) {
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM demo_records)`).Scan(&exists); err != nil {
+		return false, fmt.Errorf("query demo records: %w", err)
+	}
+	return exists, nil
+}
+
+// RemoveDemoData permanently deletes every row recorded as demo data and returns how many
+// were removed. Rows entered by hand are untouched. A demo vendor, employee, account or
+// payroll week that real rows still refer to is kept and becomes an ordinary record, and
+// real bank transactions matched to a demo expense go back to unmatched
+func (db *DB) RemoveDemoData() (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const demoExpenses = `(SELECT record_id FROM demo_records WHERE table_name = 'expenses')`
+	unlink := []struct{ name, sql string }{
+		{"delete reconciliation actions", `
+			DELETE FROM reconciliation_actions
+			WHERE expense_id IN ` + demoExpenses + ` OR prior_expense_id IN ` + demoExpenses + `
+				OR transaction_id IN (SELECT id FROM bank_transactions WHERE matched_expense_id IN ` + demoExpenses + `)`},
+		{"unmatch bank transactions", `
+			UPDATE bank_transactions
+			SET matched_expense_id = NULL, match_status = 'unmatched', match_confidence = '', matched_at = NULL
+			WHERE matched_expense_id IN ` + demoExpenses},
+	}
+	for _, stmt := range unlink {
+		if _, err := tx.Exec(stmt.sql); err != nil {
+			return 0, fmt.Errorf("%s: %w", stmt.name, err)
+		}
+	}
+
+	var removed int64
+	for _, table := range demoTables {
+		query := fmt.Sprintf(`DELETE FROM %s WHERE id IN (SELECT record_id FROM demo_records WHERE table_name = ?)`, table)
+		switch table {
+		case "bank_transactions":
+			// Demo statements may have had transactions added while reviewing them
+			query = `DELETE FROM bank_transactions WHERE id IN (SELECT record_id FROM demo_records WHERE table_name = ?)
+				OR reconciliation_id IN (SELECT record_id FROM demo_records WHERE table_name = 'bank_reconciliations')`
+		case "accounts":
+			query += ` AND NOT EXISTS (SELECT 1 FROM bank_reconciliations WHERE account_id = accounts.id)`
+		case "payroll_weeks":
+			query += ` AND NOT EXISTS (SELECT 1 FROM payroll WHERE payroll.week_id = payroll_weeks.id)`
+		case "vendors":
+			// Recurring expenses and booking rules would cascade away with the vendor
+			query += ` AND NOT EXISTS (SELECT 1 FROM expenses WHERE vendor_id = vendors.id)
+				AND NOT EXISTS (SELECT 1 FROM recurring_expenses WHERE vendor_id = vendors.id)
+				AND NOT EXISTS (SELECT 1 FROM auto_booking_rules WHERE vendor_id = vendors.id)`
+		case "employees":
+			query += ` AND NOT EXISTS (SELECT 1 FROM payroll WHERE employee_id = employees.id)`
+		}
+		result, err := tx.Exec(query, table)
+		if err != nil {
+			return removed, fmt.Errorf("remove demo %s: %w", table, err)
+		}
+		n, _ := result.RowsAffected()
+		removed += n
+	}
+
+	if _, err := tx.Exec(`DELETE FROM demo_records`); err != nil {
+		return removed, fmt.Errorf("clear demo records: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return removed, fmt.Errorf("commit: %w", err)
+	}
+	return removed, nil
+}
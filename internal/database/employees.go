@@ -3,6 +3,7 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"homebooks/internal/models"
 )
@@ -55,6 +56,9 @@ func (db *DB) GetEmployee(id int64) (models.Employee, error) {
 }
 
 func (db *DB) CreateEmployee(name string, hourlyRate float64, paymentMethod string) (int64, error) {
+	if err := (models.Employee{Name: name, HourlyRate: hourlyRate, PaymentMethod: paymentMethod}).Validate(); err != nil {
+		return 0, err
+	}
 	result, err := db.Exec(`
 		INSERT INTO employees (name, hourly_rate, payment_method) VALUES (?, ?, ?)
 	`, name, hourlyRate, paymentMethod)
@@ -64,14 +68,68 @@ func (db *DB) CreateEmployee(name string, hourlyRate float64, paymentMethod stri
 	return result.LastInsertId()
 }
 
-func (db *DB) UpdateEmployee(id int64, name string, hourlyRate float64, paymentMethod string) error {
-	_, err := db.Exec(`
+// UpdateEmployee saves an employee. A changed hourly rate is also written to the rate
+// history with its effective date (today when empty) and the user who changed it
+func (db *DB) UpdateEmployee(id int64, name string, hourlyRate float64, paymentMethod, effectiveDate, changedBy string) error {
+	if err := (models.Employee{Name: name, HourlyRate: hourlyRate, PaymentMethod: paymentMethod}).Validate(); err != nil {
+		return err
+	}
+	if effectiveDate == "" {
+		effectiveDate = time.Now().Format("2006-01-02")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var oldRate float64
+	if err := tx.QueryRow(`SELECT hourly_rate FROM employees WHERE id = ?`, id).Scan(&oldRate); err != nil {
+		return fmt.Errorf("query employee rate: %w", err)
+	}
+
+	_, err = tx.Exec(`
 		UPDATE employees SET name = ?, hourly_rate = ?, payment_method = ? WHERE id = ?
 	`, name, hourlyRate, paymentMethod, id)
 	if err != nil {
 		return fmt.Errorf("update employee: %w", err)
 	}
-	return nil
+
+	if oldRate != hourlyRate {
+		_, err = tx.Exec(`
+			INSERT INTO employee_rate_history (employee_id, old_rate, new_rate, effective_date, changed_by)
+			VALUES (?, ?, ?, ?, ?)
+		`, id, oldRate, hourlyRate, effectiveDate, changedBy)
+		if err != nil {
+			return fmt.Errorf("insert rate history: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetEmployeeRateHistory returns an employee's rate changes, newest first
+func (db *DB) GetEmployeeRateHistory(id int64) ([]models.EmployeeRateChange, error) {
+	rows, err := db.Query(`
+		SELECT id, employee_id, old_rate, new_rate, date(effective_date), changed_by, changed_at
+		FROM employee_rate_history
+		WHERE employee_id = ?
+		ORDER BY effective_date DESC, id DESC
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("query rate history: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []models.EmployeeRateChange
+	for rows.Next() {
+		var c models.EmployeeRateChange
+		if err := rows.Scan(&c.ID, &c.EmployeeID, &c.OldRate, &c.NewRate, &c.EffectiveDate, &c.ChangedBy, &c.ChangedAt); err != nil {
+			return nil, fmt.Errorf("scan rate history: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
 }
 
 func (db *DB) DeactivateEmployee(id int64) error {
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"homebooks/internal/models"
+)
+
+// ErrExpensePaid is returned by AddExpensePayment when the expense is already paid in full
+var ErrExpensePaid = errors.New("this receipt is already paid")
+
+// ErrOverpayment is returned by AddExpensePayment when a payment is more than the balance
+var ErrOverpayment = errors.New("the payment is more than the balance owed")
+
+// AddExpensePayment records a payment against an expense, the whole balance when
+// p.Amount is 0, dated today when p.Date is empty. The expense becomes paid once its
+// payments cover the amount, and otherwise stays not_paid with a smaller balance
+func (db *DB) AddExpensePayment(p models.ExpensePayment) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var amount, paid float64
+	var status string
+	err = tx.QueryRow(`
+		SELECT e.amount, e.status, `+expenseAmountPaidSQL+`
+		FROM expenses e
+		WHERE e.id = ? AND e.deleted_at IS NULL
+	`, p.ExpenseID).Scan(&amount, &status, &paid)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("expense not found")
+	}
+	if err != nil {
+		return fmt.Errorf("query expense: %w", err)
+	}
+	if status == "paid" {
+		return ErrExpensePaid
+	}
+
+	balance := amount - paid
+	if p.Amount <= 0 {
+		p.Amount = balance
+	}
+	if p.Amount > balance+0.005 {
+		return ErrOverpayment
+	}
+	p.CheckNumber = strings.TrimSpace(p.CheckNumber)
+
+	// A balance already at zero, e.g. after the amount was lowered, just gets marked paid
+	if p.Amount >= 0.005 {
+		_, err = tx.Exec(`
+			INSERT INTO expense_payments (expense_id, amount, date, payment_type, check_number)
+			VALUES (?, ?, COALESCE(NULLIF(?, ''), date('now')), ?, ?)
+		`, p.ExpenseID, p.Amount, p.Date, p.PaymentType, p.CheckNumber)
+		if err != nil {
+			return fmt.Errorf("insert expense payment: %w", err)
+		}
+	}
+
+	status = "not_paid"
+	if balance-p.Amount < 0.005 {
+		status = "paid"
+	}
+	_, err = tx.Exec(`
+		UPDATE expenses
+		SET status = ?1, payment_type = ?2, check_number = ?3,
+			date_paid = CASE WHEN ?1 = 'paid' THEN COALESCE(NULLIF(?4, ''), date('now')) ELSE date_paid END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?5
+	`, status, p.PaymentType, p.CheckNumber, p.Date, p.ExpenseID)
+	if err != nil {
+		return fmt.Errorf("update expense paid: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit expense payment: %w", err)
+	}
+	return nil
+}
+
+// ListExpensePayments returns the payments recorded against an expense, oldest first
+func (db *DB) ListExpensePayments(expenseID int64) ([]models.ExpensePayment, error) {
+	rows, err := db.Query(`
+		SELECT id, expense_id, amount, date(date), payment_type, check_number, created_at
+		FROM expense_payments
+		WHERE expense_id = ?
+		ORDER BY date(date), id
+	`, expenseID)
+	if err != nil {
+		return nil, fmt.Errorf("query expense payments: %w", err)
+	}
+	defer rows.Close()
+
+	var payments []models.ExpensePayment
+	for rows.Next() {
+		var p models.ExpensePayment
+		if err := rows.Scan(&p.ID, &p.ExpenseID, &p.Amount, &p.Date, &p.PaymentType, &p.CheckNumber, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan expense payment: %w", err)
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
@@ -3,19 +3,49 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"homebooks/internal/models"
 )
 
+// reconciledExpenseSQL is true when a transaction on a completed bank statement is matched to expense e
+const reconciledExpenseSQL = `EXISTS (
+	SELECT 1 FROM bank_transactions bt
+	JOIN bank_reconciliations br ON bt.reconciliation_id = br.id
+	WHERE bt.matched_expense_id = e.id AND br.status = 'completed'
+)`
+
+// expenseAmountPaidSQL is how much of expense e has been paid: all of it once marked
+// paid, otherwise the sum of its installments
+const expenseAmountPaidSQL = `CASE WHEN e.status = 'paid' THEN e.amount
+	ELSE COALESCE((SELECT SUM(ep.amount) FROM expense_payments ep WHERE ep.expense_id = e.id), 0) END`
+
 func (db *DB) ListExpenses(filter models.ExpenseFilter) ([]models.Expense, float64, error) {
+	var expenses []models.Expense
+	var total float64
+	err := db.EachExpense(filter, func(e models.Expense) error {
+		expenses = append(expenses, e)
+		total += e.Amount
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return expenses, total, nil
+}
+
+// EachExpense calls fn for every expense matching filter, newest first, without
+// loading them all into memory. Iteration stops at the first error fn returns
+func (db *DB) EachExpense(filter models.ExpenseFilter, fn func(models.Expense) error) error {
 	query := `
-		SELECT e.id, strftime('%m-%d-%Y', e.date), e.vendor_id, v.name, e.amount, e.invoice_number, e.status,
+		SELECT e.id, strftime('%m-%d-%Y', e.date), COALESCE(e.vendor_id, 0), COALESCE(v.name, e.payee_name), e.payee_name, e.amount, e.invoice_number, e.status,
 			   e.payment_type, e.check_number, COALESCE(strftime('%m-%d-%Y', e.date_opened), ''),
 			   COALESCE(strftime('%m-%d-%Y', e.due_date), ''), COALESCE(strftime('%m-%d-%Y', e.date_paid), ''),
-			   e.notes, e.receipt_path
+			   e.notes, e.receipt_path, e.category, ` + reconciledExpenseSQL + `, ` + expenseAmountPaidSQL + `
 		FROM expenses e
-		JOIN vendors v ON e.vendor_id = v.id
-		WHERE 1=1
+		LEFT JOIN vendors v ON e.vendor_id = v.id
+		WHERE e.deleted_at IS NULL
 	`
 	var args []interface{}
 
@@ -35,24 +65,109 @@ func (db *DB) ListExpenses(filter models.ExpenseFilter) ([]models.Expense, float
 		query += " AND e.vendor_id = ?"
 		args = append(args, filter.VendorID)
 	}
+	if filter.MinAmount > 0 {
+		query += " AND e.amount >= ?"
+		args = append(args, filter.MinAmount)
+	}
+	if filter.MaxAmount > 0 {
+		query += " AND e.amount <= ?"
+		args = append(args, filter.MaxAmount)
+	}
+	switch filter.Reconciled {
+	case "yes":
+		query += " AND " + reconciledExpenseSQL
+	case "no":
+		query += " AND NOT " + reconciledExpenseSQL
+	}
 	if len(filter.Categories) > 0 {
-		// Match any of the selected categories (OR logic)
+		// Match any of the selected categories (OR logic). An expense's own
+		// category takes precedence over its vendor's categories.
 		query += " AND ("
 		for i, cat := range filter.Categories {
 			if i > 0 {
 				query += " OR "
 			}
-			query += "(',' || v.category || ',') LIKE '%,' || ? || ',%'"
+			query += "(',' || CASE WHEN e.category != '' THEN e.category ELSE COALESCE(v.category, '') END || ',') LIKE '%,' || ? || ',%'"
 			args = append(args, cat)
 		}
 		query += ")"
 	}
 
-	query += " ORDER BY date(e.date) DESC, e.id DESC"
+	query += " ORDER BY " + expenseOrderBy(filter)
 
 	rows, err := db.Query(query, args...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("query expenses: %w", err)
+		return fmt.Errorf("query expenses: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.Expense
+		if err := rows.Scan(&e.ID, &e.Date, &e.VendorID, &e.VendorName, &e.PayeeName, &e.Amount, &e.InvoiceNumber, &e.Status,
+			&e.PaymentType, &e.CheckNumber, &e.DateOpened, &e.DueDate, &e.DatePaid, &e.Notes, &e.ReceiptPath, &e.Category,
+			&e.Reconciled, &e.AmountPaid); err != nil {
+			return fmt.Errorf("scan expense: %w", err)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// expenseSortColumns maps the sort keys an ExpenseFilter accepts to their ORDER BY
+// expressions. Only these are ever put into the query
+var expenseSortColumns = map[string]string{
+	"date":     "date(e.date)",
+	"amount":   "e.amount",
+	"vendor":   "COALESCE(v.name, e.payee_name) COLLATE NOCASE",
+	"due_date": "e.due_date IS NULL, date(e.due_date)",
+}
+
+// expenseOrderBy builds the ORDER BY clause for filter, defaulting to newest first.
+// Ties fall back to newest first so the order is stable
+func expenseOrderBy(filter models.ExpenseFilter) string {
+	column, ok := expenseSortColumns[filter.SortBy]
+	if !ok {
+		column = expenseSortColumns["date"]
+	}
+	dir := " DESC"
+	if filter.SortDir == "asc" {
+		dir = " ASC"
+	}
+	return column + dir + ", date(e.date) DESC, e.id DESC"
+}
+
+// ListUnpaidExpenses returns the expenses not yet paid in full and the total still owed
+// on them, net of any installments already paid
+func (db *DB) ListUnpaidExpenses() ([]models.Expense, float64, error) {
+	expenses, _, err := db.ListExpenses(models.ExpenseFilter{Status: "not_paid"})
+	if err != nil {
+		return nil, 0, err
+	}
+	var owed float64
+	for _, e := range expenses {
+		owed += e.Balance()
+	}
+	return expenses, owed, nil
+}
+
+// ListUncategorizedExpenses returns expenses with no category of their own whose vendor
+// has no categories assigned, including ad-hoc payees, so they can be found and fixed
+// before they drop out of category-based reports
+func (db *DB) ListUncategorizedExpenses() ([]models.Expense, float64, error) {
+	rows, err := db.Query(`
+		SELECT e.id, strftime('%m-%d-%Y', e.date), COALESCE(e.vendor_id, 0), COALESCE(v.name, e.payee_name), e.payee_name, e.amount, e.invoice_number, e.status,
+			   e.payment_type, e.check_number, COALESCE(strftime('%m-%d-%Y', e.date_opened), ''),
+			   COALESCE(strftime('%m-%d-%Y', e.due_date), ''), COALESCE(strftime('%m-%d-%Y', e.date_paid), ''),
+			   e.notes, e.receipt_path, e.category
+		FROM expenses e
+		LEFT JOIN vendors v ON e.vendor_id = v.id
+		WHERE e.deleted_at IS NULL AND e.category = '' AND TRIM(COALESCE(v.category, '')) = ''
+		ORDER BY date(e.date) DESC, e.id DESC
+	`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query uncategorized expenses: %w", err)
 	}
 	defer rows.Close()
 
@@ -60,8 +175,8 @@ func (db *DB) ListExpenses(filter models.ExpenseFilter) ([]models.Expense, float
 	var total float64
 	for rows.Next() {
 		var e models.Expense
-		if err := rows.Scan(&e.ID, &e.Date, &e.VendorID, &e.VendorName, &e.Amount, &e.InvoiceNumber, &e.Status,
-			&e.PaymentType, &e.CheckNumber, &e.DateOpened, &e.DueDate, &e.DatePaid, &e.Notes, &e.ReceiptPath); err != nil {
+		if err := rows.Scan(&e.ID, &e.Date, &e.VendorID, &e.VendorName, &e.PayeeName, &e.Amount, &e.InvoiceNumber, &e.Status,
+			&e.PaymentType, &e.CheckNumber, &e.DateOpened, &e.DueDate, &e.DatePaid, &e.Notes, &e.ReceiptPath, &e.Category); err != nil {
 			return nil, 0, fmt.Errorf("scan expense: %w", err)
 		}
 		expenses = append(expenses, e)
@@ -70,21 +185,17 @@ func (db *DB) ListExpenses(filter models.ExpenseFilter) ([]models.Expense, float
 	return expenses, total, rows.Err()
 }
 
-func (db *DB) ListUnpaidExpenses() ([]models.Expense, float64, error) {
-	return db.ListExpenses(models.ExpenseFilter{Status: "not_paid"})
-}
-
 // ListExpensesDateRange returns expenses within a date range with dates in YYYY-MM-DD format
 // Used by auto-matching to find potential matches
 func (db *DB) ListExpensesDateRange(startDate, endDate string) ([]models.Expense, error) {
 	rows, err := db.Query(`
-		SELECT e.id, date(e.date), e.vendor_id, v.name, e.amount, e.invoice_number, e.status,
+		SELECT e.id, date(e.date), COALESCE(e.vendor_id, 0), COALESCE(v.name, e.payee_name), e.payee_name, e.amount, e.invoice_number, e.status,
 			   e.payment_type, e.check_number, COALESCE(date(e.date_opened), ''),
 			   COALESCE(date(e.due_date), ''), COALESCE(date(e.date_paid), ''),
-			   e.notes, e.receipt_path
+			   e.notes, e.receipt_path, e.category
 		FROM expenses e
-		JOIN vendors v ON e.vendor_id = v.id
-		WHERE e.date >= ? AND e.date <= ?
+		LEFT JOIN vendors v ON e.vendor_id = v.id
+		WHERE e.deleted_at IS NULL AND e.date >= ? AND e.date <= ?
 		ORDER BY e.date DESC
 	`, startDate, endDate)
 	if err != nil {
@@ -95,8 +206,8 @@ func (db *DB) ListExpensesDateRange(startDate, endDate string) ([]models.Expense
 	var expenses []models.Expense
 	for rows.Next() {
 		var e models.Expense
-		if err := rows.Scan(&e.ID, &e.Date, &e.VendorID, &e.VendorName, &e.Amount, &e.InvoiceNumber, &e.Status,
-			&e.PaymentType, &e.CheckNumber, &e.DateOpened, &e.DueDate, &e.DatePaid, &e.Notes, &e.ReceiptPath); err != nil {
+		if err := rows.Scan(&e.ID, &e.Date, &e.VendorID, &e.VendorName, &e.PayeeName, &e.Amount, &e.InvoiceNumber, &e.Status,
+			&e.PaymentType, &e.CheckNumber, &e.DateOpened, &e.DueDate, &e.DatePaid, &e.Notes, &e.ReceiptPath, &e.Category); err != nil {
 			return nil, fmt.Errorf("scan expense: %w", err)
 		}
 		expenses = append(expenses, e)
@@ -107,15 +218,15 @@ func (db *DB) ListExpensesDateRange(startDate, endDate string) ([]models.Expense
 func (db *DB) GetExpense(id int64) (models.Expense, error) {
 	var e models.Expense
 	err := db.QueryRow(`
-		SELECT e.id, date(e.date), e.vendor_id, v.name, e.amount, e.invoice_number, e.status,
+		SELECT e.id, date(e.date), COALESCE(e.vendor_id, 0), COALESCE(v.name, e.payee_name), e.payee_name, e.amount, e.invoice_number, e.status,
 			   e.payment_type, e.check_number, COALESCE(date(e.date_opened), ''),
 			   COALESCE(date(e.due_date), ''), COALESCE(date(e.date_paid), ''),
-			   e.notes, e.receipt_path
+			   e.notes, e.receipt_path, e.category, `+expenseAmountPaidSQL+`
 		FROM expenses e
-		JOIN vendors v ON e.vendor_id = v.id
-		WHERE e.id = ?
-	`, id).Scan(&e.ID, &e.Date, &e.VendorID, &e.VendorName, &e.Amount, &e.InvoiceNumber, &e.Status,
-		&e.PaymentType, &e.CheckNumber, &e.DateOpened, &e.DueDate, &e.DatePaid, &e.Notes, &e.ReceiptPath)
+		LEFT JOIN vendors v ON e.vendor_id = v.id
+		WHERE e.id = ? AND e.deleted_at IS NULL
+	`, id).Scan(&e.ID, &e.Date, &e.VendorID, &e.VendorName, &e.PayeeName, &e.Amount, &e.InvoiceNumber, &e.Status,
+		&e.PaymentType, &e.CheckNumber, &e.DateOpened, &e.DueDate, &e.DatePaid, &e.Notes, &e.ReceiptPath, &e.Category, &e.AmountPaid)
 	if err == sql.ErrNoRows {
 		return e, fmt.Errorf("expense not found")
 	}
@@ -125,8 +236,91 @@ func (db *DB) GetExpense(id int64) (models.Expense, error) {
 	return e, nil
 }
 
+// SearchExpenses finds up to limit expenses, newest first, whose invoice number, notes,
+// check number or vendor/payee name contains q. Substring matches can't use an index, so
+// the query walks idx_expenses_date newest first and stops once limit rows match
+func (db *DB) SearchExpenses(q string, limit int) ([]models.Expense, error) {
+	pattern := containsPattern(q)
+	rows, err := db.Query(`
+		SELECT e.id, strftime('%m-%d-%Y', e.date), COALESCE(e.vendor_id, 0), COALESCE(v.name, e.payee_name), e.payee_name, e.amount, e.invoice_number, e.status,
+			   e.payment_type, e.check_number, e.notes, e.receipt_path, e.category
+		FROM expenses e
+		LEFT JOIN vendors v ON e.vendor_id = v.id
+		WHERE e.deleted_at IS NULL
+		  AND (e.invoice_number LIKE ?1 ESCAPE '\'
+		       OR e.notes LIKE ?1 ESCAPE '\'
+		       OR e.check_number LIKE ?1 ESCAPE '\'
+		       OR e.payee_name LIKE ?1 ESCAPE '\'
+		       OR v.name LIKE ?1 ESCAPE '\')
+		ORDER BY e.date DESC, e.id DESC
+		LIMIT ?2
+	`, pattern, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search expenses: %w", err)
+	}
+	defer rows.Close()
+
+	var expenses []models.Expense
+	for rows.Next() {
+		var e models.Expense
+		if err := rows.Scan(&e.ID, &e.Date, &e.VendorID, &e.VendorName, &e.PayeeName, &e.Amount, &e.InvoiceNumber, &e.Status,
+			&e.PaymentType, &e.CheckNumber, &e.Notes, &e.ReceiptPath, &e.Category); err != nil {
+			return nil, fmt.Errorf("scan expense: %w", err)
+		}
+		expenses = append(expenses, e)
+	}
+	return expenses, rows.Err()
+}
+
+// containsPattern builds a LIKE pattern matching values that contain q, with q's own
+// wildcards escaped so they match literally. Use it with ESCAPE '\'
+func containsPattern(q string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q) + "%"
+}
+
+// FindPossibleDuplicateExpense looks for an existing expense that e may be a second entry
+// of: same payee, amount and date, or same payee and invoice number. found is false when
+// there is none
+func (db *DB) FindPossibleDuplicateExpense(e models.Expense) (dup models.Expense, found bool, err error) {
+	payeeMatch := "e.vendor_id = ?"
+	var payee interface{} = e.VendorID
+	if e.VendorID <= 0 {
+		payeeMatch = "e.vendor_id IS NULL AND e.payee_name = ?"
+		payee = e.PayeeName
+	}
+
+	var id int64
+	err = db.QueryRow(`
+		SELECT e.id
+		FROM expenses e
+		WHERE e.deleted_at IS NULL AND `+payeeMatch+`
+		  AND ((date(e.date) = date(?) AND ABS(e.amount - ?) < 0.005)
+		       OR (? != '' AND e.invoice_number = ?))
+		ORDER BY e.id DESC
+		LIMIT 1
+	`, payee, e.Date, e.Amount, e.InvoiceNumber, e.InvoiceNumber).Scan(&id)
+	if err == sql.ErrNoRows {
+		return dup, false, nil
+	}
+	if err != nil {
+		return dup, false, fmt.Errorf("query duplicate expense: %w", err)
+	}
+
+	dup, err = db.GetExpense(id)
+	if err != nil {
+		return dup, false, err
+	}
+	return dup, true, nil
+}
+
 func (db *DB) CreateExpense(e models.Expense) (int64, error) {
-	var dateOpened, dueDate, datePaid interface{}
+	if err := e.Validate(); err != nil {
+		return 0, err
+	}
+	var vendorID, dateOpened, dueDate, datePaid interface{}
+	if e.VendorID > 0 {
+		vendorID = e.VendorID
+	}
 	if e.DateOpened != "" {
 		dateOpened = e.DateOpened
 	}
@@ -138,9 +332,9 @@ func (db *DB) CreateExpense(e models.Expense) (int64, error) {
 	}
 
 	result, err := db.Exec(`
-		INSERT INTO expenses (date, vendor_id, amount, invoice_number, status, payment_type, check_number, date_opened, due_date, date_paid, notes, receipt_path)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, e.Date, e.VendorID, e.Amount, e.InvoiceNumber, e.Status, e.PaymentType, e.CheckNumber, dateOpened, dueDate, datePaid, e.Notes, e.ReceiptPath)
+		INSERT INTO expenses (date, vendor_id, payee_name, amount, invoice_number, status, payment_type, check_number, date_opened, due_date, date_paid, notes, receipt_path, category)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, e.Date, vendorID, e.PayeeName, e.Amount, e.InvoiceNumber, e.Status, e.PaymentType, e.CheckNumber, dateOpened, dueDate, datePaid, e.Notes, e.ReceiptPath, e.Category)
 	if err != nil {
 		return 0, fmt.Errorf("insert expense: %w", err)
 	}
@@ -148,7 +342,13 @@ func (db *DB) CreateExpense(e models.Expense) (int64, error) {
 }
 
 func (db *DB) UpdateExpense(e models.Expense) error {
-	var dateOpened, dueDate, datePaid interface{}
+	if err := e.Validate(); err != nil {
+		return err
+	}
+	var vendorID, dateOpened, dueDate, datePaid interface{}
+	if e.VendorID > 0 {
+		vendorID = e.VendorID
+	}
 	if e.DateOpened != "" {
 		dateOpened = e.DateOpened
 	}
@@ -159,18 +359,115 @@ func (db *DB) UpdateExpense(e models.Expense) error {
 		datePaid = e.DatePaid
 	}
 
-	_, err := db.Exec(`
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if e.Status == "not_paid" {
+		if err := clearExpensePayments(tx, e.ID); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`
 		UPDATE expenses
-		SET date = ?, vendor_id = ?, amount = ?, invoice_number = ?, status = ?, payment_type = ?,
+		SET date = ?, vendor_id = ?, payee_name = ?, amount = ?, invoice_number = ?, status = ?, payment_type = ?,
 			check_number = ?, date_opened = ?, due_date = ?, date_paid = ?, notes = ?, receipt_path = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
-	`, e.Date, e.VendorID, e.Amount, e.InvoiceNumber, e.Status, e.PaymentType, e.CheckNumber, dateOpened, dueDate, datePaid, e.Notes, e.ReceiptPath, e.ID)
+	`, e.Date, vendorID, e.PayeeName, e.Amount, e.InvoiceNumber, e.Status, e.PaymentType, e.CheckNumber, dateOpened, dueDate, datePaid, e.Notes, e.ReceiptPath, e.ID)
 	if err != nil {
 		return fmt.Errorf("update expense: %w", err)
 	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit expense update: %w", err)
+	}
 	return nil
 }
 
+// clearExpensePayments deletes the payments of an expense that is paid, ahead of it
+// being marked not_paid, so the installments that paid it off stop counting as paid
+// and as money out. A partly paid expense is already not_paid and keeps its payments
+func clearExpensePayments(tx *sql.Tx, id int64) error {
+	_, err := tx.Exec(`
+		DELETE FROM expense_payments
+		WHERE expense_id IN (SELECT id FROM expenses WHERE id = ? AND status = 'paid' AND deleted_at IS NULL)
+	`, id)
+	if err != nil {
+		return fmt.Errorf("clear expense %d payments: %w", id, err)
+	}
+	return nil
+}
+
+// BulkUpdateExpenses applies the non-empty fields of update to every expense in ids
+// within a single transaction and returns the number of expenses updated. Expenses
+// in the trash are skipped
+func (db *DB) BulkUpdateExpenses(ids []int64, update models.ExpenseBulkUpdate) (int64, error) {
+	if err := update.Validate(); err != nil {
+		return 0, err
+	}
+	var sets []string
+	var args []interface{}
+	if update.Category != "" {
+		category := update.Category
+		if category == models.ExpenseCategoryVendorDefault {
+			category = ""
+		}
+		sets = append(sets, "category = ?")
+		args = append(args, category)
+	}
+	if update.Status != "" {
+		sets = append(sets, "status = ?")
+		args = append(args, update.Status)
+		switch update.Status {
+		case "paid":
+			sets = append(sets, "date_paid = COALESCE(date_paid, date('now'))")
+		case "not_paid":
+			sets = append(sets, "date_paid = NULL")
+		}
+	}
+	if update.VendorID > 0 {
+		sets = append(sets, "vendor_id = ?", "payee_name = ''")
+		args = append(args, update.VendorID)
+	}
+	if len(sets) == 0 || len(ids) == 0 {
+		return 0, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("UPDATE expenses SET " + strings.Join(sets, ", ") + ", updated_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL")
+	if err != nil {
+		return 0, fmt.Errorf("prepare bulk update: %w", err)
+	}
+	defer stmt.Close()
+
+	var updated int64
+	for _, id := range ids {
+		if update.Status == "not_paid" {
+			if err := clearExpensePayments(tx, id); err != nil {
+				return 0, err
+			}
+		}
+		result, err := stmt.Exec(append(args, id)...)
+		if err != nil {
+			return 0, fmt.Errorf("bulk update expense %d: %w", id, err)
+		}
+		n, _ := result.RowsAffected()
+		updated += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit bulk update: %w", err)
+	}
+	return updated, nil
+}
+
 // UpdateExpenseReceipt updates only the receipt path for an expense (used for quick upload)
 func (db *DB) UpdateExpenseReceipt(id int64, receiptPath string) error {
 	_, err := db.Exec(`
@@ -195,49 +492,214 @@ func (db *DB) GetExpenseReceiptPath(id int64) (string, error) {
 	return path, nil
 }
 
+// MarkExpensePaid pays off whatever is still owed on an expense today
 func (db *DB) MarkExpensePaid(id int64, paymentType, checkNumber string) error {
-	_, err := db.Exec(`
-		UPDATE expenses
-		SET status = 'paid', payment_type = ?, check_number = ?, date_paid = date('now'), updated_at = CURRENT_TIMESTAMP
-		WHERE id = ?
-	`, paymentType, checkNumber, id)
+	return db.AddExpensePayment(models.ExpensePayment{ExpenseID: id, PaymentType: paymentType, CheckNumber: checkNumber})
+}
+
+// MarkExpensePaidOn pays off an expense as of a given date, e.g. when it clears the bank
+func (db *DB) MarkExpensePaidOn(id int64, datePaid, paymentType, checkNumber string) error {
+	return db.AddExpensePayment(models.ExpensePayment{ExpenseID: id, Date: datePaid, PaymentType: paymentType, CheckNumber: checkNumber})
+}
+
+// DeleteExpense moves an expense to the trash
+func (db *DB) DeleteExpense(id int64) error {
+	_, err := db.Exec(`UPDATE expenses SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL`, id)
 	if err != nil {
-		return fmt.Errorf("mark expense paid: %w", err)
+		return fmt.Errorf("delete expense: %w", err)
 	}
 	return nil
 }
 
-func (db *DB) DeleteExpense(id int64) error {
-	_, err := db.Exec(`DELETE FROM expenses WHERE id = ?`, id)
+// RestoreExpense brings an expense back from the trash
+func (db *DB) RestoreExpense(id int64) error {
+	_, err := db.Exec(`UPDATE expenses SET deleted_at = NULL WHERE id = ?`, id)
 	if err != nil {
-		return fmt.Errorf("delete expense: %w", err)
+		return fmt.Errorf("restore expense: %w", err)
 	}
 	return nil
 }
 
+// PurgeExpense permanently deletes an expense that is already in the trash. Bank
+// transactions matched to it go back to unmatched, and the review actions that refer
+// to it are dropped since they could no longer be undone
+func (db *DB) PurgeExpense(id int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var trashed bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM expenses WHERE id = ? AND deleted_at IS NOT NULL)`, id).Scan(&trashed)
+	if err != nil {
+		return fmt.Errorf("query expense: %w", err)
+	}
+	if !trashed {
+		return nil
+	}
+
+	stmts := []struct{ name, sql string }{
+		{"delete reconciliation actions", `
+			DELETE FROM reconciliation_actions
+			WHERE expense_id = ?1 OR prior_expense_id = ?1
+				OR transaction_id IN (SELECT id FROM bank_transactions WHERE matched_expense_id = ?1)`},
+		{"unmatch bank transactions", `
+			UPDATE bank_transactions
+			SET matched_expense_id = NULL, match_status = 'unmatched', match_confidence = '', matched_at = NULL
+			WHERE matched_expense_id = ?1`},
+		{"purge expense", `DELETE FROM expenses WHERE id = ?1`},
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt.sql, id); err != nil {
+			return fmt.Errorf("%s: %w", stmt.name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit expense purge: %w", err)
+	}
+	return nil
+}
+
+// ListDeletedExpenses returns expenses in the trash, most recently deleted first
+func (db *DB) ListDeletedExpenses() ([]models.TrashItem, error) {
+	rows, err := db.Query(`
+		SELECT e.id, strftime('%m-%d-%Y', e.date), COALESCE(v.name, e.payee_name), e.amount, strftime('%m-%d-%Y', e.deleted_at)
+		FROM expenses e
+		LEFT JOIN vendors v ON e.vendor_id = v.id
+		WHERE e.deleted_at IS NOT NULL
+		ORDER BY e.deleted_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query deleted expenses: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.TrashItem
+	for rows.Next() {
+		t := models.TrashItem{Type: "expenses"}
+		if err := rows.Scan(&t.ID, &t.Date, &t.Description, &t.Amount, &t.DeletedAt); err != nil {
+			return nil, fmt.Errorf("scan deleted expense: %w", err)
+		}
+		items = append(items, t)
+	}
+	return items, rows.Err()
+}
+
 // GetTodayExpensesTotal returns the total expenses entered for today
 func (db *DB) GetTodayExpensesTotal() (float64, error) {
 	var total sql.NullFloat64
-	err := db.QueryRow(`SELECT SUM(amount) FROM expenses WHERE date = date('now')`).Scan(&total)
+	err := db.QueryRow(`SELECT SUM(amount) FROM expenses WHERE date = date('now') AND deleted_at IS NULL`).Scan(&total)
 	if err != nil {
 		return 0, fmt.Errorf("query today expenses total: %w", err)
 	}
 	return total.Float64, nil
 }
 
-// GetLastCheckNumber returns the most recent check number used for expenses
-func (db *DB) GetLastExpenseCheckNumber() (string, error) {
-	var checkNum sql.NullString
-	err := db.QueryRow(`
-		SELECT check_number FROM expenses
-		WHERE check_number != '' AND payment_type = 'check'
-		ORDER BY id DESC LIMIT 1
-	`).Scan(&checkNum)
-	if err == sql.ErrNoRows {
-		return "", nil
+// GetLastCheckNumber returns the highest check number written so far, comparing the
+// numbers they end in. Expenses, their installments and payroll share one checkbook, so
+// all three count, trashed rows included since those checks were still written. Numbers
+// that don't end in digits are skipped
+func (db *DB) GetLastCheckNumber() (string, error) {
+	rows, err := db.Query(`
+		SELECT check_number FROM expenses WHERE payment_type = 'check' AND check_number != ''
+		UNION
+		SELECT check_number FROM expense_payments WHERE payment_type = 'check' AND check_number != ''
+		UNION
+		SELECT check_number FROM payroll WHERE payment_method = 'check' AND check_number != ''
+	`)
+	if err != nil {
+		return "", fmt.Errorf("query check numbers: %w", err)
+	}
+	defer rows.Close()
+
+	var last string
+	var highest uint64
+	for rows.Next() {
+		var number string
+		if err := rows.Scan(&number); err != nil {
+			return "", fmt.Errorf("scan check number: %w", err)
+		}
+		number = strings.TrimSpace(number)
+		_, digits := splitCheckNumber(number)
+		n, err := strconv.ParseUint(digits, 10, 64)
+		if err == nil && (last == "" || n > highest) {
+			last, highest = number, n
+		}
 	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("query check numbers: %w", err)
+	}
+	return last, nil
+}
+
+// CheckNumberInUse returns the expenses and payroll entries, not deleted, already paid
+// by check with number, counting each installment paid on an expense. Both share one
+// checkbook, so a number should appear once
+func (db *DB) CheckNumberInUse(number string) ([]models.CheckUse, error) {
+	number = strings.TrimSpace(number)
+	if number == "" {
+		return nil, nil
+	}
+	rows, err := db.Query(`
+		SELECT 'expense', e.id, COALESCE(v.name, e.payee_name), date(e.date), e.amount
+		FROM expenses e
+		LEFT JOIN vendors v ON e.vendor_id = v.id
+		WHERE e.deleted_at IS NULL AND (
+			e.payment_type = 'check' AND TRIM(e.check_number) = ?1
+			OR e.id IN (SELECT expense_id FROM expense_payments WHERE payment_type = 'check' AND TRIM(check_number) = ?1)
+		)
+		UNION ALL
+		SELECT 'payroll', p.id, emp.name, COALESCE(NULLIF(p.date_paid, ''), pw.period_end), p.total_hours * p.hourly_rate - COALESCE(p.withholding, 0)
+		FROM payroll p
+		JOIN employees emp ON p.employee_id = emp.id
+		JOIN payroll_weeks pw ON p.week_id = pw.id
+		WHERE p.deleted_at IS NULL AND p.payment_method = 'check' AND TRIM(p.check_number) = ?1
+	`, number)
+	if err != nil {
+		return nil, fmt.Errorf("query check number uses: %w", err)
+	}
+	defer rows.Close()
+
+	var uses []models.CheckUse
+	for rows.Next() {
+		var u models.CheckUse
+		if err := rows.Scan(&u.Source, &u.ID, &u.Payee, &u.Date, &u.Amount); err != nil {
+			return nil, fmt.Errorf("scan check number use: %w", err)
+		}
+		uses = append(uses, u)
+	}
+	return uses, rows.Err()
+}
+
+// NextCheckNumber suggests the number for the next check, expense or payroll: the
+// highest one written plus one, or "" when there is none to go on
+func (db *DB) NextCheckNumber() (string, error) {
+	last, err := db.GetLastCheckNumber()
+	if err != nil {
+		return "", err
+	}
+	return nextCheckNumber(last), nil
+}
+
+// splitCheckNumber splits check into its prefix and the digits it ends in, which are
+// "" when it doesn't end in a number
+func splitCheckNumber(check string) (prefix, digits string) {
+	i := len(check)
+	for i > 0 && check[i-1] >= '0' && check[i-1] <= '9' {
+		i--
+	}
+	return check[:i], check[i:]
+}
+
+// nextCheckNumber returns the check number after last, keeping any prefix and zero
+// padding ("A-0099" gives "A-0100"). It is "" when last doesn't end in a number
+func nextCheckNumber(last string) string {
+	prefix, number := splitCheckNumber(last)
+	n, err := strconv.ParseUint(number, 10, 64)
 	if err != nil {
-		return "", fmt.Errorf("query last check number: %w", err)
+		return ""
 	}
-	return checkNum.String, nil
+	return prefix + fmt.Sprintf("%0*d", len(number), n+1)
 }
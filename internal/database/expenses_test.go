@@ -0,0 +1,260 @@
+package database
+
+import (
+	"slices"
+	"testing"
+
+	"homebooks/internal/models"
+)
+
+func TestListExpensesAmountRange(t *testing.T) {
+	db := openTestDB(t)
+	for _, amount := range []float64{10, 25.50, 40, 99.99} {
+		_, err := db.CreateExpense(models.Expense{
+			Date:      "2026-10-01",
+			PayeeName: "Jetro",
+			Amount:    amount,
+			Status:    "not_paid",
+		})
+		if err != nil {
+			t.Fatalf("create expense %.2f: %v", amount, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		min, max float64
+		want     []float64
+	}{
+		{"no bounds", 0, 0, []float64{10, 25.50, 40, 99.99}},
+		{"min equal to an amount", 25.50, 0, []float64{25.50, 40, 99.99}},
+		{"max equal to an amount", 0, 40, []float64{10, 25.50, 40}},
+		{"both bounds", 25.50, 40, []float64{25.50, 40}},
+		{"both bounds on one amount", 40, 40, []float64{40}},
+		{"bounds between amounts", 11, 25.49, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expenses, _, err := db.ListExpenses(models.ExpenseFilter{
+				MinAmount: tt.min,
+				MaxAmount: tt.max,
+				SortBy:    "amount",
+				SortDir:   "asc",
+			})
+			if err != nil {
+				t.Fatalf("ListExpenses: %v", err)
+			}
+			var got []float64
+			for _, e := range expenses {
+				got = append(got, e.Amount)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got amounts %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBulkUpdateExpensesStatus(t *testing.T) {
+	db := openTestDB(t)
+	id, err := db.CreateExpense(models.Expense{
+		Date: "2026-10-01", PayeeName: "Jetro", Amount: 25, Status: "paid", PaymentType: "cash", DatePaid: "2026-10-02",
+	})
+	if err != nil {
+		t.Fatalf("create expense: %v", err)
+	}
+
+	if _, err := db.BulkUpdateExpenses([]int64{id}, models.ExpenseBulkUpdate{Status: "not_paid"}); err != nil {
+		t.Fatalf("bulk not_paid: %v", err)
+	}
+	e, err := db.GetExpense(id)
+	if err != nil {
+		t.Fatalf("GetExpense: %v", err)
+	}
+	if e.Status != "not_paid" || e.DatePaid != "" {
+		t.Errorf("after not_paid: status %q, date paid %q; want not_paid with no date paid", e.Status, e.DatePaid)
+	}
+
+	if _, err := db.BulkUpdateExpenses([]int64{id}, models.ExpenseBulkUpdate{Status: "paid"}); err != nil {
+		t.Fatalf("bulk paid: %v", err)
+	}
+	e, err = db.GetExpense(id)
+	if err != nil {
+		t.Fatalf("GetExpense: %v", err)
+	}
+	if e.Status != "paid" || e.DatePaid == "" {
+		t.Errorf("after paid: status %q, date paid %q; want paid with a date paid", e.Status, e.DatePaid)
+	}
+
+	trashedID, err := db.CreateExpense(models.Expense{Date: "2026-10-01", PayeeName: "Jetro", Amount: 30, Status: "not_paid"})
+	if err != nil {
+		t.Fatalf("create expense: %v", err)
+	}
+	if err := db.DeleteExpense(trashedID); err != nil {
+		t.Fatalf("DeleteExpense: %v", err)
+	}
+	n, err := db.BulkUpdateExpenses([]int64{id, trashedID}, models.ExpenseBulkUpdate{Category: "Food"})
+	if err != nil {
+		t.Fatalf("bulk category: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("bulk update counted %d expenses; want 1, skipping the trashed one", n)
+	}
+	var category string
+	if err := db.QueryRow(`SELECT category FROM expenses WHERE id = ?`, trashedID).Scan(&category); err != nil {
+		t.Fatalf("query trashed expense: %v", err)
+	}
+	if category != "" {
+		t.Errorf("trashed expense category = %q; want it left unchanged", category)
+	}
+}
+
+func TestMarkingInstallmentExpenseUnpaidClearsPayments(t *testing.T) {
+	db := openTestDB(t)
+	newPaidOff := func() int64 {
+		t.Helper()
+		id, err := db.CreateExpense(models.Expense{Date: "2026-10-01", PayeeName: "Jetro", Amount: 100, Status: "not_paid"})
+		if err != nil {
+			t.Fatalf("create expense: %v", err)
+		}
+		for _, date := range []string{"2026-10-05", "2026-10-12"} {
+			if err := db.AddExpensePayment(models.ExpensePayment{ExpenseID: id, Amount: 50, Date: date, PaymentType: "cash"}); err != nil {
+				t.Fatalf("AddExpensePayment: %v", err)
+			}
+		}
+		return id
+	}
+	checkUnpaid := func(id int64) {
+		t.Helper()
+		e, err := db.GetExpense(id)
+		if err != nil {
+			t.Fatalf("GetExpense: %v", err)
+		}
+		if e.PaymentStatus() != "not_paid" || e.Balance() != 100 {
+			t.Errorf("payment status %q, balance %.2f; want not_paid with 100.00 owed", e.PaymentStatus(), e.Balance())
+		}
+		payments, err := db.ListExpensePayments(id)
+		if err != nil {
+			t.Fatalf("ListExpensePayments: %v", err)
+		}
+		if len(payments) != 0 {
+			t.Errorf("%d payments left; want none", len(payments))
+		}
+	}
+
+	bulkID := newPaidOff()
+	if _, err := db.BulkUpdateExpenses([]int64{bulkID}, models.ExpenseBulkUpdate{Status: "not_paid"}); err != nil {
+		t.Fatalf("bulk not_paid: %v", err)
+	}
+	checkUnpaid(bulkID)
+
+	editID := newPaidOff()
+	e, err := db.GetExpense(editID)
+	if err != nil {
+		t.Fatalf("GetExpense: %v", err)
+	}
+	e.Status, e.DatePaid = "not_paid", ""
+	if err := db.UpdateExpense(e); err != nil {
+		t.Fatalf("UpdateExpense: %v", err)
+	}
+	checkUnpaid(editID)
+
+	// A partly paid expense is already not_paid, so saving it keeps its installment
+	partID, err := db.CreateExpense(models.Expense{Date: "2026-10-01", PayeeName: "Jetro", Amount: 100, Status: "not_paid"})
+	if err != nil {
+		t.Fatalf("create expense: %v", err)
+	}
+	if err := db.AddExpensePayment(models.ExpensePayment{ExpenseID: partID, Amount: 40, Date: "2026-10-05"}); err != nil {
+		t.Fatalf("AddExpensePayment: %v", err)
+	}
+	if _, err := db.BulkUpdateExpenses([]int64{partID}, models.ExpenseBulkUpdate{Status: "not_paid"}); err != nil {
+		t.Fatalf("bulk not_paid: %v", err)
+	}
+	if e, err := db.GetExpense(partID); err != nil || e.AmountPaid != 40 {
+		t.Errorf("partly paid expense: amount paid %.2f, err %v; want 40.00 kept", e.AmountPaid, err)
+	}
+}
+
+func TestPurgeExpenseMatchedToBankTransaction(t *testing.T) {
+	db := openTestDB(t)
+	reconID, err := db.CreateReconciliation(models.BankReconciliation{StatementDate: "2026-10-31", Status: "parsed"})
+	if err != nil {
+		t.Fatalf("create reconciliation: %v", err)
+	}
+	txnID, err := db.CreateBankTransaction(&models.BankTransaction{
+		ReconciliationID: reconID, PostingDate: "2026-10-02", Description: "CHECK 1001",
+		Amount: -25, TransactionType: "check", Category: "expense",
+	})
+	if err != nil {
+		t.Fatalf("create transaction: %v", err)
+	}
+	expenseID, err := db.CreateExpense(models.Expense{Date: "2026-10-01", PayeeName: "Jetro", Amount: 25, Status: "paid"})
+	if err != nil {
+		t.Fatalf("create expense: %v", err)
+	}
+	before, err := db.GetBankTransaction(txnID)
+	if err != nil {
+		t.Fatalf("GetBankTransaction: %v", err)
+	}
+	if err := db.MatchBankTransaction(txnID, expenseID, "manual"); err != nil {
+		t.Fatalf("MatchBankTransaction: %v", err)
+	}
+	if err := db.RecordReconciliationAction("match", before, expenseID); err != nil {
+		t.Fatalf("RecordReconciliationAction: %v", err)
+	}
+
+	if err := db.DeleteExpense(expenseID); err != nil {
+		t.Fatalf("DeleteExpense: %v", err)
+	}
+	if err := db.PurgeExpense(expenseID); err != nil {
+		t.Fatalf("PurgeExpense: %v", err)
+	}
+
+	if _, err := db.GetExpense(expenseID); err == nil {
+		t.Errorf("expense %d still exists after purge", expenseID)
+	}
+	txn, err := db.GetBankTransaction(txnID)
+	if err != nil {
+		t.Fatalf("GetBankTransaction: %v", err)
+	}
+	if txn.MatchStatus != "unmatched" || txn.MatchedExpenseID != nil {
+		t.Errorf("transaction status %q, matched expense %v; want unmatched with none", txn.MatchStatus, txn.MatchedExpenseID)
+	}
+	if a, err := db.GetLastReconciliationAction(reconID); err != nil || a != nil {
+		t.Errorf("last review action = %+v, err %v; want none left to undo", a, err)
+	}
+}
+
+func TestNextCheckNumberSharedCheckbook(t *testing.T) {
+	db := openTestDB(t)
+	if next, err := db.NextCheckNumber(); err != nil || next != "" {
+		t.Fatalf("NextCheckNumber on an empty book = %q, %v; want \"\"", next, err)
+	}
+
+	// The highest number is on an installment, and lower or non-numeric ones are
+	// written after it
+	installment, err := db.CreateExpense(models.Expense{Date: "2026-10-01", PayeeName: "Sysco", Amount: 80, Status: "not_paid"})
+	if err != nil {
+		t.Fatalf("CreateExpense: %v", err)
+	}
+	if err := db.AddExpensePayment(models.ExpensePayment{ExpenseID: installment, Amount: 20, PaymentType: "check", CheckNumber: "1002"}); err != nil {
+		t.Fatalf("AddExpensePayment: %v", err)
+	}
+	createPaidPayroll(t, db) // check 1001
+	for _, number := range []string{"999", "VOID"} {
+		id, err := db.CreateExpense(models.Expense{Date: "2026-10-02", PayeeName: "Jetro", Amount: 30, Status: "not_paid"})
+		if err != nil {
+			t.Fatalf("CreateExpense: %v", err)
+		}
+		if err := db.MarkExpensePaid(id, "check", number); err != nil {
+			t.Fatalf("MarkExpensePaid: %v", err)
+		}
+	}
+
+	if last, err := db.GetLastCheckNumber(); err != nil || last != "1002" {
+		t.Errorf("GetLastCheckNumber = %q, %v; want 1002", last, err)
+	}
+	if next, err := db.NextCheckNumber(); err != nil || next != "1003" {
+		t.Errorf("NextCheckNumber = %q, %v; want 1003", next, err)
+	}
+}
@@ -0,0 +1,18 @@
+package database
+
+import "fmt"
+
+// StoredFileInUse reports whether any receipt or bank statement still points at a file
+// in the filestore. Identical uploads share one stored file, so a file may only be
+// removed once nothing references it
+func (db *DB) StoredFileInUse(path string) (bool, error) {
+	var count int
+	err := db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM expenses WHERE receipt_path = ?) +
+		       (SELECT COUNT(*) FROM bank_reconciliations WHERE file_path = ?)
+	`, path, path).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("check stored file references: %w", err)
+	}
+	return count > 0, nil
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -26,7 +27,7 @@ func (db *DB) CreateJob(jobType string, payload any) (int64, error) {
 	return result.LastInsertId()
 }
 
-// ClaimNextJob atomically claims the next pending job for processing
+// ClaimNextJob atomically claims the next pending job that is due for processing
 func (db *DB) ClaimNextJob() (*models.Job, error) {
 	tx, err := db.Begin()
 	if err != nil {
@@ -36,15 +37,15 @@ func (db *DB) ClaimNextJob() (*models.Job, error) {
 
 	// Find next pending job
 	var job models.Job
-	var startedAt, completedAt sql.NullTime
+	var startedAt, completedAt, nextRunAt sql.NullTime
 	err = tx.QueryRow(`
-		SELECT id, job_type, payload, status, progress, result, attempts, max_attempts, created_at, started_at, completed_at
+		SELECT id, job_type, payload, status, progress, result, attempts, max_attempts, created_at, started_at, completed_at, next_run_at, cancel_requested
 		FROM jobs
-		WHERE status = 'pending'
+		WHERE status = 'pending' AND (next_run_at IS NULL OR next_run_at <= ?)
 		ORDER BY created_at ASC
 		LIMIT 1
-	`).Scan(&job.ID, &job.JobType, &job.Payload, &job.Status, &job.Progress, &job.Result,
-		&job.Attempts, &job.MaxAttempts, &job.CreatedAt, &startedAt, &completedAt)
+	`, time.Now().UTC()).Scan(&job.ID, &job.JobType, &job.Payload, &job.Status, &job.Progress, &job.Result,
+		&job.Attempts, &job.MaxAttempts, &job.CreatedAt, &startedAt, &completedAt, &nextRunAt, &job.CancelRequested)
 
 	if err == sql.ErrNoRows {
 		return nil, nil // No pending jobs
@@ -59,6 +60,9 @@ func (db *DB) ClaimNextJob() (*models.Job, error) {
 	if completedAt.Valid {
 		job.CompletedAt = &completedAt.Time
 	}
+	if nextRunAt.Valid {
+		job.NextRunAt = &nextRunAt.Time
+	}
 
 	// Claim the job
 	now := time.Now()
@@ -85,13 +89,13 @@ func (db *DB) ClaimNextJob() (*models.Job, error) {
 // GetJob returns a job by ID
 func (db *DB) GetJob(id int64) (*models.Job, error) {
 	var job models.Job
-	var startedAt, completedAt sql.NullTime
+	var startedAt, completedAt, nextRunAt sql.NullTime
 	err := db.QueryRow(`
-		SELECT id, job_type, payload, status, progress, result, attempts, max_attempts, created_at, started_at, completed_at
+		SELECT id, job_type, payload, status, progress, result, attempts, max_attempts, created_at, started_at, completed_at, next_run_at, cancel_requested
 		FROM jobs
 		WHERE id = ?
 	`, id).Scan(&job.ID, &job.JobType, &job.Payload, &job.Status, &job.Progress, &job.Result,
-		&job.Attempts, &job.MaxAttempts, &job.CreatedAt, &startedAt, &completedAt)
+		&job.Attempts, &job.MaxAttempts, &job.CreatedAt, &startedAt, &completedAt, &nextRunAt, &job.CancelRequested)
 
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("job not found")
@@ -106,10 +110,48 @@ func (db *DB) GetJob(id int64) (*models.Job, error) {
 	if completedAt.Valid {
 		job.CompletedAt = &completedAt.Time
 	}
+	if nextRunAt.Valid {
+		job.NextRunAt = &nextRunAt.Time
+	}
 
 	return &job, nil
 }
 
+// ListJobs returns the most recent jobs, newest first
+func (db *DB) ListJobs(limit int) ([]models.Job, error) {
+	rows, err := db.Query(`
+		SELECT id, job_type, payload, status, progress, result, attempts, max_attempts, created_at, started_at, completed_at, next_run_at, cancel_requested
+		FROM jobs
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []models.Job
+	for rows.Next() {
+		var job models.Job
+		var startedAt, completedAt, nextRunAt sql.NullTime
+		if err := rows.Scan(&job.ID, &job.JobType, &job.Payload, &job.Status, &job.Progress, &job.Result,
+			&job.Attempts, &job.MaxAttempts, &job.CreatedAt, &startedAt, &completedAt, &nextRunAt, &job.CancelRequested); err != nil {
+			return nil, fmt.Errorf("scan job: %w", err)
+		}
+		if startedAt.Valid {
+			job.StartedAt = &startedAt.Time
+		}
+		if completedAt.Valid {
+			job.CompletedAt = &completedAt.Time
+		}
+		if nextRunAt.Valid {
+			job.NextRunAt = &nextRunAt.Time
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
 // UpdateJobProgress updates the progress percentage of a running job
 func (db *DB) UpdateJobProgress(id int64, progress int) error {
 	_, err := db.Exec(`
@@ -147,13 +189,45 @@ func (db *DB) FailJob(id int64, errMsg string) error {
 	return nil
 }
 
-// RetryJob resets a job to pending status for retry
-func (db *DB) RetryJob(id int64) error {
+// ErrJobFinished is returned by RequestJobCancel for a job that is no longer pending or running
+var ErrJobFinished = errors.New("job has already finished")
+
+// RequestJobCancel flags a pending or running job for cancellation. The worker stops a
+// running job at its next check; a pending one is made due now so it is claimed and
+// failed straight away rather than after any backoff
+func (db *DB) RequestJobCancel(id int64) error {
+	result, err := db.Exec(`
+		UPDATE jobs
+		SET cancel_requested = 1, next_run_at = NULL
+		WHERE id = ? AND status IN ('pending', 'running')
+	`, id)
+	if err != nil {
+		return fmt.Errorf("request job cancel: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrJobFinished
+	}
+	return nil
+}
+
+// JobCancelRequested reports whether cancellation has been requested for a job
+func (db *DB) JobCancelRequested(id int64) (bool, error) {
+	var requested bool
+	if err := db.QueryRow(`SELECT cancel_requested FROM jobs WHERE id = ?`, id).Scan(&requested); err != nil {
+		return false, fmt.Errorf("query job cancel: %w", err)
+	}
+	return requested, nil
+}
+
+// RetryJob resets a job to pending status so it is claimed again once runAt arrives.
+// A job that had already failed starts over with a full set of attempts
+func (db *DB) RetryJob(id int64, runAt time.Time) error {
 	_, err := db.Exec(`
 		UPDATE jobs
-		SET status = 'pending', started_at = NULL
+		SET status = 'pending', started_at = NULL, completed_at = NULL, next_run_at = ?, cancel_requested = 0,
+			attempts = CASE WHEN status = 'failed' THEN 0 ELSE attempts END
 		WHERE id = ?
-	`, id)
+	`, runAt.UTC(), id)
 	if err != nil {
 		return fmt.Errorf("retry job: %w", err)
 	}
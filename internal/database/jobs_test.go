@@ -0,0 +1,51 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaimNextJobSkipsJobNotYetDue(t *testing.T) {
+	db := openTestDB(t)
+
+	id, err := db.CreateJob("backup", map[string]string{})
+	if err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	if err := db.RetryJob(id, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("RetryJob: %v", err)
+	}
+
+	job, err := db.ClaimNextJob()
+	if err != nil {
+		t.Fatalf("ClaimNextJob: %v", err)
+	}
+	if job != nil {
+		t.Fatalf("claimed job %d before its next_run_at", job.ID)
+	}
+
+	// A job that is due is still claimed while the delayed one waits
+	dueID, err := db.CreateJob("backup", map[string]string{})
+	if err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	job, err = db.ClaimNextJob()
+	if err != nil {
+		t.Fatalf("ClaimNextJob: %v", err)
+	}
+	if job == nil || job.ID != dueID {
+		t.Fatalf("claimed %+v, want job %d", job, dueID)
+	}
+
+	// Once next_run_at has passed the delayed job is claimed
+	if err := db.RetryJob(id, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("RetryJob: %v", err)
+	}
+	job, err = db.ClaimNextJob()
+	if err != nil {
+		t.Fatalf("ClaimNextJob: %v", err)
+	}
+	if job == nil || job.ID != id {
+		t.Fatalf("claimed %+v, want job %d", job, id)
+	}
+}
@@ -0,0 +1,4 @@
+-- Baseline. Everything up to here is created by schema.sql, with columns added to
+-- older databases by ensureColumn in Init. Later schema changes go in numbered files
+-- after this one: 0002_add_something.sql, 0003_... Each file runs once, inside a
+-- transaction, and is recorded in schema_migrations.
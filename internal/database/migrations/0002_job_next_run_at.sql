@@ -0,0 +1,2 @@
+-- When a pending job may next be claimed; failed attempts push it back. NULL means now
+ALTER TABLE jobs ADD COLUMN next_run_at DATETIME;
@@ -0,0 +1,2 @@
+-- Set from the UI to stop a job; the worker cancels it and marks it failed
+ALTER TABLE jobs ADD COLUMN cancel_requested INTEGER DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Payment method prefilled on new receipts for the vendor
+ALTER TABLE vendors ADD COLUMN default_payment_type TEXT CHECK(default_payment_type IN ('cash', 'check', 'debit', 'credit', '')) DEFAULT '';
@@ -0,0 +1,25 @@
+-- Delivery sales per platform, so platforms can be added without new columns. The
+-- grubhub/doordash/ubereats columns on delivery_sales are copied here and no longer
+-- read; delivery_sales keeps one row per day for the notes
+CREATE TABLE delivery_platform_sales (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    date DATE NOT NULL REFERENCES delivery_sales(date) ON DELETE CASCADE,
+    platform TEXT NOT NULL,
+    subtotal REAL DEFAULT 0,
+    net REAL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(date, platform)
+);
+
+INSERT INTO delivery_platform_sales (date, platform, subtotal, net)
+SELECT date, 'grubhub', COALESCE(grubhub_subtotal, 0), COALESCE(grubhub_net, 0) FROM delivery_sales
+WHERE COALESCE(grubhub_subtotal, 0) != 0 OR COALESCE(grubhub_net, 0) != 0;
+
+INSERT INTO delivery_platform_sales (date, platform, subtotal, net)
+SELECT date, 'doordash', COALESCE(doordash_subtotal, 0), COALESCE(doordash_net, 0) FROM delivery_sales
+WHERE COALESCE(doordash_subtotal, 0) != 0 OR COALESCE(doordash_net, 0) != 0;
+
+INSERT INTO delivery_platform_sales (date, platform, subtotal, net)
+SELECT date, 'ubereats', COALESCE(ubereats_earnings, 0), COALESCE(ubereats_payout, 0) FROM delivery_sales
+WHERE COALESCE(ubereats_earnings, 0) != 0 OR COALESCE(ubereats_payout, 0) != 0;
@@ -0,0 +1,10 @@
+-- Sales tax returns filed and paid, so the sales tax report can tell what's outstanding
+CREATE TABLE tax_filings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    amount REAL NOT NULL DEFAULT 0,
+    paid_date DATE NOT NULL,
+    notes TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
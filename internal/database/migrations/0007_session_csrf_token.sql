@@ -0,0 +1,2 @@
+-- Per-session token that every form post must echo back, so other sites can't post as a signed-in user
+ALTER TABLE sessions ADD COLUMN csrf_token TEXT DEFAULT '';
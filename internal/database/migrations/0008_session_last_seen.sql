@@ -0,0 +1,2 @@
+-- When a session's expiry was last pushed forward, so active sessions slide instead of ending 30 days after login
+ALTER TABLE sessions ADD COLUMN last_seen_at DATETIME;
@@ -0,0 +1,2 @@
+-- Whether the login asked to stay signed in; other sessions are short and end with the browser
+ALTER TABLE sessions ADD COLUMN remember INTEGER DEFAULT 1;
@@ -0,0 +1,14 @@
+-- Payments made against an expense, so a bill can be paid in installments. An expense
+-- stays not_paid until its payments cover the amount; one marked paid counts as paid
+-- in full whether or not it has payments recorded
+CREATE TABLE expense_payments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    expense_id INTEGER NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    amount REAL NOT NULL,
+    date DATE NOT NULL,
+    payment_type TEXT DEFAULT '',
+    check_number TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_expense_payments_expense_id ON expense_payments(expense_id);
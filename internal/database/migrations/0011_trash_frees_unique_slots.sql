@@ -0,0 +1,59 @@
+-- A sale or payroll entry in the trash no longer holds its date+shift or week+employee,
+-- so the day can be re-entered and the trashed one still restored later. SQLite can't
+-- drop a UNIQUE constraint, so both tables are rebuilt with partial unique indexes.
+-- Nothing references either table, so foreign keys can stay on
+CREATE TABLE daily_sales_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    date DATE NOT NULL,
+    shift TEXT CHECK(shift IN ('breakfast', 'lunch', 'dinner')) NOT NULL,
+    net_sales REAL NOT NULL,
+    taxes REAL NOT NULL,
+    credit_card REAL NOT NULL,
+    cash_receipt REAL NOT NULL,
+    cash_on_hand REAL NOT NULL,
+    notes TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+
+INSERT INTO daily_sales_new (id, date, shift, net_sales, taxes, credit_card, cash_receipt, cash_on_hand, notes, created_at, updated_at, deleted_at)
+SELECT id, date, shift, net_sales, taxes, credit_card, cash_receipt, cash_on_hand, notes, created_at, updated_at, deleted_at
+FROM daily_sales;
+
+DROP TABLE daily_sales;
+ALTER TABLE daily_sales_new RENAME TO daily_sales;
+
+CREATE INDEX idx_daily_sales_date ON daily_sales(date);
+CREATE UNIQUE INDEX idx_daily_sales_date_shift ON daily_sales(date, shift) WHERE deleted_at IS NULL;
+
+CREATE TABLE payroll_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    week_id INTEGER NOT NULL REFERENCES payroll_weeks(id),
+    employee_id INTEGER NOT NULL REFERENCES employees(id),
+    total_hours REAL NOT NULL,
+    hourly_rate REAL NOT NULL,
+    withholding REAL DEFAULT 0,
+    payment_method TEXT CHECK(payment_method IN ('cash', 'check')) NOT NULL,
+    check_number TEXT DEFAULT '',
+    status TEXT CHECK(status IN ('paid', 'not_paid')) DEFAULT 'not_paid',
+    date_paid DATE,
+    notes TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+
+INSERT INTO payroll_new (id, week_id, employee_id, total_hours, hourly_rate, withholding, payment_method,
+    check_number, status, date_paid, notes, created_at, updated_at, deleted_at)
+SELECT id, week_id, employee_id, total_hours, hourly_rate, withholding, payment_method,
+    check_number, status, date_paid, notes, created_at, updated_at, deleted_at
+FROM payroll;
+
+DROP TABLE payroll;
+ALTER TABLE payroll_new RENAME TO payroll;
+
+CREATE INDEX idx_payroll_week_id ON payroll(week_id);
+CREATE INDEX idx_payroll_status ON payroll(status);
+CREATE INDEX idx_payroll_employee_id ON payroll(employee_id);
+CREATE UNIQUE INDEX idx_payroll_week_employee ON payroll(week_id, employee_id) WHERE deleted_at IS NULL;
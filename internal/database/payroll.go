@@ -2,7 +2,10 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 
 	"homebooks/internal/models"
 )
@@ -32,11 +35,11 @@ func (db *DB) ListPayroll(filter models.PayrollFilter) ([]models.Payroll, float6
 		SELECT p.id, p.week_id, p.employee_id, e.name,
 			   strftime('%m-%d-%Y', w.period_start), strftime('%m-%d-%Y', w.period_end),
 			   p.total_hours, p.hourly_rate, p.payment_method, p.check_number, p.status,
-			   COALESCE(strftime('%m-%d-%Y', p.date_paid), ''), p.notes
+			   COALESCE(strftime('%m-%d-%Y', p.date_paid), ''), p.notes, p.withholding
 		FROM payroll p
 		JOIN employees e ON p.employee_id = e.id
 		JOIN payroll_weeks w ON p.week_id = w.id
-		WHERE 1=1
+		WHERE p.deleted_at IS NULL
 	`
 	var args []interface{}
 
@@ -70,7 +73,7 @@ func (db *DB) ListPayroll(filter models.PayrollFilter) ([]models.Payroll, float6
 	for rows.Next() {
 		var p models.Payroll
 		if err := rows.Scan(&p.ID, &p.WeekID, &p.EmployeeID, &p.EmployeeName, &p.PeriodStart, &p.PeriodEnd, &p.TotalHours,
-			&p.HourlyRate, &p.PaymentMethod, &p.CheckNumber, &p.Status, &p.DatePaid, &p.Notes); err != nil {
+			&p.HourlyRate, &p.PaymentMethod, &p.CheckNumber, &p.Status, &p.DatePaid, &p.Notes, &p.Withholding); err != nil {
 			return nil, 0, fmt.Errorf("scan payroll: %w", err)
 		}
 		payrolls = append(payrolls, p)
@@ -89,13 +92,13 @@ func (db *DB) GetPayroll(id int64) (models.Payroll, error) {
 		SELECT p.id, p.week_id, p.employee_id, e.name,
 			   date(w.period_start), date(w.period_end),
 			   p.total_hours, p.hourly_rate, p.payment_method, p.check_number, p.status,
-			   COALESCE(date(p.date_paid), ''), p.notes
+			   COALESCE(date(p.date_paid), ''), p.notes, p.withholding
 		FROM payroll p
 		JOIN employees e ON p.employee_id = e.id
 		JOIN payroll_weeks w ON p.week_id = w.id
-		WHERE p.id = ?
+		WHERE p.id = ? AND p.deleted_at IS NULL
 	`, id).Scan(&p.ID, &p.WeekID, &p.EmployeeID, &p.EmployeeName, &p.PeriodStart, &p.PeriodEnd, &p.TotalHours,
-		&p.HourlyRate, &p.PaymentMethod, &p.CheckNumber, &p.Status, &p.DatePaid, &p.Notes)
+		&p.HourlyRate, &p.PaymentMethod, &p.CheckNumber, &p.Status, &p.DatePaid, &p.Notes, &p.Withholding)
 	if err == sql.ErrNoRows {
 		return p, fmt.Errorf("payroll not found")
 	}
@@ -112,33 +115,73 @@ func (db *DB) CreatePayroll(p models.Payroll) (int64, error) {
 	}
 
 	result, err := db.Exec(`
-		INSERT INTO payroll (week_id, employee_id, total_hours, hourly_rate, payment_method, check_number, status, date_paid, notes)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, p.WeekID, p.EmployeeID, p.TotalHours, p.HourlyRate, p.PaymentMethod, p.CheckNumber, p.Status, datePaid, p.Notes)
+		INSERT INTO payroll (week_id, employee_id, total_hours, hourly_rate, payment_method, check_number, status, date_paid, notes, withholding)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, p.WeekID, p.EmployeeID, p.TotalHours, p.HourlyRate, p.PaymentMethod, p.CheckNumber, p.Status, datePaid, p.Notes, p.Withholding)
 	if err != nil {
 		return 0, fmt.Errorf("insert payroll: %w", err)
 	}
 	return result.LastInsertId()
 }
 
+// ErrPayrollPaid is returned by UpdatePayroll when an entry that is already paid would
+// have its pay, employee, week or status changed
+var ErrPayrollPaid = errors.New("this payroll is already paid; reopen it before changing hours, rate, withholding, employee or week")
+
+// UpdatePayroll saves an entry. Once paid, its hours, rate, withholding, employee, week
+// and status are fixed (ErrPayrollPaid) until it is reopened with ReopenPayroll; notes
+// and check details can still be edited
 func (db *DB) UpdatePayroll(p models.Payroll) error {
+	var status string
+	var weekID, employeeID int64
+	var hours, rate, withholding float64
+	err := db.QueryRow(`
+		SELECT status, week_id, employee_id, total_hours, hourly_rate, COALESCE(withholding, 0) FROM payroll WHERE id = ?
+	`, p.ID).Scan(&status, &weekID, &employeeID, &hours, &rate, &withholding)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("get payroll: %w", err)
+	}
+	// The form posts amounts to the cent, so only a change of at least a cent counts
+	changed := func(old, new float64) bool { return math.Abs(old-new) >= 0.005 }
+	if err == nil && status == "paid" {
+		if p.Status != "paid" || p.WeekID != weekID || p.EmployeeID != employeeID ||
+			changed(hours, p.TotalHours) || changed(rate, p.HourlyRate) || changed(withholding, p.Withholding) {
+			return ErrPayrollPaid
+		}
+		// Keep the exact amounts paid rather than the form's rounding of them
+		p.TotalHours, p.HourlyRate, p.Withholding = hours, rate, withholding
+	}
+
 	var datePaid any
 	if p.DatePaid != "" {
 		datePaid = p.DatePaid
 	}
 
-	_, err := db.Exec(`
+	_, err = db.Exec(`
 		UPDATE payroll
 		SET week_id = ?, employee_id = ?, total_hours = ?, hourly_rate = ?,
-			payment_method = ?, check_number = ?, status = ?, date_paid = ?, notes = ?, updated_at = CURRENT_TIMESTAMP
+			payment_method = ?, check_number = ?, status = ?, date_paid = ?, notes = ?, withholding = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
-	`, p.WeekID, p.EmployeeID, p.TotalHours, p.HourlyRate, p.PaymentMethod, p.CheckNumber, p.Status, datePaid, p.Notes, p.ID)
+	`, p.WeekID, p.EmployeeID, p.TotalHours, p.HourlyRate, p.PaymentMethod, p.CheckNumber, p.Status, datePaid, p.Notes, p.Withholding, p.ID)
 	if err != nil {
 		return fmt.Errorf("update payroll: %w", err)
 	}
 	return nil
 }
 
+// ReopenPayroll marks a paid entry unpaid again so its pay can be corrected
+func (db *DB) ReopenPayroll(id int64) error {
+	_, err := db.Exec(`
+		UPDATE payroll
+		SET status = 'not_paid', date_paid = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND deleted_at IS NULL
+	`, id)
+	if err != nil {
+		return fmt.Errorf("reopen payroll: %w", err)
+	}
+	return nil
+}
+
 func (db *DB) MarkPayrollPaid(id int64) error {
 	_, err := db.Exec(`
 		UPDATE payroll
@@ -151,14 +194,110 @@ func (db *DB) MarkPayrollPaid(id int64) error {
 	return nil
 }
 
+// DeletePayroll moves a payroll entry to the trash
 func (db *DB) DeletePayroll(id int64) error {
-	_, err := db.Exec(`DELETE FROM payroll WHERE id = ?`, id)
+	_, err := db.Exec(`UPDATE payroll SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL`, id)
 	if err != nil {
 		return fmt.Errorf("delete payroll: %w", err)
 	}
 	return nil
 }
 
+// ErrPayrollSlotTaken is returned by RestorePayroll when the employee has been given
+// another entry for the same week
+var ErrPayrollSlotTaken = errors.New("this employee has another payroll entry for the week; delete it before restoring this one")
+
+// RestorePayroll brings a payroll entry back from the trash, unless the employee has
+// another entry for the week by now (ErrPayrollSlotTaken)
+func (db *DB) RestorePayroll(id int64) error {
+	var taken bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM payroll p
+			JOIN payroll t ON t.week_id = p.week_id AND t.employee_id = p.employee_id
+			WHERE t.id = ? AND p.id != t.id AND p.deleted_at IS NULL
+		)
+	`, id).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("check payroll slot: %w", err)
+	}
+	if taken {
+		return ErrPayrollSlotTaken
+	}
+
+	_, err = db.Exec(`UPDATE payroll SET deleted_at = NULL WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("restore payroll: %w", err)
+	}
+	return nil
+}
+
+// PurgePayroll permanently deletes a payroll entry that is already in the trash
+func (db *DB) PurgePayroll(id int64) error {
+	_, err := db.Exec(`DELETE FROM payroll WHERE id = ? AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return fmt.Errorf("purge payroll: %w", err)
+	}
+	return nil
+}
+
+// ListDeletedPayroll returns payroll entries in the trash, most recently deleted first
+func (db *DB) ListDeletedPayroll() ([]models.TrashItem, error) {
+	rows, err := db.Query(`
+		SELECT p.id, strftime('%m-%d-%Y', w.period_end), e.name, p.total_hours * p.hourly_rate, strftime('%m-%d-%Y', p.deleted_at)
+		FROM payroll p
+		JOIN employees e ON p.employee_id = e.id
+		JOIN payroll_weeks w ON p.week_id = w.id
+		WHERE p.deleted_at IS NOT NULL
+		ORDER BY p.deleted_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query deleted payroll: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.TrashItem
+	for rows.Next() {
+		t := models.TrashItem{Type: "payroll"}
+		if err := rows.Scan(&t.ID, &t.Date, &t.Description, &t.Amount, &t.DeletedAt); err != nil {
+			return nil, fmt.Errorf("scan deleted payroll: %w", err)
+		}
+		items = append(items, t)
+	}
+	return items, rows.Err()
+}
+
+// GetPreviousWeekHours returns employee_id -> hours from the latest payroll week before
+// weekStart that has any entries, or an empty map when there is none
+func (db *DB) GetPreviousWeekHours(weekStart string) (map[int64]float64, error) {
+	rows, err := db.Query(`
+		SELECT p.employee_id, p.total_hours
+		FROM payroll p
+		WHERE p.deleted_at IS NULL AND p.week_id = (
+			SELECT w.id FROM payroll_weeks w
+			JOIN payroll p2 ON p2.week_id = w.id AND p2.deleted_at IS NULL
+			WHERE date(w.period_start) < date(?)
+			ORDER BY w.period_start DESC
+			LIMIT 1
+		)
+	`, weekStart)
+	if err != nil {
+		return nil, fmt.Errorf("query previous week hours: %w", err)
+	}
+	defer rows.Close()
+
+	hours := make(map[int64]float64)
+	for rows.Next() {
+		var employeeID int64
+		var h float64
+		if err := rows.Scan(&employeeID, &h); err != nil {
+			return nil, fmt.Errorf("scan previous week hours: %w", err)
+		}
+		hours[employeeID] = h
+	}
+	return hours, rows.Err()
+}
+
 // GetWeeklyPayroll returns payroll entries for all active employees for a given week
 // Returns a map of employee_id -> Payroll (nil if no entry exists for that employee)
 func (db *DB) GetWeeklyPayroll(weekStart, weekEnd string) ([]models.WeeklyPayrollEntry, float64, error) {
@@ -171,10 +310,10 @@ func (db *DB) GetWeeklyPayroll(weekStart, weekEnd string) ([]models.WeeklyPayrol
 	// Get existing payroll entries for this week via payroll_weeks join
 	rows, err := db.Query(`
 		SELECT p.id, p.week_id, p.employee_id, p.total_hours, p.hourly_rate, p.payment_method,
-			   p.check_number, p.status, COALESCE(strftime('%m-%d-%Y', p.date_paid), ''), p.notes
+			   p.check_number, p.status, COALESCE(strftime('%m-%d-%Y', p.date_paid), ''), p.notes, p.withholding
 		FROM payroll p
 		JOIN payroll_weeks w ON p.week_id = w.id
-		WHERE w.period_start = ? AND w.period_end = ?
+		WHERE w.period_start = ? AND w.period_end = ? AND p.deleted_at IS NULL
 	`, weekStart, weekEnd)
 	if err != nil {
 		return nil, 0, fmt.Errorf("query weekly payroll: %w", err)
@@ -186,7 +325,7 @@ func (db *DB) GetWeeklyPayroll(weekStart, weekEnd string) ([]models.WeeklyPayrol
 	for rows.Next() {
 		var p models.Payroll
 		if err := rows.Scan(&p.ID, &p.WeekID, &p.EmployeeID, &p.TotalHours, &p.HourlyRate, &p.PaymentMethod,
-			&p.CheckNumber, &p.Status, &p.DatePaid, &p.Notes); err != nil {
+			&p.CheckNumber, &p.Status, &p.DatePaid, &p.Notes, &p.Withholding); err != nil {
 			return nil, 0, fmt.Errorf("scan payroll: %w", err)
 		}
 		payrollMap[p.EmployeeID] = &p
@@ -228,7 +367,7 @@ func (db *DB) GetWeeklyPayrollByWeekID(weekID int64) ([]models.WeeklyPayrollEntr
 	rows, err := db.Query(`
 		SELECT DISTINCT e.id, e.name, e.hourly_rate, e.payment_method, e.active
 		FROM employees e
-		LEFT JOIN payroll p ON p.employee_id = e.id AND p.week_id = ?
+		LEFT JOIN payroll p ON p.employee_id = e.id AND p.week_id = ? AND p.deleted_at IS NULL
 		WHERE e.active = 1 OR p.id IS NOT NULL
 		ORDER BY e.name
 	`, weekID)
@@ -249,9 +388,9 @@ func (db *DB) GetWeeklyPayrollByWeekID(weekID int64) ([]models.WeeklyPayrollEntr
 	// Get existing payroll entries for this week
 	payrollRows, err := db.Query(`
 		SELECT p.id, p.week_id, p.employee_id, p.total_hours, p.hourly_rate, p.payment_method,
-			   p.check_number, p.status, COALESCE(strftime('%m-%d-%Y', p.date_paid), ''), p.notes
+			   p.check_number, p.status, COALESCE(strftime('%m-%d-%Y', p.date_paid), ''), p.notes, p.withholding
 		FROM payroll p
-		WHERE p.week_id = ?
+		WHERE p.week_id = ? AND p.deleted_at IS NULL
 	`, weekID)
 	if err != nil {
 		return nil, 0, fmt.Errorf("query weekly payroll: %w", err)
@@ -263,7 +402,7 @@ func (db *DB) GetWeeklyPayrollByWeekID(weekID int64) ([]models.WeeklyPayrollEntr
 	for payrollRows.Next() {
 		var p models.Payroll
 		if err := payrollRows.Scan(&p.ID, &p.WeekID, &p.EmployeeID, &p.TotalHours, &p.HourlyRate, &p.PaymentMethod,
-			&p.CheckNumber, &p.Status, &p.DatePaid, &p.Notes); err != nil {
+			&p.CheckNumber, &p.Status, &p.DatePaid, &p.Notes, &p.Withholding); err != nil {
 			return nil, 0, fmt.Errorf("scan payroll: %w", err)
 		}
 		payrollMap[p.EmployeeID] = &p
@@ -297,7 +436,7 @@ func (db *DB) UpsertWeeklyPayroll(employeeID int64, weekStart, weekEnd string, h
 	_, err = db.Exec(`
 		INSERT INTO payroll (week_id, employee_id, total_hours, hourly_rate, payment_method, status)
 		VALUES (?, ?, ?, ?, ?, 'not_paid')
-		ON CONFLICT(week_id, employee_id) DO UPDATE SET
+		ON CONFLICT(week_id, employee_id) WHERE deleted_at IS NULL DO UPDATE SET
 			total_hours = excluded.total_hours,
 			hourly_rate = excluded.hourly_rate,
 			payment_method = excluded.payment_method,
@@ -323,6 +462,94 @@ func (db *DB) MarkPayrollPaidWithDetails(id int64, paymentMethod, checkNumber st
 	return nil
 }
 
+// ErrCheckSequence is returned by PayPayrollWeek when the first check number can't be
+// counted on from
+var ErrCheckSequence = errors.New("the first check number must end in a number")
+
+// ErrCheckNumberUsed is returned by PayPayrollWeek when a check number it would write
+// is already on an expense or payroll entry
+var ErrCheckNumberUsed = errors.New("check number already used")
+
+// PayPayrollWeek marks every unpaid entry in the week paid by check, numbering the
+// checks from first in employee name order. It pays all of them or none, and returns
+// how many were paid and the last check number written
+func (db *DB) PayPayrollWeek(weekID int64, first string) (int, string, error) {
+	first = strings.TrimSpace(first)
+	if nextCheckNumber(first) == "" {
+		return 0, "", ErrCheckSequence
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, "", fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT p.id FROM payroll p
+		JOIN employees e ON p.employee_id = e.id
+		WHERE p.week_id = ? AND p.status = 'not_paid' AND p.deleted_at IS NULL
+		ORDER BY e.name, p.id
+	`, weekID)
+	if err != nil {
+		return 0, "", fmt.Errorf("query unpaid payroll: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, "", fmt.Errorf("scan unpaid payroll: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, "", fmt.Errorf("query unpaid payroll: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, "", nil
+	}
+
+	number, last := first, ""
+	for _, id := range ids {
+		var used bool
+		err := tx.QueryRow(`
+			SELECT EXISTS (
+				SELECT 1 FROM expenses
+				WHERE deleted_at IS NULL AND payment_type = 'check' AND TRIM(check_number) = ?1
+				UNION ALL
+				SELECT 1 FROM expense_payments
+				WHERE payment_type = 'check' AND TRIM(check_number) = ?1
+				UNION ALL
+				SELECT 1 FROM payroll
+				WHERE deleted_at IS NULL AND payment_method = 'check' AND TRIM(check_number) = ?1
+			)
+		`, number).Scan(&used)
+		if err != nil {
+			return 0, "", fmt.Errorf("query check number uses: %w", err)
+		}
+		if used {
+			return 0, "", fmt.Errorf("%w: #%s", ErrCheckNumberUsed, number)
+		}
+
+		_, err = tx.Exec(`
+			UPDATE payroll
+			SET status = 'paid', payment_method = 'check', check_number = ?, date_paid = date('now'), updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, number, id)
+		if err != nil {
+			return 0, "", fmt.Errorf("mark payroll paid: %w", err)
+		}
+		last, number = number, nextCheckNumber(number)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, "", fmt.Errorf("commit pay week: %w", err)
+	}
+	return len(ids), last, nil
+}
+
 // ListPayrollWeeks returns a summary of past payroll weeks
 func (db *DB) ListPayrollWeeks(limit int) ([]models.PayrollWeekSummary, error) {
 	rows, err := db.Query(`
@@ -335,9 +562,10 @@ func (db *DB) ListPayrollWeeks(limit int) ([]models.PayrollWeekSummary, error) {
 			COUNT(*) as employee_count,
 			SUM(p.total_hours) as total_hours,
 			SUM(p.total_hours * p.hourly_rate) as total_pay,
+			SUM(COALESCE(p.withholding, 0)) as total_withholding,
 			SUM(CASE WHEN p.status = 'paid' THEN 1 ELSE 0 END) as paid_count
 		FROM payroll_weeks w
-		JOIN payroll p ON p.week_id = w.id
+		JOIN payroll p ON p.week_id = w.id AND p.deleted_at IS NULL
 		GROUP BY w.id
 		ORDER BY w.period_start DESC
 		LIMIT ?
@@ -351,27 +579,10 @@ func (db *DB) ListPayrollWeeks(limit int) ([]models.PayrollWeekSummary, error) {
 	for rows.Next() {
 		var w models.PayrollWeekSummary
 		if err := rows.Scan(&w.WeekID, &w.PeriodStart, &w.PeriodEnd, &w.PeriodStartDisplay, &w.PeriodEndDisplay,
-			&w.EmployeeCount, &w.TotalHours, &w.TotalPay, &w.PaidCount); err != nil {
+			&w.EmployeeCount, &w.TotalHours, &w.TotalPay, &w.TotalWithholding, &w.PaidCount); err != nil {
 			return nil, fmt.Errorf("scan payroll week: %w", err)
 		}
 		weeks = append(weeks, w)
 	}
 	return weeks, rows.Err()
 }
-
-// GetLastPayrollCheckNumber returns the most recent check number used for payroll
-func (db *DB) GetLastPayrollCheckNumber() (string, error) {
-	var checkNum sql.NullString
-	err := db.QueryRow(`
-		SELECT check_number FROM payroll
-		WHERE check_number != '' AND payment_method = 'check'
-		ORDER BY id DESC LIMIT 1
-	`).Scan(&checkNum)
-	if err == sql.ErrNoRows {
-		return "", nil
-	}
-	if err != nil {
-		return "", fmt.Errorf("query last check number: %w", err)
-	}
-	return checkNum.String, nil
-}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"homebooks/internal/models"
+)
+
+// createPaidPayroll adds an employee and a paid entry for them and returns the entry
+func createPaidPayroll(t *testing.T, db *DB) models.Payroll {
+	t.Helper()
+	employeeID, err := db.CreateEmployee("Ana", 15, "check")
+	if err != nil {
+		t.Fatalf("create employee: %v", err)
+	}
+	weekID, err := db.GetOrCreatePayrollWeek("2026-10-05", "2026-10-11")
+	if err != nil {
+		t.Fatalf("create week: %v", err)
+	}
+	p := models.Payroll{
+		WeekID:        weekID,
+		EmployeeID:    employeeID,
+		TotalHours:    40,
+		HourlyRate:    15,
+		Withholding:   50,
+		PaymentMethod: "check",
+		CheckNumber:   "1001",
+		Status:        "paid",
+		DatePaid:      "2026-10-12",
+	}
+	p.ID, err = db.CreatePayroll(p)
+	if err != nil {
+		t.Fatalf("create payroll: %v", err)
+	}
+	return p
+}
+
+func TestUpdatePayrollPaidAllowsNotes(t *testing.T) {
+	db := openTestDB(t)
+	p := createPaidPayroll(t, db)
+
+	p.Notes = "Picked up Friday"
+	p.CheckNumber = "1002"
+	if err := db.UpdatePayroll(p); err != nil {
+		t.Fatalf("notes-only update: %v", err)
+	}
+
+	got, err := db.GetPayroll(p.ID)
+	if err != nil {
+		t.Fatalf("get payroll: %v", err)
+	}
+	if got.Notes != "Picked up Friday" || got.CheckNumber != "1002" {
+		t.Errorf("notes/check = %q/%q, want saved", got.Notes, got.CheckNumber)
+	}
+	if got.Status != "paid" || got.TotalHours != 40 || got.HourlyRate != 15 {
+		t.Errorf("paid entry changed: %+v", got)
+	}
+}
+
+func TestUpdatePayrollPaidRejectsPayChanges(t *testing.T) {
+	db := openTestDB(t)
+	p := createPaidPayroll(t, db)
+	otherEmployee, err := db.CreateEmployee("Ben", 18, "cash")
+	if err != nil {
+		t.Fatalf("create employee: %v", err)
+	}
+	otherWeek, err := db.GetOrCreatePayrollWeek("2026-10-12", "2026-10-18")
+	if err != nil {
+		t.Fatalf("create week: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(*models.Payroll)
+	}{
+		{"hours", func(p *models.Payroll) { p.TotalHours = 42 }},
+		{"rate", func(p *models.Payroll) { p.HourlyRate = 16 }},
+		{"withholding", func(p *models.Payroll) { p.Withholding = 0 }},
+		{"employee", func(p *models.Payroll) { p.EmployeeID = otherEmployee }},
+		{"week", func(p *models.Payroll) { p.WeekID = otherWeek }},
+		{"unpaid with new hours", func(p *models.Payroll) { p.Status = "not_paid"; p.TotalHours = 42 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edit := p
+			tt.change(&edit)
+			if err := db.UpdatePayroll(edit); !errors.Is(err, ErrPayrollPaid) {
+				t.Fatalf("UpdatePayroll = %v, want ErrPayrollPaid", err)
+			}
+			got, err := db.GetPayroll(p.ID)
+			if err != nil {
+				t.Fatalf("get payroll: %v", err)
+			}
+			if got.Status != "paid" || got.TotalHours != 40 || got.HourlyRate != 15 || got.EmployeeID != p.EmployeeID || got.WeekID != p.WeekID {
+				t.Errorf("rejected update still changed the entry: %+v", got)
+			}
+		})
+	}
+}
+
+func TestReopenPayrollAllowsPayChanges(t *testing.T) {
+	db := openTestDB(t)
+	p := createPaidPayroll(t, db)
+
+	if err := db.ReopenPayroll(p.ID); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	p.Status = "not_paid"
+	p.DatePaid = ""
+	p.TotalHours = 42
+	if err := db.UpdatePayroll(p); err != nil {
+		t.Fatalf("update after reopen: %v", err)
+	}
+	got, err := db.GetPayroll(p.ID)
+	if err != nil {
+		t.Fatalf("get payroll: %v", err)
+	}
+	if got.TotalHours != 42 || got.Status != "not_paid" {
+		t.Errorf("got hours %v status %q, want 42 not_paid", got.TotalHours, got.Status)
+	}
+}
+
+func TestTrashedPayrollStaysRestorable(t *testing.T) {
+	db := openTestDB(t)
+	old := createPaidPayroll(t, db)
+	if err := db.DeletePayroll(old.ID); err != nil {
+		t.Fatalf("DeletePayroll: %v", err)
+	}
+
+	// Re-entering the week must not throw away the trashed entry
+	again := old
+	again.TotalHours = 38
+	newID, err := db.CreatePayroll(again)
+	if err != nil {
+		t.Fatalf("create payroll over a trashed one: %v", err)
+	}
+	trashed, err := db.ListDeletedPayroll()
+	if err != nil {
+		t.Fatalf("ListDeletedPayroll: %v", err)
+	}
+	if len(trashed) != 1 || trashed[0].ID != old.ID {
+		t.Fatalf("trash = %+v; want the old entry still there", trashed)
+	}
+
+	if err := db.RestorePayroll(old.ID); !errors.Is(err, ErrPayrollSlotTaken) {
+		t.Errorf("restore while the week is taken: err = %v; want ErrPayrollSlotTaken", err)
+	}
+	if err := db.DeletePayroll(newID); err != nil {
+		t.Fatalf("DeletePayroll: %v", err)
+	}
+	if err := db.RestorePayroll(old.ID); err != nil {
+		t.Errorf("restore once the week is free: %v", err)
+	}
+	if p, err := db.GetPayroll(old.ID); err != nil || p.TotalHours != 40 {
+		t.Errorf("restored entry: hours %.2f, err %v; want 40", p.TotalHours, err)
+	}
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"homebooks/internal/models"
+)
+
+// RecordReconciliationAction saves a review action taken on a transaction. before is
+// the transaction as it was before the action; expenseID is the expense the action
+// matched or created (0 = none)
+func (db *DB) RecordReconciliationAction(action string, before *models.BankTransaction, expenseID int64) error {
+	var priorExpenseID sql.NullInt64
+	if before.MatchedExpenseID != nil {
+		priorExpenseID = sql.NullInt64{Int64: *before.MatchedExpenseID, Valid: true}
+	}
+	var priorMatchedAt sql.NullTime
+	if before.MatchedAt != nil {
+		priorMatchedAt = sql.NullTime{Time: *before.MatchedAt, Valid: true}
+	}
+	var expense sql.NullInt64
+	if expenseID > 0 {
+		expense = sql.NullInt64{Int64: expenseID, Valid: true}
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO reconciliation_actions (reconciliation_id, transaction_id, action, expense_id,
+			prior_status, prior_expense_id, prior_confidence, prior_notes, prior_matched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, before.ReconciliationID, before.ID, action, expense,
+		before.MatchStatus, priorExpenseID, before.MatchConfidence, before.Notes, priorMatchedAt)
+	if err != nil {
+		return fmt.Errorf("record reconciliation action: %w", err)
+	}
+	return nil
+}
+
+// GetLastReconciliationAction returns the most recent review action on a reconciliation,
+// or nil if there is nothing to undo
+func (db *DB) GetLastReconciliationAction(reconciliationID int64) (*models.ReconciliationAction, error) {
+	var a models.ReconciliationAction
+	var expenseID, priorExpenseID sql.NullInt64
+	var priorMatchedAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT a.id, a.reconciliation_id, a.transaction_id, a.action, a.expense_id,
+			   a.prior_status, a.prior_expense_id, a.prior_confidence, a.prior_notes, a.prior_matched_at,
+			   a.created_at, t.description, t.amount
+		FROM reconciliation_actions a
+		JOIN bank_transactions t ON a.transaction_id = t.id
+		WHERE a.reconciliation_id = ?
+		ORDER BY a.id DESC
+		LIMIT 1
+	`, reconciliationID).Scan(&a.ID, &a.ReconciliationID, &a.TransactionID, &a.Action, &expenseID,
+		&a.PriorStatus, &priorExpenseID, &a.PriorConfidence, &a.PriorNotes, &priorMatchedAt,
+		&a.CreatedAt, &a.TransactionDescription, &a.TransactionAmount)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get last reconciliation action: %w", err)
+	}
+	a.ExpenseID = expenseID.Int64
+	if priorExpenseID.Valid {
+		a.PriorExpenseID = &priorExpenseID.Int64
+	}
+	if priorMatchedAt.Valid {
+		a.PriorMatchedAt = &priorMatchedAt.Time
+	}
+	return &a, nil
+}
+
+// UndoReconciliationAction puts the action's transaction back the way it was and removes
+// the action from the history
+func (db *DB) UndoReconciliationAction(a models.ReconciliationAction) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin undo: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE bank_transactions
+		SET match_status = ?, matched_expense_id = ?, match_confidence = ?, notes = ?, matched_at = ?
+		WHERE id = ?
+	`, a.PriorStatus, a.PriorExpenseID, a.PriorConfidence, a.PriorNotes, a.PriorMatchedAt, a.TransactionID)
+	if err != nil {
+		return fmt.Errorf("restore bank transaction: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM reconciliation_actions WHERE id = ?`, a.ID); err != nil {
+		return fmt.Errorf("delete reconciliation action: %w", err)
+	}
+
+	return tx.Commit()
+}
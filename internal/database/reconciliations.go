@@ -3,21 +3,28 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"math"
 
 	"homebooks/internal/models"
 )
 
-// ListReconciliations returns all bank reconciliations ordered by date descending
-func (db *DB) ListReconciliations() ([]models.BankReconciliation, error) {
+// ListReconciliations returns bank reconciliations ordered by date descending, optionally
+// limited to one account (0 = all)
+func (db *DB) ListReconciliations(accountID int64) ([]models.BankReconciliation, error) {
 	rows, err := db.Query(`
-		SELECT id, date(statement_date), strftime('%m-%d-%Y', statement_date),
-			   starting_balance, ending_balance, status, file_path,
-			   account_last_four, parse_job_id, parsed_at, reconciled_at,
-			   notes, electronic_deposits, electronic_payments, checks_paid, service_fees,
-			   created_at, updated_at
-		FROM bank_reconciliations
-		ORDER BY statement_date DESC
-	`)
+		SELECT r.id, date(r.statement_date), strftime('%m-%d-%Y', r.statement_date),
+			   r.starting_balance, r.ending_balance, r.status, r.file_path,
+			   r.account_last_four, r.parse_job_id, r.parsed_at, r.reconciled_at,
+			   r.notes, r.electronic_deposits, r.electronic_payments, r.checks_paid, r.service_fees,
+			   COALESCE(r.default_vendor_id, 0), r.discrepancy_notes, r.accepted_discrepancy_amount,
+			   COALESCE(r.account_id, 0), COALESCE(a.name, ''), COALESCE(a.last_four, ''),
+			   r.calculated_ending_balance, r.balance_matches,
+			   r.created_at, r.updated_at
+		FROM bank_reconciliations r
+		LEFT JOIN accounts a ON r.account_id = a.id
+		WHERE ? = 0 OR r.account_id = ?
+		ORDER BY r.statement_date DESC
+	`, accountID, accountID)
 	if err != nil {
 		return nil, fmt.Errorf("query reconciliations: %w", err)
 	}
@@ -32,6 +39,9 @@ func (db *DB) ListReconciliations() ([]models.BankReconciliation, error) {
 			&r.StartingBalance, &r.EndingBalance, &r.Status, &r.FilePath,
 			&r.AccountLastFour, &parseJobID, &parsedAt, &reconciledAt,
 			&r.Notes, &r.ElectronicDeposits, &r.ElectronicPayments, &r.ChecksPaid, &r.ServiceFees,
+			&r.DefaultVendorID, &r.DiscrepancyNotes, &r.AcceptedDiscrepancy,
+			&r.AccountID, &r.AccountName, &r.AccountExpectedLastFour,
+			&r.CalculatedEndingBalance, &r.BalanceMatches,
 			&r.CreatedAt, &r.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("scan reconciliation: %w", err)
 		}
@@ -55,17 +65,24 @@ func (db *DB) GetReconciliation(id int64) (models.BankReconciliation, error) {
 	var parseJobID sql.NullInt64
 	var parsedAt, reconciledAt sql.NullTime
 	err := db.QueryRow(`
-		SELECT id, date(statement_date), strftime('%m-%d-%Y', statement_date),
-			   starting_balance, ending_balance, status, file_path,
-			   account_last_four, parse_job_id, parsed_at, reconciled_at,
-			   notes, electronic_deposits, electronic_payments, checks_paid, service_fees,
-			   created_at, updated_at
-		FROM bank_reconciliations
-		WHERE id = ?
+		SELECT r.id, date(r.statement_date), strftime('%m-%d-%Y', r.statement_date),
+			   r.starting_balance, r.ending_balance, r.status, r.file_path,
+			   r.account_last_four, r.parse_job_id, r.parsed_at, r.reconciled_at,
+			   r.notes, r.electronic_deposits, r.electronic_payments, r.checks_paid, r.service_fees,
+			   COALESCE(r.default_vendor_id, 0), r.discrepancy_notes, r.accepted_discrepancy_amount,
+			   COALESCE(r.account_id, 0), COALESCE(a.name, ''), COALESCE(a.last_four, ''),
+			   r.calculated_ending_balance, r.balance_matches,
+			   r.created_at, r.updated_at
+		FROM bank_reconciliations r
+		LEFT JOIN accounts a ON r.account_id = a.id
+		WHERE r.id = ?
 	`, id).Scan(&r.ID, &r.StatementDate, &r.StatementDateDisplay,
 		&r.StartingBalance, &r.EndingBalance, &r.Status, &r.FilePath,
 		&r.AccountLastFour, &parseJobID, &parsedAt, &reconciledAt,
 		&r.Notes, &r.ElectronicDeposits, &r.ElectronicPayments, &r.ChecksPaid, &r.ServiceFees,
+		&r.DefaultVendorID, &r.DiscrepancyNotes, &r.AcceptedDiscrepancy,
+		&r.AccountID, &r.AccountName, &r.AccountExpectedLastFour,
+		&r.CalculatedEndingBalance, &r.BalanceMatches,
 		&r.CreatedAt, &r.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return r, fmt.Errorf("reconciliation not found")
@@ -85,12 +102,30 @@ func (db *DB) GetReconciliation(id int64) (models.BankReconciliation, error) {
 	return r, nil
 }
 
+// GetReconciliationIDByFile returns the reconciliation whose statement is stored at
+// path, or 0 if there is none
+func (db *DB) GetReconciliationIDByFile(path string) (int64, error) {
+	var id int64
+	err := db.QueryRow(`SELECT id FROM bank_reconciliations WHERE file_path = ? ORDER BY id LIMIT 1`, path).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("query reconciliation by file: %w", err)
+	}
+	return id, nil
+}
+
 // CreateReconciliation creates a new bank reconciliation
 func (db *DB) CreateReconciliation(r models.BankReconciliation) (int64, error) {
+	var accountID interface{}
+	if r.AccountID > 0 {
+		accountID = r.AccountID
+	}
 	result, err := db.Exec(`
-		INSERT INTO bank_reconciliations (statement_date, starting_balance, ending_balance, status, file_path, notes)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, r.StatementDate, r.StartingBalance, r.EndingBalance, r.Status, r.FilePath, r.Notes)
+		INSERT INTO bank_reconciliations (statement_date, starting_balance, ending_balance, status, file_path, notes, account_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, r.StatementDate, r.StartingBalance, r.EndingBalance, r.Status, r.FilePath, r.Notes, accountID)
 	if err != nil {
 		return 0, fmt.Errorf("insert reconciliation: %w", err)
 	}
@@ -112,6 +147,36 @@ func (db *DB) UpdateReconciliation(r models.BankReconciliation) error {
 	return nil
 }
 
+// RecheckReconciliationBalance recomputes the parse-time balance check against the
+// reconciliation's current balances and transactions, for after a misread balance has
+// been corrected. Like the parser's check, a statement with no balances always matches
+func (db *DB) RecheckReconciliationBalance(id int64) (bool, error) {
+	var starting, ending, transactions float64
+	err := db.QueryRow(`
+		SELECT r.starting_balance, r.ending_balance,
+		       (SELECT COALESCE(SUM(amount), 0) FROM bank_transactions WHERE reconciliation_id = r.id)
+		FROM bank_reconciliations r
+		WHERE r.id = ?
+	`, id).Scan(&starting, &ending, &transactions)
+	if err != nil {
+		return false, fmt.Errorf("query reconciliation balance: %w", err)
+	}
+
+	calculated := math.Round((starting+transactions)*100) / 100
+	matches := (starting == 0 && ending == 0) ||
+		math.Abs(math.Round((calculated-ending)*100)/100) <= models.BalanceTolerance
+
+	_, err = db.Exec(`
+		UPDATE bank_reconciliations
+		SET calculated_ending_balance = ?, balance_matches = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, calculated, matches, id)
+	if err != nil {
+		return false, fmt.Errorf("update reconciliation balance check: %w", err)
+	}
+	return matches, nil
+}
+
 // UpdateReconciliationStatus updates just the status of a reconciliation
 func (db *DB) UpdateReconciliationStatus(id int64, status string) error {
 	_, err := db.Exec(`
@@ -125,6 +190,19 @@ func (db *DB) UpdateReconciliationStatus(id int64, status string) error {
 	return nil
 }
 
+// UpdateReconciliationDiscrepancy records an accepted balance difference and the reason for it
+func (db *DB) UpdateReconciliationDiscrepancy(id int64, amount float64, notes string) error {
+	_, err := db.Exec(`
+		UPDATE bank_reconciliations
+		SET accepted_discrepancy_amount = ?, discrepancy_notes = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, amount, notes, id)
+	if err != nil {
+		return fmt.Errorf("update reconciliation discrepancy: %w", err)
+	}
+	return nil
+}
+
 // UpdateReconciliationParseJob sets the parse job ID for a reconciliation
 func (db *DB) UpdateReconciliationParseJob(id int64, jobID int64) error {
 	_, err := db.Exec(`
@@ -140,15 +218,18 @@ func (db *DB) UpdateReconciliationParseJob(id int64, jobID int64) error {
 
 // UpdateReconciliationParsed updates a reconciliation after parsing completes
 func (db *DB) UpdateReconciliationParsed(id int64, startingBalance, endingBalance float64, accountLastFour string,
-	electronicDeposits, electronicPayments, checksPaid, serviceFees float64) error {
+	electronicDeposits, electronicPayments, checksPaid, serviceFees float64,
+	calculatedEnding float64, balanceMatches bool) error {
 	_, err := db.Exec(`
 		UPDATE bank_reconciliations
 		SET starting_balance = ?, ending_balance = ?, account_last_four = ?,
 		    electronic_deposits = ?, electronic_payments = ?, checks_paid = ?, service_fees = ?,
+		    calculated_ending_balance = ?, balance_matches = ?,
 		    status = 'parsed', parsed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
 	`, startingBalance, endingBalance, accountLastFour,
-		electronicDeposits, electronicPayments, checksPaid, serviceFees, id)
+		electronicDeposits, electronicPayments, checksPaid, serviceFees,
+		calculatedEnding, balanceMatches, id)
 	if err != nil {
 		return fmt.Errorf("update reconciliation parsed: %w", err)
 	}
@@ -168,6 +249,45 @@ func (db *DB) UpdateReconciliationCompleted(id int64) error {
 	return nil
 }
 
+// UpdateReconciliationDefaultVendor sets the vendor prefilled during review (0 clears it)
+func (db *DB) UpdateReconciliationDefaultVendor(id int64, vendorID int64) error {
+	var v interface{}
+	if vendorID > 0 {
+		v = vendorID
+	}
+	_, err := db.Exec(`
+		UPDATE bank_reconciliations
+		SET default_vendor_id = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, v, id)
+	if err != nil {
+		return fmt.Errorf("update reconciliation default vendor: %w", err)
+	}
+	return nil
+}
+
+// GetPreviousReconciliationID returns the statement for the same account that precedes the
+// given one chronologically
+func (db *DB) GetPreviousReconciliationID(id int64) (int64, error) {
+	var prevID int64
+	err := db.QueryRow(`
+		SELECT p.id
+		FROM bank_reconciliations r
+		JOIN bank_reconciliations p ON p.account_id IS r.account_id AND p.account_last_four = r.account_last_four
+		WHERE r.id = ? AND p.id != r.id
+		  AND (p.statement_date < r.statement_date OR (p.statement_date = r.statement_date AND p.id < r.id))
+		ORDER BY p.statement_date DESC, p.id DESC
+		LIMIT 1
+	`, id).Scan(&prevID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("previous reconciliation not found")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("query previous reconciliation: %w", err)
+	}
+	return prevID, nil
+}
+
 // DeleteReconciliation deletes a reconciliation by ID
 func (db *DB) DeleteReconciliation(id int64) error {
 	_, err := db.Exec(`DELETE FROM bank_reconciliations WHERE id = ?`, id)
@@ -177,12 +297,14 @@ func (db *DB) DeleteReconciliation(id int64) error {
 	return nil
 }
 
-// GetReconciledMonths returns a set of months (YYYY-MM format) that have reconciliations
-func (db *DB) GetReconciledMonths() (map[string]bool, error) {
+// GetReconciledMonths returns a set of months (YYYY-MM format) that have reconciliations,
+// optionally limited to one account (0 = all)
+func (db *DB) GetReconciledMonths(accountID int64) (map[string]bool, error) {
 	rows, err := db.Query(`
 		SELECT DISTINCT strftime('%Y-%m', statement_date)
 		FROM bank_reconciliations
-	`)
+		WHERE ? = 0 OR account_id = ?
+	`, accountID, accountID)
 	if err != nil {
 		return nil, fmt.Errorf("query reconciled months: %w", err)
 	}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"homebooks/internal/models"
+)
+
+// ListRecurringExpenses returns all recurring expenses ordered by day of month
+func (db *DB) ListRecurringExpenses() ([]models.RecurringExpense, error) {
+	rows, err := db.Query(`
+		SELECT r.id, r.vendor_id, v.name, r.amount, r.day_of_month, r.payment_type, r.created_at
+		FROM recurring_expenses r
+		JOIN vendors v ON r.vendor_id = v.id
+		ORDER BY r.day_of_month, v.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query recurring expenses: %w", err)
+	}
+	defer rows.Close()
+
+	var recurring []models.RecurringExpense
+	for rows.Next() {
+		var r models.RecurringExpense
+		if err := rows.Scan(&r.ID, &r.VendorID, &r.VendorName, &r.Amount, &r.DayOfMonth,
+			&r.PaymentType, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan recurring expense: %w", err)
+		}
+		recurring = append(recurring, r)
+	}
+	return recurring, rows.Err()
+}
+
+// CreateRecurringExpense inserts a new recurring expense
+func (db *DB) CreateRecurringExpense(r models.RecurringExpense) (int64, error) {
+	result, err := db.Exec(`
+		INSERT INTO recurring_expenses (vendor_id, amount, day_of_month, payment_type) VALUES (?, ?, ?, ?)
+	`, r.VendorID, r.Amount, r.DayOfMonth, r.PaymentType)
+	if err != nil {
+		return 0, fmt.Errorf("insert recurring expense: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// DeleteRecurringExpense removes a recurring expense. Expenses it already generated are kept
+func (db *DB) DeleteRecurringExpense(id int64) error {
+	_, err := db.Exec(`DELETE FROM recurring_expenses WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete recurring expense: %w", err)
+	}
+	return nil
+}
+
+// GenerateRecurringExpenses creates the not-paid expenses for every recurring expense in
+// the month containing t. A vendor that already has an expense that month is skipped, so
+// running it twice (or after entering the bill by hand) doesn't double up. Returns the
+// number of expenses created
+func (db *DB) GenerateRecurringExpenses(t time.Time) (int, error) {
+	recurring, err := db.ListRecurringExpenses()
+	if err != nil {
+		return 0, err
+	}
+
+	monthStart := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	startDate := monthStart.Format("2006-01-02")
+	endDate := monthStart.AddDate(0, 1, -1).Format("2006-01-02")
+
+	created := 0
+	for _, r := range recurring {
+		var exists bool
+		err := db.QueryRow(`
+			SELECT EXISTS (
+				SELECT 1 FROM expenses
+				WHERE vendor_id = ? AND date BETWEEN ? AND ? AND deleted_at IS NULL
+			)
+		`, r.VendorID, startDate, endDate).Scan(&exists)
+		if err != nil {
+			return created, fmt.Errorf("check existing expense for vendor %d: %w", r.VendorID, err)
+		}
+		if exists {
+			continue
+		}
+
+		if _, err := db.CreateExpense(r.Expense(t)); err != nil {
+			return created, fmt.Errorf("create recurring expense %d: %w", r.ID, err)
+		}
+		created++
+	}
+	return created, nil
+}
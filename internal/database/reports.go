@@ -0,0 +1,371 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"homebooks/internal/models"
+)
+
+// GetIncomeBreakdown totals income between two dates (inclusive) for each of models.IncomeCategories
+func (db *DB) GetIncomeBreakdown(startDate, endDate string) ([]models.IncomeLine, error) {
+	// Card payments include sales tax, so the card share of net sales is scaled back by net/(net+tax)
+	var cardSales, cashSales float64
+	err := db.QueryRow(`
+		SELECT
+			COALESCE(SUM(CASE WHEN net_sales + taxes > 0 THEN credit_card * net_sales / (net_sales + taxes) ELSE 0 END), 0),
+			COALESCE(SUM(net_sales), 0)
+		FROM daily_sales
+		WHERE date BETWEEN ? AND ? AND deleted_at IS NULL
+	`, startDate, endDate).Scan(&cardSales, &cashSales)
+	if err != nil {
+		return nil, fmt.Errorf("sum dine-in sales: %w", err)
+	}
+	cashSales -= cardSales
+
+	lines := make([]models.IncomeLine, 0, len(models.IncomeCategories))
+	for _, cat := range models.IncomeCategories {
+		line := models.IncomeLine{Category: cat.Name}
+		switch cat.Source {
+		case models.IncomeSourceCardSales:
+			line.Amount = cardSales
+		case models.IncomeSourceCashSales:
+			line.Amount = cashSales
+		case models.IncomeSourceDelivery:
+			line.Amount, err = db.SumDeliveryNet(cat.Platform, startDate, endDate)
+			if err != nil {
+				return nil, err
+			}
+		case models.IncomeSourceDeposits:
+			err = db.QueryRow(`
+				SELECT COALESCE(SUM(amount), 0)
+				FROM bank_transactions
+				WHERE category = ? AND amount > 0 AND match_status != 'personal'
+				  AND posting_date BETWEEN ? AND ?
+			`, cat.BankCategory, startDate, endDate).Scan(&line.Amount)
+			if err != nil {
+				return nil, fmt.Errorf("sum %s deposits: %w", cat.BankCategory, err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown income source %q", cat.Source)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// GetMonthToDateSummary totals the current month's sales, delivery, expenses and payroll so far
+func (db *DB) GetMonthToDateSummary() (models.MonthToDateSummary, error) {
+	var startDate, endDate string
+	err := db.QueryRow(`SELECT date('now', 'start of month'), date('now')`).Scan(&startDate, &endDate)
+	if err != nil {
+		return models.MonthToDateSummary{}, fmt.Errorf("query current month: %w", err)
+	}
+	return db.summarizePeriod(startDate, endDate)
+}
+
+// summarizePeriod totals sales, delivery, expenses and payroll between two dates (inclusive)
+func (db *DB) summarizePeriod(startDate, endDate string) (models.MonthToDateSummary, error) {
+	s := models.MonthToDateSummary{StartDate: startDate, EndDate: endDate}
+	err := db.QueryRow(`
+		SELECT COALESCE(SUM(net_sales), 0) FROM daily_sales
+		WHERE date BETWEEN ? AND ? AND deleted_at IS NULL
+	`, s.StartDate, s.EndDate).Scan(&s.NetSales)
+	if err != nil {
+		return s, fmt.Errorf("sum sales: %w", err)
+	}
+
+	for _, platform := range models.DeliveryPlatforms {
+		net, err := db.SumDeliveryNet(platform.Key, s.StartDate, s.EndDate)
+		if err != nil {
+			return s, err
+		}
+		s.DeliveryNet += net
+	}
+
+	err = db.QueryRow(`
+		SELECT COALESCE(SUM(amount), 0) FROM expenses
+		WHERE date BETWEEN ? AND ? AND deleted_at IS NULL
+	`, s.StartDate, s.EndDate).Scan(&s.Expenses)
+	if err != nil {
+		return s, fmt.Errorf("sum expenses: %w", err)
+	}
+
+	// Payroll counts toward the month its week ends in, paid or not
+	err = db.QueryRow(`
+		SELECT COALESCE(SUM(p.total_hours * p.hourly_rate), 0)
+		FROM payroll p
+		JOIN payroll_weeks w ON p.week_id = w.id
+		WHERE w.period_end BETWEEN ? AND ? AND p.deleted_at IS NULL
+	`, s.StartDate, s.EndDate).Scan(&s.Payroll)
+	if err != nil {
+		return s, fmt.Errorf("sum payroll: %w", err)
+	}
+
+	return s, nil
+}
+
+// GetCashFlow totals a month's (YYYY-MM) cash in and out. Inflows are net sales and
+// delivery net for days in the month. Outflows are cash basis: an expense counts in the
+// month each payment on it was made (one marked paid without payments in the month it
+// was paid, or its date when no paid date was recorded) and payroll in the month it was
+// paid (its week end likewise); bills and payroll with nothing paid are left out
+func (db *DB) GetCashFlow(month string) (models.CashFlow, error) {
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return models.CashFlow{}, fmt.Errorf("parse month: %w", err)
+	}
+	c := models.CashFlow{
+		Month:     month,
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   start.AddDate(0, 1, -1).Format("2006-01-02"),
+	}
+
+	err = db.QueryRow(`
+		SELECT COALESCE(SUM(net_sales), 0) FROM daily_sales
+		WHERE date BETWEEN ? AND ? AND deleted_at IS NULL
+	`, c.StartDate, c.EndDate).Scan(&c.NetSales)
+	if err != nil {
+		return c, fmt.Errorf("sum sales: %w", err)
+	}
+
+	err = db.QueryRow(`
+		SELECT COALESCE(SUM(net), 0) FROM delivery_platform_sales
+		WHERE date BETWEEN ? AND ?
+	`, c.StartDate, c.EndDate).Scan(&c.DeliveryNet)
+	if err != nil {
+		return c, fmt.Errorf("sum delivery net: %w", err)
+	}
+
+	// Installments count on the day each was paid, partly paid bills included. Whatever
+	// a paid expense's payments don't cover, all of it when it was marked paid without
+	// any, counts on its paid date
+	err = db.QueryRow(`
+		SELECT COALESCE(SUM(amount), 0) FROM (
+			SELECT ep.amount
+			FROM expense_payments ep
+			JOIN expenses e ON ep.expense_id = e.id
+			WHERE e.deleted_at IS NULL AND date(ep.date) BETWEEN ?1 AND ?2
+			UNION ALL
+			SELECT MAX(e.amount - COALESCE((SELECT SUM(ep.amount) FROM expense_payments ep WHERE ep.expense_id = e.id), 0), 0)
+			FROM expenses e
+			WHERE e.status = 'paid' AND e.deleted_at IS NULL
+			  AND COALESCE(e.date_paid, e.date) BETWEEN ?1 AND ?2
+		)
+	`, c.StartDate, c.EndDate).Scan(&c.Expenses)
+	if err != nil {
+		return c, fmt.Errorf("sum paid expenses: %w", err)
+	}
+
+	err = db.QueryRow(`
+		SELECT COALESCE(SUM(p.total_hours * p.hourly_rate), 0)
+		FROM payroll p
+		JOIN payroll_weeks w ON p.week_id = w.id
+		WHERE p.status = 'paid' AND p.deleted_at IS NULL
+		  AND COALESCE(p.date_paid, w.period_end) BETWEEN ? AND ?
+	`, c.StartDate, c.EndDate).Scan(&c.Payroll)
+	if err != nil {
+		return c, fmt.Errorf("sum paid payroll: %w", err)
+	}
+
+	return c, nil
+}
+
+// GetMonthlyPL builds the profit and loss summary for a month (YYYY-MM), with expenses
+// broken down by vendor category. Months without data come back as zeros
+func (db *DB) GetMonthlyPL(month string) (models.MonthlyPL, error) {
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return models.MonthlyPL{}, fmt.Errorf("parse month: %w", err)
+	}
+	startDate := start.Format("2006-01-02")
+	endDate := start.AddDate(0, 1, -1).Format("2006-01-02")
+
+	totals, err := db.summarizePeriod(startDate, endDate)
+	if err != nil {
+		return models.MonthlyPL{}, err
+	}
+	pl := models.MonthlyPL{
+		Month:       month,
+		StartDate:   startDate,
+		EndDate:     endDate,
+		NetSales:    totals.NetSales,
+		DeliveryNet: totals.DeliveryNet,
+		Payroll:     totals.Payroll,
+	}
+
+	// An expense's own category wins over its vendor's; a vendor with several
+	// categories is counted under the first so nothing is double counted
+	rows, err := db.Query(`
+		SELECT CASE WHEN e.category != '' THEN e.category ELSE COALESCE(v.category, '') END, e.amount
+		FROM expenses e
+		LEFT JOIN vendors v ON e.vendor_id = v.id
+		WHERE e.date BETWEEN ? AND ? AND e.deleted_at IS NULL
+	`, startDate, endDate)
+	if err != nil {
+		return pl, fmt.Errorf("query expenses by category: %w", err)
+	}
+	defer rows.Close()
+
+	byCategory := make(map[string]float64)
+	for rows.Next() {
+		var categories string
+		var amount float64
+		if err := rows.Scan(&categories, &amount); err != nil {
+			return pl, fmt.Errorf("scan expense category: %w", err)
+		}
+		category, _, _ := strings.Cut(categories, ",")
+		byCategory[strings.TrimSpace(category)] += amount
+	}
+	if err := rows.Err(); err != nil {
+		return pl, err
+	}
+
+	for _, cat := range models.VendorCategories {
+		pl.Expenses = append(pl.Expenses, models.ExpenseCategoryLine{Category: cat, Amount: byCategory[cat]})
+		delete(byCategory, cat)
+	}
+	for _, amount := range byCategory {
+		pl.Uncategorized += amount
+	}
+
+	return pl, nil
+}
+
+// GetSalesTrend returns total net sales (dine-in plus delivery net) for each of the
+// last count weeks or months, oldest first and ending with the current one. Weeks
+// start on Monday. Periods without sales are included as zero so a chart of them is
+// evenly spaced
+func (db *DB) GetSalesTrend(period string, count int) ([]models.SalesTrendPoint, error) {
+	if count < 1 {
+		return nil, nil
+	}
+
+	// Work out the periods first, then drop each day's total into its period
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	var periodStart func(time.Time) time.Time
+	var next func(time.Time) time.Time
+	var label func(time.Time) string
+	switch period {
+	case "week":
+		periodStart = func(t time.Time) time.Time { return t.AddDate(0, 0, -(int(t.Weekday())+6)%7) }
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }
+		label = func(t time.Time) string { return t.Format("Jan 2") }
+	case "month":
+		periodStart = func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local) }
+		next = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+		label = func(t time.Time) string { return t.Format("Jan 2006") }
+	default:
+		return nil, fmt.Errorf("unknown trend period %q", period)
+	}
+
+	first := periodStart(today)
+	for i := 1; i < count; i++ {
+		first = periodStart(first.AddDate(0, 0, -1))
+	}
+	points := make([]models.SalesTrendPoint, 0, count)
+	index := make(map[string]int, count)
+	for start := first; len(points) < count; start = next(start) {
+		index[start.Format("2006-01-02")] = len(points)
+		points = append(points, models.SalesTrendPoint{
+			Label:     label(start),
+			StartDate: start.Format("2006-01-02"),
+			EndDate:   next(start).AddDate(0, 0, -1).Format("2006-01-02"),
+		})
+	}
+	startDate, endDate := points[0].StartDate, points[len(points)-1].EndDate
+
+	rows, err := db.Query(`
+		SELECT date, SUM(net_sales) FROM daily_sales
+		WHERE date BETWEEN ? AND ? AND deleted_at IS NULL
+		GROUP BY date
+		UNION ALL
+		SELECT date, SUM(net) FROM delivery_platform_sales
+		WHERE date BETWEEN ? AND ?
+		GROUP BY date
+	`, startDate, endDate, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("query sales trend: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var date string
+		var amount float64
+		if err := rows.Scan(&date, &amount); err != nil {
+			return nil, fmt.Errorf("scan sales trend: %w", err)
+		}
+		day, err := time.ParseInLocation("2006-01-02", date[:min(len(date), 10)], time.Local)
+		if err != nil {
+			continue
+		}
+		if i, ok := index[periodStart(day).Format("2006-01-02")]; ok {
+			points[i].NetSales += amount
+		}
+	}
+	return points, rows.Err()
+}
+
+// GetVendorSpend totals expenses between two dates (inclusive) per vendor and per
+// vendor category, largest first. Expenses with only a payee name are grouped by that
+// name. Categories come from the vendor record, and a vendor with several categories
+// adds its whole spend to each one, so category totals double count such vendors and
+// may sum to more than the overall total. Expenses without a vendor category are
+// listed as "Uncategorized"
+func (db *DB) GetVendorSpend(startDate, endDate string) (models.VendorSpend, error) {
+	spend := models.VendorSpend{StartDate: startDate, EndDate: endDate}
+	rows, err := db.Query(`
+		SELECT COALESCE(v.id, 0), COALESCE(v.name, e.payee_name, ''), COALESCE(v.category, ''),
+			COUNT(*), SUM(e.amount)
+		FROM expenses e
+		LEFT JOIN vendors v ON e.vendor_id = v.id
+		WHERE e.date BETWEEN ? AND ? AND e.deleted_at IS NULL
+		GROUP BY COALESCE(v.id, 0), CASE WHEN v.id IS NULL THEN e.payee_name ELSE '' END
+	`, startDate, endDate)
+	if err != nil {
+		return spend, fmt.Errorf("query vendor spend: %w", err)
+	}
+	defer rows.Close()
+
+	byCategory := make(map[string]*models.CategorySpendLine)
+	for rows.Next() {
+		var line models.VendorSpendLine
+		var categories string
+		if err := rows.Scan(&line.VendorID, &line.Name, &categories, &line.Count, &line.Total); err != nil {
+			return spend, fmt.Errorf("scan vendor spend: %w", err)
+		}
+		spend.Vendors = append(spend.Vendors, line)
+		spend.Total += line.Total
+
+		cats := strings.Split(categories, ",")
+		if strings.TrimSpace(categories) == "" {
+			cats = []string{"Uncategorized"}
+		}
+		for _, cat := range cats {
+			cat = strings.TrimSpace(cat)
+			if byCategory[cat] == nil {
+				byCategory[cat] = &models.CategorySpendLine{Category: cat}
+			}
+			byCategory[cat].Count += line.Count
+			byCategory[cat].Total += line.Total
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return spend, err
+	}
+
+	for _, line := range byCategory {
+		spend.Categories = append(spend.Categories, *line)
+	}
+	sort.Slice(spend.Vendors, func(i, j int) bool { return spend.Vendors[i].Total > spend.Vendors[j].Total })
+	sort.Slice(spend.Categories, func(i, j int) bool {
+		if spend.Categories[i].Total != spend.Categories[j].Total {
+			return spend.Categories[i].Total > spend.Categories[j].Total
+		}
+		return spend.Categories[i].Category < spend.Categories[j].Category
+	})
+	return spend, nil
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"math"
+	"testing"
+
+	"homebooks/internal/models"
+)
+
+func TestGetCashFlowExpensePayments(t *testing.T) {
+	db := openTestDB(t)
+
+	create := func(e models.Expense) int64 {
+		t.Helper()
+		e.PayeeName = "Jetro"
+		id, err := db.CreateExpense(e)
+		if err != nil {
+			t.Fatalf("create expense: %v", err)
+		}
+		return id
+	}
+	pay := func(id int64, amount float64, date string) {
+		t.Helper()
+		err := db.AddExpensePayment(models.ExpensePayment{ExpenseID: id, Amount: amount, Date: date, PaymentType: "check"})
+		if err != nil {
+			t.Fatalf("add payment: %v", err)
+		}
+	}
+
+	// Paid off in two installments across September and October
+	installments := create(models.Expense{Date: "2026-09-01", Amount: 300, Status: "not_paid"})
+	pay(installments, 100, "2026-09-20")
+	pay(installments, 200, "2026-10-05")
+
+	// Still partly owed, with one payment in October
+	partial := create(models.Expense{Date: "2026-10-01", Amount: 80, Status: "not_paid"})
+	pay(partial, 50, "2026-10-10")
+
+	// Marked paid before payments were recorded, so only its paid date says when
+	create(models.Expense{Date: "2026-09-28", Amount: 40, Status: "paid", PaymentType: "cash", DatePaid: "2026-10-02"})
+
+	// Nothing paid yet
+	create(models.Expense{Date: "2026-10-03", Amount: 500, Status: "not_paid"})
+
+	tests := []struct {
+		month string
+		want  float64
+	}{
+		{"2026-09", 100},
+		{"2026-10", 200 + 50 + 40},
+		{"2026-11", 0},
+	}
+	for _, tt := range tests {
+		c, err := db.GetCashFlow(tt.month)
+		if err != nil {
+			t.Fatalf("GetCashFlow(%s): %v", tt.month, err)
+		}
+		if math.Abs(c.Expenses-tt.want) > 0.005 {
+			t.Errorf("GetCashFlow(%s).Expenses = %.2f, want %.2f", tt.month, c.Expenses, tt.want)
+		}
+	}
+}
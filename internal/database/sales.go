@@ -2,7 +2,10 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"time"
 
 	"homebooks/internal/models"
@@ -12,7 +15,7 @@ func (db *DB) ListSales(filter models.SalesFilter) ([]models.DailySale, error) {
 	query := `
 		SELECT id, strftime('%m-%d-%Y', date), shift, net_sales, taxes, credit_card, cash_receipt, cash_on_hand, notes
 		FROM daily_sales
-		WHERE 1=1
+		WHERE deleted_at IS NULL
 	`
 	var args []interface{}
 
@@ -44,11 +47,32 @@ func (db *DB) ListSales(filter models.SalesFilter) ([]models.DailySale, error) {
 	return sales, rows.Err()
 }
 
+// ListSalesWithVariance returns the shifts between startDate and endDate whose cash on
+// hand is off from the expected cash by more than threshold either way, largest first.
+// The expected-cash formula lives on DailySale, so the filtering happens after the fetch
+func (db *DB) ListSalesWithVariance(startDate, endDate string, threshold float64) ([]models.DailySale, error) {
+	sales, err := db.ListSales(models.SalesFilter{StartDate: startDate, EndDate: endDate})
+	if err != nil {
+		return nil, err
+	}
+
+	var flagged []models.DailySale
+	for _, s := range sales {
+		if math.Abs(s.Variance()) > threshold {
+			flagged = append(flagged, s)
+		}
+	}
+	sort.SliceStable(flagged, func(i, j int) bool {
+		return math.Abs(flagged[i].Variance()) > math.Abs(flagged[j].Variance())
+	})
+	return flagged, nil
+}
+
 func (db *DB) ListRecentSales(days int) ([]models.DailySale, error) {
 	rows, err := db.Query(`
 		SELECT id, strftime('%m-%d-%Y', date), shift, net_sales, taxes, credit_card, cash_receipt, cash_on_hand, notes
 		FROM daily_sales
-		WHERE date >= date('now', '-' || ? || ' days')
+		WHERE deleted_at IS NULL AND date >= date('now', '-' || ? || ' days')
 		ORDER BY date(date) DESC, CASE shift WHEN 'dinner' THEN 1 WHEN 'lunch' THEN 2 WHEN 'breakfast' THEN 3 END
 	`, days)
 	if err != nil {
@@ -72,7 +96,7 @@ func (db *DB) ListRecentSalesGrouped(days int) ([]models.DateGroup, float64, err
 	rows, err := db.Query(`
 		SELECT id, date(date), strftime('%m-%d-%Y', date), shift, net_sales, taxes, credit_card, cash_receipt, cash_on_hand, notes
 		FROM daily_sales
-		WHERE date >= date('now', '-' || ? || ' days')
+		WHERE deleted_at IS NULL AND date >= date('now', '-' || ? || ' days')
 		ORDER BY date(date) DESC, CASE shift WHEN 'dinner' THEN 1 WHEN 'lunch' THEN 2 WHEN 'breakfast' THEN 3 END
 	`, days)
 	if err != nil {
@@ -130,11 +154,17 @@ func (db *DB) ListRecentSalesGrouped(days int) ([]models.DateGroup, float64, err
 		return nil, 0, fmt.Errorf("fetch delivery sales: %w", err)
 	}
 
-	// Attach delivery data to each DateGroup
+	dayNotes, err := db.GetDayNotesForDates(dates)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Attach delivery data and day notes to each DateGroup
 	for i := range dateGroups {
 		if delivery, ok := deliveryMap[dateGroups[i].RawDate]; ok {
 			dateGroups[i].Delivery = delivery
 		}
+		dateGroups[i].DayNote = dayNotes[dateGroups[i].RawDate]
 	}
 
 	return dateGroups, grandTotal, nil
@@ -145,7 +175,7 @@ func (db *DB) GetSale(id int64) (models.DailySale, error) {
 	err := db.QueryRow(`
 		SELECT id, date(date), shift, net_sales, taxes, credit_card, cash_receipt, cash_on_hand, notes
 		FROM daily_sales
-		WHERE id = ?
+		WHERE id = ? AND deleted_at IS NULL
 	`, id).Scan(&s.ID, &s.Date, &s.Shift, &s.NetSales, &s.Taxes, &s.CreditCard, &s.CashReceipt, &s.CashOnHand, &s.Notes)
 	if err == sql.ErrNoRows {
 		return s, fmt.Errorf("sale not found")
@@ -161,7 +191,7 @@ func (db *DB) UpsertSale(s models.DailySale) (int64, error) {
 	result, err := db.Exec(`
 		INSERT INTO daily_sales (date, shift, net_sales, taxes, credit_card, cash_receipt, cash_on_hand, notes)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT(date, shift) DO UPDATE SET
+		ON CONFLICT(date, shift) WHERE deleted_at IS NULL DO UPDATE SET
 			net_sales = excluded.net_sales,
 			taxes = excluded.taxes,
 			credit_card = excluded.credit_card,
@@ -188,27 +218,120 @@ func (db *DB) UpdateSale(s models.DailySale) error {
 	return nil
 }
 
+// DeleteSale moves a sale to the trash
 func (db *DB) DeleteSale(id int64) error {
-	_, err := db.Exec(`DELETE FROM daily_sales WHERE id = ?`, id)
+	_, err := db.Exec(`UPDATE daily_sales SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL`, id)
 	if err != nil {
 		return fmt.Errorf("delete sale: %w", err)
 	}
 	return nil
 }
 
+// ErrSaleSlotTaken is returned by RestoreSale when another sale has since been entered
+// for the same date and shift
+var ErrSaleSlotTaken = errors.New("a sale for this date and shift has been entered since; delete it before restoring this one")
+
+// RestoreSale brings a sale back from the trash, unless its date and shift now hold
+// another sale (ErrSaleSlotTaken)
+func (db *DB) RestoreSale(id int64) error {
+	var taken bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM daily_sales s
+			JOIN daily_sales t ON t.date = s.date AND t.shift = s.shift
+			WHERE t.id = ? AND s.id != t.id AND s.deleted_at IS NULL
+		)
+	`, id).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("check sale slot: %w", err)
+	}
+	if taken {
+		return ErrSaleSlotTaken
+	}
+
+	_, err = db.Exec(`UPDATE daily_sales SET deleted_at = NULL WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("restore sale: %w", err)
+	}
+	return nil
+}
+
+// PurgeSale permanently deletes a sale that is already in the trash
+func (db *DB) PurgeSale(id int64) error {
+	_, err := db.Exec(`DELETE FROM daily_sales WHERE id = ? AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return fmt.Errorf("purge sale: %w", err)
+	}
+	return nil
+}
+
+// ListDeletedSales returns sales in the trash, most recently deleted first
+func (db *DB) ListDeletedSales() ([]models.TrashItem, error) {
+	rows, err := db.Query(`
+		SELECT id, strftime('%m-%d-%Y', date), shift, net_sales, strftime('%m-%d-%Y', deleted_at)
+		FROM daily_sales
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query deleted sales: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.TrashItem
+	for rows.Next() {
+		t := models.TrashItem{Type: "sales"}
+		if err := rows.Scan(&t.ID, &t.Date, &t.Description, &t.Amount, &t.DeletedAt); err != nil {
+			return nil, fmt.Errorf("scan deleted sale: %w", err)
+		}
+		items = append(items, t)
+	}
+	return items, rows.Err()
+}
+
 // GetTodaySalesTotal returns the total net sales for today
 func (db *DB) GetTodaySalesTotal() (float64, error) {
 	var total sql.NullFloat64
-	err := db.QueryRow(`SELECT SUM(net_sales) FROM daily_sales WHERE date = date('now')`).Scan(&total)
+	err := db.QueryRow(`SELECT SUM(net_sales) FROM daily_sales WHERE date = date('now') AND deleted_at IS NULL`).Scan(&total)
 	if err != nil {
 		return 0, fmt.Errorf("query today sales total: %w", err)
 	}
 	return total.Float64, nil
 }
 
+// GetWeekToDateSales returns net sales plus delivery net payouts from this week's Monday
+// through today, matching the payroll week. On a Monday that is just today's sales
+func (db *DB) GetWeekToDateSales() (float64, error) {
+	return db.salesIncomeToDate(`date('now', '-6 days', 'weekday 1')`)
+}
+
+// GetMonthToDateSales returns net sales plus delivery net payouts from the first of the
+// month through today
+func (db *DB) GetMonthToDateSales() (float64, error) {
+	return db.salesIncomeToDate(`date('now', 'start of month')`)
+}
+
+// salesIncomeToDate totals net sales and delivery net from startExpr, an SQLite date
+// expression, through today
+func (db *DB) salesIncomeToDate(startExpr string) (float64, error) {
+	var total float64
+	err := db.QueryRow(`
+		WITH period AS (SELECT ` + startExpr + ` AS start_date, date('now') AS end_date)
+		SELECT
+			(SELECT COALESCE(SUM(s.net_sales), 0) FROM daily_sales s, period p
+			 WHERE s.date BETWEEN p.start_date AND p.end_date AND s.deleted_at IS NULL) +
+			(SELECT COALESCE(SUM(d.net), 0) FROM delivery_platform_sales d, period p
+			 WHERE d.date BETWEEN p.start_date AND p.end_date)
+	`).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("query sales to date: %w", err)
+	}
+	return total, nil
+}
+
 // GetShiftsForDate returns the shifts that already have entries for a given date
 func (db *DB) GetShiftsForDate(date string) ([]string, error) {
-	rows, err := db.Query(`SELECT shift FROM daily_sales WHERE date = ?`, date)
+	rows, err := db.Query(`SELECT shift FROM daily_sales WHERE date = ? AND deleted_at IS NULL`, date)
 	if err != nil {
 		return nil, fmt.Errorf("query shifts for date: %w", err)
 	}
@@ -254,6 +377,7 @@ func (db *DB) ListSalesGrouped() (*models.GroupedSalesData, error) {
 	rows, err := db.Query(`
 		SELECT id, date(date), strftime('%m-%d-%Y', date), shift, net_sales, taxes, credit_card, cash_receipt, cash_on_hand, notes
 		FROM daily_sales
+		WHERE deleted_at IS NULL
 		ORDER BY date(date) DESC, CASE shift WHEN 'dinner' THEN 1 WHEN 'lunch' THEN 2 WHEN 'breakfast' THEN 3 END
 	`)
 	if err != nil {
@@ -391,35 +515,120 @@ func (db *DB) ListSalesGrouped() (*models.GroupedSalesData, error) {
 		return nil, fmt.Errorf("fetch delivery sales: %w", err)
 	}
 
-	// Attach delivery data to Today
+	dayNotes, err := db.GetDayNotesForDates(dates)
+	if err != nil {
+		return nil, err
+	}
+
+	// Attach delivery data and day note to Today
 	if delivery, ok := deliveryMap[today]; ok {
 		result.Today.Delivery = delivery
 	}
+	result.Today.DayNote = dayNotes[today]
 
-	// Attach delivery data to ThisMonth DateGroups
+	// Attach delivery data and day notes to ThisMonth DateGroups
 	for i := range result.ThisMonth.DateGroups {
 		if delivery, ok := deliveryMap[result.ThisMonth.DateGroups[i].RawDate]; ok {
 			result.ThisMonth.DateGroups[i].Delivery = delivery
 		}
+		result.ThisMonth.DateGroups[i].DayNote = dayNotes[result.ThisMonth.DateGroups[i].RawDate]
 	}
 
-	// Attach delivery data to PrevMonths DateGroups
+	// Attach delivery data and day notes to PrevMonths DateGroups
 	for i := range result.PrevMonths {
 		for j := range result.PrevMonths[i].DateGroups {
 			if delivery, ok := deliveryMap[result.PrevMonths[i].DateGroups[j].RawDate]; ok {
 				result.PrevMonths[i].DateGroups[j].Delivery = delivery
 			}
+			result.PrevMonths[i].DateGroups[j].DayNote = dayNotes[result.PrevMonths[i].DateGroups[j].RawDate]
 		}
 	}
 
-	// Attach delivery data to PrevYears DateGroups
+	// Attach delivery data and day notes to PrevYears DateGroups
 	for i := range result.PrevYears {
 		for j := range result.PrevYears[i].DateGroups {
 			if delivery, ok := deliveryMap[result.PrevYears[i].DateGroups[j].RawDate]; ok {
 				result.PrevYears[i].DateGroups[j].Delivery = delivery
 			}
+			result.PrevYears[i].DateGroups[j].DayNote = dayNotes[result.PrevYears[i].DateGroups[j].RawDate]
 		}
 	}
 
 	return result, nil
 }
+
+// ListSalesByDate returns sales in the filter's date range as one group per date,
+// oldest first, with delivery data attached. Dates with only delivery data get a
+// group of their own so exports don't drop them
+func (db *DB) ListSalesByDate(filter models.SalesFilter) ([]models.DateGroup, error) {
+	rows, err := db.Query(`
+		SELECT id, date(date), strftime('%m-%d-%Y', date), shift, net_sales, taxes, credit_card, cash_receipt, cash_on_hand, notes
+		FROM daily_sales
+		WHERE deleted_at IS NULL AND (? = '' OR date >= ?) AND (? = '' OR date <= ?)
+		ORDER BY date(date), CASE shift WHEN 'breakfast' THEN 1 WHEN 'lunch' THEN 2 WHEN 'dinner' THEN 3 END
+	`, filter.StartDate, filter.StartDate, filter.EndDate, filter.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("query sales: %w", err)
+	}
+	defer rows.Close()
+
+	group := &models.SalesGroup{}
+	for rows.Next() {
+		var s models.DailySale
+		var rawDate string
+		if err := rows.Scan(&s.ID, &rawDate, &s.Date, &s.Shift, &s.NetSales, &s.Taxes, &s.CreditCard, &s.CashReceipt, &s.CashOnHand, &s.Notes); err != nil {
+			return nil, fmt.Errorf("scan sale: %w", err)
+		}
+		addToDateGroup(group, s, rawDate, s.Date)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	deliveryRows, err := db.Query(`
+		SELECT date(date), strftime('%m-%d-%Y', date)
+		FROM delivery_sales
+		WHERE (? = '' OR date >= ?) AND (? = '' OR date <= ?)
+	`, filter.StartDate, filter.StartDate, filter.EndDate, filter.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("query delivery dates: %w", err)
+	}
+	defer deliveryRows.Close()
+
+	seen := make(map[string]bool)
+	for _, dg := range group.DateGroups {
+		seen[dg.RawDate] = true
+	}
+	for deliveryRows.Next() {
+		var rawDate, displayDate string
+		if err := deliveryRows.Scan(&rawDate, &displayDate); err != nil {
+			return nil, fmt.Errorf("scan delivery date: %w", err)
+		}
+		if !seen[rawDate] {
+			group.DateGroups = append(group.DateGroups, models.DateGroup{Date: displayDate, RawDate: rawDate})
+			seen[rawDate] = true
+		}
+	}
+	if err := deliveryRows.Err(); err != nil {
+		return nil, err
+	}
+
+	dateGroups := group.DateGroups
+	sort.Slice(dateGroups, func(i, j int) bool {
+		return dateGroups[i].RawDate < dateGroups[j].RawDate
+	})
+
+	var dates []string
+	for _, dg := range dateGroups {
+		dates = append(dates, dg.RawDate)
+	}
+	deliveryMap, err := db.GetDeliverySalesForDates(dates)
+	if err != nil {
+		return nil, fmt.Errorf("fetch delivery sales: %w", err)
+	}
+	for i := range dateGroups {
+		dateGroups[i].Delivery = deliveryMap[dateGroups[i].RawDate]
+	}
+
+	return dateGroups, nil
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"homebooks/internal/models"
+)
+
+// GetSalesTaxOwed totals the sales tax collected across all shifts between startDate and
+// endDate (inclusive), one entry per calendar month with a running total. Months with
+// no sales are included at zero, and each month carries the filing that covers it, if any
+func (db *DB) GetSalesTaxOwed(startDate, endDate string) ([]models.SalesTaxMonth, error) {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date %q", startDate)
+	}
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date %q", endDate)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end date is before start date")
+	}
+
+	rows, err := db.Query(`
+		SELECT strftime('%Y-%m', date), SUM(taxes)
+		FROM daily_sales
+		WHERE deleted_at IS NULL AND date BETWEEN ? AND ?
+		GROUP BY strftime('%Y-%m', date)
+	`, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("query sales tax: %w", err)
+	}
+	defer rows.Close()
+
+	taxes := make(map[string]float64)
+	for rows.Next() {
+		var month string
+		var amount float64
+		if err := rows.Scan(&month, &amount); err != nil {
+			return nil, fmt.Errorf("scan sales tax: %w", err)
+		}
+		taxes[month] = amount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	filings, err := db.ListTaxFilings()
+	if err != nil {
+		return nil, err
+	}
+
+	var months []models.SalesTaxMonth
+	var running float64
+	for first := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC); !first.After(end); first = first.AddDate(0, 1, 0) {
+		m := models.SalesTaxMonth{
+			Month:     first.Format("Jan 2006"),
+			StartDate: first.Format("2006-01-02"),
+			EndDate:   first.AddDate(0, 1, -1).Format("2006-01-02"),
+			Taxes:     taxes[first.Format("2006-01")],
+		}
+		m.StartDate = max(m.StartDate, startDate)
+		m.EndDate = min(m.EndDate, endDate)
+		running += m.Taxes
+		m.RunningTotal = running
+
+		for i := range filings {
+			if filings[i].PeriodStart <= m.StartDate && filings[i].PeriodEnd >= m.EndDate {
+				m.Filing = &filings[i]
+				break
+			}
+		}
+		months = append(months, m)
+	}
+	return months, nil
+}
+
+// ListTaxFilings returns every sales tax filing, most recent period first
+func (db *DB) ListTaxFilings() ([]models.TaxFiling, error) {
+	rows, err := db.Query(`
+		SELECT id, date(period_start), date(period_end), amount, date(paid_date), COALESCE(notes, ''), created_at
+		FROM tax_filings
+		ORDER BY period_start DESC, id DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query tax filings: %w", err)
+	}
+	defer rows.Close()
+
+	var filings []models.TaxFiling
+	for rows.Next() {
+		var f models.TaxFiling
+		if err := rows.Scan(&f.ID, &f.PeriodStart, &f.PeriodEnd, &f.Amount, &f.PaidDate, &f.Notes, &f.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan tax filing: %w", err)
+		}
+		filings = append(filings, f)
+	}
+	return filings, rows.Err()
+}
+
+// CreateTaxFiling records a period's sales tax as filed and paid
+func (db *DB) CreateTaxFiling(f models.TaxFiling) (int64, error) {
+	result, err := db.Exec(`
+		INSERT INTO tax_filings (period_start, period_end, amount, paid_date, notes) VALUES (?, ?, ?, ?, ?)
+	`, f.PeriodStart, f.PeriodEnd, f.Amount, f.PaidDate, f.Notes)
+	if err != nil {
+		return 0, fmt.Errorf("insert tax filing: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// DeleteTaxFiling removes a filing, putting its period back to outstanding
+func (db *DB) DeleteTaxFiling(id int64) error {
+	_, err := db.Exec(`DELETE FROM tax_filings WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete tax filing: %w", err)
+	}
+	return nil
+}
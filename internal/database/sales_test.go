@@ -0,0 +1,50 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"homebooks/internal/models"
+)
+
+func TestTrashedSaleStaysRestorable(t *testing.T) {
+	db := openTestDB(t)
+	sale := models.DailySale{Date: "2026-10-05", Shift: "lunch", NetSales: 500, Taxes: 40, CreditCard: 300, CashReceipt: 200, CashOnHand: 200}
+	oldID, err := db.UpsertSale(sale)
+	if err != nil {
+		t.Fatalf("UpsertSale: %v", err)
+	}
+	if err := db.DeleteSale(oldID); err != nil {
+		t.Fatalf("DeleteSale: %v", err)
+	}
+
+	// Re-entering the shift adds a new sale and leaves the trashed one alone
+	sale.NetSales = 450
+	newID, err := db.UpsertSale(sale)
+	if err != nil {
+		t.Fatalf("UpsertSale over a trashed sale: %v", err)
+	}
+	if newID == oldID {
+		t.Fatalf("re-entered sale reused id %d of the trashed one", oldID)
+	}
+	sale.NetSales = 475
+	if _, err := db.UpsertSale(sale); err != nil {
+		t.Fatalf("UpsertSale again: %v", err)
+	}
+	if s, err := db.GetSale(newID); err != nil || s.NetSales != 475 {
+		t.Errorf("second save: net sales %.2f, err %v; want 475 saved over the active sale", s.NetSales, err)
+	}
+
+	if err := db.RestoreSale(oldID); !errors.Is(err, ErrSaleSlotTaken) {
+		t.Errorf("restore while the shift is taken: err = %v; want ErrSaleSlotTaken", err)
+	}
+	if err := db.DeleteSale(newID); err != nil {
+		t.Fatalf("DeleteSale: %v", err)
+	}
+	if err := db.RestoreSale(oldID); err != nil {
+		t.Errorf("restore once the shift is free: %v", err)
+	}
+	if s, err := db.GetSale(oldID); err != nil || s.NetSales != 500 {
+		t.Errorf("restored sale: net sales %.2f, err %v; want 500", s.NetSales, err)
+	}
+}
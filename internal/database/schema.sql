@@ -29,6 +29,7 @@ CREATE TABLE IF NOT EXISTS daily_sales (
     notes TEXT DEFAULT '',
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME,
     UNIQUE(date, shift)
 );
 
@@ -46,10 +47,33 @@ CREATE TABLE IF NOT EXISTS delivery_sales (
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Per-date context (weather, local events) kept apart from per-shift sale notes
+CREATE TABLE IF NOT EXISTS sales_day_notes (
+    date DATE PRIMARY KEY,
+    note TEXT NOT NULL DEFAULT '',
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Rows inserted by cmd/seed, so demo data can be removed again
+CREATE TABLE IF NOT EXISTS demo_records (
+    table_name TEXT NOT NULL,
+    record_id INTEGER NOT NULL,
+    PRIMARY KEY (table_name, record_id)
+);
+
+CREATE TABLE IF NOT EXISTS cash_deposits (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    date DATE NOT NULL,
+    amount REAL NOT NULL,
+    notes TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE TABLE IF NOT EXISTS expenses (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     date DATE NOT NULL,
-    vendor_id INTEGER NOT NULL REFERENCES vendors(id),
+    vendor_id INTEGER REFERENCES vendors(id),
+    payee_name TEXT DEFAULT '',
     amount REAL NOT NULL,
     invoice_number TEXT DEFAULT '',
     status TEXT CHECK(status IN ('paid', 'not_paid')) DEFAULT 'not_paid',
@@ -60,8 +84,10 @@ CREATE TABLE IF NOT EXISTS expenses (
     date_paid DATE,
     notes TEXT DEFAULT '',
     receipt_path TEXT DEFAULT '',
+    category TEXT DEFAULT '',
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
 );
 
 CREATE TABLE IF NOT EXISTS payroll_weeks (
@@ -72,12 +98,24 @@ CREATE TABLE IF NOT EXISTS payroll_weeks (
     UNIQUE(period_start, period_end)
 );
 
+-- Hourly rate changes, so old raises can be traced; payroll entries keep the rate they were paid at
+CREATE TABLE IF NOT EXISTS employee_rate_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    employee_id INTEGER NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    old_rate REAL NOT NULL,
+    new_rate REAL NOT NULL,
+    effective_date DATE NOT NULL,
+    changed_by TEXT DEFAULT '',
+    changed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE TABLE IF NOT EXISTS payroll (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     week_id INTEGER NOT NULL REFERENCES payroll_weeks(id),
     employee_id INTEGER NOT NULL REFERENCES employees(id),
     total_hours REAL NOT NULL,
     hourly_rate REAL NOT NULL,
+    withholding REAL DEFAULT 0,
     payment_method TEXT CHECK(payment_method IN ('cash', 'check')) NOT NULL,
     check_number TEXT DEFAULT '',
     status TEXT CHECK(status IN ('paid', 'not_paid')) DEFAULT 'not_paid',
@@ -85,9 +123,18 @@ CREATE TABLE IF NOT EXISTS payroll (
     notes TEXT DEFAULT '',
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME,
     UNIQUE(week_id, employee_id)
 );
 
+-- Bank accounts statements are uploaded for
+CREATE TABLE IF NOT EXISTS accounts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    last_four TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE TABLE IF NOT EXISTS bank_reconciliations (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     statement_date DATE NOT NULL,
@@ -105,6 +152,13 @@ CREATE TABLE IF NOT EXISTS bank_reconciliations (
     electronic_payments REAL DEFAULT 0,
     checks_paid REAL DEFAULT 0,
     service_fees REAL DEFAULT 0,
+    default_vendor_id INTEGER,
+    discrepancy_notes TEXT DEFAULT '',
+    accepted_discrepancy_amount REAL DEFAULT 0,
+    account_id INTEGER REFERENCES accounts(id),
+    -- Starting balance plus parsed transactions, checked against ending_balance at parse time
+    calculated_ending_balance REAL DEFAULT 0,
+    balance_matches INTEGER DEFAULT 1,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
@@ -117,11 +171,12 @@ CREATE TABLE IF NOT EXISTS bank_transactions (
     amount REAL NOT NULL,
     transaction_type TEXT NOT NULL,
     category TEXT DEFAULT '',
+    platform TEXT DEFAULT '',
     check_number TEXT DEFAULT '',
     vendor_hint TEXT DEFAULT '',
     reference_number TEXT DEFAULT '',
     matched_expense_id INTEGER,
-    match_status TEXT CHECK(match_status IN ('unmatched', 'matched', 'ignored', 'created')) DEFAULT 'unmatched',
+    match_status TEXT CHECK(match_status IN ('unmatched', 'matched', 'ignored', 'created', 'personal')) DEFAULT 'unmatched',
     match_confidence TEXT DEFAULT '',
     matched_at DATETIME,
     notes TEXT DEFAULT '',
@@ -130,6 +185,38 @@ CREATE TABLE IF NOT EXISTS bank_transactions (
     FOREIGN KEY (matched_expense_id) REFERENCES expenses(id)
 );
 
+-- Review actions on bank transactions with the state they replaced, so the latest can be undone
+CREATE TABLE IF NOT EXISTS reconciliation_actions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reconciliation_id INTEGER NOT NULL REFERENCES bank_reconciliations(id) ON DELETE CASCADE,
+    transaction_id INTEGER NOT NULL REFERENCES bank_transactions(id) ON DELETE CASCADE,
+    action TEXT NOT NULL CHECK(action IN ('match', 'unmatch', 'ignore', 'personal', 'create')),
+    expense_id INTEGER,
+    prior_status TEXT NOT NULL,
+    prior_expense_id INTEGER,
+    prior_confidence TEXT DEFAULT '',
+    prior_notes TEXT DEFAULT '',
+    prior_matched_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS auto_booking_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    vendor_id INTEGER NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+    category TEXT DEFAULT '',
+    pattern TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS recurring_expenses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    vendor_id INTEGER NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+    amount REAL NOT NULL,
+    day_of_month INTEGER NOT NULL CHECK(day_of_month BETWEEN 1 AND 31),
+    payment_type TEXT CHECK(payment_type IN ('cash', 'check', 'debit', 'credit', '')) DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE TABLE IF NOT EXISTS jobs (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     job_type TEXT NOT NULL,
@@ -144,15 +231,32 @@ CREATE TABLE IF NOT EXISTS jobs (
     completed_at DATETIME
 );
 
+-- Named logins; while there are none, the single shared password is used
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    password_hash TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE TABLE IF NOT EXISTS sessions (
     token TEXT PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     expires_at DATETIME NOT NULL
 );
 
+-- App-wide key/value settings, such as the login password hash
+CREATE TABLE IF NOT EXISTS settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
 -- Indexes for common queries
 CREATE INDEX IF NOT EXISTS idx_daily_sales_date ON daily_sales(date);
 CREATE INDEX IF NOT EXISTS idx_delivery_sales_date ON delivery_sales(date);
+CREATE INDEX IF NOT EXISTS idx_cash_deposits_date ON cash_deposits(date);
 CREATE INDEX IF NOT EXISTS idx_expenses_date ON expenses(date);
 CREATE INDEX IF NOT EXISTS idx_expenses_status ON expenses(status);
 CREATE INDEX IF NOT EXISTS idx_expenses_vendor_id ON expenses(vendor_id);
@@ -166,4 +270,8 @@ CREATE INDEX IF NOT EXISTS idx_reconciliations_status ON bank_reconciliations(st
 CREATE INDEX IF NOT EXISTS idx_bank_txn_recon ON bank_transactions(reconciliation_id);
 CREATE INDEX IF NOT EXISTS idx_bank_txn_status ON bank_transactions(match_status);
 CREATE INDEX IF NOT EXISTS idx_bank_txn_date ON bank_transactions(posting_date);
+CREATE INDEX IF NOT EXISTS idx_reconciliation_actions_recon ON reconciliation_actions(reconciliation_id);
+CREATE INDEX IF NOT EXISTS idx_employee_rate_history_employee ON employee_rate_history(employee_id);
+CREATE INDEX IF NOT EXISTS idx_auto_booking_rules_vendor ON auto_booking_rules(vendor_id);
+CREATE INDEX IF NOT EXISTS idx_recurring_expenses_vendor ON recurring_expenses(vendor_id);
 CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status);
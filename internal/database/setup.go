@@ -0,0 +1,32 @@
+package database
+
+import (
+	"fmt"
+
+	"homebooks/internal/models"
+)
+
+// GetSetupProgress reports which of the first-run data entry steps have been done.
+// PasswordSet is left for the caller, since the password lives in the environment
+func (db *DB) GetSetupProgress() (models.SetupProgress, error) {
+	var s models.SetupProgress
+	err := db.QueryRow(`
+		SELECT
+			EXISTS (SELECT 1 FROM vendors),
+			EXISTS (SELECT 1 FROM employees),
+			EXISTS (SELECT 1 FROM daily_sales WHERE deleted_at IS NULL)
+	`).Scan(&s.HasVendors, &s.HasEmployees, &s.HasSales)
+	if err != nil {
+		return s, fmt.Errorf("query setup progress: %w", err)
+	}
+	return s, nil
+}
+
+// IsFreshInstall reports whether no vendors, employees or sales have been entered yet
+func (db *DB) IsFreshInstall() (bool, error) {
+	s, err := db.GetSetupProgress()
+	if err != nil {
+		return false, err
+	}
+	return !s.HasVendors && !s.HasEmployees && !s.HasSales, nil
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"homebooks/internal/models"
@@ -9,7 +10,7 @@ import (
 
 func (db *DB) ListVendors() ([]models.Vendor, error) {
 	rows, err := db.Query(`
-		SELECT id, name, category, description
+		SELECT id, name, category, description, COALESCE(default_payment_type, '')
 		FROM vendors
 		ORDER BY name
 	`)
@@ -21,7 +22,34 @@ func (db *DB) ListVendors() ([]models.Vendor, error) {
 	var vendors []models.Vendor
 	for rows.Next() {
 		var v models.Vendor
-		if err := rows.Scan(&v.ID, &v.Name, &v.Category, &v.Description); err != nil {
+		if err := rows.Scan(&v.ID, &v.Name, &v.Category, &v.Description, &v.DefaultPaymentType); err != nil {
+			return nil, fmt.Errorf("scan vendor: %w", err)
+		}
+		vendors = append(vendors, v)
+	}
+	return vendors, rows.Err()
+}
+
+// SearchVendors returns up to limit vendors whose name contains q, ignoring case.
+// An empty q matches every vendor
+func (db *DB) SearchVendors(q string, limit int) ([]models.Vendor, error) {
+	pattern := containsPattern(q)
+	rows, err := db.Query(`
+		SELECT id, name, category, description, COALESCE(default_payment_type, '')
+		FROM vendors
+		WHERE name LIKE ? ESCAPE '\'
+		ORDER BY name
+		LIMIT ?
+	`, pattern, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search vendors: %w", err)
+	}
+	defer rows.Close()
+
+	var vendors []models.Vendor
+	for rows.Next() {
+		var v models.Vendor
+		if err := rows.Scan(&v.ID, &v.Name, &v.Category, &v.Description, &v.DefaultPaymentType); err != nil {
 			return nil, fmt.Errorf("scan vendor: %w", err)
 		}
 		vendors = append(vendors, v)
@@ -32,10 +60,10 @@ func (db *DB) ListVendors() ([]models.Vendor, error) {
 func (db *DB) GetVendor(id int64) (models.Vendor, error) {
 	var v models.Vendor
 	err := db.QueryRow(`
-		SELECT id, name, category, description
+		SELECT id, name, category, description, COALESCE(default_payment_type, '')
 		FROM vendors
 		WHERE id = ?
-	`, id).Scan(&v.ID, &v.Name, &v.Category, &v.Description)
+	`, id).Scan(&v.ID, &v.Name, &v.Category, &v.Description, &v.DefaultPaymentType)
 	if err == sql.ErrNoRows {
 		return v, fmt.Errorf("vendor not found")
 	}
@@ -45,20 +73,20 @@ func (db *DB) GetVendor(id int64) (models.Vendor, error) {
 	return v, nil
 }
 
-func (db *DB) CreateVendor(name, category, description string) (int64, error) {
+func (db *DB) CreateVendor(name, category, description, defaultPaymentType string) (int64, error) {
 	result, err := db.Exec(`
-		INSERT INTO vendors (name, category, description) VALUES (?, ?, ?)
-	`, name, category, description)
+		INSERT INTO vendors (name, category, description, default_payment_type) VALUES (?, ?, ?, ?)
+	`, name, category, description, defaultPaymentType)
 	if err != nil {
 		return 0, fmt.Errorf("insert vendor: %w", err)
 	}
 	return result.LastInsertId()
 }
 
-func (db *DB) UpdateVendor(id int64, name, category, description string) error {
+func (db *DB) UpdateVendor(id int64, name, category, description, defaultPaymentType string) error {
 	_, err := db.Exec(`
-		UPDATE vendors SET name = ?, category = ?, description = ? WHERE id = ?
-	`, name, category, description, id)
+		UPDATE vendors SET name = ?, category = ?, description = ?, default_payment_type = ? WHERE id = ?
+	`, name, category, description, defaultPaymentType, id)
 	if err != nil {
 		return fmt.Errorf("update vendor: %w", err)
 	}
@@ -72,3 +100,54 @@ func (db *DB) DeleteVendor(id int64) error {
 	}
 	return nil
 }
+
+// ErrMergeSelf is returned by MergeVendors when the source and target are the same vendor
+var ErrMergeSelf = errors.New("cannot merge a vendor into itself")
+
+// MergeVendors moves everything that points at the source vendor over to the target and
+// then deletes the source, all in one transaction. Recurring expenses and auto-booking
+// rules cascade on vendor delete, so they have to be moved first or they would be lost.
+// Returns the number of expenses moved
+func (db *DB) MergeVendors(sourceID, targetID int64) (int64, error) {
+	if sourceID == targetID {
+		return 0, ErrMergeSelf
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM vendors WHERE id IN (?, ?)`, sourceID, targetID).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("check vendors: %w", err)
+	}
+	if exists != 2 {
+		return 0, sql.ErrNoRows
+	}
+
+	result, err := tx.Exec(`UPDATE expenses SET vendor_id = ? WHERE vendor_id = ?`, targetID, sourceID)
+	if err != nil {
+		return 0, fmt.Errorf("move expenses: %w", err)
+	}
+	moved, _ := result.RowsAffected()
+
+	if _, err := tx.Exec(`UPDATE recurring_expenses SET vendor_id = ? WHERE vendor_id = ?`, targetID, sourceID); err != nil {
+		return 0, fmt.Errorf("move recurring expenses: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE auto_booking_rules SET vendor_id = ? WHERE vendor_id = ?`, targetID, sourceID); err != nil {
+		return 0, fmt.Errorf("move auto-booking rules: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE bank_reconciliations SET default_vendor_id = ? WHERE default_vendor_id = ?`, targetID, sourceID); err != nil {
+		return 0, fmt.Errorf("move reconciliation defaults: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM vendors WHERE id = ?`, sourceID); err != nil {
+		return 0, fmt.Errorf("delete vendor: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit merge: %w", err)
+	}
+	return moved, nil
+}
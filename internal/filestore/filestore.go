@@ -1,91 +1,250 @@
 package filestore
 
 import (
-	"crypto/rand"
+	"bytes"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 )
 
-// Store handles local file storage
-type Store struct {
-	basePath string
+// DefaultMaxSize is the upload limit used when SaveOptions.MaxSize is not set
+const DefaultMaxSize = 10 << 20
+
+// MaxUploadSizeFromEnv returns the upload limit in bytes set by HOMEBOOKS_MAX_UPLOAD_MB,
+// or DefaultMaxSize when it is unset or not a positive number
+func MaxUploadSizeFromEnv() int64 {
+	mb, err := strconv.ParseInt(os.Getenv("HOMEBOOKS_MAX_UPLOAD_MB"), 10, 64)
+	if err != nil || mb <= 0 {
+		return DefaultMaxSize
+	}
+	return mb << 20
 }
 
-// New creates a new file store with the given base path
-func New(basePath string) (*Store, error) {
-	// Create base directory if it doesn't exist
-	if err := os.MkdirAll(basePath, 0755); err != nil {
-		return nil, fmt.Errorf("create filestore directory: %w", err)
+// Content types accepted for each kind of upload, as reported by DetectContentType.
+// CSV and OFX statements sniff as plain text or XML
+var (
+	ReceiptTypes   = []string{"application/pdf", "image/jpeg", "image/png", "image/heic"}
+	StatementTypes = []string{"application/pdf", "text/plain", "text/xml"}
+)
+
+// Errors returned by SaveValidated when an upload is rejected
+var (
+	ErrFileTooLarge = errors.New("file is too large")
+	ErrFileType     = errors.New("file type is not allowed")
+)
+
+// SaveOptions limits what SaveValidated accepts
+type SaveOptions struct {
+	MaxSize      int64    // bytes; DefaultMaxSize when zero
+	AllowedTypes []string // content types without parameters, e.g. "image/png"
+}
+
+// Store keeps uploaded files. Files are named by the SHA-256 of their content, so
+// the same bytes uploaded twice are stored once
+type Store interface {
+	// Save stores a file and returns the path to record for it
+	Save(filename string, r io.Reader) (string, error)
+	// SaveValidated is Save with a content type and size check first
+	SaveValidated(filename string, r io.Reader, opts SaveOptions) (string, error)
+	// SaveThumbnail writes a JPEG thumbnail of a stored image and returns its path
+	SaveThumbnail(filename string) (string, error)
+	// Get opens a stored file; the caller closes it
+	Get(filename string) (io.ReadCloser, error)
+	// Delete removes a stored file and its thumbnail. A missing file is not an error
+	Delete(filename string) error
+	// Exists reports whether a file with the given content hash is stored
+	Exists(hash string) bool
+	// FullPath says where a file lives, for logs: a filesystem path or a URL
+	FullPath(filename string) string
+	// LocalPath returns a path on local disk with the file's content, for readers that
+	// need a real file. Call cleanup once done with it
+	LocalPath(filename string) (path string, cleanup func(), err error)
+}
+
+// backend is where a store keeps its files. Hashing, validation and thumbnails are
+// shared on top of it
+type backend interface {
+	// put moves a finished temp file in under name; sum is its hex SHA-256
+	put(name string, tmp *os.File, sum string) error
+	get(name string) (io.ReadCloser, error)
+	// remove deletes a file, treating a missing one as success
+	remove(name string) error
+	exists(name string) bool
+	existsPrefix(prefix string) bool
+	fullPath(name string) string
+	// tempDir is where uploads are written while their hash is worked out
+	tempDir() string
+}
+
+type store struct {
+	backend
+}
+
+// NewFromEnv returns an S3 store when HOMEBOOKS_S3_BUCKET is set, and otherwise a
+// local store at basePath
+func NewFromEnv(basePath string) (Store, error) {
+	if cfg, ok := S3ConfigFromEnv(); ok {
+		return NewS3(cfg)
 	}
-	return &Store{basePath: basePath}, nil
+	return New(basePath)
 }
 
-// Save stores a file and returns the relative path
-func (s *Store) Save(filename string, r io.Reader) (string, error) {
-	// Generate unique filename to avoid collisions
-	uniqueID, err := generateID()
+// Save stores a file named by the SHA-256 of its content, keeping the original
+// extension, and returns the relative path. If identical content with the same
+// extension is already stored, that path is returned and nothing new is written
+func (s *store) Save(filename string, r io.Reader) (string, error) {
+	tmp, sum, err := s.writeTemp(r)
 	if err != nil {
-		return "", fmt.Errorf("generate file id: %w", err)
+		return "", err
 	}
+	defer os.Remove(tmp.Name()) // no-op once a local store renames it
+	defer tmp.Close()
 
-	// Preserve original extension
-	ext := filepath.Ext(filename)
-	newFilename := uniqueID + ext
+	newFilename := sum + strings.ToLower(filepath.Ext(filename))
+	if s.exists(newFilename) {
+		return newFilename, nil
+	}
+	if err := s.put(newFilename, tmp, sum); err != nil {
+		return "", fmt.Errorf("store file: %w", err)
+	}
+	return newFilename, nil
+}
 
-	// Create full path
-	fullPath := filepath.Join(s.basePath, newFilename)
+// writeTemp copies r to a temporary file, since the hash isn't known until the end.
+// The file is returned open and rewound, along with its hex SHA-256
+func (s *store) writeTemp(r io.Reader) (*os.File, string, error) {
+	tmp, err := os.CreateTemp(s.tempDir(), ".upload-*")
+	if err != nil {
+		return nil, "", fmt.Errorf("create file: %w", err)
+	}
 
-	// Create file
-	f, err := os.Create(fullPath)
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), r)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
 	if err != nil {
-		return "", fmt.Errorf("create file: %w", err)
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, "", fmt.Errorf("write file: %w", err)
 	}
-	defer f.Close()
+	return tmp, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Exists reports whether a file with the given SHA-256 content hash is stored, under
+// any extension
+func (s *store) Exists(hash string) bool {
+	return s.existsPrefix(hash+".") || s.exists(hash)
+}
 
-	// Copy data to file
-	if _, err := io.Copy(f, r); err != nil {
-		os.Remove(fullPath) // Clean up on error
-		return "", fmt.Errorf("write file: %w", err)
+// SaveValidated stores a file like Save, but first sniffs its content type against
+// opts.AllowedTypes and rejects it if it is larger than opts.MaxSize
+func (s *store) SaveValidated(filename string, r io.Reader, opts SaveOptions) (string, error) {
+	maxSize := opts.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
 	}
 
-	return newFilename, nil
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("read file: %w", err)
+	}
+	head = head[:n]
+
+	contentType := DetectContentType(head)
+	if !slices.Contains(opts.AllowedTypes, contentType) {
+		return "", fmt.Errorf("%w (%s)", ErrFileType, contentType)
+	}
+
+	// Allow one byte past the limit so an oversized file can be told apart
+	limited := &io.LimitedReader{R: io.MultiReader(bytes.NewReader(head), r), N: maxSize + 1}
+	path, err := s.Save(filename, limited)
+	if err != nil {
+		return "", err
+	}
+	if limited.N == 0 {
+		s.Delete(path)
+		return "", fmt.Errorf("%w (limit is %d MB)", ErrFileTooLarge, maxSize>>20)
+	}
+	return path, nil
+}
+
+// DetectContentType sniffs the content type of a file from its first 512 bytes. It is
+// http.DetectContentType without parameters, plus HEIC, which phones save photos as
+func DetectContentType(head []byte) string {
+	if len(head) >= 12 && string(head[4:8]) == "ftyp" {
+		switch string(head[8:12]) {
+		case "heic", "heix", "hevc", "hevx", "mif1", "msf1":
+			return "image/heic"
+		}
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	return contentType
 }
 
 // Get returns a reader for the file at the given path
-func (s *Store) Get(filename string) (*os.File, error) {
-	fullPath := filepath.Join(s.basePath, filename)
-	f, err := os.Open(fullPath)
+func (s *store) Get(filename string) (io.ReadCloser, error) {
+	rc, err := s.get(filename)
 	if err != nil {
 		return nil, fmt.Errorf("open file: %w", err)
 	}
-	return f, nil
+	return rc, nil
 }
 
-// Delete removes the file at the given path
-func (s *Store) Delete(filename string) error {
+// Delete removes the file at the given path, along with its thumbnail if it has one
+func (s *store) Delete(filename string) error {
 	if filename == "" {
 		return nil
 	}
-	fullPath := filepath.Join(s.basePath, filename)
-	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+	if err := s.remove(filename); err != nil {
 		return fmt.Errorf("delete file: %w", err)
 	}
+	if CanThumbnail(filename) {
+		s.remove(ThumbnailPath(filename))
+	}
 	return nil
 }
 
-// FullPath returns the full filesystem path for a filename
-func (s *Store) FullPath(filename string) string {
-	return filepath.Join(s.basePath, filename)
+// FullPath returns the full filesystem path or URL for a filename
+func (s *store) FullPath(filename string) string {
+	return s.fullPath(filename)
 }
 
-// generateID creates a random 16-character hex string
-func generateID() (string, error) {
-	bytes := make([]byte, 8)
-	if _, err := rand.Read(bytes); err != nil {
-		return "", err
+// LocalPath returns the file's own path in a local store. Other stores download it
+// to a temporary file, keeping the extension so parsers can tell the format
+func (s *store) LocalPath(filename string) (string, func(), error) {
+	if l, ok := s.backend.(*localBackend); ok {
+		return l.fullPath(filename), func() {}, nil
+	}
+
+	rc, err := s.Get(filename)
+	if err != nil {
+		return "", nil, err
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp("", "homebooks-*"+filepath.Ext(filename))
+	if err != nil {
+		return "", nil, fmt.Errorf("create temp file: %w", err)
+	}
+	cleanup := func() { os.Remove(tmp.Name()) }
+
+	_, err = io.Copy(tmp, rc)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("download file: %w", err)
 	}
-	return hex.EncodeToString(bytes), nil
+	return tmp.Name(), cleanup, nil
 }
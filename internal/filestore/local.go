@@ -0,0 +1,57 @@
+package filestore
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// localBackend keeps files in a directory on disk
+type localBackend struct {
+	basePath string
+}
+
+// New creates a file store in the given local directory
+func New(basePath string) (Store, error) {
+	// Create base directory if it doesn't exist
+	if err := os.MkdirAll(basePath, 0755); err != nil {
+		return nil, fmt.Errorf("create filestore directory: %w", err)
+	}
+	return &store{&localBackend{basePath: basePath}}, nil
+}
+
+func (l *localBackend) put(name string, tmp *os.File, sum string) error {
+	return os.Rename(tmp.Name(), l.fullPath(name))
+}
+
+func (l *localBackend) get(name string) (io.ReadCloser, error) {
+	return os.Open(l.fullPath(name))
+}
+
+func (l *localBackend) remove(name string) error {
+	if err := os.Remove(l.fullPath(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (l *localBackend) exists(name string) bool {
+	_, err := os.Stat(l.fullPath(name))
+	return err == nil
+}
+
+func (l *localBackend) existsPrefix(prefix string) bool {
+	matches, _ := filepath.Glob(l.fullPath(prefix + "*"))
+	return len(matches) > 0
+}
+
+func (l *localBackend) fullPath(name string) string {
+	return filepath.Join(l.basePath, name)
+}
+
+// Uploads are written in the store's own directory so the final rename stays on
+// one filesystem
+func (l *localBackend) tempDir() string {
+	return l.basePath
+}
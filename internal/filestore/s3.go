@@ -0,0 +1,310 @@
+package filestore
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty body, signed for requests without one
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Config points a store at an S3-compatible bucket (AWS, MinIO, R2, B2 and the like)
+type S3Config struct {
+	Endpoint     string // e.g. https://s3.us-east-1.amazonaws.com or http://minio:9000
+	Region       string
+	Bucket       string
+	Prefix       string // key prefix inside the bucket, e.g. "uploads/"
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// S3ConfigFromEnv reads the S3 settings. ok is false when HOMEBOOKS_S3_BUCKET is
+// unset, meaning files stay on local disk. Credentials fall back to the standard
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY variables
+func S3ConfigFromEnv() (cfg S3Config, ok bool) {
+	cfg.Bucket = os.Getenv("HOMEBOOKS_S3_BUCKET")
+	if cfg.Bucket == "" {
+		return cfg, false
+	}
+
+	cfg.Region = envOr("HOMEBOOKS_S3_REGION", "us-east-1")
+	cfg.Endpoint = envOr("HOMEBOOKS_S3_ENDPOINT", "https://s3."+cfg.Region+".amazonaws.com")
+	cfg.Prefix = os.Getenv("HOMEBOOKS_S3_PREFIX")
+	cfg.AccessKey = envOr("HOMEBOOKS_S3_ACCESS_KEY", os.Getenv("AWS_ACCESS_KEY_ID"))
+	cfg.SecretKey = envOr("HOMEBOOKS_S3_SECRET_KEY", os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	cfg.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	return cfg, true
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// s3Backend talks to the bucket with path-style requests signed with AWS Signature
+// Version 4, which every S3-compatible service accepts
+type s3Backend struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// NewS3 creates a file store in an S3-compatible bucket, checking up front that the
+// bucket can be reached with the given credentials
+func NewS3(cfg S3Config) (Store, error) {
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("s3 filestore: access key and secret key are required")
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("s3 filestore: invalid endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Prefix = strings.Trim(cfg.Prefix, "/"); cfg.Prefix != "" {
+		cfg.Prefix += "/"
+	}
+
+	b := &s3Backend{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 2 * time.Minute},
+	}
+
+	resp, err := b.do(http.MethodHead, "", nil, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return nil, fmt.Errorf("check bucket: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("check bucket %s: %s", cfg.Bucket, resp.Status)
+	}
+	return &store{b}, nil
+}
+
+func (b *s3Backend) put(name string, tmp *os.File, sum string) error {
+	info, err := tmp.Stat()
+	if err != nil {
+		return err
+	}
+	resp, err := b.do(http.MethodPut, name, nil, tmp, info.Size(), sum)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+func (b *s3Backend) get(name string) (io.ReadCloser, error) {
+	resp, err := b.do(http.MethodGet, name, nil, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %w", name, os.ErrNotExist)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, s3Error(resp)
+	}
+	return resp.Body, nil
+}
+
+// S3 answers a delete of a missing key with success, so there is no not-found case
+func (b *s3Backend) remove(name string) error {
+	resp, err := b.do(http.MethodDelete, name, nil, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+func (b *s3Backend) exists(name string) bool {
+	resp, err := b.do(http.MethodHead, name, nil, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+func (b *s3Backend) existsPrefix(prefix string) bool {
+	query := url.Values{
+		"list-type": {"2"},
+		"max-keys":  {"1"},
+		"prefix":    {b.cfg.Prefix + prefix},
+	}
+	resp, err := b.do(http.MethodGet, "", query, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+
+	var result struct {
+		KeyCount int `xml:"KeyCount"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false
+	}
+	return result.KeyCount > 0
+}
+
+func (b *s3Backend) fullPath(name string) string {
+	return "s3://" + b.cfg.Bucket + "/" + b.cfg.Prefix + name
+}
+
+func (b *s3Backend) tempDir() string {
+	return os.TempDir()
+}
+
+// do sends a signed request for an object, or for the bucket itself when name is
+// empty. payloadHash is the hex SHA-256 of body
+func (b *s3Backend) do(method, name string, query url.Values, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	canonicalPath := "/" + uriEncode(b.cfg.Bucket, false)
+	if name != "" {
+		canonicalPath += "/" + uriEncode(b.cfg.Prefix+name, true)
+	}
+	canonicalQuery := canonicalQueryString(query)
+
+	rawURL := b.endpoint.Scheme + "://" + b.endpoint.Host + canonicalPath
+	if canonicalQuery != "" {
+		rawURL += "?" + canonicalQuery
+	}
+	req, err := http.NewRequest(method, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+
+	b.sign(req, canonicalPath, canonicalQuery, payloadHash, time.Now().UTC())
+	return b.client.Do(req)
+}
+
+// sign adds the SigV4 headers. The signing key is derived from the secret, the date,
+// the region and the service, so a leaked signature is only good for one day
+func (b *s3Backend) sign(req *http.Request, canonicalPath, canonicalQuery, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if b.cfg.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", b.cfg.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for key := range req.Header {
+		headers[strings.ToLower(key)] = strings.TrimSpace(req.Header.Get(key))
+	}
+	names := make([]string, 0, len(headers))
+	for key := range headers {
+		names = append(names, key)
+	}
+	slices.Sort(names)
+
+	var canonicalHeaders strings.Builder
+	for _, key := range names {
+		canonicalHeaders.WriteString(key + ":" + headers[key] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalPath,
+		canonicalQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + b.cfg.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+b.cfg.SecretKey), day)
+	key = hmacSHA256(key, b.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		b.cfg.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQueryString sorts and encodes query parameters the way SigV4 expects,
+// which differs from url.Values.Encode in how spaces are written
+func canonicalQueryString(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var parts []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, uriEncode(key, false)+"="+uriEncode(value, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes everything but the RFC 3986 unreserved characters, and
+// slashes too unless keepSlash is set
+func uriEncode(s string, keepSlash bool) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && keepSlash:
+			out.WriteByte(c)
+		default:
+			fmt.Fprintf(&out, "%%%02X", c)
+		}
+	}
+	return out.String()
+}
+
+// s3Error turns an S3 error response into an error, using its XML message if any
+func s3Error(resp *http.Response) error {
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body); err != nil || body.Code == "" {
+		return fmt.Errorf("s3: %s", resp.Status)
+	}
+	return fmt.Errorf("s3: %s: %s (%s)", resp.Status, body.Message, body.Code)
+}
@@ -0,0 +1,82 @@
+package filestore
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png" // register the PNG decoder for image.Decode
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// ThumbnailSize is the longest edge, in pixels, of a generated thumbnail
+const ThumbnailSize = 400
+
+// CanThumbnail reports whether a thumbnail can be made for the stored file, which is
+// only true for JPEG and PNG images
+func CanThumbnail(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".jpg", ".jpeg", ".png":
+		return true
+	}
+	return false
+}
+
+// ThumbnailPath returns where the thumbnail for a stored file is kept
+func ThumbnailPath(filename string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename)) + "_thumb.jpg"
+}
+
+// SaveThumbnail writes a JPEG thumbnail of a stored image alongside it, scaled down to
+// fit ThumbnailSize, and returns the thumbnail's path
+func (s *store) SaveThumbnail(filename string) (string, error) {
+	if !CanThumbnail(filename) {
+		return "", fmt.Errorf("no thumbnail for %s files", filepath.Ext(filename))
+	}
+
+	f, err := s.get(filename)
+	if err != nil {
+		return "", fmt.Errorf("open image: %w", err)
+	}
+	defer f.Close()
+
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return "", fmt.Errorf("decode image: %w", err)
+	}
+
+	// Never scale up; small images are re-encoded at their own size
+	b := src.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if longest := max(width, height); longest > ThumbnailSize {
+		width = max(width*ThumbnailSize/longest, 1)
+		height = max(height*ThumbnailSize/longest, 1)
+	}
+
+	// JPEG has no alpha, so transparent PNGs go on white rather than black
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return "", fmt.Errorf("encode thumbnail: %w", err)
+	}
+
+	tmp, sum, err := s.writeTemp(&buf)
+	if err != nil {
+		return "", fmt.Errorf("create thumbnail: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	thumbPath := ThumbnailPath(filename)
+	if err := s.put(thumbPath, tmp, sum); err != nil {
+		return "", fmt.Errorf("store thumbnail: %w", err)
+	}
+	return thumbPath, nil
+}
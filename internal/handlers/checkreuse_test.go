@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"homebooks/internal/models"
+)
+
+func postForm(mux *http.ServeMux, path string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPayingWithReusedCheckNumberWarns(t *testing.T) {
+	mux, db := newTestMux(t)
+	paidID, err := db.CreateExpense(models.Expense{Date: "2026-10-01", PayeeName: "Sysco", Amount: 80, Status: "not_paid"})
+	if err != nil {
+		t.Fatalf("CreateExpense: %v", err)
+	}
+	if err := db.MarkExpensePaid(paidID, "check", "1001"); err != nil {
+		t.Fatalf("MarkExpensePaid: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		form url.Values
+	}{
+		{"installment", "/expenses/%d/pay", url.Values{"amount": {"20"}, "date": {"2026-10-02"}, "payment_type": {"check"}, "check_number": {"1001"}}},
+		{"quick pay", "/expenses/%d/quick-pay", url.Values{"payment_type": {"check"}, "check_number": {"1001"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := db.CreateExpense(models.Expense{Date: "2026-10-01", PayeeName: "Jetro", Amount: 30, Status: "not_paid"})
+			if err != nil {
+				t.Fatalf("CreateExpense: %v", err)
+			}
+			path := fmt.Sprintf(tt.path, id)
+
+			rec := postForm(mux, path, tt.form)
+			if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Check #1001 has already been used") {
+				t.Fatalf("status %d; want the payment form with the reuse warning", rec.Code)
+			}
+			if got, _ := db.GetExpense(id); got.AmountPaid != 0 {
+				t.Fatalf("paid %.2f before the reuse was confirmed", got.AmountPaid)
+			}
+
+			tt.form.Set("reuse_check", "1")
+			if rec := postForm(mux, path, tt.form); rec.Code != http.StatusFound {
+				t.Fatalf("status %d with reuse_check; want a redirect", rec.Code)
+			}
+			if got, _ := db.GetExpense(id); got.AmountPaid == 0 {
+				t.Errorf("not paid after the reuse was confirmed")
+			}
+		})
+	}
+}
+
+func TestPayrollPayWithReusedCheckNumberWarns(t *testing.T) {
+	mux, db := newTestMux(t)
+	expenseID, err := db.CreateExpense(models.Expense{Date: "2026-10-01", PayeeName: "Sysco", Amount: 80, Status: "not_paid"})
+	if err != nil {
+		t.Fatalf("CreateExpense: %v", err)
+	}
+	if err := db.MarkExpensePaid(expenseID, "check", "1001"); err != nil {
+		t.Fatalf("MarkExpensePaid: %v", err)
+	}
+	employeeID, err := db.CreateEmployee("Ana", 15, "check")
+	if err != nil {
+		t.Fatalf("CreateEmployee: %v", err)
+	}
+	weekID, err := db.GetOrCreatePayrollWeek("2026-10-05", "2026-10-11")
+	if err != nil {
+		t.Fatalf("GetOrCreatePayrollWeek: %v", err)
+	}
+	id, err := db.CreatePayroll(models.Payroll{WeekID: weekID, EmployeeID: employeeID, TotalHours: 40, HourlyRate: 15, PaymentMethod: "check", Status: "not_paid"})
+	if err != nil {
+		t.Fatalf("CreatePayroll: %v", err)
+	}
+	path := fmt.Sprintf("/payroll/entry/%d/pay", id)
+	form := url.Values{"payment_method": {"check"}, "check_number": {"1001"}}
+
+	rec := postForm(mux, path, form)
+	loc, _ := url.Parse(rec.Header().Get("Location"))
+	if rec.Code != http.StatusFound || loc.Path != fmt.Sprintf("/payroll/weeks/%d/edit", weekID) ||
+		!strings.Contains(loc.Query().Get("error"), "check #1001 has already been used (Sysco") {
+		t.Fatalf("got %d to %q; want the week page with the reuse listed", rec.Code, rec.Header().Get("Location"))
+	}
+	if got, _ := db.GetPayroll(id); got.Status == "paid" {
+		t.Fatalf("paid before the reuse was confirmed")
+	}
+
+	form.Set("reuse_check", "1")
+	if rec := postForm(mux, path, form); rec.Code != http.StatusFound || strings.Contains(rec.Header().Get("Location"), "error") {
+		t.Fatalf("got %d to %q with reuse_check; want the week page", rec.Code, rec.Header().Get("Location"))
+	}
+	if got, _ := db.GetPayroll(id); got.Status != "paid" || got.CheckNumber != "1001" {
+		t.Errorf("entry is %s with check %q; want paid with 1001", got.Status, got.CheckNumber)
+	}
+}
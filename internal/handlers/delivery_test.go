@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"homebooks/internal/models"
+)
+
+func TestDeliverySaveRejectsBadAmount(t *testing.T) {
+	mux, db := newTestMux(t)
+	err := db.UpsertDeliverySales(models.DeliverySales{
+		Date:      "2026-10-05",
+		Platforms: []models.DeliveryPlatformSales{{Platform: "grubhub", Subtotal: 120, Net: 95}},
+	})
+	if err != nil {
+		t.Fatalf("UpsertDeliverySales: %v", err)
+	}
+
+	form := url.Values{"date": {"2026-10-05"}, "editing": {"1"}, "grubhub_subtotal": {"12.5o"}, "grubhub_net": {"95"}}
+	req := httptest.NewRequest(http.MethodPost, "/sales/delivery", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want the form shown again with %d", rec.Code, http.StatusOK)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "Grubhub gross must be a number") || !strings.Contains(body, `value="12.5o"`) {
+		t.Errorf("form does not show the field error next to the submitted value")
+	}
+	saved, err := db.GetDeliverySalesForDate("2026-10-05")
+	if err != nil {
+		t.Fatalf("GetDeliverySalesForDate: %v", err)
+	}
+	if got := saved.Platform("grubhub").Subtotal; got != 120 {
+		t.Errorf("stored Grubhub gross = %.2f; want 120 left alone", got)
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"homebooks/internal/models"
+)
+
+func TestFormsRejectUnreadableNumbers(t *testing.T) {
+	mux, db := newTestMux(t)
+	employeeID, err := db.CreateEmployee("Ana", 15, "check")
+	if err != nil {
+		t.Fatalf("CreateEmployee: %v", err)
+	}
+	reconID, err := db.CreateReconciliation(models.BankReconciliation{StatementDate: "2026-01-31", Status: "parsed"})
+	if err != nil {
+		t.Fatalf("CreateReconciliation: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		form      url.Values
+		wantError string
+		unchanged func() bool
+	}{
+		{
+			name:      "new employee rate",
+			path:      "/employees",
+			form:      url.Values{"name": {"Bo"}, "hourly_rate": {"1s.50"}, "payment_method": {"cash"}},
+			wantError: "Hourly rate must be a number",
+			unchanged: func() bool { list, _ := db.ListEmployees(false); return len(list) == 1 },
+		},
+		{
+			name:      "employee rate",
+			path:      fmt.Sprintf("/employees/%d", employeeID),
+			form:      url.Values{"name": {"Ana"}, "hourly_rate": {"0"}, "payment_method": {"check"}},
+			wantError: "Hourly rate must be greater than zero",
+			unchanged: func() bool { e, _ := db.GetEmployee(employeeID); return e.HourlyRate == 15 },
+		},
+		{
+			name:      "recurring amount",
+			path:      "/expenses/recurring",
+			form:      url.Values{"vendor_id": {"1"}, "amount": {"12,50"}, "day_of_month": {"1"}, "payment_type": {"cash"}},
+			wantError: "Amount must be a number",
+			unchanged: func() bool { list, _ := db.ListRecurringExpenses(); return len(list) == 0 },
+		},
+		{
+			name:      "payroll hours",
+			path:      "/payroll/save",
+			form:      url.Values{"week_start": {"2026-10-05"}, "week_end": {"2026-10-11"}, fmt.Sprintf("hours_%d", employeeID): {"4o"}},
+			wantError: "Nothing was saved: Hours for Ana must be a number",
+			unchanged: func() bool {
+				entries, _, _ := db.GetWeeklyPayroll("2026-10-05", "2026-10-11")
+				for _, e := range entries {
+					if e.Payroll != nil {
+						return false
+					}
+				}
+				return true
+			},
+		},
+		{
+			name:      "discrepancy",
+			path:      fmt.Sprintf("/bank-statements/%d/discrepancy", reconID),
+			form:      url.Values{"amount": {"-4.2x"}, "notes": {"timing"}},
+			wantError: "Discrepancy must be a number",
+			unchanged: func() bool { rec, _ := db.GetReconciliation(reconID); return rec.DiscrepancyNotes == "" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postForm(mux, tt.path, tt.form)
+			var got string
+			switch rec.Code {
+			case http.StatusOK:
+				got = rec.Body.String()
+			case http.StatusFound:
+				loc, _ := url.Parse(rec.Header().Get("Location"))
+				got = loc.Query().Get("error")
+			}
+			if !strings.Contains(got, tt.wantError) {
+				t.Errorf("status %d without %q", rec.Code, tt.wantError)
+			}
+			if !tt.unchanged() {
+				t.Errorf("saved despite the bad value")
+			}
+		})
+	}
+}
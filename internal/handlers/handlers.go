@@ -1,12 +1,20 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
+	"math"
 	"net/http"
+	"net/url"
+	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -16,6 +24,8 @@ import (
 	"homebooks/internal/filestore"
 	"homebooks/internal/logger"
 	"homebooks/internal/models"
+	"homebooks/internal/parser"
+	"homebooks/internal/reconciliation"
 	"homebooks/internal/version"
 )
 
@@ -23,21 +33,66 @@ type Handler struct {
 	db    *database.DB
 	auth  *auth.Auth
 	tmpl  *template.Template
-	files *filestore.Store
+	files filestore.Store
+	// allowAdHocPayee lets expenses use a free-text payee instead of a vendor
+	allowAdHocPayee bool
+	// cashOpeningFloat is the undeposited cash on hand before the first recorded day
+	cashOpeningFloat float64
+	// deliveryPayoutDays is how many days of delivery sales a platform deposit covers
+	deliveryPayoutDays int
+	// matchOpts tunes auto-matching when it is re-run from the review page
+	matchOpts reconciliation.MatchOptions
+	// maxUploadSize is the largest receipt or statement file accepted, in bytes
+	maxUploadSize int64
 }
 
-func New(db *database.DB, a *auth.Auth, tmpl *template.Template, files *filestore.Store) *Handler {
+func New(db *database.DB, a *auth.Auth, tmpl *template.Template, files filestore.Store) *Handler {
+	allowAdHoc, _ := strconv.ParseBool(os.Getenv("HOMEBOOKS_ALLOW_ADHOC_PAYEE"))
+	openingFloat, _ := strconv.ParseFloat(os.Getenv("HOMEBOOKS_CASH_OPENING_FLOAT"), 64)
+	payoutDays, err := strconv.Atoi(os.Getenv("HOMEBOOKS_DELIVERY_PAYOUT_DAYS"))
+	if err != nil || payoutDays <= 0 {
+		payoutDays = 7
+	}
 	return &Handler{
-		db:    db,
-		auth:  a,
-		tmpl:  tmpl,
-		files: files,
+		db:                 db,
+		auth:               a,
+		tmpl:               tmpl,
+		files:              files,
+		allowAdHocPayee:    allowAdHoc,
+		cashOpeningFloat:   openingFloat,
+		deliveryPayoutDays: payoutDays,
+		matchOpts:          reconciliation.MatchOptionsFromEnv(),
+		maxUploadSize:      filestore.MaxUploadSizeFromEnv(),
+	}
+}
+
+// TemplateFuncs are the functions pages can call. They are placeholders at parse time;
+// render swaps in ones bound to the request
+var TemplateFuncs = csrfFuncs("")
+
+// csrfFuncs returns the template functions that put a session's CSRF token into pages:
+// csrfField for a hidden form input and csrfToken for scripts
+func csrfFuncs(token string) template.FuncMap {
+	return template.FuncMap{
+		"csrfToken": func() string { return token },
+		"csrfField": func() template.HTML {
+			if token == "" {
+				return ""
+			}
+			return template.HTML(`<input type="hidden" name="` + auth.CSRFFieldName + `" value="` + template.HTMLEscapeString(token) + `">`)
+		},
 	}
 }
 
+// render executes a page for this request. The parsed templates are never executed
+// directly; each request works on a clone so the CSRF functions can be bound to it
 func (h *Handler) render(w http.ResponseWriter, r *http.Request, name string, data map[string]interface{}) {
 	data["Version"] = version.Version
-	err := h.tmpl.ExecuteTemplate(w, name, data)
+	data["CurrentUser"] = auth.CurrentUser(r.Context())
+	tmpl, err := h.tmpl.Clone()
+	if err == nil {
+		err = tmpl.Funcs(csrfFuncs(auth.CSRFToken(r.Context()))).ExecuteTemplate(w, name, data)
+	}
 	if err != nil {
 		l := logger.FromContext(r.Context())
 		l.Error("template_render_error", "template", name, "error", err.Error())
@@ -49,29 +104,39 @@ func (h *Handler) render(w http.ResponseWriter, r *http.Request, name string, da
 func (h *Handler) LoginPage(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	token := h.auth.GetSessionFromRequest(r)
-	if token != "" && h.auth.ValidateSession(ctx, token) {
-		http.Redirect(w, r, "/", http.StatusFound)
-		return
+	if token != "" {
+		if _, ok := h.auth.ValidateSession(ctx, token); ok {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
 	}
-	h.render(w, r, "login.html", map[string]interface{}{"Error": ""})
+	h.render(w, r, "login.html", map[string]interface{}{"Error": "", "HasUsers": h.auth.HasUsers()})
 }
 
 func (h *Handler) LoginSubmit(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	name := r.FormValue("name")
 	password := r.FormValue("password")
-
-	if !h.auth.CheckPassword(ctx, password) {
-		h.render(w, r, "login.html", map[string]interface{}{"Error": "Invalid password"})
+	remember := r.FormValue("remember") != ""
+	hasUsers := h.auth.HasUsers()
+
+	user, ok := h.auth.Login(ctx, name, password)
+	if !ok {
+		msg := "Invalid password"
+		if hasUsers {
+			msg = "Invalid name or password"
+		}
+		h.render(w, r, "login.html", map[string]interface{}{"Error": msg, "HasUsers": hasUsers, "Name": name, "Remember": remember})
 		return
 	}
 
-	token, err := h.auth.CreateSession(ctx)
+	token, err := h.auth.CreateSession(ctx, user, remember)
 	if err != nil {
-		h.render(w, r, "login.html", map[string]interface{}{"Error": "Failed to create session"})
+		h.render(w, r, "login.html", map[string]interface{}{"Error": "Failed to create session", "HasUsers": hasUsers, "Name": name, "Remember": remember})
 		return
 	}
 
-	h.auth.SetSessionCookie(w, token)
+	h.auth.SetSessionCookie(w, token, remember)
 	http.Redirect(w, r, "/", http.StatusFound)
 }
 
@@ -85,21 +150,199 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/login", http.StatusFound)
 }
 
+// SettingsPassword shows the change password form
+func (h *Handler) SettingsPassword(w http.ResponseWriter, r *http.Request) {
+	var success string
+	if r.URL.Query().Get("changed") != "" {
+		success = "Password changed"
+	}
+	h.render(w, r, "settings_password.html", map[string]interface{}{
+		"Title":   "Change Password",
+		"Active":  "settings",
+		"Success": success,
+		"Error":   r.URL.Query().Get("error"),
+	})
+}
+
+// SettingsPasswordUpdate changes the login password after checking the current one
+func (h *Handler) SettingsPasswordUpdate(w http.ResponseWriter, r *http.Request) {
+	password := r.FormValue("new_password")
+
+	var msg string
+	switch {
+	case len(password) < 8:
+		msg = "New password must be at least 8 characters"
+	case password != r.FormValue("confirm_password"):
+		msg = "New passwords do not match"
+	}
+	if msg != "" {
+		http.Redirect(w, r, "/settings/password?"+url.Values{"error": {msg}}.Encode(), http.StatusFound)
+		return
+	}
+
+	if err := h.auth.ChangePassword(r.Context(), r.FormValue("current_password"), password); err != nil {
+		msg = "Failed to change password"
+		if errors.Is(err, auth.ErrWrongPassword) {
+			msg = "Current password is incorrect"
+		}
+		http.Redirect(w, r, "/settings/password?"+url.Values{"error": {msg}}.Encode(), http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, "/settings/password?changed=1", http.StatusFound)
+}
+
+// SettingsUsers lists the named logins
+func (h *Handler) SettingsUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.auth.ListUsers()
+	if err != nil {
+		logger.FromContext(r.Context()).Error("user_list_error", "error", err.Error())
+	}
+
+	var currentID int64
+	if u := auth.CurrentUser(r.Context()); u != nil {
+		currentID = u.ID
+	}
+
+	h.render(w, r, "settings_users.html", map[string]interface{}{
+		"Title":     "Users",
+		"Active":    "settings",
+		"Users":     users,
+		"CurrentID": currentID,
+		"Error":     r.URL.Query().Get("error"),
+	})
+}
+
+// SettingsUsersCreate adds a named login. Adding the first one ends shared-password
+// sessions, so the new user is signed in straight away
+func (h *Handler) SettingsUsersCreate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	name := strings.TrimSpace(r.FormValue("name"))
+	password := r.FormValue("password")
+
+	var msg string
+	switch {
+	case name == "":
+		msg = "Name is required"
+	case len(password) < 8:
+		msg = "Password must be at least 8 characters"
+	}
+	if msg != "" {
+		http.Redirect(w, r, "/settings/users?"+url.Values{"error": {msg}}.Encode(), http.StatusFound)
+		return
+	}
+
+	first := !h.auth.HasUsers()
+	id, err := h.auth.CreateUser(ctx, name, password)
+	if err != nil {
+		msg = "Failed to create user"
+		if errors.Is(err, auth.ErrUserExists) {
+			msg = "A user with that name already exists"
+		} else {
+			logger.FromContext(ctx).Error("user_create_error", "error", err.Error())
+		}
+		http.Redirect(w, r, "/settings/users?"+url.Values{"error": {msg}}.Encode(), http.StatusFound)
+		return
+	}
+
+	if first {
+		remember := auth.KeepSignedIn(ctx)
+		if token, err := h.auth.CreateSession(ctx, &auth.User{ID: id, Name: name}, remember); err == nil {
+			h.auth.SetSessionCookie(w, token, remember)
+		}
+	}
+	http.Redirect(w, r, "/settings/users", http.StatusFound)
+}
+
+// SettingsUsersDelete removes a named login. Users can't delete themselves
+func (h *Handler) SettingsUsersDelete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, _ := strconv.ParseInt(r.FormValue("id"), 10, 64)
+
+	if u := auth.CurrentUser(ctx); u != nil && u.ID == id {
+		http.Redirect(w, r, "/settings/users?"+url.Values{"error": {"You can't delete yourself"}}.Encode(), http.StatusFound)
+		return
+	}
+
+	if err := h.auth.DeleteUser(ctx, id); err != nil {
+		logger.FromContext(ctx).Error("user_delete_error", "error", err.Error())
+		http.Redirect(w, r, "/settings/users?"+url.Values{"error": {"Failed to delete user"}}.Encode(), http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, "/settings/users", http.StatusFound)
+}
+
+// SettingsBackup downloads a consistent copy of the database, taken while the app
+// keeps running
+func (h *Handler) SettingsBackup(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	tmp, err := os.CreateTemp("", "homebooks-backup-*.db")
+	if err != nil {
+		l.Error("backup_error", "error", err.Error())
+		http.Error(w, "Failed to create backup", http.StatusInternalServerError)
+		return
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := h.db.BackupTo(tmp.Name()); err != nil {
+		l.Error("backup_error", "error", err.Error())
+		http.Error(w, "Failed to create backup", http.StatusInternalServerError)
+		return
+	}
+
+	file, err := os.Open(tmp.Name())
+	if err != nil {
+		l.Error("backup_error", "error", err.Error())
+		http.Error(w, "Failed to create backup", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	l.Info("backup_downloaded")
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"homebooks_%s.db\"", time.Now().Format("2006-01-02_150405")))
+	io.Copy(w, file)
+}
+
 // Dashboard
 func (h *Handler) Dashboard(w http.ResponseWriter, r *http.Request) {
 	unpaidExpenses, expenseTotal, _ := h.db.ListUnpaidExpenses()
 	recentSalesGrouped, recentSalesTotal, _ := h.db.ListRecentSalesGrouped(7)
 	todaySalesTotal, _ := h.db.GetTodaySalesTotal()
+	weekToDateSales, err := h.db.GetWeekToDateSales()
+	if err != nil {
+		logger.FromContext(r.Context()).Error("week_to_date_sales_error", "error", err.Error())
+	}
+	monthToDateSales, err := h.db.GetMonthToDateSales()
+	if err != nil {
+		logger.FromContext(r.Context()).Error("month_to_date_sales_error", "error", err.Error())
+	}
 	todayExpensesTotal, _ := h.db.GetTodayExpensesTotal()
+	setup, err := h.db.GetSetupProgress()
+	if err != nil {
+		logger.FromContext(r.Context()).Error("setup_progress_error", "error", err.Error())
+	}
+	setup.PasswordSet = !h.auth.UsingDefaultPassword()
+	fresh, _ := h.db.IsFreshInstall()
+	monthToDate, err := h.db.GetMonthToDateSummary()
+	if err != nil {
+		logger.FromContext(r.Context()).Error("month_to_date_error", "error", err.Error())
+	}
 
 	data := models.DashboardData{
 		TodaySalesTotal:     todaySalesTotal,
+		WeekToDateSales:     weekToDateSales,
+		MonthToDateSales:    monthToDateSales,
 		TodayExpensesTotal:  todayExpensesTotal,
 		UnpaidExpensesTotal: expenseTotal,
 		UnpaidExpensesCount: len(unpaidExpenses),
 		RecentSalesGrouped:  recentSalesGrouped,
 		RecentSalesTotal:    recentSalesTotal,
 		UnpaidExpenses:      unpaidExpenses,
+		Setup:               setup,
+		FreshInstall:        fresh,
+		MonthToDate:         monthToDate,
 	}
 
 	h.render(w, r, "dashboard.html", map[string]interface{}{
@@ -109,6 +352,9 @@ func (h *Handler) Dashboard(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// paymentTypes are the accepted payment_type values, "" meaning not specified
+var paymentTypes = []string{"", "cash", "check", "debit", "credit"}
+
 // Vendors handlers
 func (h *Handler) VendorsList(w http.ResponseWriter, r *http.Request) {
 	vendors, err := h.db.ListVendors()
@@ -130,12 +376,75 @@ func (h *Handler) VendorsShow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	expenses, total, _ := h.db.ListExpenses(models.ExpenseFilter{VendorID: id})
+	rules, _ := h.db.ListAutoBookingRules(id)
+	vendors, _ := h.db.ListVendors()
+
+	var success string
+	if merged := r.URL.Query().Get("merged"); merged != "" {
+		success = fmt.Sprintf("Vendor merged, %s expense(s) moved.", merged)
+	}
+
 	h.render(w, r, "vendors_show.html", map[string]interface{}{
-		"Title":    vendor.Name,
-		"Active":   "vendors",
-		"Vendor":   vendor,
-		"Expenses": expenses,
-		"Total":    total,
+		"Title":      vendor.Name,
+		"Active":     "vendors",
+		"Vendor":     vendor,
+		"Vendors":    vendors,
+		"Expenses":   expenses,
+		"Total":      total,
+		"Rules":      rules,
+		"Categories": models.VendorCategories,
+		"Error":      r.URL.Query().Get("error"),
+		"Success":    success,
+	})
+}
+
+// VendorsSearchAPI lists vendors whose name contains q, for type-ahead pickers
+func (h *Handler) VendorsSearchAPI(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+	limit = min(limit, 200)
+
+	vendors, err := h.db.SearchVendors(strings.TrimSpace(r.URL.Query().Get("q")), limit)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("vendor_search_error", "error", err.Error())
+		http.Error(w, "search failed", http.StatusInternalServerError)
+		return
+	}
+
+	results := make([]map[string]any, 0, len(vendors))
+	for _, v := range vendors {
+		results = append(results, map[string]any{
+			"id":                   v.ID,
+			"name":                 v.Name,
+			"categories":           v.CategoryList(),
+			"default_payment_type": v.DefaultPaymentType,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"vendors": results})
+}
+
+// VendorAPI returns a vendor's defaults so the receipt form can prefill them
+func (h *Handler) VendorAPI(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid vendor id", http.StatusBadRequest)
+		return
+	}
+	vendor, err := h.db.GetVendor(id)
+	if err != nil {
+		http.Error(w, "vendor not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":                   vendor.ID,
+		"name":                 vendor.Name,
+		"categories":           vendor.CategoryList(),
+		"default_payment_type": vendor.DefaultPaymentType,
 	})
 }
 
@@ -151,27 +460,33 @@ func (h *Handler) VendorsNew(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) VendorsCreate(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	name := r.FormValue("name")
-	categories := r.Form["category"]
-	category := strings.Join(categories, ",")
+	category, catErr := models.JoinVendorCategories(r.Form["category"])
 	description := r.FormValue("description")
-
-	if name == "" {
+	paymentType := r.FormValue("default_payment_type")
+
+	if name == "" || catErr != nil || !slices.Contains(paymentTypes, paymentType) {
+		errMsg := "Name is required"
+		if catErr != nil {
+			errMsg = catErr.Error()
+		} else if name != "" {
+			errMsg = "Invalid payment type"
+		}
 		h.render(w, r, "vendors_form.html", map[string]interface{}{
 			"Title":      "New Vendor",
 			"Active":     "vendors",
-			"Vendor":     models.Vendor{Name: name, Category: category, Description: description},
+			"Vendor":     models.Vendor{Name: name, Category: category, Description: description, DefaultPaymentType: paymentType},
 			"Categories": models.VendorCategories,
-			"Error":      "Name is required",
+			"Error":      errMsg,
 		})
 		return
 	}
 
-	_, err := h.db.CreateVendor(name, category, description)
+	_, err := h.db.CreateVendor(name, category, description, paymentType)
 	if err != nil {
 		h.render(w, r, "vendors_form.html", map[string]interface{}{
 			"Title":      "New Vendor",
 			"Active":     "vendors",
-			"Vendor":     models.Vendor{Name: name, Category: category, Description: description},
+			"Vendor":     models.Vendor{Name: name, Category: category, Description: description, DefaultPaymentType: paymentType},
 			"Categories": models.VendorCategories,
 			"Error":      "Vendor already exists or error occurred",
 		})
@@ -200,27 +515,33 @@ func (h *Handler) VendorsUpdate(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	name := r.FormValue("name")
-	categories := r.Form["category"]
-	category := strings.Join(categories, ",")
+	category, catErr := models.JoinVendorCategories(r.Form["category"])
 	description := r.FormValue("description")
-
-	if name == "" {
+	paymentType := r.FormValue("default_payment_type")
+
+	if name == "" || catErr != nil || !slices.Contains(paymentTypes, paymentType) {
+		errMsg := "Name is required"
+		if catErr != nil {
+			errMsg = catErr.Error()
+		} else if name != "" {
+			errMsg = "Invalid payment type"
+		}
 		h.render(w, r, "vendors_form.html", map[string]interface{}{
 			"Title":      "Edit Vendor",
 			"Active":     "vendors",
-			"Vendor":     models.Vendor{ID: id, Name: name, Category: category, Description: description},
+			"Vendor":     models.Vendor{ID: id, Name: name, Category: category, Description: description, DefaultPaymentType: paymentType},
 			"Categories": models.VendorCategories,
-			"Error":      "Name is required",
+			"Error":      errMsg,
 		})
 		return
 	}
 
-	err := h.db.UpdateVendor(id, name, category, description)
+	err := h.db.UpdateVendor(id, name, category, description, paymentType)
 	if err != nil {
 		h.render(w, r, "vendors_form.html", map[string]interface{}{
 			"Title":      "Edit Vendor",
 			"Active":     "vendors",
-			"Vendor":     models.Vendor{ID: id, Name: name, Category: category, Description: description},
+			"Vendor":     models.Vendor{ID: id, Name: name, Category: category, Description: description, DefaultPaymentType: paymentType},
 			"Categories": models.VendorCategories,
 			"Error":      "Error updating vendor",
 		})
@@ -236,6 +557,82 @@ func (h *Handler) VendorsDelete(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/vendors", http.StatusFound)
 }
 
+// VendorsMerge folds a duplicate vendor into another one, moving its expenses, recurring
+// expenses and rules across before deleting it
+func (h *Handler) VendorsMerge(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Redirect(w, r, "/vendors", http.StatusFound)
+		return
+	}
+	targetID, err := strconv.ParseInt(r.FormValue("target_vendor_id"), 10, 64)
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("/vendors/%d?%s", id, url.Values{"error": {"Choose a vendor to merge into"}}.Encode()), http.StatusFound)
+		return
+	}
+
+	moved, err := h.db.MergeVendors(id, targetID)
+	if err != nil {
+		msg := "Error merging vendors"
+		if errors.Is(err, database.ErrMergeSelf) {
+			msg = "A vendor cannot be merged into itself"
+		}
+		l.Error("vendor_merge_error", "vendor_id", id, "target_vendor_id", targetID, "error", err.Error())
+		http.Redirect(w, r, fmt.Sprintf("/vendors/%d?%s", id, url.Values{"error": {msg}}.Encode()), http.StatusFound)
+		return
+	}
+
+	l.Info("vendor_merged", "vendor_id", id, "target_vendor_id", targetID, "expenses_moved", moved)
+	http.Redirect(w, r, fmt.Sprintf("/vendors/%d?merged=%d", targetID, moved), http.StatusFound)
+}
+
+// VendorsRuleCreate adds an auto-booking rule that books matching statement lines to the vendor
+func (h *Handler) VendorsRuleCreate(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Redirect(w, r, "/vendors", http.StatusFound)
+		return
+	}
+
+	rule := models.AutoBookingRule{
+		VendorID: id,
+		Category: r.FormValue("category"),
+		Pattern:  strings.TrimSpace(r.FormValue("pattern")),
+	}
+	if rule.Pattern == "" || (rule.Category != "" && !slices.Contains(models.VendorCategories, rule.Category)) {
+		http.Redirect(w, r, fmt.Sprintf("/vendors/%d", id), http.StatusFound)
+		return
+	}
+
+	ruleID, err := h.db.CreateAutoBookingRule(rule)
+	if err != nil {
+		l.Error("auto_booking_rule_create_error", "vendor_id", id, "error", err.Error())
+	} else {
+		l.Info("auto_booking_rule_created", "rule_id", ruleID, "vendor_id", id, "pattern", rule.Pattern)
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/vendors/%d", id), http.StatusFound)
+}
+
+// VendorsRuleDelete removes an auto-booking rule
+func (h *Handler) VendorsRuleDelete(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	ruleID, err := strconv.ParseInt(r.PathValue("ruleID"), 10, 64)
+	if err == nil {
+		if err := h.db.DeleteAutoBookingRule(ruleID); err != nil {
+			l.Error("auto_booking_rule_delete_error", "rule_id", ruleID, "error", err.Error())
+		}
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/vendors/%d", id), http.StatusFound)
+}
+
 // Employees handlers
 func (h *Handler) EmployeesList(w http.ResponseWriter, r *http.Request) {
 	employees, _ := h.db.ListEmployees(false)
@@ -247,1102 +644,3529 @@ func (h *Handler) EmployeesList(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) EmployeesCreate(w http.ResponseWriter, r *http.Request) {
-	name := r.FormValue("name")
-	hourlyRate, _ := strconv.ParseFloat(r.FormValue("hourly_rate"), 64)
+	name := strings.TrimSpace(r.FormValue("name"))
 	paymentMethod := r.FormValue("payment_method")
-
-	if name == "" || hourlyRate <= 0 {
+	fieldErrs := formErrors{}
+	hourlyRate := fieldErrs.hourlyRate(r)
+	if name == "" {
+		fieldErrs["name"] = "Name is required"
+	}
+	renderErr := func(err error) {
 		employees, _ := h.db.ListEmployees(false)
 		h.render(w, r, "employees_list.html", map[string]interface{}{
-			"Title":     "Employees",
-			"Active":    "employees",
-			"Employees": employees,
-			"Error":     "Name and valid hourly rate are required",
+			"Title":       "Employees",
+			"Active":      "employees",
+			"Employees":   employees,
+			"Error":       err.Error(),
+			"FieldErrors": fieldErrs,
+			"Input":       r.Form,
 		})
+	}
+	if err := fieldErrs.err(); err != nil {
+		renderErr(err)
 		return
 	}
 
 	_, err := h.db.CreateEmployee(name, hourlyRate, paymentMethod)
 	if err != nil {
-		employees, _ := h.db.ListEmployees(false)
-		h.render(w, r, "employees_list.html", map[string]interface{}{
-			"Title":     "Employees",
-			"Active":    "employees",
-			"Employees": employees,
-			"Error":     "Error creating employee",
-		})
+		renderErr(errors.New("Error creating employee"))
 		return
 	}
 
 	http.Redirect(w, r, "/employees", http.StatusFound)
 }
 
-func (h *Handler) EmployeesDeactivate(w http.ResponseWriter, r *http.Request) {
-	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	h.db.DeactivateEmployee(id)
-	http.Redirect(w, r, "/employees", http.StatusFound)
-}
-
-func (h *Handler) EmployeesReactivate(w http.ResponseWriter, r *http.Request) {
+// EmployeesShow shows an employee with their pay-rate history
+func (h *Handler) EmployeesShow(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	h.db.ReactivateEmployee(id)
-	http.Redirect(w, r, "/employees", http.StatusFound)
-}
-
-// Sales handlers
-func (h *Handler) SalesList(w http.ResponseWriter, r *http.Request) {
-	grouped, _ := h.db.ListSalesGrouped()
-	h.render(w, r, "sales_list.html", map[string]any{
-		"Title":     "Daily Sales",
-		"Active":    "sales",
-		"Grouped":   grouped,
-		"TodayDate": time.Now().Format("2006-01-02"),
-	})
-}
-
-func (h *Handler) SalesNew(w http.ResponseWriter, r *http.Request) {
-	sale := models.DailySale{Date: time.Now().Format("2006-01-02")}
-	h.render(w, r, "sales_form.html", map[string]interface{}{
-		"Title":  "New Sale",
-		"Active": "sales",
-		"Sale":   sale,
-	})
-}
-
-func (h *Handler) SalesCreate(w http.ResponseWriter, r *http.Request) {
-	sale := models.DailySale{
-		Date:  r.FormValue("date"),
-		Shift: r.FormValue("shift"),
-		Notes: r.FormValue("notes"),
-	}
-	sale.NetSales, _ = strconv.ParseFloat(r.FormValue("net_sales"), 64)
-	sale.Taxes, _ = strconv.ParseFloat(r.FormValue("taxes"), 64)
-	sale.CreditCard, _ = strconv.ParseFloat(r.FormValue("credit_card"), 64)
-	sale.CashReceipt, _ = strconv.ParseFloat(r.FormValue("cash_receipt"), 64)
-	sale.CashOnHand, _ = strconv.ParseFloat(r.FormValue("cash_on_hand"), 64)
-
-	_, err := h.db.UpsertSale(sale)
+	employee, err := h.db.GetEmployee(id)
 	if err != nil {
-		h.render(w, r, "sales_form.html", map[string]interface{}{
-			"Title":  "New Sale",
-			"Active": "sales",
-			"Sale":   sale,
-			"Error":  err.Error(),
-		})
+		http.Redirect(w, r, "/employees", http.StatusFound)
 		return
 	}
-	http.Redirect(w, r, "/sales", http.StatusFound)
+	history, err := h.db.GetEmployeeRateHistory(id)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("employee_rate_history_error", "employee_id", id, "error", err.Error())
+	}
+	h.render(w, r, "employees_show.html", map[string]interface{}{
+		"Title":       employee.Name,
+		"Active":      "employees",
+		"Employee":    employee,
+		"RateHistory": history,
+	})
 }
 
-func (h *Handler) SalesEdit(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) EmployeesEdit(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	sale, err := h.db.GetSale(id)
+	employee, err := h.db.GetEmployee(id)
 	if err != nil {
-		http.Redirect(w, r, "/sales", http.StatusFound)
+		http.Redirect(w, r, "/employees", http.StatusFound)
 		return
 	}
-	h.render(w, r, "sales_form.html", map[string]interface{}{
-		"Title":  "Edit Sale",
-		"Active": "sales",
-		"Sale":   sale,
+	h.render(w, r, "employees_form.html", map[string]interface{}{
+		"Title":         "Edit Employee",
+		"Active":        "employees",
+		"Employee":      employee,
+		"EffectiveDate": time.Now().Format("2006-01-02"),
 	})
 }
 
-func (h *Handler) SalesUpdate(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) EmployeesUpdate(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	sale := models.DailySale{
-		ID:    id,
-		Date:  r.FormValue("date"),
-		Shift: r.FormValue("shift"),
-		Notes: r.FormValue("notes"),
+	employee, err := h.db.GetEmployee(id)
+	if err != nil {
+		http.Redirect(w, r, "/employees", http.StatusFound)
+		return
 	}
-	sale.NetSales, _ = strconv.ParseFloat(r.FormValue("net_sales"), 64)
-	sale.Taxes, _ = strconv.ParseFloat(r.FormValue("taxes"), 64)
-	sale.CreditCard, _ = strconv.ParseFloat(r.FormValue("credit_card"), 64)
-	sale.CashReceipt, _ = strconv.ParseFloat(r.FormValue("cash_receipt"), 64)
-	sale.CashOnHand, _ = strconv.ParseFloat(r.FormValue("cash_on_hand"), 64)
 
-	err := h.db.UpdateSale(sale)
-	if err != nil {
-		h.render(w, r, "sales_form.html", map[string]interface{}{
-			"Title":  "Edit Sale",
-			"Active": "sales",
-			"Sale":   sale,
-			"Error":  err.Error(),
+	employee.Name = strings.TrimSpace(r.FormValue("name"))
+	employee.PaymentMethod = r.FormValue("payment_method")
+	effectiveDate := r.FormValue("effective_date")
+	fieldErrs := formErrors{}
+	employee.HourlyRate = fieldErrs.hourlyRate(r)
+	if employee.Name == "" {
+		fieldErrs["name"] = "Name is required"
+	}
+
+	if err := fieldErrs.err(); err != nil {
+		h.render(w, r, "employees_form.html", map[string]interface{}{
+			"Title":         "Edit Employee",
+			"Active":        "employees",
+			"Employee":      employee,
+			"EffectiveDate": effectiveDate,
+			"Error":         err.Error(),
+			"FieldErrors":   fieldErrs,
+			"Input":         r.Form,
 		})
 		return
 	}
-	http.Redirect(w, r, "/sales", http.StatusFound)
+
+	var changedBy string
+	if u := auth.CurrentUser(r.Context()); u != nil {
+		changedBy = u.Name
+	}
+	if err := h.db.UpdateEmployee(id, employee.Name, employee.HourlyRate, employee.PaymentMethod, effectiveDate, changedBy); err != nil {
+		h.render(w, r, "employees_form.html", map[string]interface{}{
+			"Title":         "Edit Employee",
+			"Active":        "employees",
+			"Employee":      employee,
+			"EffectiveDate": effectiveDate,
+			"Error":         "Error updating employee",
+		})
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/employees/%d", id), http.StatusFound)
 }
 
-func (h *Handler) SalesDelete(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) EmployeesDeactivate(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	h.db.DeleteSale(id)
-	http.Redirect(w, r, "/sales", http.StatusFound)
+	h.db.DeactivateEmployee(id)
+	http.Redirect(w, r, "/employees", http.StatusFound)
 }
 
-// SalesShiftsAPI returns existing shifts for a given date as JSON
-func (h *Handler) SalesShiftsAPI(w http.ResponseWriter, r *http.Request) {
-	date := r.URL.Query().Get("date")
-	shifts, err := h.db.GetShiftsForDate(date)
-	if err != nil {
-		shifts = []string{}
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string][]string{"shifts": shifts})
+func (h *Handler) EmployeesReactivate(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	h.db.ReactivateEmployee(id)
+	http.Redirect(w, r, "/employees", http.StatusFound)
 }
 
-// Delivery Sales handlers
-func (h *Handler) DeliveryNew(w http.ResponseWriter, r *http.Request) {
-	date := r.URL.Query().Get("date")
-	if date == "" {
-		date = time.Now().Format("2006-01-02")
+// formErrors collects per-field problems found while reading a submitted form, keyed by
+// input name, so the form can be shown again with a message beside each field
+type formErrors map[string]string
+
+// errFormFields is shown above a form re-rendered with field errors
+var errFormFields = errors.New("Please fix the highlighted fields")
+
+// amount reads a dollar amount from field. Blank, non-numeric and negative values are
+// recorded as errors and read as 0
+func (fe formErrors) amount(r *http.Request, field, label string) float64 {
+	f := fe.signedAmount(r, field, label)
+	if f < 0 {
+		fe[field] = label + " can't be negative"
+		return 0
 	}
-	delivery := models.DeliverySales{Date: date}
-	h.render(w, r, "delivery_form.html", map[string]any{
-		"Title":    "Add Delivery Sales",
-		"Active":   "sales",
-		"Delivery": delivery,
-	})
+	return f
 }
 
-func (h *Handler) DeliveryEdit(w http.ResponseWriter, r *http.Request) {
-	date := r.PathValue("date")
-	delivery, err := h.db.GetDeliverySalesForDate(date)
-	if err != nil {
-		http.Error(w, "Error fetching delivery sales", http.StatusInternalServerError)
-		return
+// signedAmount is amount for a field that may hold a negative figure, such as a
+// difference between two balances
+func (fe formErrors) signedAmount(r *http.Request, field, label string) float64 {
+	value := strings.TrimSpace(r.FormValue(field))
+	if value == "" {
+		fe[field] = label + " is required"
+		return 0
 	}
-	if delivery == nil {
-		delivery = &models.DeliverySales{Date: date}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		fe[field] = label + " must be a number"
+		return 0
 	}
-	h.render(w, r, "delivery_form.html", map[string]any{
-		"Title":    "Edit Delivery Sales",
-		"Active":   "sales",
-		"Delivery": *delivery,
-	})
+	return f
 }
 
-func (h *Handler) DeliverySave(w http.ResponseWriter, r *http.Request) {
-	grubhubSubtotal, _ := strconv.ParseFloat(r.FormValue("grubhub_subtotal"), 64)
-	grubhubNet, _ := strconv.ParseFloat(r.FormValue("grubhub_net"), 64)
-	doordashSubtotal, _ := strconv.ParseFloat(r.FormValue("doordash_subtotal"), 64)
-	doordashNet, _ := strconv.ParseFloat(r.FormValue("doordash_net"), 64)
-	ubereatsEarnings, _ := strconv.ParseFloat(r.FormValue("ubereats_earnings"), 64)
-	ubereatsPayout, _ := strconv.ParseFloat(r.FormValue("ubereats_payout"), 64)
-
-	delivery := models.DeliverySales{
-		Date:             r.FormValue("date"),
-		GrubhubSubtotal:  grubhubSubtotal,
-		GrubhubNet:       grubhubNet,
-		DoordashSubtotal: doordashSubtotal,
-		DoordashNet:      doordashNet,
-		UberEatsEarnings: ubereatsEarnings,
-		UberEatsPayout:   ubereatsPayout,
-		Notes:            r.FormValue("notes"),
-	}
-
-	err := h.db.UpsertDeliverySales(delivery)
-	if err != nil {
-		h.render(w, r, "delivery_form.html", map[string]any{
-			"Title":    "Edit Delivery Sales",
-			"Active":   "sales",
-			"Delivery": delivery,
-			"Error":    err.Error(),
-		})
-		return
+// optionalAmount is amount for a field that may be left blank, which reads as 0
+func (fe formErrors) optionalAmount(r *http.Request, field, label string) float64 {
+	if strings.TrimSpace(r.FormValue(field)) == "" {
+		return 0
 	}
-
-	http.Redirect(w, r, "/sales", http.StatusFound)
+	return fe.amount(r, field, label)
 }
 
-// Expenses handlers
-func (h *Handler) ExpensesList(w http.ResponseWriter, r *http.Request) {
-	vendorID, _ := strconv.ParseInt(r.URL.Query().Get("vendor_id"), 10, 64)
-	filter := models.ExpenseFilter{
-		StartDate:  r.URL.Query().Get("start_date"),
-		EndDate:    r.URL.Query().Get("end_date"),
-		Status:     r.URL.Query().Get("status"),
-		VendorID:   vendorID,
-		Categories: r.URL.Query()["category"],
+// hourlyRate reads an employee's pay rate from the hourly_rate field, which must be
+// more than zero
+func (fe formErrors) hourlyRate(r *http.Request) float64 {
+	rate := fe.amount(r, "hourly_rate", "Hourly rate")
+	if rate == 0 && fe["hourly_rate"] == "" {
+		fe["hourly_rate"] = "Hourly rate must be greater than zero"
 	}
-	expenses, total, _ := h.db.ListExpenses(filter)
-	vendors, _ := h.db.ListVendors()
-	h.render(w, r, "expenses_list.html", map[string]interface{}{
-		"Title":      "Expenses",
-		"Active":     "expenses",
-		"Expenses":   expenses,
-		"Total":      total,
-		"Vendors":    vendors,
-		"Filter":     filter,
-		"Categories": models.VendorCategories,
-	})
-}
-
-func (h *Handler) ExpensesNew(w http.ResponseWriter, r *http.Request) {
-	vendors, _ := h.db.ListVendors()
-	lastCheck, _ := h.db.GetLastExpenseCheckNumber()
-	h.render(w, r, "expenses_form.html", map[string]interface{}{
-		"Title":           "New Expense",
-		"Active":          "expenses",
-		"Expense":         models.Expense{Date: time.Now().Format("2006-01-02")},
-		"Vendors":         vendors,
-		"LastCheckNumber": lastCheck,
-	})
+	return rate
 }
 
-func (h *Handler) ExpensesCreate(w http.ResponseWriter, r *http.Request) {
-	l := logger.FromContext(r.Context())
-
-	// Parse multipart form for file upload (5MB limit)
-	if err := r.ParseMultipartForm(5 << 20); err != nil {
-		l.Error("expense_parse_form_error", "error", err.Error())
+// optionalID reads a record ID from field, where blank means none (0)
+func (fe formErrors) optionalID(r *http.Request, field, label string) int64 {
+	value := strings.TrimSpace(r.FormValue(field))
+	if value == "" {
+		return 0
 	}
-
-	vendorID, _ := strconv.ParseInt(r.FormValue("vendor_id"), 10, 64)
-	amount, _ := strconv.ParseFloat(r.FormValue("amount"), 64)
-
-	expense := models.Expense{
-		Date:          r.FormValue("date"),
-		VendorID:      vendorID,
-		Amount:        amount,
-		InvoiceNumber: r.FormValue("invoice_number"),
-		Status:        r.FormValue("status"),
-		PaymentType:   r.FormValue("payment_type"),
-		CheckNumber:   r.FormValue("check_number"),
-		DateOpened:    r.FormValue("date_opened"),
-		DueDate:       r.FormValue("due_date"),
-		DatePaid:      r.FormValue("date_paid"),
-		Notes:         r.FormValue("notes"),
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		fe[field] = label + " is not valid"
+		return 0
 	}
+	return id
+}
 
-	// Handle receipt file upload
-	file, header, err := r.FormFile("receipt")
-	if err == nil {
-		defer file.Close()
-		storedPath, err := h.files.Save(header.Filename, file)
-		if err != nil {
-			l.Error("expense_receipt_save_error", "error", err.Error())
-		} else {
-			expense.ReceiptPath = storedPath
+// date reads a calendar date from field as YYYY-MM-DD. A bad value is recorded as an
+// error and returned unchanged so the form can show it again
+func (fe formErrors) date(r *http.Request, field, label string, required bool) string {
+	value := strings.TrimSpace(r.FormValue(field))
+	if value == "" {
+		if required {
+			fe[field] = label + " is required"
 		}
+		return ""
 	}
-
-	_, err = h.db.CreateExpense(expense)
+	date, err := parseFormDate(value)
 	if err != nil {
-		// Clean up uploaded file on error
-		if expense.ReceiptPath != "" {
-			h.files.Delete(expense.ReceiptPath)
-		}
-		vendors, _ := h.db.ListVendors()
-		lastCheck, _ := h.db.GetLastExpenseCheckNumber()
-		h.render(w, r, "expenses_form.html", map[string]interface{}{
-			"Title":           "New Expense",
-			"Active":          "expenses",
-			"Expense":         expense,
-			"Vendors":         vendors,
-			"LastCheckNumber": lastCheck,
-			"Error":           err.Error(),
-		})
-		return
+		fe[field] = label + " " + err.Error()
+		return value
 	}
-	http.Redirect(w, r, "/expenses", http.StatusFound)
+	return date
 }
 
-func (h *Handler) ExpensesEdit(w http.ResponseWriter, r *http.Request) {
-	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	expense, err := h.db.GetExpense(id)
+// parseFormDate checks a submitted date and returns it as YYYY-MM-DD. A full timestamp,
+// as older rows and some browsers send, is cut down to its date
+func parseFormDate(value string) (string, error) {
+	t, err := time.Parse("2006-01-02", value)
 	if err != nil {
-		http.Redirect(w, r, "/expenses", http.StatusFound)
-		return
+		t, err = time.Parse(time.RFC3339, value)
 	}
-	vendors, _ := h.db.ListVendors()
-	lastCheck, _ := h.db.GetLastExpenseCheckNumber()
-	h.render(w, r, "expenses_form.html", map[string]interface{}{
-		"Title":           "Edit Expense",
-		"Active":          "expenses",
-		"Expense":         expense,
-		"Vendors":         vendors,
-		"LastCheckNumber": lastCheck,
+	if err != nil {
+		return "", errors.New("must be a valid date (YYYY-MM-DD)")
+	}
+	if t.Year() < 1900 || t.Year() > 2999 {
+		return "", errors.New("is not a real date")
+	}
+	return t.Format("2006-01-02"), nil
+}
+
+// err returns errFormFields when any field had a problem
+func (fe formErrors) err() error {
+	if len(fe) > 0 {
+		return errFormFields
+	}
+	return nil
+}
+
+// Sales handlers
+func (h *Handler) SalesList(w http.ResponseWriter, r *http.Request) {
+	grouped, _ := h.db.ListSalesGrouped()
+	h.render(w, r, "sales_list.html", map[string]any{
+		"Title":     "Daily Sales",
+		"Active":    "sales",
+		"Grouped":   grouped,
+		"TodayDate": time.Now().Format("2006-01-02"),
 	})
 }
 
-func (h *Handler) ExpensesUpdate(w http.ResponseWriter, r *http.Request) {
+// SalesExportCSV downloads daily sales in a date range as CSV, one row per date with
+// shift net sales, cash totals and delivery platform figures
+func (h *Handler) SalesExportCSV(w http.ResponseWriter, r *http.Request) {
 	l := logger.FromContext(r.Context())
+	filter := models.SalesFilter{
+		StartDate: r.URL.Query().Get("start_date"),
+		EndDate:   r.URL.Query().Get("end_date"),
+	}
 
-	// Parse multipart form for file upload (5MB limit)
-	if err := r.ParseMultipartForm(5 << 20); err != nil {
-		l.Error("expense_parse_form_error", "error", err.Error())
+	days, err := h.db.ListSalesByDate(filter)
+	if err != nil {
+		l.Error("sales_export_error", "error", err.Error())
+		http.Error(w, "Failed to export sales", http.StatusInternalServerError)
+		return
 	}
 
-	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	vendorID, _ := strconv.ParseInt(r.FormValue("vendor_id"), 10, 64)
-	amount, _ := strconv.ParseFloat(r.FormValue("amount"), 64)
+	from, to := filter.StartDate, filter.EndDate
+	if from == "" {
+		from = "start"
+	}
+	if to == "" {
+		to = time.Now().Format("2006-01-02")
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"sales_%s_to_%s.csv\"", from, to))
 
-	// Get existing receipt path to preserve if no new file uploaded
-	oldReceiptPath, _ := h.db.GetExpenseReceiptPath(id)
+	money := func(v float64) string { return fmt.Sprintf("%.2f", v) }
 
-	expense := models.Expense{
-		ID:            id,
-		Date:          r.FormValue("date"),
-		VendorID:      vendorID,
-		Amount:        amount,
-		InvoiceNumber: r.FormValue("invoice_number"),
-		Status:        r.FormValue("status"),
-		PaymentType:   r.FormValue("payment_type"),
-		CheckNumber:   r.FormValue("check_number"),
-		DateOpened:    r.FormValue("date_opened"),
-		DueDate:       r.FormValue("due_date"),
-		DatePaid:      r.FormValue("date_paid"),
-		Notes:         r.FormValue("notes"),
-		ReceiptPath:   oldReceiptPath, // Preserve existing receipt by default
+	header := []string{"Date", "Breakfast Net", "Lunch Net", "Dinner Net", "Taxes", "Credit Card", "Cash On Hand", "Variance"}
+	for _, platform := range models.DeliveryPlatforms {
+		header = append(header, platform.Name+" Subtotal", platform.Name+" Net")
 	}
 
-	// Handle new receipt file upload
-	var newReceiptPath string
-	file, header, err := r.FormFile("receipt")
-	if err == nil {
-		defer file.Close()
-		storedPath, err := h.files.Save(header.Filename, file)
-		if err != nil {
-			l.Error("expense_receipt_save_error", "error", err.Error())
-		} else {
-			newReceiptPath = storedPath
-			expense.ReceiptPath = storedPath
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	for _, day := range days {
+		taxes, creditCard, cashOnHand, variance := day.Totals()
+		delivery := models.DeliverySales{}
+		if day.Delivery != nil {
+			delivery = *day.Delivery
+		}
+		record := []string{
+			day.Date,
+			money(day.ShiftNetSales("breakfast")), money(day.ShiftNetSales("lunch")), money(day.ShiftNetSales("dinner")),
+			money(taxes), money(creditCard), money(cashOnHand), money(variance),
 		}
+		for _, platform := range models.DeliveryPlatforms {
+			sales := delivery.Platform(platform.Key)
+			record = append(record, money(sales.Subtotal), money(sales.Net))
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		l.Error("sales_export_error", "error", err.Error())
+		return
 	}
 
-	err = h.db.UpdateExpense(expense)
+	l.Info("sales_exported", "days", len(days), "start_date", filter.StartDate, "end_date", filter.EndDate)
+}
+
+func (h *Handler) SalesNew(w http.ResponseWriter, r *http.Request) {
+	sale := models.DailySale{Date: time.Now().Format("2006-01-02")}
+	h.render(w, r, "sales_form.html", map[string]interface{}{
+		"Title":  "New Sale",
+		"Active": "sales",
+		"Sale":   sale,
+	})
+}
+
+func (h *Handler) SalesCreate(w http.ResponseWriter, r *http.Request) {
+	sale, fieldErrs := saleFromForm(r)
+
+	err := fieldErrs.err()
+	if err == nil {
+		_, err = h.db.UpsertSale(sale)
+	}
 	if err != nil {
-		// Clean up newly uploaded file on error
-		if newReceiptPath != "" {
-			h.files.Delete(newReceiptPath)
-		}
-		vendors, _ := h.db.ListVendors()
-		lastCheck, _ := h.db.GetLastExpenseCheckNumber()
-		h.render(w, r, "expenses_form.html", map[string]interface{}{
-			"Title":           "Edit Expense",
-			"Active":          "expenses",
-			"Expense":         expense,
-			"Vendors":         vendors,
-			"LastCheckNumber": lastCheck,
-			"Error":           err.Error(),
+		h.render(w, r, "sales_form.html", map[string]interface{}{
+			"Title":       "New Sale",
+			"Active":      "sales",
+			"Sale":        sale,
+			"Error":       err.Error(),
+			"FieldErrors": fieldErrs,
+			"Input":       r.Form,
 		})
 		return
 	}
+	http.Redirect(w, r, "/sales", http.StatusFound)
+}
 
-	// Delete old receipt file if a new one was uploaded successfully
-	if newReceiptPath != "" && oldReceiptPath != "" && oldReceiptPath != newReceiptPath {
-		h.files.Delete(oldReceiptPath)
+// saleFromForm reads a shift's sales from the submitted form
+func saleFromForm(r *http.Request) (models.DailySale, formErrors) {
+	fieldErrs := formErrors{}
+	sale := models.DailySale{
+		Date:        fieldErrs.date(r, "date", "Date", true),
+		Shift:       r.FormValue("shift"),
+		Notes:       r.FormValue("notes"),
+		NetSales:    fieldErrs.amount(r, "net_sales", "Net sales"),
+		Taxes:       fieldErrs.amount(r, "taxes", "Taxes"),
+		CreditCard:  fieldErrs.amount(r, "credit_card", "Credit card"),
+		CashReceipt: fieldErrs.amount(r, "cash_receipt", "Cash receipt"),
+		CashOnHand:  fieldErrs.amount(r, "cash_on_hand", "Cash on hand"),
 	}
-
-	http.Redirect(w, r, "/expenses", http.StatusFound)
+	return sale, fieldErrs
 }
 
-func (h *Handler) ExpensesPayForm(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) SalesEdit(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	expense, err := h.db.GetExpense(id)
+	sale, err := h.db.GetSale(id)
 	if err != nil {
-		http.Redirect(w, r, "/expenses", http.StatusFound)
+		http.Redirect(w, r, "/sales", http.StatusFound)
 		return
 	}
-	lastCheck, _ := h.db.GetLastExpenseCheckNumber()
-	h.render(w, r, "expenses_pay.html", map[string]interface{}{
-		"Title":           "Mark Expense Paid",
-		"Active":          "expenses",
-		"Expense":         expense,
-		"LastCheckNumber": lastCheck,
+	h.render(w, r, "sales_form.html", map[string]interface{}{
+		"Title":  "Edit Sale",
+		"Active": "sales",
+		"Sale":   sale,
 	})
 }
 
-func (h *Handler) ExpensesPay(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) SalesUpdate(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	paymentType := r.FormValue("payment_type")
-	checkNumber := r.FormValue("check_number")
-	h.db.MarkExpensePaid(id, paymentType, checkNumber)
-	http.Redirect(w, r, "/expenses", http.StatusFound)
+	sale, fieldErrs := saleFromForm(r)
+	sale.ID = id
+
+	err := fieldErrs.err()
+	if err == nil {
+		err = h.db.UpdateSale(sale)
+	}
+	if err != nil {
+		h.render(w, r, "sales_form.html", map[string]interface{}{
+			"Title":       "Edit Sale",
+			"Active":      "sales",
+			"Sale":        sale,
+			"Error":       err.Error(),
+			"FieldErrors": fieldErrs,
+			"Input":       r.Form,
+		})
+		return
+	}
+	http.Redirect(w, r, "/sales", http.StatusFound)
 }
 
-func (h *Handler) ExpensesDelete(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) SalesDelete(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	// Delete receipt file if exists
-	if receiptPath, err := h.db.GetExpenseReceiptPath(id); err == nil && receiptPath != "" {
-		h.files.Delete(receiptPath)
+	h.db.DeleteSale(id)
+	http.Redirect(w, r, "/sales", http.StatusFound)
+}
+
+// SalesShiftsAPI returns existing shifts for a given date as JSON
+func (h *Handler) SalesShiftsAPI(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	shifts, err := h.db.GetShiftsForDate(date)
+	if err != nil {
+		shifts = []string{}
 	}
-	h.db.DeleteExpense(id)
-	http.Redirect(w, r, "/expenses", http.StatusFound)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"shifts": shifts})
 }
 
-// ExpensesDownloadReceipt serves the receipt file for an expense
-func (h *Handler) ExpensesDownloadReceipt(w http.ResponseWriter, r *http.Request) {
-	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	receiptPath, err := h.db.GetExpenseReceiptPath(id)
-	if err != nil || receiptPath == "" {
-		http.Error(w, "Receipt not found", http.StatusNotFound)
-		return
+// SalesTrendAPI returns total net sales for each of the last N weeks or months
+// (?period=week|month&count=12) for the dashboard chart
+func (h *Handler) SalesTrendAPI(w http.ResponseWriter, r *http.Request) {
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "week"
+	}
+	count, err := strconv.Atoi(r.URL.Query().Get("count"))
+	if err != nil || count < 1 {
+		count = 12
 	}
+	count = min(count, 104)
 
-	file, err := h.files.Get(receiptPath)
+	points, err := h.db.GetSalesTrend(period, count)
 	if err != nil {
-		http.Error(w, "Receipt file not found", http.StatusNotFound)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
 
-	// Determine content type from extension
-	contentType := "application/octet-stream"
-	ext := strings.ToLower(filepath.Ext(receiptPath))
-	switch ext {
-	case ".pdf":
-		contentType = "application/pdf"
-	case ".jpg", ".jpeg":
-		contentType = "image/jpeg"
-	case ".png":
-		contentType = "image/png"
-	case ".gif":
-		contentType = "image/gif"
+	trend := make([]map[string]any, 0, len(points))
+	for _, p := range points {
+		trend = append(trend, map[string]any{
+			"label":      p.Label,
+			"start_date": p.StartDate,
+			"end_date":   p.EndDate,
+			"net_sales":  math.Round(p.NetSales*100) / 100,
+		})
 	}
-
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"receipt%s\"", ext))
-	io.Copy(w, file)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"period": period, "trend": trend})
 }
 
-// ExpensesUploadReceipt handles quick receipt upload from list page
-func (h *Handler) ExpensesUploadReceipt(w http.ResponseWriter, r *http.Request) {
+// Cash handlers
+func (h *Handler) CashLedger(w http.ResponseWriter, r *http.Request) {
 	l := logger.FromContext(r.Context())
-	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
 
-	// Parse multipart form (5MB limit)
-	if err := r.ParseMultipartForm(5 << 20); err != nil {
-		l.Error("receipt_upload_parse_error", "error", err.Error())
-		http.Error(w, "Failed to parse form", http.StatusBadRequest)
-		return
+	ledger, err := h.db.GetCashLedger(h.cashOpeningFloat)
+	if err != nil {
+		l.Error("cash_ledger_error", "error", err.Error())
 	}
 
-	file, header, err := r.FormFile("receipt")
-	if err != nil {
-		http.Error(w, "No file provided", http.StatusBadRequest)
-		return
+	// Show the most recent day first, limited to the requested range
+	var days []models.CashLedgerDay
+	var flagged int
+	for i := len(ledger) - 1; i >= 0; i-- {
+		d := ledger[i]
+		if (startDate != "" && d.Date < startDate) || (endDate != "" && d.Date > endDate) {
+			continue
+		}
+		if d.Flagged() {
+			flagged++
+		}
+		days = append(days, d)
 	}
-	defer file.Close()
 
-	// Get old receipt path for cleanup
-	oldReceiptPath, _ := h.db.GetExpenseReceiptPath(id)
+	var current models.CashLedgerDay
+	if len(ledger) > 0 {
+		current = ledger[len(ledger)-1]
+	}
 
-	// Save new file
-	storedPath, err := h.files.Save(header.Filename, file)
+	deposits, err := h.db.ListCashDeposits()
 	if err != nil {
-		l.Error("receipt_upload_save_error", "error", err.Error())
-		http.Error(w, "Failed to save file", http.StatusInternalServerError)
-		return
-	}
-
-	// Update database
-	if err := h.db.UpdateExpenseReceipt(id, storedPath); err != nil {
-		h.files.Delete(storedPath) // Clean up on error
-		l.Error("receipt_upload_db_error", "error", err.Error())
-		http.Error(w, "Failed to update expense", http.StatusInternalServerError)
-		return
-	}
-
-	// Delete old file after successful update
-	if oldReceiptPath != "" {
-		h.files.Delete(oldReceiptPath)
+		l.Error("cash_deposit_list_error", "error", err.Error())
 	}
 
-	l.Info("receipt_uploaded", "expense_id", id)
-	http.Redirect(w, r, "/expenses", http.StatusFound)
+	h.render(w, r, "cash_ledger.html", map[string]any{
+		"Title":        "Cash Ledger",
+		"Active":       "sales",
+		"Error":        r.URL.Query().Get("error"),
+		"Days":         days,
+		"Current":      current,
+		"FlaggedCount": flagged,
+		"OpeningFloat": h.cashOpeningFloat,
+		"Deposits":     deposits,
+		"StartDate":    startDate,
+		"EndDate":      endDate,
+		"TodayDate":    time.Now().Format("2006-01-02"),
+	})
 }
 
-// ExpensesDeleteReceipt removes the receipt from an expense
-func (h *Handler) ExpensesDeleteReceipt(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CashDepositCreate(w http.ResponseWriter, r *http.Request) {
 	l := logger.FromContext(r.Context())
-	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-
-	receiptPath, err := h.db.GetExpenseReceiptPath(id)
-	if err != nil || receiptPath == "" {
-		http.Redirect(w, r, fmt.Sprintf("/expenses/%d/edit", id), http.StatusFound)
+	fieldErrs := formErrors{}
+	deposit := models.CashDeposit{
+		Date:   fieldErrs.date(r, "date", "Deposit date", true),
+		Amount: fieldErrs.amount(r, "amount", "Deposit amount"),
+		Notes:  r.FormValue("notes"),
+	}
+	if _, bad := fieldErrs["amount"]; !bad && deposit.Amount <= 0 {
+		fieldErrs["amount"] = "Deposit amount must be more than zero"
+	}
+	if len(fieldErrs) > 0 {
+		var msgs []string
+		for _, msg := range fieldErrs {
+			msgs = append(msgs, msg)
+		}
+		slices.Sort(msgs)
+		http.Redirect(w, r, "/sales/cash?"+url.Values{"error": {"Deposit not saved: " + strings.Join(msgs, "; ")}}.Encode(), http.StatusFound)
 		return
 	}
-
-	// Clear receipt path in database
-	if err := h.db.UpdateExpenseReceipt(id, ""); err != nil {
-		l.Error("receipt_delete_db_error", "error", err.Error())
-		http.Redirect(w, r, fmt.Sprintf("/expenses/%d/edit", id), http.StatusFound)
+	if _, err := h.db.CreateCashDeposit(deposit); err != nil {
+		l.Error("cash_deposit_create_error", "error", err.Error())
+		http.Redirect(w, r, "/sales/cash?"+url.Values{"error": {"Failed to save the deposit"}}.Encode(), http.StatusFound)
 		return
 	}
-
-	// Delete the file
-	h.files.Delete(receiptPath)
-	l.Info("receipt_deleted", "expense_id", id)
-	http.Redirect(w, r, fmt.Sprintf("/expenses/%d/edit", id), http.StatusFound)
+	http.Redirect(w, r, "/sales/cash", http.StatusFound)
 }
 
-// Payroll handlers
-
-// getWeekBounds calculates the Monday and Sunday for a given date
-func getWeekBounds(date time.Time) (string, string) {
-	// Find Monday of the week
-	weekday := int(date.Weekday())
-	if weekday == 0 {
-		weekday = 7 // Sunday = 7
+func (h *Handler) CashDepositDelete(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err := h.db.DeleteCashDeposit(id); err != nil {
+		logger.FromContext(r.Context()).Error("cash_deposit_delete_error", "id", id, "error", err.Error())
 	}
-	monday := date.AddDate(0, 0, -(weekday - 1))
-	sunday := monday.AddDate(0, 0, 6)
-	return monday.Format("2006-01-02"), sunday.Format("2006-01-02")
+	http.Redirect(w, r, "/sales/cash", http.StatusFound)
 }
 
-func (h *Handler) PayrollList(w http.ResponseWriter, r *http.Request) {
-	weeks, err := h.db.ListPayrollWeeks(50)
-	if err != nil {
-		logger.FromContext(r.Context()).Error("payroll_weeks_error", "error", err.Error())
+// Delivery Sales handlers
+func (h *Handler) DeliveryNew(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
 	}
-
-	h.render(w, r, "payroll_list.html", map[string]any{
-		"Title":  "Payroll",
-		"Active": "payroll",
-		"Weeks":  weeks,
+	delivery := models.DeliverySales{Date: date}
+	h.render(w, r, "delivery_form.html", map[string]any{
+		"Title":     "Add Delivery Sales",
+		"Active":    "sales",
+		"Delivery":  delivery,
+		"Platforms": models.DeliveryPlatforms,
 	})
 }
 
-func (h *Handler) PayrollWeekNew(w http.ResponseWriter, r *http.Request) {
-	// Default to current week
-	weekStart, weekEnd := getWeekBounds(time.Now())
-
-	entries, total, _ := h.db.GetWeeklyPayroll(weekStart, weekEnd)
-	lastCheck, _ := h.db.GetLastPayrollCheckNumber()
-
-	// Format dates for display
-	weekStartDate, _ := time.Parse("2006-01-02", weekStart)
-	weekEndDate, _ := time.Parse("2006-01-02", weekEnd)
-	weekStartDisplay := weekStartDate.Format("Jan 2")
-	weekEndDisplay := weekEndDate.Format("Jan 2, 2006")
-
-	h.render(w, r, "payroll_week_edit.html", map[string]any{
-		"Title":           "New Payroll Week",
-		"Active":          "payroll",
-		"Entries":         entries,
-		"Total":           total,
-		"WeekStart":       weekStart,
-		"WeekEnd":         weekEnd,
-		"WeekDisplay":     weekStartDisplay + " - " + weekEndDisplay,
-		"LastCheckNumber": lastCheck,
+func (h *Handler) DeliveryEdit(w http.ResponseWriter, r *http.Request) {
+	date := r.PathValue("date")
+	delivery, err := h.db.GetDeliverySalesForDate(date)
+	if err != nil {
+		http.Error(w, "Error fetching delivery sales", http.StatusInternalServerError)
+		return
+	}
+	if delivery == nil {
+		delivery = &models.DeliverySales{Date: date}
+	}
+	h.render(w, r, "delivery_form.html", map[string]any{
+		"Title":     "Edit Delivery Sales",
+		"Active":    "sales",
+		"Delivery":  *delivery,
+		"Platforms": models.DeliveryPlatforms,
 	})
 }
 
-func (h *Handler) PayrollWeekEdit(w http.ResponseWriter, r *http.Request) {
-	weekIDStr := r.PathValue("id")
-	weekID, err := strconv.ParseInt(weekIDStr, 10, 64)
+func (h *Handler) DeliverySave(w http.ResponseWriter, r *http.Request) {
+	date := r.FormValue("date")
+	existing, err := h.db.GetDeliverySalesForDate(date)
 	if err != nil {
-		http.Error(w, "Invalid week ID", http.StatusBadRequest)
+		http.Error(w, "Error fetching delivery sales", http.StatusInternalServerError)
 		return
 	}
+	if existing == nil {
+		existing = &models.DeliverySales{Date: date}
+	}
 
-	// Get the week details
-	week, err := h.db.GetPayrollWeek(weekID)
-	if err != nil {
-		http.Error(w, "Week not found", http.StatusNotFound)
-		return
+	// Blank fields leave what was saved before alone, so one platform can be entered
+	// now and another later without zeroing the first. The edit form shows the saved
+	// values, so there a blank field really was cleared and everything is written
+	editing := r.FormValue("editing") != ""
+	given := func(field string) bool {
+		return editing || strings.TrimSpace(r.FormValue(field)) != ""
 	}
 
-	entries, total, _ := h.db.GetWeeklyPayrollByWeekID(weekID)
-	lastCheck, _ := h.db.GetLastPayrollCheckNumber()
+	fieldErrs := formErrors{}
+	delivery := models.DeliverySales{ID: existing.ID, Date: date, Notes: existing.Notes}
+	if given("notes") {
+		delivery.Notes = r.FormValue("notes")
+	}
+	for _, platform := range models.DeliveryPlatforms {
+		sales := existing.Platform(platform.Key)
+		if field := platform.Key + "_subtotal"; given(field) {
+			sales.Subtotal = fieldErrs.optionalAmount(r, field, platform.Name+" gross")
+		}
+		if field := platform.Key + "_net"; given(field) {
+			sales.Net = fieldErrs.optionalAmount(r, field, platform.Name+" net")
+		}
+		delivery.Platforms = append(delivery.Platforms, sales)
+	}
 
-	// Format dates for display
-	weekStartDate, _ := time.Parse("2006-01-02", week.PeriodStart)
-	weekEndDate, _ := time.Parse("2006-01-02", week.PeriodEnd)
-	weekStartDisplay := weekStartDate.Format("Jan 2")
-	weekEndDisplay := weekEndDate.Format("Jan 2, 2006")
+	err = fieldErrs.err()
+	if err == nil {
+		err = h.db.UpsertDeliverySales(delivery)
+	}
+	if err != nil {
+		title := "Add Delivery Sales"
+		if delivery.ID != 0 {
+			title = "Edit Delivery Sales"
+		}
+		h.render(w, r, "delivery_form.html", map[string]any{
+			"Title":       title,
+			"Active":      "sales",
+			"Delivery":    delivery,
+			"Platforms":   models.DeliveryPlatforms,
+			"Error":       err.Error(),
+			"FieldErrors": fieldErrs,
+			"Input":       r.Form,
+		})
+		return
+	}
 
-	h.render(w, r, "payroll_week_edit.html", map[string]any{
-		"Title":           "Edit Payroll - " + weekEndDisplay,
-		"Active":          "payroll",
-		"Entries":         entries,
-		"Total":           total,
-		"WeekID":          weekID,
-		"WeekStart":       week.PeriodStart,
-		"WeekEnd":         week.PeriodEnd,
-		"WeekDisplay":     weekStartDisplay + " - " + weekEndDisplay,
-		"LastCheckNumber": lastCheck,
-	})
+	http.Redirect(w, r, "/sales", http.StatusFound)
 }
 
-func (h *Handler) PayrollWeekDetail(w http.ResponseWriter, r *http.Request) {
-	weekIDStr := r.PathValue("id")
-	weekID, err := strconv.ParseInt(weekIDStr, 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid week ID", http.StatusBadRequest)
+// SalesDayNoteSave sets or clears the context note for a sales date
+func (h *Handler) SalesDayNoteSave(w http.ResponseWriter, r *http.Request) {
+	date := r.FormValue("date")
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		http.Redirect(w, r, "/sales", http.StatusFound)
 		return
 	}
 
-	// Get the week details
-	week, err := h.db.GetPayrollWeek(weekID)
-	if err != nil {
-		http.Error(w, "Week not found", http.StatusNotFound)
-		return
+	if err := h.db.SaveDayNote(date, strings.TrimSpace(r.FormValue("note"))); err != nil {
+		logger.FromContext(r.Context()).Error("day_note_save_error", "date", date, "error", err.Error())
 	}
 
-	entries, total, _ := h.db.GetWeeklyPayrollByWeekID(weekID)
-	lastCheck, _ := h.db.GetLastPayrollCheckNumber()
+	http.Redirect(w, r, "/sales", http.StatusFound)
+}
 
-	// Format dates for display (handle both "2006-01-02" and "2006-01-02T15:04:05Z" formats)
-	weekStartDate, err := time.Parse("2006-01-02", week.PeriodStart)
-	if err != nil {
-		weekStartDate, err = time.Parse(time.RFC3339, week.PeriodStart)
-		if err != nil {
-			http.Error(w, "Invalid period start date: "+week.PeriodStart, http.StatusInternalServerError)
-			return
-		}
+// Expenses handlers
+func (h *Handler) ExpensesList(w http.ResponseWriter, r *http.Request) {
+	filter := expenseFilter(r.URL.Query())
+	expenses, total, _ := h.db.ListExpenses(filter)
+	vendors, _ := h.db.ListVendors()
+
+	var success string
+	if updated := r.URL.Query().Get("updated"); updated != "" {
+		success = fmt.Sprintf("Updated %s receipt(s)", updated)
 	}
-	weekEndDate, err := time.Parse("2006-01-02", week.PeriodEnd)
-	if err != nil {
-		weekEndDate, err = time.Parse(time.RFC3339, week.PeriodEnd)
-		if err != nil {
-			http.Error(w, "Invalid period end date: "+week.PeriodEnd, http.StatusInternalServerError)
-			return
+	if paid := r.URL.Query().Get("paid"); paid != "" {
+		success = "Marked " + paid + " paid by " + r.URL.Query().Get("paid_by")
+		if check := r.URL.Query().Get("check"); check != "" {
+			success += " #" + check
 		}
 	}
-	weekStartDisplay := weekStartDate.Format("Jan 2")
-	weekEndDisplay := weekEndDate.Format("Jan 2, 2006")
+	if partial := r.URL.Query().Get("partial"); partial != "" {
+		success = fmt.Sprintf("Recorded a $%s payment to %s; $%s still owed", r.URL.Query().Get("amount"), partial, r.URL.Query().Get("balance"))
+	}
 
-	h.render(w, r, "payroll_detail.html", map[string]interface{}{
-		"Title":           "Payroll - " + weekStartDisplay + " to " + weekEndDisplay,
-		"Active":          "payroll",
-		"Entries":         entries,
-		"Total":           total,
-		"WeekID":          weekID,
-		"WeekStart":       week.PeriodStart,
-		"WeekEnd":         week.PeriodEnd,
-		"WeekDisplay":     weekStartDisplay + " - " + weekEndDisplay,
-		"LastCheckNumber": lastCheck,
+	h.render(w, r, "expenses_list.html", map[string]interface{}{
+		"Title":       "Expenses",
+		"Active":      "expenses",
+		"Expenses":    expenses,
+		"Total":       total,
+		"Vendors":     vendors,
+		"Filter":      filter,
+		"Categories":  models.VendorCategories,
+		"FilterQuery": filterQuery(r.URL.Query()),
+		"ExportURL":   template.URL("/expenses/export.csv?" + filterQuery(r.URL.Query())),
+		"SortLinks":   expenseSortLinks(r.URL.Query(), filter),
+		"Success":     success,
+		"Error":       r.URL.Query().Get("error"),
 	})
 }
 
-func (h *Handler) PayrollNew(w http.ResponseWriter, r *http.Request) {
-	employees, _ := h.db.ListEmployees(true)
-	lastCheck, _ := h.db.GetLastPayrollCheckNumber()
-	h.render(w, r, "payroll_form.html", map[string]interface{}{
-		"Title":           "New Payroll",
-		"Active":          "payroll",
-		"Payroll":         models.Payroll{},
-		"Employees":       employees,
-		"LastCheckNumber": lastCheck,
-	})
+// expenseFilter reads the expense list filters from a query string
+func expenseFilter(q url.Values) models.ExpenseFilter {
+	vendorID, _ := strconv.ParseInt(q.Get("vendor_id"), 10, 64)
+	minAmount, _ := strconv.ParseFloat(q.Get("min_amount"), 64)
+	maxAmount, _ := strconv.ParseFloat(q.Get("max_amount"), 64)
+	return models.ExpenseFilter{
+		StartDate:  q.Get("start_date"),
+		EndDate:    q.Get("end_date"),
+		Status:     q.Get("status"),
+		VendorID:   vendorID,
+		Categories: q["category"],
+		Reconciled: q.Get("reconciled"),
+		MinAmount:  minAmount,
+		MaxAmount:  maxAmount,
+		SortBy:     q.Get("sort"),
+		SortDir:    q.Get("dir"),
+	}
 }
 
-func (h *Handler) PayrollCreate(w http.ResponseWriter, r *http.Request) {
-	employeeID, _ := strconv.ParseInt(r.FormValue("employee_id"), 10, 64)
-	totalHours, _ := strconv.ParseFloat(r.FormValue("total_hours"), 64)
-	hourlyRate, _ := strconv.ParseFloat(r.FormValue("hourly_rate"), 64)
+// sortLink is a sortable column header: where clicking it goes, and the arrow showing
+// the current direction when the list is sorted by it
+type sortLink struct {
+	URL   string
+	Arrow string
+}
 
-	payroll := models.Payroll{
-		EmployeeID:    employeeID,
-		PeriodStart:   r.FormValue("period_start"),
-		PeriodEnd:     r.FormValue("period_end"),
-		TotalHours:    totalHours,
-		HourlyRate:    hourlyRate,
-		PaymentMethod: r.FormValue("payment_method"),
-		CheckNumber:   r.FormValue("check_number"),
-		Status:        r.FormValue("status"),
-		DatePaid:      r.FormValue("date_paid"),
-		Notes:         r.FormValue("notes"),
+// expenseSortLinks builds the header links for the expense list's sortable columns,
+// keeping the current filters. Clicking the sorted column flips its direction; other
+// columns start descending, except vendor which starts A-Z
+func expenseSortLinks(q url.Values, filter models.ExpenseFilter) map[string]sortLink {
+	current := filter.SortBy
+	if current == "" {
+		current = "date"
+	}
+	links := make(map[string]sortLink)
+	for _, key := range []string{"date", "amount", "vendor", "due_date"} {
+		params, _ := url.ParseQuery(filterQuery(q))
+		params.Set("sort", key)
+		dir := "desc"
+		if key == "vendor" {
+			dir = "asc"
+		}
+		var arrow string
+		if key == current {
+			if filter.SortDir == "asc" {
+				arrow = "\u25B2"
+				dir = "desc"
+			} else {
+				arrow = "\u25BC"
+				dir = "asc"
+			}
+		}
+		params.Set("dir", dir)
+		links[key] = sortLink{URL: "/expenses?" + params.Encode(), Arrow: arrow}
 	}
+	return links
+}
+
+// ExpensesExportCSV downloads the expenses matching the list filters as a CSV file.
+// Rows are written as they are read so large exports aren't held in memory
+func (h *Handler) ExpensesExportCSV(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+	filter := expenseFilter(r.URL.Query())
 
-	_, err := h.db.CreatePayroll(payroll)
+	from, to := filter.StartDate, filter.EndDate
+	if from == "" {
+		from = "start"
+	}
+	if to == "" {
+		to = time.Now().Format("2006-01-02")
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"expenses_%s_to_%s.csv\"", from, to))
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Date", "Vendor", "Amount", "Invoice Number", "Status", "Payment Type", "Check Number", "Due Date", "Date Paid", "Notes"})
+
+	count := 0
+	err := h.db.EachExpense(filter, func(e models.Expense) error {
+		count++
+		status := "Paid"
+		if e.Status != "paid" {
+			status = "Not Paid"
+		}
+		if err := cw.Write([]string{
+			e.Date, e.VendorName, fmt.Sprintf("%.2f", e.Amount), e.InvoiceNumber, status,
+			e.PaymentType, e.CheckNumber, e.DueDate, e.DatePaid, e.Notes,
+		}); err != nil {
+			return err
+		}
+		// Flush periodically so the response streams instead of building up in the writer
+		if count%100 == 0 {
+			cw.Flush()
+			return cw.Error()
+		}
+		return nil
+	})
+	cw.Flush()
+	if err == nil {
+		err = cw.Error()
+	}
 	if err != nil {
-		employees, _ := h.db.ListEmployees(true)
-		lastCheck, _ := h.db.GetLastPayrollCheckNumber()
-		h.render(w, r, "payroll_form.html", map[string]interface{}{
-			"Title":           "New Payroll",
-			"Active":          "payroll",
-			"Payroll":         payroll,
-			"Employees":       employees,
-			"LastCheckNumber": lastCheck,
-			"Error":           err.Error(),
-		})
+		// Headers are already sent, so all that's left is to log it
+		l.Error("expense_export_error", "error", err.Error())
 		return
 	}
-	http.Redirect(w, r, "/payroll", http.StatusFound)
+
+	l.Info("expenses_exported", "count", count, "start_date", filter.StartDate, "end_date", filter.EndDate)
 }
 
-func (h *Handler) PayrollEdit(w http.ResponseWriter, r *http.Request) {
-	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	payroll, err := h.db.GetPayroll(id)
-	if err != nil {
-		http.Redirect(w, r, "/payroll", http.StatusFound)
+// ExpensesBulkUpdate sets a common category, status or vendor on the selected expenses
+func (h *Handler) ExpensesBulkUpdate(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+	r.ParseForm()
+
+	redirect := func(key, value string) {
+		redirectToExpenseList(w, r, url.Values{key: {value}})
+	}
+
+	var ids []int64
+	for _, v := range r.Form["expense_id"] {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err == nil && id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		redirect("error", "Select at least one receipt")
 		return
 	}
-	employees, _ := h.db.ListEmployees(true)
-	lastCheck, _ := h.db.GetLastPayrollCheckNumber()
-	h.render(w, r, "payroll_form.html", map[string]interface{}{
-		"Title":           "Edit Payroll",
-		"Active":          "payroll",
-		"Payroll":         payroll,
-		"Employees":       employees,
-		"LastCheckNumber": lastCheck,
-	})
-}
 
-func (h *Handler) PayrollUpdate(w http.ResponseWriter, r *http.Request) {
-	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	employeeID, _ := strconv.ParseInt(r.FormValue("employee_id"), 10, 64)
-	totalHours, _ := strconv.ParseFloat(r.FormValue("total_hours"), 64)
-	hourlyRate, _ := strconv.ParseFloat(r.FormValue("hourly_rate"), 64)
+	vendorID, _ := strconv.ParseInt(r.FormValue("bulk_vendor_id"), 10, 64)
+	update := models.ExpenseBulkUpdate{
+		Category: r.FormValue("bulk_category"),
+		Status:   r.FormValue("bulk_status"),
+		VendorID: vendorID,
+	}
 
-	payroll := models.Payroll{
-		ID:            id,
-		EmployeeID:    employeeID,
-		PeriodStart:   r.FormValue("period_start"),
-		PeriodEnd:     r.FormValue("period_end"),
-		TotalHours:    totalHours,
-		HourlyRate:    hourlyRate,
-		PaymentMethod: r.FormValue("payment_method"),
-		CheckNumber:   r.FormValue("check_number"),
-		Status:        r.FormValue("status"),
-		DatePaid:      r.FormValue("date_paid"),
-		Notes:         r.FormValue("notes"),
+	if err := update.Validate(); err != nil {
+		redirect("error", "Invalid "+err.Error())
+		return
+	}
+	if update.VendorID > 0 {
+		if _, err := h.db.GetVendor(update.VendorID); err != nil {
+			redirect("error", "Invalid vendor")
+			return
+		}
+	}
+	if update.Category == "" && update.Status == "" && update.VendorID == 0 {
+		redirect("error", "Choose a category, status or vendor to apply")
+		return
 	}
 
-	err := h.db.UpdatePayroll(payroll)
+	updated, err := h.db.BulkUpdateExpenses(ids, update)
 	if err != nil {
-		employees, _ := h.db.ListEmployees(true)
-		lastCheck, _ := h.db.GetLastPayrollCheckNumber()
-		h.render(w, r, "payroll_form.html", map[string]interface{}{
-			"Title":           "Edit Payroll",
-			"Active":          "payroll",
-			"Payroll":         payroll,
-			"Employees":       employees,
-			"LastCheckNumber": lastCheck,
-			"Error":           err.Error(),
-		})
+		l.Error("expense_bulk_update_error", "count", len(ids), "error", err.Error())
+		redirect("error", "Failed to update receipts")
 		return
 	}
-	http.Redirect(w, r, "/payroll", http.StatusFound)
+
+	l.Info("expense_bulk_updated", "selected", len(ids), "updated", updated)
+	redirect("updated", strconv.FormatInt(updated, 10))
 }
 
-func (h *Handler) PayrollPay(w http.ResponseWriter, r *http.Request) {
-	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	paymentMethod := r.FormValue("payment_method")
-	checkNumber := r.FormValue("check_number")
-	week := r.FormValue("week")
+// redirectToExpenseList returns to the expense list with notice, keeping the filters the
+// list was showing (posted back as filter_query)
+func redirectToExpenseList(w http.ResponseWriter, r *http.Request, notice url.Values) {
+	returnQuery, _ := url.ParseQuery(r.FormValue("filter_query"))
+	target := "/expenses?" + notice.Encode()
+	if fq := filterQuery(returnQuery); fq != "" {
+		target += "&" + fq
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
 
-	h.db.MarkPayrollPaidWithDetails(id, paymentMethod, checkNumber)
+// filterQuery re-encodes the expense list filters from q, dropping one-off notices
+func filterQuery(q url.Values) string {
+	filters := url.Values{}
+	for _, key := range []string{"start_date", "end_date", "status", "vendor_id", "category", "reconciled", "min_amount", "max_amount", "sort", "dir"} {
+		if v, ok := q[key]; ok {
+			filters[key] = v
+		}
+	}
+	return filters.Encode()
+}
 
-	if week != "" {
-		http.Redirect(w, r, "/payroll?week="+week, http.StatusFound)
-		return
+func (h *Handler) ExpensesUncategorized(w http.ResponseWriter, r *http.Request) {
+	expenses, total, err := h.db.ListUncategorizedExpenses()
+	if err != nil {
+		logger.FromContext(r.Context()).Error("expense_uncategorized_error", "error", err.Error())
 	}
-	http.Redirect(w, r, "/payroll", http.StatusFound)
+	h.render(w, r, "expenses_uncategorized.html", map[string]interface{}{
+		"Title":    "Uncategorized Spend",
+		"Active":   "expenses",
+		"Expenses": expenses,
+		"Total":    total,
+	})
 }
 
-func (h *Handler) PayrollSaveHours(w http.ResponseWriter, r *http.Request) {
-	weekStart := r.FormValue("week_start")
-	weekEnd := r.FormValue("week_end")
+// Search finds receipts by a scrap of invoice number, note, check number or vendor name,
+// along with any vendors whose name matches
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
 
-	// Get all active employees to process their hours
-	employees, _ := h.db.ListEmployees(true)
-	for _, emp := range employees {
-		hoursStr := r.FormValue(fmt.Sprintf("hours_%d", emp.ID))
-		hours, _ := strconv.ParseFloat(hoursStr, 64)
-		if hours > 0 {
-			h.db.UpsertWeeklyPayroll(emp.ID, weekStart, weekEnd, hours, emp.HourlyRate, emp.PaymentMethod)
+	var expenses []models.Expense
+	var vendors []models.Vendor
+	if q != "" {
+		var err error
+		expenses, err = h.db.SearchExpenses(q, searchLimit)
+		if err != nil {
+			l.Error("search_expenses_error", "error", err.Error())
+		}
+		vendors, err = h.db.SearchVendors(q, searchLimit)
+		if err != nil {
+			l.Error("search_vendors_error", "error", err.Error())
 		}
 	}
 
-	http.Redirect(w, r, "/payroll", http.StatusFound)
+	h.render(w, r, "search.html", map[string]any{
+		"Title":    "Search",
+		"Active":   "expenses",
+		"Query":    q,
+		"Expenses": expenses,
+		"Vendors":  vendors,
+		"Limit":    searchLimit,
+	})
 }
 
-func (h *Handler) PayrollDelete(w http.ResponseWriter, r *http.Request) {
+// searchLimit caps each section of the search results page
+const searchLimit = 100
+
+// ExpensesRecurring lists the monthly recurring expenses
+func (h *Handler) ExpensesRecurring(w http.ResponseWriter, r *http.Request) {
+	recurring, err := h.db.ListRecurringExpenses()
+	if err != nil {
+		logger.FromContext(r.Context()).Error("recurring_expense_list_error", "error", err.Error())
+	}
+	vendors, _ := h.db.ListVendors()
+
+	var success string
+	if generated := r.URL.Query().Get("generated"); generated != "" {
+		success = fmt.Sprintf("Created %s receipt(s) for %s", generated, time.Now().Format("January 2006"))
+	}
+
+	h.render(w, r, "expenses_recurring.html", map[string]interface{}{
+		"Title":     "Recurring Expenses",
+		"Active":    "expenses",
+		"Recurring": recurring,
+		"Vendors":   vendors,
+		"Month":     time.Now().Format("January 2006"),
+		"Success":   success,
+		"Error":     r.URL.Query().Get("error"),
+	})
+}
+
+// ExpensesRecurringCreate adds a recurring expense for a vendor
+func (h *Handler) ExpensesRecurringCreate(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	vendorID, _ := strconv.ParseInt(r.FormValue("vendor_id"), 10, 64)
+	fieldErrs := formErrors{}
+	amount := fieldErrs.amount(r, "amount", "Amount")
+	day, _ := strconv.Atoi(r.FormValue("day_of_month"))
+	recurring := models.RecurringExpense{
+		VendorID:    vendorID,
+		Amount:      amount,
+		DayOfMonth:  day,
+		PaymentType: r.FormValue("payment_type"),
+	}
+
+	var problem string
+	switch {
+	case recurring.VendorID <= 0:
+		problem = "Choose a vendor"
+	case fieldErrs["amount"] != "":
+		problem = fieldErrs["amount"]
+	case recurring.Amount <= 0:
+		problem = "Amount must be greater than zero"
+	case recurring.DayOfMonth < 1 || recurring.DayOfMonth > 31:
+		problem = "Day of month must be between 1 and 31"
+	case !slices.Contains(paymentTypes, recurring.PaymentType):
+		problem = "Invalid payment type"
+	}
+	if problem != "" {
+		http.Redirect(w, r, "/expenses/recurring?"+url.Values{"error": {problem}}.Encode(), http.StatusFound)
+		return
+	}
+
+	id, err := h.db.CreateRecurringExpense(recurring)
+	if err != nil {
+		l.Error("recurring_expense_create_error", "vendor_id", vendorID, "error", err.Error())
+	} else {
+		l.Info("recurring_expense_created", "recurring_id", id, "vendor_id", vendorID, "amount", amount)
+	}
+
+	http.Redirect(w, r, "/expenses/recurring", http.StatusFound)
+}
+
+// ExpensesRecurringDelete removes a recurring expense
+func (h *Handler) ExpensesRecurringDelete(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err == nil {
+		if err := h.db.DeleteRecurringExpense(id); err != nil {
+			l.Error("recurring_expense_delete_error", "recurring_id", id, "error", err.Error())
+		}
+	}
+
+	http.Redirect(w, r, "/expenses/recurring", http.StatusFound)
+}
+
+// ExpensesRecurringGenerate creates this month's not-paid expenses from the recurring
+// expenses, skipping vendors that already have an expense this month
+func (h *Handler) ExpensesRecurringGenerate(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	created, err := h.db.GenerateRecurringExpenses(time.Now())
+	if err != nil {
+		l.Error("recurring_expense_generate_error", "created", created, "error", err.Error())
+		http.Redirect(w, r, "/expenses/recurring?"+url.Values{"error": {"Failed to generate recurring expenses"}}.Encode(), http.StatusFound)
+		return
+	}
+	l.Info("recurring_expenses_generated", "created", created)
+
+	http.Redirect(w, r, fmt.Sprintf("/expenses/recurring?generated=%d", created), http.StatusFound)
+}
+
+func (h *Handler) ExpensesNew(w http.ResponseWriter, r *http.Request) {
+	vendors, _ := h.db.ListVendors()
+	lastCheck, _ := h.db.GetLastCheckNumber()
+	nextCheck, _ := h.db.NextCheckNumber()
+
+	// Coming from a vendor page, start with that vendor and its defaults filled in
+	expense := models.Expense{Date: time.Now().Format("2006-01-02")}
+	if vendorID, _ := strconv.ParseInt(r.URL.Query().Get("vendor_id"), 10, 64); vendorID > 0 {
+		if vendor, err := h.db.GetVendor(vendorID); err == nil {
+			expense.VendorID = vendor.ID
+			expense.PaymentType = vendor.DefaultPaymentType
+		}
+	}
+
+	h.render(w, r, "expenses_form.html", map[string]interface{}{
+		"Title":                "New Expense",
+		"Active":               "expenses",
+		"Expense":              expense,
+		"Vendors":              vendors,
+		"LastCheckNumber":      lastCheck,
+		"SuggestedCheckNumber": nextCheck,
+		"AllowAdHocPayee":      h.allowAdHocPayee,
+	})
+}
+
+func (h *Handler) ExpensesCreate(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	// Parse multipart form for file upload (5MB limit)
+	if err := r.ParseMultipartForm(5 << 20); err != nil {
+		l.Error("expense_parse_form_error", "error", err.Error())
+	}
+
+	fieldErrs := formErrors{}
+	vendorID := fieldErrs.optionalID(r, "vendor_id", "Vendor")
+	amount := fieldErrs.amount(r, "amount", "Amount")
+
+	expense := models.Expense{
+		Date:          fieldErrs.date(r, "date", "Receipt date", true),
+		VendorID:      vendorID,
+		PayeeName:     strings.TrimSpace(r.FormValue("payee_name")),
+		Amount:        amount,
+		InvoiceNumber: r.FormValue("invoice_number"),
+		Status:        r.FormValue("status"),
+		PaymentType:   r.FormValue("payment_type"),
+		CheckNumber:   r.FormValue("check_number"),
+		DateOpened:    fieldErrs.date(r, "date_opened", "Date opened", false),
+		DueDate:       fieldErrs.date(r, "due_date", "Due date", false),
+		DatePaid:      fieldErrs.date(r, "date_paid", "Date paid", false),
+		Notes:         r.FormValue("notes"),
+	}
+
+	err := fieldErrs.err()
+	if err == nil {
+		err = h.validateExpensePayee(&expense)
+	}
+
+	// Ask before saving what looks like the same invoice entered twice
+	var duplicate *models.Expense
+	if err == nil && r.FormValue("save_anyway") == "" {
+		dup, found, dupErr := h.db.FindPossibleDuplicateExpense(expense)
+		if dupErr != nil {
+			l.Error("expense_duplicate_check_error", "error", dupErr.Error())
+		} else if found {
+			l.Info("expense_possible_duplicate", "existing_id", dup.ID, "vendor_id", expense.VendorID, "amount", expense.Amount)
+			duplicate = &dup
+		}
+	}
+
+	var checkUses []models.CheckUse
+	if err == nil {
+		checkUses = h.checkNumberReused(r, expense.PaymentType, expense.CheckNumber, "expense", 0)
+	}
+	warn := duplicate != nil || len(checkUses) > 0
+
+	// Handle receipt file upload
+	if err == nil && !warn {
+		file, header, fileErr := r.FormFile("receipt")
+		if fileErr == nil {
+			defer file.Close()
+			expense.ReceiptPath, err = h.saveReceipt(r, header.Filename, file)
+		}
+	}
+	if err == nil && !warn {
+		_, err = h.db.CreateExpense(expense)
+	}
+	if err != nil || warn {
+		// Clean up uploaded file on error
+		if expense.ReceiptPath != "" {
+			h.deleteStoredFile(r, expense.ReceiptPath)
+			expense.ReceiptPath = ""
+		}
+		var errMsg string
+		if err != nil {
+			errMsg = err.Error()
+		}
+		vendors, _ := h.db.ListVendors()
+		lastCheck, _ := h.db.GetLastCheckNumber()
+		nextCheck, _ := h.db.NextCheckNumber()
+		h.render(w, r, "expenses_form.html", map[string]interface{}{
+			"Title":                "New Expense",
+			"Active":               "expenses",
+			"Expense":              expense,
+			"Vendors":              vendors,
+			"LastCheckNumber":      lastCheck,
+			"SuggestedCheckNumber": nextCheck,
+			"AllowAdHocPayee":      h.allowAdHocPayee,
+			"Error":                errMsg,
+			"FieldErrors":          fieldErrs,
+			"Input":                r.Form,
+			"Duplicate":            duplicate,
+			"CheckUses":            checkUses,
+		})
+		return
+	}
+	http.Redirect(w, r, "/expenses", http.StatusFound)
+}
+
+// validateExpensePayee checks that an expense has a vendor, or an ad-hoc payee
+// name when those are allowed. A payee name is dropped once a vendor is chosen.
+func (h *Handler) validateExpensePayee(e *models.Expense) error {
+	if e.VendorID > 0 {
+		e.PayeeName = ""
+		return nil
+	}
+	if !h.allowAdHocPayee {
+		return fmt.Errorf("vendor is required")
+	}
+	if e.PayeeName == "" {
+		return fmt.Errorf("select a vendor or enter a payee name")
+	}
+	e.VendorName = e.PayeeName
+	return nil
+}
+
+// checkNumberReused returns the other expenses and payroll entries already paid with the
+// check number being saved, so the form can warn before it is used twice. source and id
+// name the record being saved (id 0 when new). Ticking reuse_check skips the warning
+func (h *Handler) checkNumberReused(r *http.Request, paymentType, number, source string, id int64) []models.CheckUse {
+	if paymentType != "check" || r.FormValue("reuse_check") != "" {
+		return nil
+	}
+	uses, err := h.db.CheckNumberInUse(number)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("check_number_lookup_error", "error", err.Error())
+		return nil
+	}
+	others := uses[:0]
+	for _, u := range uses {
+		if u.Source != source || u.ID != id {
+			others = append(others, u)
+		}
+	}
+	if len(others) > 0 {
+		logger.FromContext(r.Context()).Info("check_number_reused", "check_number", number, "uses", len(others))
+	}
+	return others
+}
+
+func (h *Handler) ExpensesEdit(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	week := r.FormValue("week")
-	h.db.DeletePayroll(id)
-	if week != "" {
-		http.Redirect(w, r, "/payroll?week="+week, http.StatusFound)
+	expense, err := h.db.GetExpense(id)
+	if err != nil {
+		http.Redirect(w, r, "/expenses", http.StatusFound)
+		return
+	}
+	vendors, _ := h.db.ListVendors()
+	lastCheck, _ := h.db.GetLastCheckNumber()
+	nextCheck, _ := h.db.NextCheckNumber()
+	h.render(w, r, "expenses_form.html", map[string]interface{}{
+		"Title":                "Edit Expense",
+		"Active":               "expenses",
+		"Expense":              expense,
+		"Vendors":              vendors,
+		"LastCheckNumber":      lastCheck,
+		"SuggestedCheckNumber": nextCheck,
+		"AllowAdHocPayee":      h.allowAdHocPayee,
+	})
+}
+
+func (h *Handler) ExpensesUpdate(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	// Parse multipart form for file upload (5MB limit)
+	if err := r.ParseMultipartForm(5 << 20); err != nil {
+		l.Error("expense_parse_form_error", "error", err.Error())
+	}
+
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	fieldErrs := formErrors{}
+	vendorID := fieldErrs.optionalID(r, "vendor_id", "Vendor")
+	amount := fieldErrs.amount(r, "amount", "Amount")
+
+	// Get existing receipt path to preserve if no new file uploaded
+	oldReceiptPath, _ := h.db.GetExpenseReceiptPath(id)
+
+	expense := models.Expense{
+		ID:            id,
+		Date:          fieldErrs.date(r, "date", "Receipt date", true),
+		VendorID:      vendorID,
+		PayeeName:     strings.TrimSpace(r.FormValue("payee_name")),
+		Amount:        amount,
+		InvoiceNumber: r.FormValue("invoice_number"),
+		Status:        r.FormValue("status"),
+		PaymentType:   r.FormValue("payment_type"),
+		CheckNumber:   r.FormValue("check_number"),
+		DateOpened:    fieldErrs.date(r, "date_opened", "Date opened", false),
+		DueDate:       fieldErrs.date(r, "due_date", "Due date", false),
+		DatePaid:      fieldErrs.date(r, "date_paid", "Date paid", false),
+		Notes:         r.FormValue("notes"),
+		ReceiptPath:   oldReceiptPath, // Preserve existing receipt by default
+	}
+
+	// Handle new receipt file upload
+	var newReceiptPath string
+	var receiptErr error
+	file, header, err := r.FormFile("receipt")
+	if err == nil {
+		defer file.Close()
+		newReceiptPath, receiptErr = h.saveReceipt(r, header.Filename, file)
+		if newReceiptPath != "" {
+			expense.ReceiptPath = newReceiptPath
+		}
+	}
+
+	err = fieldErrs.err()
+	if err == nil {
+		err = receiptErr
+	}
+	if err == nil {
+		err = h.validateExpensePayee(&expense)
+	}
+	var checkUses []models.CheckUse
+	if err == nil {
+		checkUses = h.checkNumberReused(r, expense.PaymentType, expense.CheckNumber, "expense", id)
+	}
+	if err == nil && len(checkUses) == 0 {
+		err = h.db.UpdateExpense(expense)
+	}
+	if err != nil || len(checkUses) > 0 {
+		// Clean up newly uploaded file on error
+		if newReceiptPath != "" {
+			h.deleteStoredFile(r, newReceiptPath)
+			expense.ReceiptPath = oldReceiptPath
+		}
+		var errMsg string
+		if err != nil {
+			errMsg = err.Error()
+		}
+		vendors, _ := h.db.ListVendors()
+		lastCheck, _ := h.db.GetLastCheckNumber()
+		nextCheck, _ := h.db.NextCheckNumber()
+		h.render(w, r, "expenses_form.html", map[string]interface{}{
+			"Title":                "Edit Expense",
+			"Active":               "expenses",
+			"Expense":              expense,
+			"Vendors":              vendors,
+			"LastCheckNumber":      lastCheck,
+			"SuggestedCheckNumber": nextCheck,
+			"AllowAdHocPayee":      h.allowAdHocPayee,
+			"Error":                errMsg,
+			"FieldErrors":          fieldErrs,
+			"Input":                r.Form,
+			"CheckUses":            checkUses,
+		})
+		return
+	}
+
+	// Delete old receipt file if a new one was uploaded successfully
+	if newReceiptPath != "" && oldReceiptPath != "" && oldReceiptPath != newReceiptPath {
+		h.deleteStoredFile(r, oldReceiptPath)
+	}
+
+	http.Redirect(w, r, "/expenses", http.StatusFound)
+}
+
+func (h *Handler) ExpensesPayForm(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	expense, err := h.db.GetExpense(id)
+	if err != nil {
+		http.Redirect(w, r, "/expenses", http.StatusFound)
+		return
+	}
+	h.renderExpensePay(w, r, expense, nil, nil, nil)
+}
+
+// renderExpensePay shows the payment form for expense with the installments paid so far.
+// After an error or a reused check number the submitted values are shown again
+func (h *Handler) renderExpensePay(w http.ResponseWriter, r *http.Request, expense models.Expense, fieldErrs formErrors, err error, checkUses []models.CheckUse) {
+	payments, perr := h.db.ListExpensePayments(expense.ID)
+	if perr != nil {
+		logger.FromContext(r.Context()).Error("expense_payments_error", "expense_id", expense.ID, "error", perr.Error())
+	}
+	lastCheck, _ := h.db.GetLastCheckNumber()
+	nextCheck, _ := h.db.NextCheckNumber()
+	var errMsg string
+	var input url.Values
+	if err != nil {
+		errMsg = err.Error()
+	}
+	if err != nil || len(checkUses) > 0 {
+		input = r.Form
+	}
+	h.render(w, r, "expenses_pay.html", map[string]interface{}{
+		"Title":                "Mark Expense Paid",
+		"Active":               "expenses",
+		"Expense":              expense,
+		"Payments":             payments,
+		"Today":                time.Now().Format("2006-01-02"),
+		"LastCheckNumber":      lastCheck,
+		"SuggestedCheckNumber": nextCheck,
+		"Error":                errMsg,
+		"FieldErrors":          fieldErrs,
+		"Input":                input,
+		"CheckUses":            checkUses,
+	})
+}
+
+// ExpensesPay records a payment against an expense: the whole balance, or part of it
+// when a smaller amount is posted, leaving the rest owed
+func (h *Handler) ExpensesPay(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	expense, err := h.db.GetExpense(id)
+	if err != nil {
+		http.Redirect(w, r, "/expenses", http.StatusFound)
+		return
+	}
+
+	fieldErrs := formErrors{}
+	payment := models.ExpensePayment{
+		ExpenseID:   id,
+		Amount:      fieldErrs.amount(r, "amount", "Amount"),
+		Date:        fieldErrs.date(r, "date", "Date paid", true),
+		PaymentType: r.FormValue("payment_type"),
+		CheckNumber: strings.TrimSpace(r.FormValue("check_number")),
+	}
+	if !slices.Contains(paymentTypes, payment.PaymentType) {
+		fieldErrs["payment_type"] = "Choose a payment type"
+	}
+	if payment.PaymentType != "check" {
+		payment.CheckNumber = ""
+	}
+	if len(fieldErrs) == 0 && payment.Amount <= 0 {
+		fieldErrs["amount"] = "Amount must be greater than zero"
+	}
+	if err := fieldErrs.err(); err != nil {
+		h.renderExpensePay(w, r, expense, fieldErrs, err, nil)
+		return
+	}
+	if uses := h.checkNumberReused(r, payment.PaymentType, payment.CheckNumber, "expense", id); len(uses) > 0 {
+		h.renderExpensePay(w, r, expense, nil, nil, uses)
+		return
+	}
+
+	err = h.db.AddExpensePayment(payment)
+	if errors.Is(err, database.ErrExpensePaid) || errors.Is(err, database.ErrOverpayment) {
+		h.renderExpensePay(w, r, expense, nil, err, nil)
+		return
+	}
+	if err != nil {
+		l.Error("expense_pay_error", "expense_id", id, "error", err.Error())
+		h.renderExpensePay(w, r, expense, nil, errors.New("Failed to record the payment"), nil)
+		return
+	}
+	l.Info("expense_payment_added", "expense_id", id, "amount", payment.Amount, "payment_type", payment.PaymentType)
+
+	balance := expense.Balance() - payment.Amount
+	if balance >= 0.005 {
+		notice := url.Values{
+			"partial": {expense.VendorName},
+			"amount":  {fmt.Sprintf("%.2f", payment.Amount)},
+			"balance": {fmt.Sprintf("%.2f", balance)},
+		}
+		redirectToExpenseList(w, r, notice)
+		return
+	}
+	notice := url.Values{"paid": {expense.VendorName}, "paid_by": {payment.PaymentType}}
+	if payment.CheckNumber != "" {
+		notice.Set("check", payment.CheckNumber)
+	}
+	redirectToExpenseList(w, r, notice)
+}
+
+// ExpensesQuickPay marks an expense paid today straight from the list, by the posted
+// payment type or else the vendor's default. Paying by check takes the number after the
+// last check written
+func (h *Handler) ExpensesQuickPay(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	redirectErr := func(msg string) {
+		redirectToExpenseList(w, r, url.Values{"error": {msg}})
+	}
+
+	expense, err := h.db.GetExpense(id)
+	if err != nil {
+		redirectErr("Receipt not found")
+		return
+	}
+	if expense.Status == "paid" {
+		redirectErr(expense.VendorName + " is already paid")
+		return
+	}
+
+	paymentType := r.FormValue("payment_type")
+	if paymentType == "" && expense.VendorID > 0 {
+		if vendor, err := h.db.GetVendor(expense.VendorID); err == nil {
+			paymentType = vendor.DefaultPaymentType
+		}
+	}
+	if paymentType == "" {
+		redirectErr(expense.VendorName + " has no default payment type. Use Mark Paid to choose one")
+		return
+	}
+	if !slices.Contains(paymentTypes, paymentType) {
+		redirectErr("Invalid payment type")
+		return
+	}
+
+	checkNumber := strings.TrimSpace(r.FormValue("check_number"))
+	if paymentType == "check" && checkNumber == "" {
+		checkNumber, err = h.db.NextCheckNumber()
+		if err != nil {
+			l.Error("expense_quick_pay_error", "expense_id", id, "error", err.Error())
+		}
+		if checkNumber == "" {
+			redirectErr("No earlier check number to continue from. Use Mark Paid to enter one")
+			return
+		}
+	}
+	if paymentType != "check" {
+		checkNumber = ""
+	}
+
+	// A reused check number goes to the payment form, where it can be changed or kept
+	if uses := h.checkNumberReused(r, paymentType, checkNumber, "expense", id); len(uses) > 0 {
+		r.Form.Set("amount", fmt.Sprintf("%.2f", expense.Balance()))
+		r.Form.Set("date", time.Now().Format("2006-01-02"))
+		r.Form.Set("payment_type", paymentType)
+		r.Form.Set("check_number", checkNumber)
+		h.renderExpensePay(w, r, expense, nil, nil, uses)
+		return
+	}
+
+	if err := h.db.MarkExpensePaid(id, paymentType, checkNumber); err != nil {
+		l.Error("expense_quick_pay_error", "expense_id", id, "error", err.Error())
+		redirectErr("Failed to mark receipt paid")
+		return
+	}
+	l.Info("expense_quick_paid", "expense_id", id, "payment_type", paymentType, "check_number", checkNumber)
+
+	notice := url.Values{"paid": {expense.VendorName}, "paid_by": {paymentType}}
+	if checkNumber != "" {
+		notice.Set("check", checkNumber)
+	}
+	redirectToExpenseList(w, r, notice)
+}
+
+func (h *Handler) ExpensesDelete(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	// The receipt file is kept until the expense is purged from the trash
+	h.db.DeleteExpense(id)
+	http.Redirect(w, r, "/expenses", http.StatusFound)
+}
+
+// ExpensesTrash lists deleted expenses so they can be restored or purged
+func (h *Handler) ExpensesTrash(w http.ResponseWriter, r *http.Request) {
+	expenses, err := h.db.ListDeletedExpenses()
+	if err != nil {
+		logger.FromContext(r.Context()).Error("expenses_trash_error", "error", err.Error())
+	}
+	h.render(w, r, "trash.html", map[string]interface{}{
+		"Title":        "Deleted Receipts",
+		"Active":       "expenses",
+		"ExpensesOnly": true,
+		"Error":        r.URL.Query().Get("error"),
+		"Sections": []trashSection{
+			{Heading: "Receipts", Items: expenses},
+		},
+	})
+}
+
+// ExpensesRestore brings a deleted expense back from the trash
+func (h *Handler) ExpensesRestore(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err := h.db.RestoreExpense(id); err != nil {
+		logger.FromContext(r.Context()).Error("expense_restore_failed", "expense_id", id, "error", err.Error())
+	}
+	http.Redirect(w, r, "/expenses/trash", http.StatusFound)
+}
+
+// ExpensesDownloadReceipt serves the receipt file for an expense
+func (h *Handler) ExpensesDownloadReceipt(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	receiptPath, err := h.db.GetExpenseReceiptPath(id)
+	if err != nil || receiptPath == "" {
+		http.Error(w, "Receipt not found", http.StatusNotFound)
+		return
+	}
+
+	file, err := h.files.Get(receiptPath)
+	if err != nil {
+		http.Error(w, "Receipt file not found", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	ext := strings.ToLower(filepath.Ext(receiptPath))
+	w.Header().Set("Content-Type", storedFileContentType(receiptPath))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"receipt%s\"", ext))
+	io.Copy(w, file)
+}
+
+// storedFileContentType picks the content type to serve a stored receipt or statement
+// with, from its extension. Text statements are served as plain text so they open in
+// the browser rather than downloading
+func storedFileContentType(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf":
+		return "application/pdf"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".gif":
+		return "image/gif"
+	case ".heic":
+		return "image/heic"
+	case ".csv", ".txt", ".ofx", ".qfx":
+		return "text/plain; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
+
+// ExpensesUploadReceipt handles quick receipt upload from list page
+func (h *Handler) ExpensesUploadReceipt(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+	// Parse multipart form (5MB limit)
+	if err := r.ParseMultipartForm(5 << 20); err != nil {
+		l.Error("receipt_upload_parse_error", "error", err.Error())
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("receipt")
+	if err != nil {
+		http.Error(w, "No file provided", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	// Get old receipt path for cleanup
+	oldReceiptPath, _ := h.db.GetExpenseReceiptPath(id)
+
+	// Save new file
+	storedPath, err := h.saveReceipt(r, header.Filename, file)
+	if errors.Is(err, filestore.ErrFileType) || errors.Is(err, filestore.ErrFileTooLarge) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		l.Error("receipt_upload_save_error", "error", err.Error())
+		http.Error(w, "Failed to save file", http.StatusInternalServerError)
+		return
+	}
+
+	// Update database
+	if err := h.db.UpdateExpenseReceipt(id, storedPath); err != nil {
+		h.deleteStoredFile(r, storedPath) // Clean up on error
+		l.Error("receipt_upload_db_error", "error", err.Error())
+		http.Error(w, "Failed to update expense", http.StatusInternalServerError)
+		return
+	}
+
+	// Delete old file after successful update
+	if oldReceiptPath != "" {
+		h.deleteStoredFile(r, oldReceiptPath)
+	}
+
+	l.Info("receipt_uploaded", "expense_id", id)
+	http.Redirect(w, r, "/expenses", http.StatusFound)
+}
+
+// receiptPlaceholder stands in for thumbnails of receipts that can't be previewed, such
+// as PDFs, labelled with the file type
+const receiptPlaceholder = `<svg xmlns="http://www.w3.org/2000/svg" width="300" height="400" viewBox="0 0 300 400">` +
+	`<rect width="300" height="400" fill="#f3f4f6"/>` +
+	`<text x="150" y="210" font-family="sans-serif" font-size="48" font-weight="bold" fill="#9ca3af" text-anchor="middle">%s</text>` +
+	`</svg>`
+
+// deleteStoredFile removes a file from the filestore unless another receipt or statement
+// still uses it, which happens when the same file is uploaded twice
+func (h *Handler) deleteStoredFile(r *http.Request, path string) {
+	if path == "" {
+		return
+	}
+	inUse, err := h.db.StoredFileInUse(path)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("stored_file_check_error", "path", path, "error", err.Error())
+		return
+	}
+	if !inUse {
+		h.files.Delete(path)
+	}
+}
+
+// saveReceipt stores an uploaded receipt after checking its type and size, and makes
+// its thumbnail
+func (h *Handler) saveReceipt(r *http.Request, filename string, file io.Reader) (string, error) {
+	storedPath, err := h.files.SaveValidated(filename, file, filestore.SaveOptions{
+		MaxSize:      h.maxUploadSize,
+		AllowedTypes: filestore.ReceiptTypes,
+	})
+	if err != nil {
+		return "", fmt.Errorf("receipt: %w", err)
+	}
+	h.saveReceiptThumbnail(r, storedPath)
+	return storedPath, nil
+}
+
+// saveReceiptThumbnail generates a thumbnail for a newly stored receipt image. Failing
+// is not fatal; ExpensesReceiptThumb tries again when the thumbnail is first requested
+func (h *Handler) saveReceiptThumbnail(r *http.Request, storedPath string) {
+	if !filestore.CanThumbnail(storedPath) {
+		return
+	}
+	if _, err := h.files.SaveThumbnail(storedPath); err != nil {
+		logger.FromContext(r.Context()).Warn("receipt_thumbnail_error", "path", storedPath, "error", err.Error())
+	}
+}
+
+// ExpensesReceiptThumb serves a small preview of an expense's receipt, or a placeholder
+// when the receipt is not an image
+func (h *Handler) ExpensesReceiptThumb(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	receiptPath, err := h.db.GetExpenseReceiptPath(id)
+	if err != nil || receiptPath == "" {
+		http.Error(w, "Receipt not found", http.StatusNotFound)
+		return
+	}
+
+	if !filestore.CanThumbnail(receiptPath) {
+		label := strings.ToUpper(strings.TrimPrefix(filepath.Ext(receiptPath), "."))
+		w.Header().Set("Content-Type", "image/svg+xml")
+		fmt.Fprintf(w, receiptPlaceholder, template.HTMLEscapeString(label))
+		return
+	}
+
+	// Receipts uploaded before thumbnails existed get one on first view
+	thumbPath := filestore.ThumbnailPath(receiptPath)
+	file, err := h.files.Get(thumbPath)
+	if err != nil {
+		if thumbPath, err = h.files.SaveThumbnail(receiptPath); err == nil {
+			file, err = h.files.Get(thumbPath)
+		}
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Warn("receipt_thumbnail_error", "expense_id", id, "error", err.Error())
+		http.Error(w, "Thumbnail not available", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	io.Copy(w, file)
+}
+
+// ExpensesDeleteReceipt removes the receipt from an expense
+func (h *Handler) ExpensesDeleteReceipt(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+	receiptPath, err := h.db.GetExpenseReceiptPath(id)
+	if err != nil || receiptPath == "" {
+		http.Redirect(w, r, fmt.Sprintf("/expenses/%d/edit", id), http.StatusFound)
+		return
+	}
+
+	// Clear receipt path in database
+	if err := h.db.UpdateExpenseReceipt(id, ""); err != nil {
+		l.Error("receipt_delete_db_error", "error", err.Error())
+		http.Redirect(w, r, fmt.Sprintf("/expenses/%d/edit", id), http.StatusFound)
+		return
+	}
+
+	// Delete the file
+	h.deleteStoredFile(r, receiptPath)
+	l.Info("receipt_deleted", "expense_id", id)
+	http.Redirect(w, r, fmt.Sprintf("/expenses/%d/edit", id), http.StatusFound)
+}
+
+// Payroll handlers
+
+// getWeekBounds calculates the Monday and Sunday for a given date
+func getWeekBounds(date time.Time) (string, string) {
+	// Find Monday of the week
+	weekday := int(date.Weekday())
+	if weekday == 0 {
+		weekday = 7 // Sunday = 7
+	}
+	monday := date.AddDate(0, 0, -(weekday - 1))
+	sunday := monday.AddDate(0, 0, 6)
+	return monday.Format("2006-01-02"), sunday.Format("2006-01-02")
+}
+
+func (h *Handler) PayrollList(w http.ResponseWriter, r *http.Request) {
+	weeks, err := h.db.ListPayrollWeeks(50)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("payroll_weeks_error", "error", err.Error())
+	}
+
+	lastWeekStart, _ := getWeekBounds(time.Now().AddDate(0, 0, -7))
+	h.render(w, r, "payroll_list.html", map[string]any{
+		"Title":         "Payroll",
+		"Active":        "payroll",
+		"Weeks":         weeks,
+		"LastWeekStart": lastWeekStart,
+	})
+}
+
+// PayrollWeekNew opens payroll for the current week, or for the week holding the day
+// given as ?week_start= or ?date=. An invalid day falls back to the current week
+func (h *Handler) PayrollWeekNew(w http.ResponseWriter, r *http.Request) {
+	day := time.Now()
+	for _, key := range []string{"week_start", "date"} {
+		value := r.URL.Query().Get(key)
+		if value == "" {
+			continue
+		}
+		if date, err := parseFormDate(value); err == nil {
+			day, _ = time.Parse("2006-01-02", date)
+		}
+		break
+	}
+	weekStart, weekEnd := getWeekBounds(day)
+
+	entries, total, _ := h.db.GetWeeklyPayroll(weekStart, weekEnd)
+	lastCheck, _ := h.db.GetLastCheckNumber()
+	nextCheck, _ := h.db.NextCheckNumber()
+
+	// Format dates for display
+	weekStartDate, _ := time.Parse("2006-01-02", weekStart)
+	weekEndDate, _ := time.Parse("2006-01-02", weekEnd)
+	weekStartDisplay := weekStartDate.Format("Jan 2")
+	weekEndDisplay := weekEndDate.Format("Jan 2, 2006")
+	unpaid, weekID := unpaidPayroll(entries)
+
+	var success string
+	if copied := r.URL.Query().Get("copied"); copied != "" {
+		success = fmt.Sprintf("Copied hours for %s employee(s) from the previous week", copied)
+	}
+
+	h.render(w, r, "payroll_week_edit.html", map[string]any{
+		"Title":                "New Payroll Week",
+		"Active":               "payroll",
+		"Entries":              entries,
+		"Total":                total,
+		"WeekID":               weekID,
+		"Unpaid":               unpaid,
+		"Success":              success,
+		"Error":                r.URL.Query().Get("error"),
+		"WeekStart":            weekStart,
+		"WeekEnd":              weekEnd,
+		"WeekDisplay":          weekStartDisplay + " - " + weekEndDisplay,
+		"PrevWeekStart":        weekStartDate.AddDate(0, 0, -7).Format("2006-01-02"),
+		"NextWeekStart":        weekStartDate.AddDate(0, 0, 7).Format("2006-01-02"),
+		"LastCheckNumber":      lastCheck,
+		"SuggestedCheckNumber": nextCheck,
+	})
+}
+
+// unpaidPayroll counts the entries still to be paid and returns the payroll week they
+// belong to, or 0 when there are none
+func unpaidPayroll(entries []models.WeeklyPayrollEntry) (int, int64) {
+	var count int
+	var weekID int64
+	for _, e := range entries {
+		if e.Payroll != nil && e.Payroll.Status == "not_paid" {
+			count++
+			weekID = e.Payroll.WeekID
+		}
+	}
+	return count, weekID
+}
+
+func (h *Handler) PayrollWeekEdit(w http.ResponseWriter, r *http.Request) {
+	weekIDStr := r.PathValue("id")
+	weekID, err := strconv.ParseInt(weekIDStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid week ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get the week details
+	week, err := h.db.GetPayrollWeek(weekID)
+	if err != nil {
+		http.Error(w, "Week not found", http.StatusNotFound)
+		return
+	}
+
+	entries, total, _ := h.db.GetWeeklyPayrollByWeekID(weekID)
+	lastCheck, _ := h.db.GetLastCheckNumber()
+	nextCheck, _ := h.db.NextCheckNumber()
+
+	// Format dates for display
+	weekStartDate, _ := time.Parse("2006-01-02", week.PeriodStart)
+	weekEndDate, _ := time.Parse("2006-01-02", week.PeriodEnd)
+	weekStartDisplay := weekStartDate.Format("Jan 2")
+	weekEndDisplay := weekEndDate.Format("Jan 2, 2006")
+
+	var success string
+	if paid := r.URL.Query().Get("paid"); paid != "" {
+		first, last := r.URL.Query().Get("first_check"), r.URL.Query().Get("last_check")
+		success = fmt.Sprintf("Paid %s employee(s) by check #%s", paid, first)
+		if last != first {
+			success += " through #" + last
+		}
+	}
+	unpaid, _ := unpaidPayroll(entries)
+
+	h.render(w, r, "payroll_week_edit.html", map[string]any{
+		"Title":                "Edit Payroll - " + weekEndDisplay,
+		"Active":               "payroll",
+		"Entries":              entries,
+		"Total":                total,
+		"WeekID":               weekID,
+		"Unpaid":               unpaid,
+		"Success":              success,
+		"Error":                r.URL.Query().Get("error"),
+		"WeekStart":            week.PeriodStart,
+		"WeekEnd":              week.PeriodEnd,
+		"WeekDisplay":          weekStartDisplay + " - " + weekEndDisplay,
+		"LastCheckNumber":      lastCheck,
+		"SuggestedCheckNumber": nextCheck,
+	})
+}
+
+func (h *Handler) PayrollWeekDetail(w http.ResponseWriter, r *http.Request) {
+	weekIDStr := r.PathValue("id")
+	weekID, err := strconv.ParseInt(weekIDStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid week ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get the week details
+	week, err := h.db.GetPayrollWeek(weekID)
+	if err != nil {
+		http.Error(w, "Week not found", http.StatusNotFound)
+		return
+	}
+
+	entries, total, _ := h.db.GetWeeklyPayrollByWeekID(weekID)
+	lastCheck, _ := h.db.GetLastCheckNumber()
+	nextCheck, _ := h.db.NextCheckNumber()
+
+	// Format dates for display (handle both "2006-01-02" and "2006-01-02T15:04:05Z" formats)
+	weekStartDate, err := time.Parse("2006-01-02", week.PeriodStart)
+	if err != nil {
+		weekStartDate, err = time.Parse(time.RFC3339, week.PeriodStart)
+		if err != nil {
+			http.Error(w, "Invalid period start date: "+week.PeriodStart, http.StatusInternalServerError)
+			return
+		}
+	}
+	weekEndDate, err := time.Parse("2006-01-02", week.PeriodEnd)
+	if err != nil {
+		weekEndDate, err = time.Parse(time.RFC3339, week.PeriodEnd)
+		if err != nil {
+			http.Error(w, "Invalid period end date: "+week.PeriodEnd, http.StatusInternalServerError)
+			return
+		}
+	}
+	weekStartDisplay := weekStartDate.Format("Jan 2")
+	weekEndDisplay := weekEndDate.Format("Jan 2, 2006")
+
+	var netTotal float64
+	for _, e := range entries {
+		if e.Payroll != nil {
+			netTotal += e.Payroll.NetPay()
+		}
+	}
+
+	h.render(w, r, "payroll_detail.html", map[string]interface{}{
+		"Title":                "Payroll - " + weekStartDisplay + " to " + weekEndDisplay,
+		"Active":               "payroll",
+		"Entries":              entries,
+		"Total":                total,
+		"NetTotal":             netTotal,
+		"WeekID":               weekID,
+		"WeekStart":            week.PeriodStart,
+		"WeekEnd":              week.PeriodEnd,
+		"WeekDisplay":          weekStartDisplay + " - " + weekEndDisplay,
+		"LastCheckNumber":      lastCheck,
+		"SuggestedCheckNumber": nextCheck,
+	})
+}
+
+func (h *Handler) PayrollNew(w http.ResponseWriter, r *http.Request) {
+	employees, _ := h.db.ListEmployees(true)
+	lastCheck, _ := h.db.GetLastCheckNumber()
+	nextCheck, _ := h.db.NextCheckNumber()
+	h.render(w, r, "payroll_form.html", map[string]interface{}{
+		"Title":                "New Payroll",
+		"Active":               "payroll",
+		"Payroll":              models.Payroll{},
+		"Employees":            employees,
+		"LastCheckNumber":      lastCheck,
+		"SuggestedCheckNumber": nextCheck,
+	})
+}
+
+func (h *Handler) PayrollCreate(w http.ResponseWriter, r *http.Request) {
+	employeeID, _ := strconv.ParseInt(r.FormValue("employee_id"), 10, 64)
+	fieldErrs := formErrors{}
+
+	payroll := models.Payroll{
+		EmployeeID:    employeeID,
+		PeriodStart:   fieldErrs.date(r, "period_start", "Period start", true),
+		PeriodEnd:     fieldErrs.date(r, "period_end", "Period end", true),
+		TotalHours:    fieldErrs.amount(r, "total_hours", "Total hours"),
+		HourlyRate:    fieldErrs.amount(r, "hourly_rate", "Hourly rate"),
+		Withholding:   fieldErrs.optionalAmount(r, "withholding", "Withholding"),
+		PaymentMethod: r.FormValue("payment_method"),
+		CheckNumber:   r.FormValue("check_number"),
+		Status:        r.FormValue("status"),
+		DatePaid:      fieldErrs.date(r, "date_paid", "Date paid", false),
+		Notes:         r.FormValue("notes"),
+	}
+	if fieldErrs["period_start"] == "" && fieldErrs["period_end"] == "" && payroll.PeriodEnd < payroll.PeriodStart {
+		fieldErrs["period_end"] = "Period end can't be before the start"
+	}
+
+	err := fieldErrs.err()
+	if err == nil {
+		err = validateWithholding(payroll)
+	}
+	var checkUses []models.CheckUse
+	if err == nil {
+		checkUses = h.checkNumberReused(r, payroll.PaymentMethod, payroll.CheckNumber, "payroll", payroll.ID)
+	}
+	if err == nil && len(checkUses) == 0 {
+		payroll.WeekID, err = h.db.GetOrCreatePayrollWeek(payroll.PeriodStart, payroll.PeriodEnd)
+	}
+	if err == nil && len(checkUses) == 0 {
+		_, err = h.db.CreatePayroll(payroll)
+	}
+	if err != nil || len(checkUses) > 0 {
+		var errMsg string
+		if err != nil {
+			errMsg = err.Error()
+		}
+		employees, _ := h.db.ListEmployees(true)
+		lastCheck, _ := h.db.GetLastCheckNumber()
+		nextCheck, _ := h.db.NextCheckNumber()
+		h.render(w, r, "payroll_form.html", map[string]interface{}{
+			"Title":                "New Payroll",
+			"Active":               "payroll",
+			"Payroll":              payroll,
+			"Employees":            employees,
+			"LastCheckNumber":      lastCheck,
+			"SuggestedCheckNumber": nextCheck,
+			"Error":                errMsg,
+			"FieldErrors":          fieldErrs,
+			"Input":                r.Form,
+			"CheckUses":            checkUses,
+		})
+		return
+	}
+	http.Redirect(w, r, "/payroll", http.StatusFound)
+}
+
+func (h *Handler) PayrollEdit(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	payroll, err := h.db.GetPayroll(id)
+	if err != nil {
+		http.Redirect(w, r, "/payroll", http.StatusFound)
+		return
+	}
+	employees, _ := h.db.ListEmployees(true)
+	lastCheck, _ := h.db.GetLastCheckNumber()
+	nextCheck, _ := h.db.NextCheckNumber()
+	h.render(w, r, "payroll_form.html", map[string]interface{}{
+		"Title":                "Edit Payroll",
+		"Active":               "payroll",
+		"Payroll":              payroll,
+		"Employees":            employees,
+		"LastCheckNumber":      lastCheck,
+		"SuggestedCheckNumber": nextCheck,
+	})
+}
+
+// PayrollStub renders a printable paystub for one payroll entry
+func (h *Handler) PayrollStub(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	payroll, err := h.db.GetPayroll(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	period := payroll.PeriodStart + " - " + payroll.PeriodEnd
+	start, errStart := time.Parse("2006-01-02", payroll.PeriodStart)
+	end, errEnd := time.Parse("2006-01-02", payroll.PeriodEnd)
+	if errStart == nil && errEnd == nil {
+		period = start.Format("Jan 2") + " - " + end.Format("Jan 2, 2006")
+	}
+	h.render(w, r, "payroll_stub.html", map[string]interface{}{
+		"Title":         "Paystub - " + payroll.EmployeeName,
+		"Payroll":       payroll,
+		"PeriodDisplay": period,
+	})
+}
+
+func (h *Handler) PayrollUpdate(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	employeeID, _ := strconv.ParseInt(r.FormValue("employee_id"), 10, 64)
+	fieldErrs := formErrors{}
+
+	payroll := models.Payroll{
+		ID:            id,
+		EmployeeID:    employeeID,
+		PeriodStart:   fieldErrs.date(r, "period_start", "Period start", true),
+		PeriodEnd:     fieldErrs.date(r, "period_end", "Period end", true),
+		TotalHours:    fieldErrs.amount(r, "total_hours", "Total hours"),
+		HourlyRate:    fieldErrs.amount(r, "hourly_rate", "Hourly rate"),
+		Withholding:   fieldErrs.optionalAmount(r, "withholding", "Withholding"),
+		PaymentMethod: r.FormValue("payment_method"),
+		CheckNumber:   r.FormValue("check_number"),
+		Status:        r.FormValue("status"),
+		DatePaid:      fieldErrs.date(r, "date_paid", "Date paid", false),
+		Notes:         r.FormValue("notes"),
+	}
+	if fieldErrs["period_start"] == "" && fieldErrs["period_end"] == "" && payroll.PeriodEnd < payroll.PeriodStart {
+		fieldErrs["period_end"] = "Period end can't be before the start"
+	}
+
+	err := fieldErrs.err()
+	if err == nil {
+		err = validateWithholding(payroll)
+	}
+	var checkUses []models.CheckUse
+	if err == nil {
+		checkUses = h.checkNumberReused(r, payroll.PaymentMethod, payroll.CheckNumber, "payroll", payroll.ID)
+	}
+	if err == nil && len(checkUses) == 0 {
+		payroll.WeekID, err = h.db.GetOrCreatePayrollWeek(payroll.PeriodStart, payroll.PeriodEnd)
+	}
+	if err == nil && len(checkUses) == 0 {
+		err = h.db.UpdatePayroll(payroll)
+	}
+	if err != nil || len(checkUses) > 0 {
+		var errMsg string
+		if err != nil {
+			errMsg = err.Error()
+		}
+		employees, _ := h.db.ListEmployees(true)
+		lastCheck, _ := h.db.GetLastCheckNumber()
+		nextCheck, _ := h.db.NextCheckNumber()
+		h.render(w, r, "payroll_form.html", map[string]interface{}{
+			"Title":                "Edit Payroll",
+			"Active":               "payroll",
+			"Payroll":              payroll,
+			"Employees":            employees,
+			"LastCheckNumber":      lastCheck,
+			"SuggestedCheckNumber": nextCheck,
+			"Error":                errMsg,
+			"FieldErrors":          fieldErrs,
+			"Input":                r.Form,
+			"CheckUses":            checkUses,
+		})
+		return
+	}
+	http.Redirect(w, r, "/payroll", http.StatusFound)
+}
+
+// PayrollReopen marks a paid entry unpaid so its hours, rate or withholding can be fixed
+func (h *Handler) PayrollReopen(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err := h.db.ReopenPayroll(id); err != nil {
+		logger.FromContext(r.Context()).Error("payroll_reopen_error", "payroll_id", id, "error", err.Error())
+	} else {
+		logger.FromContext(r.Context()).Info("payroll_reopened", "payroll_id", id)
+	}
+	http.Redirect(w, r, fmt.Sprintf("/payroll/entry/%d/edit", id), http.StatusFound)
+}
+
+// validateWithholding rejects withholding that is negative or more than the gross pay
+func validateWithholding(p models.Payroll) error {
+	if p.Withholding < 0 {
+		return fmt.Errorf("withholding cannot be negative")
+	}
+	if p.Withholding > p.TotalPay() {
+		return fmt.Errorf("withholding cannot be more than gross pay ($%.2f)", p.TotalPay())
+	}
+	return nil
+}
+
+// PayrollPay marks one entry paid from the pay dialog on the week page. A check number
+// already written elsewhere sends the user back with the other uses listed until they
+// tick "Use this check number anyway"
+func (h *Handler) PayrollPay(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	paymentMethod := r.FormValue("payment_method")
+	checkNumber := strings.TrimSpace(r.FormValue("check_number"))
+
+	entry, err := h.db.GetPayroll(id)
+	if err != nil {
+		http.Redirect(w, r, "/payroll", http.StatusFound)
+		return
+	}
+	back := fmt.Sprintf("/payroll/weeks/%d/edit", entry.WeekID)
+	redirectErr := func(msg string) {
+		http.Redirect(w, r, back+"?"+url.Values{"error": {msg}}.Encode(), http.StatusFound)
+	}
+
+	if uses := h.checkNumberReused(r, paymentMethod, checkNumber, "payroll", id); len(uses) > 0 {
+		others := make([]string, len(uses))
+		for i, u := range uses {
+			others[i] = fmt.Sprintf("%s, $%.2f on %s", u.Payee, u.Amount, u.Date)
+		}
+		redirectErr(fmt.Sprintf("%s was not paid: check #%s has already been used (%s). Tick \"Use this check number anyway\" to pay with it",
+			entry.EmployeeName, checkNumber, strings.Join(others, "; ")))
+		return
+	}
+
+	if err := h.db.MarkPayrollPaidWithDetails(id, paymentMethod, checkNumber); err != nil {
+		l.Error("payroll_pay_error", "payroll_id", id, "error", err.Error())
+		redirectErr("Failed to mark " + entry.EmployeeName + " paid")
+		return
+	}
+	l.Info("payroll_paid", "payroll_id", id, "payment_method", paymentMethod)
+	http.Redirect(w, r, back, http.StatusFound)
+}
+
+// PayrollWeekPayAll pays every unpaid entry in the week by check, numbering the checks
+// from the posted first_check_number or, when that is blank, the next suggested one
+func (h *Handler) PayrollWeekPayAll(w http.ResponseWriter, r *http.Request) {
+	weekID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid week ID", http.StatusBadRequest)
+		return
+	}
+	back := fmt.Sprintf("/payroll/weeks/%d/edit", weekID)
+
+	first := strings.TrimSpace(r.FormValue("first_check_number"))
+	if first == "" {
+		first, _ = h.db.NextCheckNumber()
+	}
+	if first == "" {
+		http.Redirect(w, r, back+"?"+url.Values{"error": {"Enter the first check number"}}.Encode(), http.StatusFound)
+		return
+	}
+
+	paid, last, err := h.db.PayPayrollWeek(weekID, first)
+	if errors.Is(err, database.ErrCheckSequence) || errors.Is(err, database.ErrCheckNumberUsed) {
+		http.Redirect(w, r, back+"?"+url.Values{"error": {"Nothing was paid: " + err.Error()}}.Encode(), http.StatusFound)
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("payroll_pay_all_error", "week_id", weekID, "error", err.Error())
+		http.Redirect(w, r, back+"?"+url.Values{"error": {"Failed to pay the week"}}.Encode(), http.StatusFound)
+		return
+	}
+	if paid == 0 {
+		http.Redirect(w, r, back+"?"+url.Values{"error": {"Nothing left to pay this week"}}.Encode(), http.StatusFound)
+		return
+	}
+
+	logger.FromContext(r.Context()).Info("payroll_week_paid", "week_id", weekID, "paid", paid, "first_check", first, "last_check", last)
+	notice := url.Values{"paid": {strconv.Itoa(paid)}, "first_check": {first}, "last_check": {last}}
+	http.Redirect(w, r, back+"?"+notice.Encode(), http.StatusFound)
+}
+
+func (h *Handler) PayrollSaveHours(w http.ResponseWriter, r *http.Request) {
+	weekStart := r.FormValue("week_start")
+	weekEnd := r.FormValue("week_end")
+
+	// Save every posted hours_<id>, not just active employees', so someone deactivated
+	// while the page was open still gets their final week. Nothing is saved while any
+	// hours are unreadable
+	l := logger.FromContext(r.Context())
+	type employeeHours struct {
+		employee models.Employee
+		hours    float64
+	}
+	var worked []employeeHours
+	fieldErrs := formErrors{}
+	for field := range r.PostForm {
+		idStr, ok := strings.CutPrefix(field, "hours_")
+		if !ok {
+			continue
+		}
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		emp, err := h.db.GetEmployee(id)
+		if err != nil {
+			l.Warn("payroll_save_hours_employee_error", "employee_id", id, "error", err.Error())
+			continue
+		}
+		if hours := fieldErrs.optionalAmount(r, field, "Hours for "+emp.Name); hours > 0 {
+			worked = append(worked, employeeHours{emp, hours})
+		}
+	}
+	if len(fieldErrs) > 0 {
+		problems := make([]string, 0, len(fieldErrs))
+		for _, msg := range fieldErrs {
+			problems = append(problems, msg)
+		}
+		slices.Sort(problems)
+		q := url.Values{"week_start": {weekStart}, "error": {"Nothing was saved: " + strings.Join(problems, "; ")}}
+		http.Redirect(w, r, "/payroll/weeks/new?"+q.Encode(), http.StatusFound)
+		return
+	}
+
+	for _, e := range worked {
+		if err := h.db.UpsertWeeklyPayroll(e.employee.ID, weekStart, weekEnd, e.hours, e.employee.HourlyRate, e.employee.PaymentMethod); err != nil {
+			l.Error("payroll_save_hours_error", "employee_id", e.employee.ID, "error", err.Error())
+		}
+	}
+
+	http.Redirect(w, r, "/payroll", http.StatusFound)
+}
+
+// PayrollWeekCopy fills in hours for employees with no entry yet this week from the most
+// recent earlier week. Saved entries, paid or not, are left as they are
+func (h *Handler) PayrollWeekCopy(w http.ResponseWriter, r *http.Request) {
+	date, err := parseFormDate(r.FormValue("week_start"))
+	if err != nil {
+		http.Error(w, "Invalid week start", http.StatusBadRequest)
+		return
+	}
+	day, _ := time.Parse("2006-01-02", date)
+	weekStart, weekEnd := getWeekBounds(day)
+	back := "/payroll/weeks/new?week_start=" + weekStart
+
+	previous, err := h.db.GetPreviousWeekHours(weekStart)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("payroll_copy_hours_error", "week_start", weekStart, "error", err.Error())
+		http.Redirect(w, r, back+"&"+url.Values{"error": {"Failed to load the previous week's hours"}}.Encode(), http.StatusFound)
+		return
+	}
+	entries, _, err := h.db.GetWeeklyPayroll(weekStart, weekEnd)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("payroll_copy_hours_error", "week_start", weekStart, "error", err.Error())
+		http.Redirect(w, r, back+"&"+url.Values{"error": {"Failed to load this week's payroll"}}.Encode(), http.StatusFound)
+		return
+	}
+
+	var copied int
+	for _, e := range entries {
+		hours := previous[e.Employee.ID]
+		if e.Payroll != nil || hours <= 0 {
+			continue
+		}
+		if err := h.db.UpsertWeeklyPayroll(e.Employee.ID, weekStart, weekEnd, hours, e.Employee.HourlyRate, e.Employee.PaymentMethod); err != nil {
+			logger.FromContext(r.Context()).Error("payroll_copy_hours_error", "week_start", weekStart, "employee_id", e.Employee.ID, "error", err.Error())
+			continue
+		}
+		copied++
+	}
+	if copied == 0 {
+		http.Redirect(w, r, back+"&"+url.Values{"error": {"Nothing to copy: the previous week has no hours for employees without an entry this week"}}.Encode(), http.StatusFound)
+		return
+	}
+
+	logger.FromContext(r.Context()).Info("payroll_hours_copied", "week_start", weekStart, "employees", copied)
+	http.Redirect(w, r, back+"&copied="+strconv.Itoa(copied), http.StatusFound)
+}
+
+func (h *Handler) PayrollDelete(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	week := r.FormValue("week")
+	h.db.DeletePayroll(id)
+	if week != "" {
+		http.Redirect(w, r, "/payroll?week="+week, http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, "/payroll", http.StatusFound)
+}
+
+// Reconciliations handlers
+
+func (h *Handler) ReconciliationsList(w http.ResponseWriter, r *http.Request) {
+	accounts, err := h.db.ListAccounts()
+	if err != nil {
+		logger.FromContext(r.Context()).Error("accounts_list_error", "error", err.Error())
+	}
+	accountID, _ := strconv.ParseInt(r.URL.Query().Get("account"), 10, 64)
+
+	reconciliations, err := h.db.ListReconciliations(accountID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("reconciliations_list_error", "error", err.Error())
+	}
+
+	ids := make([]int64, len(reconciliations))
+	for i, recon := range reconciliations {
+		ids[i] = recon.ID
+	}
+	progress, err := h.db.GetReconciliationProgress(ids)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("reconciliation_progress_error", "error", err.Error())
+	}
+
+	// Uploads go to the account being viewed, or the first one when viewing all
+	uploadAccountID := accountID
+	if uploadAccountID == 0 && len(accounts) > 0 {
+		uploadAccountID = accounts[0].ID
+	}
+
+	// Get months that already have reconciliations for that account
+	reconciledMonths, err := h.db.GetReconciledMonths(uploadAccountID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("reconciled_months_error", "error", err.Error())
+		reconciledMonths = make(map[string]bool)
+	}
+
+	// Generate available months (last 12 months, excluding already reconciled)
+	availableMonths := []models.MonthOption{}
+	now := time.Now()
+	// Start from previous month
+	current := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.Local)
+
+	for i := 0; i < 12; i++ {
+		monthValue := current.Format("2006-01")
+		if !reconciledMonths[monthValue] {
+			availableMonths = append(availableMonths, models.MonthOption{
+				Value:    monthValue,
+				Label:    current.Format("January 2006"),
+				Selected: len(availableMonths) == 0, // Select first available
+			})
+		}
+		current = current.AddDate(0, -1, 0)
+	}
+
+	h.render(w, r, "reconciliations_list.html", map[string]any{
+		"Title":           "Bank Statements",
+		"Active":          "expenses",
+		"Reconciliations": reconciliations,
+		"Progress":        progress,
+		"AvailableMonths": availableMonths,
+		"Accounts":        accounts,
+		"AccountID":       accountID,
+		"UploadAccountID": uploadAccountID,
+	})
+}
+
+// AccountsCreate adds a bank account statements can be uploaded for
+func (h *Handler) AccountsCreate(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	account := models.Account{
+		Name:     strings.TrimSpace(r.FormValue("name")),
+		LastFour: strings.TrimSpace(r.FormValue("last_four")),
+	}
+	if account.Name == "" {
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+
+	id, err := h.db.CreateAccount(account)
+	if err != nil {
+		l.Error("account_create_error", "error", err.Error())
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+	l.Info("account_created", "account_id", id, "name", account.Name)
+
+	http.Redirect(w, r, fmt.Sprintf("/bank-statements?account=%d", id), http.StatusFound)
+}
+
+func (h *Handler) ReconciliationsUpload(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	// Parse multipart form (max 10MB)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		l.Error("reconciliation_upload_parse_error", "error", err.Error())
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	// Get selected month (YYYY-MM format)
+	statementMonth := r.FormValue("statement_month")
+	if statementMonth == "" {
+		http.Error(w, "Statement month is required", http.StatusBadRequest)
+		return
+	}
+
+	// Parse month to get last day of month for statement date
+	monthTime, err := time.Parse("2006-01", statementMonth)
+	if err != nil {
+		l.Error("reconciliation_upload_month_parse", "error", err.Error())
+		http.Error(w, "Invalid month format", http.StatusBadRequest)
+		return
+	}
+	// Get last day of the month
+	statementDate := monthTime.AddDate(0, 1, -1).Format("2006-01-02")
+
+	accountID, err := h.uploadAccount(r.FormValue("account_id"))
+	if err != nil {
+		l.Error("reconciliation_upload_account_error", "error", err.Error())
+		http.Error(w, "Invalid account", http.StatusBadRequest)
+		return
+	}
+
+	// Get uploaded file
+	file, header, err := r.FormFile("statement_file")
+	if err != nil {
+		l.Error("reconciliation_upload_file_error", "error", err.Error())
+		http.Error(w, "Failed to get uploaded file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	if _, err := parser.ForFile(header.Filename, parser.Options{}); err != nil {
+		l.Error("reconciliation_upload_type_error", "filename", header.Filename)
+		http.Error(w, "Statement must be a PDF, CSV or OFX file", http.StatusBadRequest)
+		return
+	}
+
+	l.Info("reconciliation_upload", "month", statementMonth, "filename", header.Filename, "size", header.Size)
+
+	// Save file to filestore
+	filePath, err := h.files.SaveValidated(header.Filename, file, filestore.SaveOptions{
+		MaxSize:      h.maxUploadSize,
+		AllowedTypes: filestore.StatementTypes,
+	})
+	if errors.Is(err, filestore.ErrFileType) || errors.Is(err, filestore.ErrFileTooLarge) {
+		l.Warn("reconciliation_upload_rejected", "filename", header.Filename, "error", err.Error())
+		http.Error(w, "Statement "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		l.Error("reconciliation_file_save_error", "error", err.Error())
+		http.Error(w, "Failed to save uploaded file", http.StatusInternalServerError)
+		return
+	}
+
+	// Files are stored by content hash, so a re-upload lands on the same path
+	if existingID, err := h.db.GetReconciliationIDByFile(filePath); err != nil {
+		l.Error("reconciliation_duplicate_check_error", "error", err.Error())
+	} else if existingID > 0 {
+		l.Warn("reconciliation_upload_duplicate", "filename", header.Filename, "reconciliation_id", existingID)
+		http.Error(w, fmt.Sprintf("This exact file was already uploaded as statement #%d", existingID), http.StatusConflict)
+		return
+	}
+
+	// Create reconciliation record
+	recon := models.BankReconciliation{
+		StatementDate:   statementDate,
+		StartingBalance: 0,
+		EndingBalance:   0,
+		Status:          "pending",
+		FilePath:        filePath,
+		Notes:           fmt.Sprintf("Uploaded: %s", header.Filename),
+		AccountID:       accountID,
+	}
+
+	reconID, err := h.db.CreateReconciliation(recon)
+	if err != nil {
+		// Clean up saved file on error
+		h.deleteStoredFile(r, filePath)
+		l.Error("reconciliation_create_error", "error", err.Error())
+		http.Error(w, "Failed to create reconciliation", http.StatusInternalServerError)
+		return
+	}
+
+	// Queue parse job
+	jobPayload := map[string]any{
+		"reconciliation_id": reconID,
+		"file_path":         filePath,
+	}
+	jobID, err := h.db.CreateJob("parse_statement", jobPayload)
+	if err != nil {
+		l.Error("reconciliation_job_create_error", "error", err.Error())
+		http.Error(w, "Failed to queue parse job", http.StatusInternalServerError)
+		return
+	}
+
+	// Update reconciliation with job ID
+	if err := h.db.UpdateReconciliationParseJob(reconID, jobID); err != nil {
+		l.Error("reconciliation_update_job_error", "error", err.Error())
+	}
+
+	l.Info("reconciliation_job_queued", "reconciliation_id", reconID, "job_id", jobID)
+
+	// Return JSON response for frontend polling
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"reconciliation_id": reconID,
+		"job_id":            jobID,
+	})
+}
+
+// uploadAccount resolves the account a statement is uploaded for. With no accounts yet,
+// a default one is created so every statement belongs to an account
+func (h *Handler) uploadAccount(value string) (int64, error) {
+	if id, err := strconv.ParseInt(value, 10, 64); err == nil && id > 0 {
+		if _, err := h.db.GetAccount(id); err != nil {
+			return 0, err
+		}
+		return id, nil
+	}
+
+	accounts, err := h.db.ListAccounts()
+	if err != nil {
+		return 0, err
+	}
+	if len(accounts) > 0 {
+		return 0, fmt.Errorf("account is required")
+	}
+	return h.db.CreateAccount(models.Account{Name: "Checking"})
+}
+
+// ReconciliationsPreview dry-runs the parser on an uploaded statement and returns
+// the result as JSON without creating a reconciliation
+func (h *Handler) ReconciliationsPreview(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	// Nothing is stored, but the upload still gets the same size and type checks
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			l.Warn("reconciliation_preview_rejected", "error", err.Error())
+			http.Error(w, fmt.Sprintf("Statement %s (limit is %d MB)", filestore.ErrFileTooLarge, h.maxUploadSize>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+		l.Error("reconciliation_preview_parse_error", "error", err.Error())
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("statement_file")
+	if err != nil {
+		l.Error("reconciliation_preview_file_error", "error", err.Error())
+		http.Error(w, "Failed to get uploaded file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	p, err := parser.ForFile(header.Filename, parser.Options{FallbackMonth: r.FormValue("statement_month")})
+	if err != nil {
+		http.Error(w, "Statement must be a PDF, CSV or OFX file", http.StatusBadRequest)
+		return
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		l.Error("reconciliation_preview_read_error", "error", err.Error())
+		http.Error(w, "Failed to read uploaded file", http.StatusBadRequest)
+		return
+	}
+	head = head[:n]
+	if contentType := filestore.DetectContentType(head); !slices.Contains(filestore.StatementTypes, contentType) {
+		l.Warn("reconciliation_preview_rejected", "filename", header.Filename, "content_type", contentType)
+		http.Error(w, fmt.Sprintf("Statement %s (%s)", filestore.ErrFileType, contentType), http.StatusBadRequest)
+		return
+	}
+
+	// pdftotext needs a real file, so write the upload to a temp file
+	tmp, err := os.CreateTemp("", "statement-preview-*"+strings.ToLower(filepath.Ext(header.Filename)))
+	if err != nil {
+		l.Error("reconciliation_preview_temp_error", "error", err.Error())
+		http.Error(w, "Failed to save uploaded file", http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, io.MultiReader(bytes.NewReader(head), file)); err != nil {
+		tmp.Close()
+		l.Error("reconciliation_preview_write_error", "error", err.Error())
+		http.Error(w, "Failed to save uploaded file", http.StatusInternalServerError)
+		return
+	}
+	tmp.Close()
+
+	stmt, err := p.Parse(tmp.Name())
+	if err != nil {
+		l.Error("reconciliation_preview_error", "filename", header.Filename, "error", err.Error())
+		http.Error(w, "Failed to parse statement: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	var totalCredits, totalDebits float64
+	transactions := make([]map[string]any, 0, len(stmt.Transactions))
+	for _, txn := range stmt.Transactions {
+		if txn.Amount > 0 {
+			totalCredits += txn.Amount
+		} else {
+			totalDebits += txn.Amount
+		}
+		transactions = append(transactions, map[string]any{
+			"posting_date":     txn.PostingDate,
+			"description":      txn.Description,
+			"amount":           txn.Amount,
+			"transaction_type": txn.TransactionType,
+			"category":         txn.Category,
+			"check_number":     txn.CheckNumber,
+			"vendor_hint":      txn.VendorHint,
+		})
+	}
+	calculatedEnding := stmt.BeginningBalance + totalCredits + totalDebits
+	difference := math.Round((calculatedEnding-stmt.EndingBalance)*100) / 100
+	if !stmt.HasBalances() {
+		// CSV exports have no balances to diff against
+		difference = 0
+	}
+
+	l.Info("reconciliation_previewed", "filename", header.Filename, "transactions", len(stmt.Transactions))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"account_last_four":   stmt.AccountLastFour,
+		"statement_month":     stmt.StatementMonth,
+		"beginning_balance":   stmt.BeginningBalance,
+		"ending_balance":      stmt.EndingBalance,
+		"electronic_deposits": stmt.ElectronicDeposits,
+		"electronic_payments": stmt.ElectronicPayments,
+		"checks_paid":         stmt.ChecksPaid,
+		"service_fees":        stmt.ServiceFees,
+		"balance_check": map[string]any{
+			"total_credits":     totalCredits,
+			"total_debits":      totalDebits,
+			"calculated_ending": calculatedEnding,
+			"difference":        difference,
+			"balanced":          difference == 0,
+			"has_balances":      stmt.HasBalances(),
+		},
+		"transactions": transactions,
+	})
+}
+
+// ReconciliationsCompleteConfirm shows unmatched and ignored debits grouped by vendor hint
+// before a statement is marked completed
+func (h *Handler) ReconciliationsCompleteConfirm(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+
+	recon, err := h.db.GetReconciliation(id)
+	if err != nil {
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+
+	unmatched, err := h.db.GetDebitsByVendorHint(id, "unmatched")
+	if err != nil {
+		l.Error("reconciliation_complete_summary_error", "id", id, "error", err.Error())
+	}
+	ignored, err := h.db.GetDebitsByVendorHint(id, "ignored")
+	if err != nil {
+		l.Error("reconciliation_complete_summary_error", "id", id, "error", err.Error())
+	}
+
+	balance, err := h.reconciliationBalance(id)
+	if err != nil {
+		l.Error("reconciliation_balance_error", "id", id, "error", err.Error())
+	}
+
+	var unreviewed string
+	if stats, err := h.db.GetReconciliationStats(id); err != nil {
+		l.Error("reconciliation_stats_error", "id", id, "error", err.Error())
+	} else if stats.UnmatchedCount > 0 {
+		unreviewed = unreviewedMessage(stats.UnmatchedCount)
+	}
+
+	var unmatchedTotal, ignoredTotal float64
+	for _, t := range unmatched {
+		unmatchedTotal += t.Total
+	}
+	for _, t := range ignored {
+		ignoredTotal += t.Total
+	}
+
+	h.render(w, r, "reconciliation_complete.html", map[string]interface{}{
+		"Title":          "Complete Bank Statement",
+		"Active":         "expenses",
+		"Reconciliation": recon,
+		"Unmatched":      unmatched,
+		"UnmatchedTotal": unmatchedTotal,
+		"Ignored":        ignored,
+		"IgnoredTotal":   ignoredTotal,
+		"Balance":        balance,
+		"Unreviewed":     unreviewed,
+	})
+}
+
+// reconciliationBalance computes a reconciliation's balance check from its transactions
+func (h *Handler) reconciliationBalance(id int64) (models.BalanceCheck, error) {
+	recon, err := h.db.GetReconciliation(id)
+	if err != nil {
+		return models.BalanceCheck{}, err
+	}
+	stats, err := h.db.GetReconciliationStats(id)
+	if err != nil {
+		return models.BalanceCheck{}, err
+	}
+	return recon.BalanceCheck(stats.TotalCredits, stats.TotalDebits), nil
+}
+
+// ReconciliationsComplete marks a reconciliation as completed
+func (h *Handler) ReconciliationsComplete(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+
+	back := fmt.Sprintf("/bank-statements/%d", id)
+	redirectErr := func(msg string) {
+		http.Redirect(w, r, back+"?"+url.Values{"error": {msg}}.Encode(), http.StatusFound)
+	}
+
+	// Completing goes through the summary page so leftover debits get a last look
+	if r.FormValue("confirm") != "yes" {
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d/complete", id), http.StatusFound)
+		return
+	}
+
+	balance, err := h.reconciliationBalance(id)
+	if err != nil {
+		l.Error("reconciliation_balance_error", "id", id, "error", err.Error())
+		redirectErr("Failed to check the balance")
+		return
+	}
+	if !balance.Balanced() {
+		l.Warn("reconciliation_complete_unbalanced", "id", id, "remaining", balance.Remaining())
+		direction := "below"
+		if balance.Remaining() > 0 {
+			direction = "above"
+		}
+		redirectErr(fmt.Sprintf("Not completed: the calculated balance is $%.2f %s the statement after any accepted discrepancy. Resolve it or accept the discrepancy first",
+			math.Abs(balance.Remaining()), direction))
+		return
+	}
+
+	// Unreviewed transactions block completion unless the user chose to complete anyway
+	stats, err := h.db.GetReconciliationStats(id)
+	if err != nil {
+		l.Error("reconciliation_stats_error", "id", id, "error", err.Error())
+		redirectErr("Failed to count unreviewed transactions")
+		return
+	}
+	if stats.UnmatchedCount > 0 && r.FormValue("force") != "yes" {
+		l.Warn("reconciliation_complete_unreviewed", "id", id, "unmatched", stats.UnmatchedCount)
+		redirectErr(unreviewedMessage(stats.UnmatchedCount))
+		return
+	}
+
+	if err := h.db.UpdateReconciliationCompleted(id); err != nil {
+		l.Error("reconciliation_complete_error", "id", id, "error", err.Error())
+		redirectErr("Failed to complete the reconciliation")
+		return
+	}
+	l.Info("reconciliation_completed", "id", id)
+
+	http.Redirect(w, r, back, http.StatusFound)
+}
+
+// unreviewedMessage says how many transactions are still unmatched
+func unreviewedMessage(n int) string {
+	if n == 1 {
+		return "1 transaction still needs review."
+	}
+	return fmt.Sprintf("%d transactions still need review.", n)
+}
+
+// ReconciliationsReparse queues a new parse job for an existing reconciliation
+func (h *Handler) ReconciliationsReparse(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+
+	recon, err := h.db.GetReconciliation(id)
+	if err != nil {
+		l.Error("reconciliation_reparse_get_error", "id", id, "error", err.Error())
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+
+	// Reset status to pending
+	if err := h.db.UpdateReconciliationStatus(id, "pending"); err != nil {
+		l.Error("reconciliation_reparse_status_error", "id", id, "error", err.Error())
+	}
+
+	// Queue new parse job
+	jobPayload := map[string]any{
+		"reconciliation_id": id,
+		"file_path":         recon.FilePath,
+	}
+	jobID, err := h.db.CreateJob("parse_statement", jobPayload)
+	if err != nil {
+		l.Error("reconciliation_reparse_job_error", "id", id, "error", err.Error())
+		http.Error(w, "Failed to queue parse job", http.StatusInternalServerError)
+		return
+	}
+
+	// Update reconciliation with new job ID
+	if err := h.db.UpdateReconciliationParseJob(id, jobID); err != nil {
+		l.Error("reconciliation_reparse_update_error", "id", id, "error", err.Error())
+	}
+
+	l.Info("reconciliation_reparse_queued", "reconciliation_id", id, "job_id", jobID)
+
+	http.Redirect(w, r, "/bank-statements", http.StatusFound)
+}
+
+// ReconciliationsFile serves the uploaded statement inline, so it can be checked
+// alongside the parsed transactions
+func (h *Handler) ReconciliationsFile(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Statement not found", http.StatusNotFound)
+		return
+	}
+	recon, err := h.db.GetReconciliation(id)
+	if err != nil || recon.FilePath == "" {
+		http.Error(w, "Statement not found", http.StatusNotFound)
+		return
+	}
+
+	file, err := h.files.Get(recon.FilePath)
+	if err != nil {
+		logger.FromContext(r.Context()).Warn("statement_file_missing", "id", id, "path", recon.FilePath, "error", err.Error())
+		http.Error(w, "Statement file is missing from storage", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	ext := strings.ToLower(filepath.Ext(recon.FilePath))
+	w.Header().Set("Content-Type", storedFileContentType(recon.FilePath))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"statement-%s%s\"", recon.StatementDate, ext))
+	io.Copy(w, file)
+}
+
+// ReconciliationsRematch runs auto-matching again over the still-unmatched transactions,
+// e.g. after missing receipts were entered during review. Matched, ignored and created
+// transactions are left alone
+func (h *Handler) ReconciliationsRematch(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+
+	matched, err := reconciliation.AutoMatch(h.db, id, h.matchOpts)
+	if err != nil {
+		l.Error("reconciliation_rematch_error", "id", id, "error", err.Error())
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d?%s", id, url.Values{"error": {"Failed to re-run matching"}}.Encode()), http.StatusFound)
+		return
+	}
+
+	l.Info("reconciliation_rematched", "reconciliation_id", id, "matched", matched)
+	http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d?rematched=%d", id, matched), http.StatusFound)
+}
+
+// ReconciliationsDelete deletes a bank statement and all its transactions
+func (h *Handler) ReconciliationsDelete(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+
+	// Get the reconciliation to find the file path
+	recon, err := h.db.GetReconciliation(id)
+	if err != nil {
+		l.Error("reconciliation_delete_get_error", "id", id, "error", err.Error())
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+
+	// Delete all bank transactions for this reconciliation
+	if err := h.db.DeleteBankTransactions(id); err != nil {
+		l.Error("reconciliation_delete_txns_error", "id", id, "error", err.Error())
+	}
+
+	// Delete the reconciliation record
+	if err := h.db.DeleteReconciliation(id); err != nil {
+		l.Error("reconciliation_delete_error", "id", id, "error", err.Error())
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+
+	// Delete the statement file once nothing else uses it
+	h.deleteStoredFile(r, recon.FilePath)
+
+	l.Info("reconciliation_deleted", "id", id)
+	http.Redirect(w, r, "/bank-statements", http.StatusFound)
+}
+
+// parseWarnings returns the warnings recorded by the statement's last parse job
+func (h *Handler) parseWarnings(recon models.BankReconciliation) []string {
+	if recon.ParseJobID == nil {
+		return nil
+	}
+	job, err := h.db.GetJob(*recon.ParseJobID)
+	if err != nil || job.Status != "completed" {
+		return nil
+	}
+	var result struct {
+		Warnings []string `json:"warnings"`
+	}
+	json.Unmarshal([]byte(job.Result), &result)
+	return result.Warnings
+}
+
+// reviewPageSize is how many transactions the statement review shows per page
+const reviewPageSize = 50
+
+// ReconciliationsReview shows the reconciliation review page
+func (h *Handler) ReconciliationsReview(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+
+	recon, err := h.db.GetReconciliation(id)
+	if err != nil {
+		l.Error("reconciliation_get_error", "id", id, "error", err.Error())
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+
+	filter := models.BankTransactionFilter{PerPage: reviewPageSize}
+	switch d := r.URL.Query().Get("direction"); d {
+	case "credits", "debits":
+		filter.Direction = d
+	}
+	switch s := r.URL.Query().Get("status"); s {
+	case "unmatched", "matched", "ignored", "created", "personal":
+		filter.MatchStatus = s
+	}
+	filter.Page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+
+	transactions, total, err := h.db.GetBankTransactionsPaged(id, filter)
+	if err != nil {
+		l.Error("reconciliation_transactions_error", "id", id, "error", err.Error())
+	}
+	page := models.BankTransactionPage{BankTransactionFilter: filter, Total: total}
+	// A page past the end (e.g. after the last unmatched row was matched) falls back to the last one
+	if len(transactions) == 0 && page.Page > page.Pages() {
+		page.Page = page.Pages()
+		filter.Page = page.Page
+		transactions, _, err = h.db.GetBankTransactionsPaged(id, filter)
+		if err != nil {
+			l.Error("reconciliation_transactions_error", "id", id, "error", err.Error())
+		}
+	}
+
+	stats, err := h.db.GetReconciliationStats(id)
+	if err != nil {
+		l.Error("reconciliation_stats_error", "id", id, "error", err.Error())
+	}
+	var balance models.BalanceCheck
+	if stats != nil {
+		balance = recon.BalanceCheck(stats.TotalCredits, stats.TotalDebits)
+	}
+
+	payouts, err := reconciliation.CheckDeliveryPayouts(h.db, id, h.deliveryPayoutDays)
+	if err != nil {
+		l.Error("reconciliation_delivery_payouts_error", "id", id, "error", err.Error())
+	}
+	unmatchedPayouts := 0
+	for _, p := range payouts {
+		if !p.Matched() {
+			unmatchedPayouts++
+		}
+	}
+
+	// Get expenses for matching (paid expenses from around the statement period)
+	vendors, _ := h.db.ListVendors()
+	expenses, _, _ := h.db.ListExpenses(models.ExpenseFilter{Status: "paid"})
+
+	// List the default vendor's receipts first so they're easy to pick when matching
+	var defaultVendorName string
+	if recon.DefaultVendorID > 0 {
+		for _, v := range vendors {
+			if v.ID == recon.DefaultVendorID {
+				defaultVendorName = v.Name
+				break
+			}
+		}
+		sort.SliceStable(expenses, func(i, j int) bool {
+			return expenses[i].VendorID == recon.DefaultVendorID && expenses[j].VendorID != recon.DefaultVendorID
+		})
+	}
+
+	lastAction, err := h.db.GetLastReconciliationAction(id)
+	if err != nil {
+		l.Error("reconciliation_last_action_error", "id", id, "error", err.Error())
+	}
+
+	var success string
+	if rematched := r.URL.Query().Get("rematched"); rematched != "" {
+		success = fmt.Sprintf("Re-ran matching: %s new match(es)", rematched)
+	}
+	if undone := r.URL.Query().Get("undone"); undone != "" {
+		success = "Undid " + undone
+	}
+	if ignored := r.URL.Query().Get("ignored"); ignored != "" {
+		success = fmt.Sprintf("Ignored %s transaction(s)", ignored)
+	}
+	if r.URL.Query().Get("added") != "" {
+		success = "Added transaction"
+	}
+	if r.URL.Query().Get("edited") != "" {
+		success = "Updated transaction"
+	}
+	switch r.URL.Query().Get("balances") {
+	case "matched":
+		success = "Balances updated: transactions now add up to the ending balance"
+	case "mismatch":
+		success = "Balances updated, but transactions still don't add up to the ending balance"
+	}
+
+	unmatchedTypes, err := h.db.GetUnmatchedTransactionTypes(id)
+	if err != nil {
+		l.Error("reconciliation_unmatched_types_error", "id", id, "error", err.Error())
+	}
+
+	h.render(w, r, "reconciliation_edit.html", map[string]any{
+		"Title":             "Review Reconciliation",
+		"Success":           success,
+		"LastAction":        lastAction,
+		"UnmatchedTypes":    unmatchedTypes,
+		"Error":             r.URL.Query().Get("error"),
+		"ParseWarnings":     h.parseWarnings(recon),
+		"Active":            "expenses",
+		"Reconciliation":    recon,
+		"Transactions":      transactions,
+		"TxnPage":           page,
+		"HasTransactions":   stats != nil && stats.TotalTransactions > 0,
+		"Stats":             stats,
+		"Expenses":          expenses,
+		"Vendors":           vendors,
+		"DefaultVendorName": defaultVendorName,
+		"DeliveryPayouts":   payouts,
+		"UnmatchedPayouts":  unmatchedPayouts,
+		"PayoutDays":        h.deliveryPayoutDays,
+		"Balance":           balance,
+		"CreditCategories":  models.BankCreditCategories,
+		"DebitCategories":   models.BankDebitCategories,
+		"TransactionTypes":  models.BankTransactionTypes,
+	})
+}
+
+// ReconciliationsCompare shows two statements' totals side by side. b defaults to the
+// previous statement for the same account
+func (h *Handler) ReconciliationsCompare(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	aID, err := strconv.ParseInt(r.URL.Query().Get("a"), 10, 64)
+	if err != nil {
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+
+	bID, err := strconv.ParseInt(r.URL.Query().Get("b"), 10, 64)
+	if err != nil {
+		bID, err = h.db.GetPreviousReconciliationID(aID)
+		if err != nil {
+			l.Info("reconciliation_compare_no_previous", "id", aID)
+			http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", aID), http.StatusFound)
+			return
+		}
+	}
+
+	reconA, err := h.db.GetReconciliation(aID)
+	if err != nil {
+		l.Error("reconciliation_compare_get_error", "id", aID, "error", err.Error())
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+	reconB, err := h.db.GetReconciliation(bID)
+	if err != nil {
+		l.Error("reconciliation_compare_get_error", "id", bID, "error", err.Error())
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+
+	statsA, err := h.db.GetReconciliationStats(aID)
+	if err != nil {
+		l.Error("reconciliation_stats_error", "id", aID, "error", err.Error())
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+	statsB, err := h.db.GetReconciliationStats(bID)
+	if err != nil {
+		l.Error("reconciliation_stats_error", "id", bID, "error", err.Error())
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+
+	rows := []models.StatComparison{
+		{Label: "Total Credits", A: statsA.TotalCredits, B: statsB.TotalCredits},
+		{Label: "Total Debits", A: statsA.TotalDebits, B: statsB.TotalDebits},
+		{Label: "Business Debits", A: statsA.BusinessDebits(), B: statsB.BusinessDebits()},
+		{Label: "Deposits", A: statsA.ElectronicDeposits, B: statsB.ElectronicDeposits},
+		{Label: "Electronic Payments", A: statsA.ElectronicPayments, B: statsB.ElectronicPayments},
+		{Label: "Checks Paid", A: statsA.ChecksPaid, B: statsB.ChecksPaid},
+		{Label: "Service Fees", A: statsA.ServiceFees, B: statsB.ServiceFees},
+	}
+
+	reconciliations, _ := h.db.ListReconciliations(0)
+
+	h.render(w, r, "reconciliations_compare.html", map[string]any{
+		"Title":           "Compare Statements",
+		"Active":          "expenses",
+		"A":               reconA,
+		"B":               reconB,
+		"StatsA":          statsA,
+		"StatsB":          statsB,
+		"Rows":            rows,
+		"Reconciliations": reconciliations,
+	})
+}
+
+// ReconciliationsDiscrepancy records an accepted balance discrepancy and its reason
+func (h *Handler) ReconciliationsDiscrepancy(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	reconID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+
+	back := fmt.Sprintf("/bank-statements/%d", reconID)
+	redirectErr := func(msg string) {
+		http.Redirect(w, r, back+"?"+url.Values{"error": {msg}}.Encode(), http.StatusFound)
+	}
+
+	// Empty amount clears the accepted discrepancy
+	var amount float64
+	if strings.TrimSpace(r.FormValue("amount")) != "" {
+		fieldErrs := formErrors{}
+		amount = fieldErrs.signedAmount(r, "amount", "Discrepancy")
+		if msg := fieldErrs["amount"]; msg != "" {
+			redirectErr(msg)
+			return
+		}
+	}
+	notes := strings.TrimSpace(r.FormValue("notes"))
+
+	if err := h.db.UpdateReconciliationDiscrepancy(reconID, amount, notes); err != nil {
+		l.Error("discrepancy_update_error", "id", reconID, "error", err.Error())
+		redirectErr("Failed to save the discrepancy")
+		return
+	}
+	l.Info("discrepancy_updated", "id", reconID, "amount", amount)
+
+	http.Redirect(w, r, back, http.StatusFound)
+}
+
+// ReconciliationsBalances corrects the statement's starting/ending balance and account
+// digits, e.g. when the parser misread them, and re-runs the balance check
+func (h *Handler) ReconciliationsBalances(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	reconID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+
+	redirectErr := func(msg string) {
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d?%s", reconID, url.Values{"error": {msg}}.Encode()), http.StatusFound)
+	}
+
+	recon, err := h.db.GetReconciliation(reconID)
+	if err != nil {
+		l.Error("reconciliation_get_error", "id", reconID, "error", err.Error())
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+	if recon.Status == "completed" {
+		redirectErr("Completed reconciliations can't be changed")
+		return
+	}
+
+	starting, err := strconv.ParseFloat(strings.TrimSpace(r.FormValue("starting_balance")), 64)
+	if err != nil {
+		redirectErr("Starting balance must be a number")
+		return
+	}
+	ending, err := strconv.ParseFloat(strings.TrimSpace(r.FormValue("ending_balance")), 64)
+	if err != nil {
+		redirectErr("Ending balance must be a number")
+		return
+	}
+	lastFour := strings.TrimSpace(r.FormValue("account_last_four"))
+	if lastFour != "" && (len(lastFour) != 4 || strings.Trim(lastFour, "0123456789") != "") {
+		redirectErr("Account last four must be 4 digits")
 		return
 	}
-	http.Redirect(w, r, "/payroll", http.StatusFound)
-}
-
-// Reconciliations handlers
 
-func (h *Handler) ReconciliationsList(w http.ResponseWriter, r *http.Request) {
-	reconciliations, err := h.db.ListReconciliations()
-	if err != nil {
-		logger.FromContext(r.Context()).Error("reconciliations_list_error", "error", err.Error())
+	recon.StartingBalance = starting
+	recon.EndingBalance = ending
+	recon.AccountLastFour = lastFour
+	if err := h.db.UpdateReconciliation(recon); err != nil {
+		l.Error("reconciliation_balances_update_error", "id", reconID, "error", err.Error())
+		redirectErr("Failed to save balances")
+		return
 	}
 
-	// Get months that already have reconciliations
-	reconciledMonths, err := h.db.GetReconciledMonths()
+	matches, err := h.db.RecheckReconciliationBalance(reconID)
 	if err != nil {
-		logger.FromContext(r.Context()).Error("reconciled_months_error", "error", err.Error())
-		reconciledMonths = make(map[string]bool)
+		l.Error("reconciliation_balance_check_error", "id", reconID, "error", err.Error())
+		redirectErr("Balances saved, but the balance check failed")
+		return
 	}
 
-	// Generate available months (last 12 months, excluding already reconciled)
-	availableMonths := []models.MonthOption{}
-	now := time.Now()
-	// Start from previous month
-	current := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.Local)
-
-	for i := 0; i < 12; i++ {
-		monthValue := current.Format("2006-01")
-		if !reconciledMonths[monthValue] {
-			availableMonths = append(availableMonths, models.MonthOption{
-				Value:    monthValue,
-				Label:    current.Format("January 2006"),
-				Selected: len(availableMonths) == 0, // Select first available
-			})
-		}
-		current = current.AddDate(0, -1, 0)
+	l.Info("reconciliation_balances_updated", "id", reconID, "starting_balance", starting, "ending_balance", ending, "balance_matches", matches)
+	result := "mismatch"
+	if matches {
+		result = "matched"
 	}
-
-	h.render(w, r, "reconciliations_list.html", map[string]any{
-		"Title":           "Bank Statements",
-		"Active":          "expenses",
-		"Reconciliations": reconciliations,
-		"AvailableMonths": availableMonths,
-	})
+	http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d?balances=%s", reconID, result), http.StatusFound)
 }
 
-func (h *Handler) ReconciliationsUpload(w http.ResponseWriter, r *http.Request) {
+// ReconciliationsAddTransaction adds a transaction by hand for one the parser missed, so
+// the statement can still be balanced. It is matchable like any parsed transaction
+func (h *Handler) ReconciliationsAddTransaction(w http.ResponseWriter, r *http.Request) {
 	l := logger.FromContext(r.Context())
 
-	// Parse multipart form (max 10MB)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		l.Error("reconciliation_upload_parse_error", "error", err.Error())
-		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+	reconID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
 		return
 	}
 
-	// Get selected month (YYYY-MM format)
-	statementMonth := r.FormValue("statement_month")
-	if statementMonth == "" {
-		http.Error(w, "Statement month is required", http.StatusBadRequest)
-		return
+	redirectErr := func(msg string) {
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d?%s", reconID, url.Values{"error": {msg}}.Encode()), http.StatusFound)
 	}
 
-	// Parse month to get last day of month for statement date
-	monthTime, err := time.Parse("2006-01", statementMonth)
+	recon, err := h.db.GetReconciliation(reconID)
 	if err != nil {
-		l.Error("reconciliation_upload_month_parse", "error", err.Error())
-		http.Error(w, "Invalid month format", http.StatusBadRequest)
+		l.Error("reconciliation_get_error", "id", reconID, "error", err.Error())
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+	if recon.Status == "completed" {
+		redirectErr("Completed reconciliations can't be changed")
 		return
 	}
-	// Get last day of the month
-	statementDate := monthTime.AddDate(0, 1, -1).Format("2006-01-02")
 
-	// Get uploaded file
-	file, header, err := r.FormFile("statement_file")
+	txn := models.BankTransaction{
+		ReconciliationID: reconID,
+		PostingDate:      r.FormValue("posting_date"),
+		Description:      strings.TrimSpace(r.FormValue("description")),
+		TransactionType:  r.FormValue("transaction_type"),
+		CheckNumber:      strings.TrimSpace(r.FormValue("check_number")),
+	}
+	if _, err := time.Parse("2006-01-02", txn.PostingDate); err != nil {
+		redirectErr("Transaction date is required")
+		return
+	}
+	if txn.Description == "" {
+		redirectErr("Transaction description is required")
+		return
+	}
+	if !slices.Contains(models.BankTransactionTypes, txn.TransactionType) {
+		redirectErr("Unknown transaction type")
+		return
+	}
+	txn.Amount, err = strconv.ParseFloat(strings.TrimSpace(r.FormValue("amount")), 64)
+	if err != nil || txn.Amount == 0 {
+		redirectErr("Amount must be a non-zero number")
+		return
+	}
+
+	txnID, err := h.db.CreateBankTransaction(&txn)
 	if err != nil {
-		l.Error("reconciliation_upload_file_error", "error", err.Error())
-		http.Error(w, "Failed to get uploaded file", http.StatusBadRequest)
+		l.Error("transaction_add_error", "id", reconID, "error", err.Error())
+		redirectErr("Failed to add transaction")
 		return
 	}
-	defer file.Close()
+	if _, err := h.db.RecheckReconciliationBalance(reconID); err != nil {
+		l.Error("reconciliation_balance_check_error", "id", reconID, "error", err.Error())
+	}
 
-	l.Info("reconciliation_upload", "month", statementMonth, "filename", header.Filename, "size", header.Size)
+	l.Info("transaction_added", "id", reconID, "txn_id", txnID, "amount", txn.Amount, "type", txn.TransactionType)
+	http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d?added=1", reconID), http.StatusFound)
+}
 
-	// Save file to filestore
-	filePath, err := h.files.Save(header.Filename, file)
+// ReconciliationsEditTransaction corrects a transaction's date, description and amount
+// where the parser misread them, then re-runs the balance check
+func (h *Handler) ReconciliationsEditTransaction(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	reconID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
-		l.Error("reconciliation_file_save_error", "error", err.Error())
-		http.Error(w, "Failed to save uploaded file", http.StatusInternalServerError)
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
 		return
 	}
 
-	// Create reconciliation record
-	recon := models.BankReconciliation{
-		StatementDate:   statementDate,
-		StartingBalance: 0,
-		EndingBalance:   0,
-		Status:          "pending",
-		FilePath:        filePath,
-		Notes:           fmt.Sprintf("Uploaded: %s", header.Filename),
+	redirectErr := func(msg string) {
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d?%s", reconID, url.Values{"error": {msg}}.Encode()), http.StatusFound)
 	}
 
-	reconID, err := h.db.CreateReconciliation(recon)
+	txnID, err := strconv.ParseInt(r.PathValue("txnId"), 10, 64)
 	if err != nil {
-		// Clean up saved file on error
-		h.files.Delete(filePath)
-		l.Error("reconciliation_create_error", "error", err.Error())
-		http.Error(w, "Failed to create reconciliation", http.StatusInternalServerError)
+		redirectErr("Transaction not found")
 		return
 	}
-
-	// Queue parse job
-	jobPayload := map[string]any{
-		"reconciliation_id": reconID,
-		"file_path":         filePath,
+	txn, err := h.db.GetBankTransaction(txnID)
+	if err != nil || txn.ReconciliationID != reconID {
+		redirectErr("Transaction not found")
+		return
 	}
-	jobID, err := h.db.CreateJob("parse_statement", jobPayload)
+
+	recon, err := h.db.GetReconciliation(reconID)
 	if err != nil {
-		l.Error("reconciliation_job_create_error", "error", err.Error())
-		http.Error(w, "Failed to queue parse job", http.StatusInternalServerError)
+		l.Error("reconciliation_get_error", "id", reconID, "error", err.Error())
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+	if recon.Status == "completed" {
+		redirectErr("Completed reconciliations can't be changed")
 		return
 	}
 
-	// Update reconciliation with job ID
-	if err := h.db.UpdateReconciliationParseJob(reconID, jobID); err != nil {
-		l.Error("reconciliation_update_job_error", "error", err.Error())
+	postingDate := r.FormValue("posting_date")
+	if _, err := time.Parse("2006-01-02", postingDate); err != nil {
+		redirectErr("Transaction date is required")
+		return
+	}
+	description := strings.TrimSpace(r.FormValue("description"))
+	if description == "" {
+		redirectErr("Transaction description is required")
+		return
+	}
+	amount, err := strconv.ParseFloat(strings.TrimSpace(r.FormValue("amount")), 64)
+	if err != nil || amount == 0 {
+		redirectErr("Amount must be a non-zero number")
+		return
 	}
 
-	l.Info("reconciliation_job_queued", "reconciliation_id", reconID, "job_id", jobID)
+	if err := h.db.UpdateBankTransactionFields(txnID, postingDate, description, amount); err != nil {
+		l.Error("transaction_edit_error", "txn_id", txnID, "error", err.Error())
+		redirectErr("Failed to update transaction")
+		return
+	}
+	if _, err := h.db.RecheckReconciliationBalance(reconID); err != nil {
+		l.Error("reconciliation_balance_check_error", "id", reconID, "error", err.Error())
+	}
 
-	// Return JSON response for frontend polling
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{
-		"reconciliation_id": reconID,
-		"job_id":            jobID,
-	})
+	l.Info("transaction_edited", "id", reconID, "txn_id", txnID, "old_amount", txn.Amount, "amount", amount)
+	http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d?edited=1", reconID), http.StatusFound)
 }
 
-// ReconciliationsComplete marks a reconciliation as completed
-func (h *Handler) ReconciliationsComplete(w http.ResponseWriter, r *http.Request) {
+// ReconciliationsSetDefaultVendor sets or clears the vendor prefilled in the match and create forms
+func (h *Handler) ReconciliationsSetDefaultVendor(w http.ResponseWriter, r *http.Request) {
 	l := logger.FromContext(r.Context())
 
-	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	reconID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
 		http.Redirect(w, r, "/bank-statements", http.StatusFound)
 		return
 	}
 
-	if err := h.db.UpdateReconciliationStatus(id, "completed"); err != nil {
-		l.Error("reconciliation_complete_error", "id", id, "error", err.Error())
+	// Empty vendor_id clears the default
+	vendorID, _ := strconv.ParseInt(r.FormValue("vendor_id"), 10, 64)
+
+	if err := h.db.UpdateReconciliationDefaultVendor(reconID, vendorID); err != nil {
+		l.Error("default_vendor_update_error", "id", reconID, "vendor_id", vendorID, "error", err.Error())
 	} else {
-		l.Info("reconciliation_completed", "id", id)
+		l.Info("default_vendor_updated", "id", reconID, "vendor_id", vendorID)
 	}
 
-	http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", id), http.StatusFound)
+	http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
 }
 
-// ReconciliationsReparse queues a new parse job for an existing reconciliation
-func (h *Handler) ReconciliationsReparse(w http.ResponseWriter, r *http.Request) {
+// ReconciliationsMatch manually matches a bank transaction to an expense
+func (h *Handler) ReconciliationsMatch(w http.ResponseWriter, r *http.Request) {
 	l := logger.FromContext(r.Context())
 
-	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	reconID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
 		http.Redirect(w, r, "/bank-statements", http.StatusFound)
 		return
 	}
 
-	recon, err := h.db.GetReconciliation(id)
+	txnID, err := strconv.ParseInt(r.FormValue("transaction_id"), 10, 64)
 	if err != nil {
-		l.Error("reconciliation_reparse_get_error", "id", id, "error", err.Error())
-		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		l.Error("match_invalid_txn_id", "error", err.Error())
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
 		return
 	}
 
-	// Reset status to pending
-	if err := h.db.UpdateReconciliationStatus(id, "pending"); err != nil {
-		l.Error("reconciliation_reparse_status_error", "id", id, "error", err.Error())
+	expenseID, err := strconv.ParseInt(r.FormValue("expense_id"), 10, 64)
+	if err != nil {
+		l.Error("match_invalid_expense_id", "error", err.Error())
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
+		return
 	}
 
-	// Queue new parse job
-	jobPayload := map[string]any{
-		"reconciliation_id": id,
-		"file_path":         recon.FilePath,
-	}
-	jobID, err := h.db.CreateJob("parse_statement", jobPayload)
+	before, err := h.db.GetBankTransaction(txnID)
 	if err != nil {
-		l.Error("reconciliation_reparse_job_error", "id", id, "error", err.Error())
-		http.Error(w, "Failed to queue parse job", http.StatusInternalServerError)
+		l.Error("match_get_txn_error", "txn_id", txnID, "error", err.Error())
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
 		return
 	}
 
-	// Update reconciliation with new job ID
-	if err := h.db.UpdateReconciliationParseJob(id, jobID); err != nil {
-		l.Error("reconciliation_reparse_update_error", "id", id, "error", err.Error())
+	if err := h.db.MatchBankTransaction(txnID, expenseID, "manual"); err != nil {
+		l.Error("match_error", "txn_id", txnID, "expense_id", expenseID, "error", err.Error())
+	} else {
+		l.Info("transaction_matched", "txn_id", txnID, "expense_id", expenseID)
+		h.recordReviewAction(r, "match", before, expenseID)
 	}
 
-	l.Info("reconciliation_reparse_queued", "reconciliation_id", id, "job_id", jobID)
-
-	http.Redirect(w, r, "/bank-statements", http.StatusFound)
+	http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
 }
 
-// ReconciliationsDelete deletes a bank statement and all its transactions
-func (h *Handler) ReconciliationsDelete(w http.ResponseWriter, r *http.Request) {
+// ReconciliationsUnmatch removes a match from a bank transaction
+func (h *Handler) ReconciliationsUnmatch(w http.ResponseWriter, r *http.Request) {
 	l := logger.FromContext(r.Context())
 
-	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	reconID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
 		http.Redirect(w, r, "/bank-statements", http.StatusFound)
 		return
 	}
 
-	// Get the reconciliation to find the file path
-	recon, err := h.db.GetReconciliation(id)
+	txnID, err := strconv.ParseInt(r.FormValue("transaction_id"), 10, 64)
 	if err != nil {
-		l.Error("reconciliation_delete_get_error", "id", id, "error", err.Error())
-		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		l.Error("unmatch_invalid_txn_id", "error", err.Error())
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
 		return
 	}
 
-	// Delete the PDF file
-	if recon.FilePath != "" {
-		h.files.Delete(recon.FilePath)
+	// Get the transaction to check if it was a "created" expense
+	txn, err := h.db.GetBankTransaction(txnID)
+	if err != nil {
+		l.Error("unmatch_get_txn_error", "txn_id", txnID, "error", err.Error())
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
+		return
 	}
 
-	// Delete all bank transactions for this reconciliation
-	if err := h.db.DeleteBankTransactions(id); err != nil {
-		l.Error("reconciliation_delete_txns_error", "id", id, "error", err.Error())
+	// If this was a created expense, delete it
+	if txn.MatchStatus == "created" && txn.MatchedExpenseID != nil {
+		if err := h.db.DeleteExpense(*txn.MatchedExpenseID); err != nil {
+			l.Error("unmatch_delete_expense_error", "expense_id", *txn.MatchedExpenseID, "error", err.Error())
+		} else {
+			l.Info("created_expense_deleted", "txn_id", txnID, "expense_id", *txn.MatchedExpenseID)
+		}
 	}
 
-	// Delete the reconciliation record
-	if err := h.db.DeleteReconciliation(id); err != nil {
-		l.Error("reconciliation_delete_error", "id", id, "error", err.Error())
-		http.Redirect(w, r, "/bank-statements", http.StatusFound)
-		return
+	if err := h.db.UnmatchBankTransaction(txnID); err != nil {
+		l.Error("unmatch_error", "txn_id", txnID, "error", err.Error())
+	} else {
+		l.Info("transaction_unmatched", "txn_id", txnID)
+		h.recordReviewAction(r, "unmatch", txn, 0)
 	}
 
-	l.Info("reconciliation_deleted", "id", id)
-	http.Redirect(w, r, "/bank-statements", http.StatusFound)
+	http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
 }
 
-// ReconciliationsReview shows the reconciliation review page
-func (h *Handler) ReconciliationsReview(w http.ResponseWriter, r *http.Request) {
+// ReconciliationsIgnore marks a bank transaction as ignored
+func (h *Handler) ReconciliationsIgnore(w http.ResponseWriter, r *http.Request) {
 	l := logger.FromContext(r.Context())
 
-	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	reconID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
 		http.Redirect(w, r, "/bank-statements", http.StatusFound)
 		return
 	}
 
-	recon, err := h.db.GetReconciliation(id)
+	txnID, err := strconv.ParseInt(r.FormValue("transaction_id"), 10, 64)
 	if err != nil {
-		l.Error("reconciliation_get_error", "id", id, "error", err.Error())
-		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		l.Error("ignore_invalid_txn_id", "error", err.Error())
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
 		return
 	}
 
-	transactions, err := h.db.GetBankTransactions(id)
-	if err != nil {
-		l.Error("reconciliation_transactions_error", "id", id, "error", err.Error())
+	reason := r.FormValue("reason")
+	if reason == "" {
+		reason = "Manually ignored"
 	}
 
-	stats, err := h.db.GetReconciliationStats(id)
+	before, err := h.db.GetBankTransaction(txnID)
 	if err != nil {
-		l.Error("reconciliation_stats_error", "id", id, "error", err.Error())
+		l.Error("ignore_get_txn_error", "txn_id", txnID, "error", err.Error())
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
+		return
 	}
 
-	// Get expenses for matching (paid expenses from around the statement period)
-	vendors, _ := h.db.ListVendors()
-	expenses, _, _ := h.db.ListExpenses(models.ExpenseFilter{Status: "paid"})
+	if err := h.db.IgnoreBankTransaction(txnID, reason); err != nil {
+		l.Error("ignore_error", "txn_id", txnID, "error", err.Error())
+	} else {
+		l.Info("transaction_ignored", "txn_id", txnID, "reason", reason)
+		h.recordReviewAction(r, "ignore", before, 0)
+	}
 
-	h.render(w, r, "reconciliation_edit.html", map[string]any{
-		"Title":          "Review Reconciliation",
-		"Active":         "expenses",
-		"Reconciliation": recon,
-		"Transactions":   transactions,
-		"Stats":          stats,
-		"Expenses":       expenses,
-		"Vendors":        vendors,
-	})
+	http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
 }
 
-// ReconciliationsMatch manually matches a bank transaction to an expense
-func (h *Handler) ReconciliationsMatch(w http.ResponseWriter, r *http.Request) {
+// ReconciliationsIgnoreBulk ignores every unmatched transaction of one type, such as
+// transfers between the owner's accounts
+func (h *Handler) ReconciliationsIgnoreBulk(w http.ResponseWriter, r *http.Request) {
 	l := logger.FromContext(r.Context())
 
 	reconID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
@@ -1351,31 +4175,29 @@ func (h *Handler) ReconciliationsMatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	txnID, err := strconv.ParseInt(r.FormValue("transaction_id"), 10, 64)
-	if err != nil {
-		l.Error("match_invalid_txn_id", "error", err.Error())
-		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
+	txnType := r.FormValue("transaction_type")
+	if txnType == "" {
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d?%s", reconID, url.Values{"error": {"Choose a transaction type to ignore"}}.Encode()), http.StatusFound)
 		return
 	}
+	reason := r.FormValue("reason")
+	if reason == "" {
+		reason = "Ignored all " + txnType
+	}
 
-	expenseID, err := strconv.ParseInt(r.FormValue("expense_id"), 10, 64)
+	ignored, err := h.db.IgnoreBankTransactionsByType(reconID, txnType, reason)
 	if err != nil {
-		l.Error("match_invalid_expense_id", "error", err.Error())
-		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
+		l.Error("ignore_bulk_error", "id", reconID, "type", txnType, "error", err.Error())
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d?%s", reconID, url.Values{"error": {"Failed to ignore transactions"}}.Encode()), http.StatusFound)
 		return
 	}
 
-	if err := h.db.MatchBankTransaction(txnID, expenseID, "manual"); err != nil {
-		l.Error("match_error", "txn_id", txnID, "expense_id", expenseID, "error", err.Error())
-	} else {
-		l.Info("transaction_matched", "txn_id", txnID, "expense_id", expenseID)
-	}
-
-	http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
+	l.Info("transactions_ignored_bulk", "id", reconID, "type", txnType, "count", ignored)
+	http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d?ignored=%d", reconID, ignored), http.StatusFound)
 }
 
-// ReconciliationsUnmatch removes a match from a bank transaction
-func (h *Handler) ReconciliationsUnmatch(w http.ResponseWriter, r *http.Request) {
+// ReconciliationsPersonal flags a transaction as a personal charge on a mixed-use account
+func (h *Handler) ReconciliationsPersonal(w http.ResponseWriter, r *http.Request) {
 	l := logger.FromContext(r.Context())
 
 	reconID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
@@ -1386,39 +4208,40 @@ func (h *Handler) ReconciliationsUnmatch(w http.ResponseWriter, r *http.Request)
 
 	txnID, err := strconv.ParseInt(r.FormValue("transaction_id"), 10, 64)
 	if err != nil {
-		l.Error("unmatch_invalid_txn_id", "error", err.Error())
+		l.Error("personal_invalid_txn_id", "error", err.Error())
 		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
 		return
 	}
 
-	// Get the transaction to check if it was a "created" expense
-	txn, err := h.db.GetBankTransaction(txnID)
+	note := r.FormValue("note")
+	if note == "" {
+		note = "Personal"
+	}
+
+	before, err := h.db.GetBankTransaction(txnID)
 	if err != nil {
-		l.Error("unmatch_get_txn_error", "txn_id", txnID, "error", err.Error())
+		l.Error("personal_get_txn_error", "txn_id", txnID, "error", err.Error())
 		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
 		return
 	}
 
-	// If this was a created expense, delete it
-	if txn.MatchStatus == "created" && txn.MatchedExpenseID != nil {
-		if err := h.db.DeleteExpense(*txn.MatchedExpenseID); err != nil {
-			l.Error("unmatch_delete_expense_error", "expense_id", *txn.MatchedExpenseID, "error", err.Error())
-		} else {
-			l.Info("created_expense_deleted", "txn_id", txnID, "expense_id", *txn.MatchedExpenseID)
-		}
-	}
-
-	if err := h.db.UnmatchBankTransaction(txnID); err != nil {
-		l.Error("unmatch_error", "txn_id", txnID, "error", err.Error())
+	if err := h.db.MarkBankTransactionPersonal(txnID, note); err != nil {
+		l.Error("personal_error", "txn_id", txnID, "error", err.Error())
 	} else {
-		l.Info("transaction_unmatched", "txn_id", txnID)
+		l.Info("transaction_marked_personal", "txn_id", txnID)
+		// Only unmatched transactions can be marked personal; anything else was left as is
+		if before.MatchStatus == "unmatched" {
+			h.recordReviewAction(r, "personal", before, 0)
+		}
 	}
 
 	http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
 }
 
-// ReconciliationsIgnore marks a bank transaction as ignored
-func (h *Handler) ReconciliationsIgnore(w http.ResponseWriter, r *http.Request) {
+// ReconciliationsUndo reverts the most recent review action on a statement. Undoing a
+// created expense deletes it, as unmatching would; undoing the unmatch of a created
+// expense brings it back from the trash
+func (h *Handler) ReconciliationsUndo(w http.ResponseWriter, r *http.Request) {
 	l := logger.FromContext(r.Context())
 
 	reconID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
@@ -1427,25 +4250,46 @@ func (h *Handler) ReconciliationsIgnore(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	txnID, err := strconv.ParseInt(r.FormValue("transaction_id"), 10, 64)
+	action, err := h.db.GetLastReconciliationAction(reconID)
 	if err != nil {
-		l.Error("ignore_invalid_txn_id", "error", err.Error())
-		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
+		l.Error("undo_get_action_error", "id", reconID, "error", err.Error())
+	}
+	if action == nil {
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d?%s", reconID, url.Values{"error": {"Nothing to undo"}}.Encode()), http.StatusFound)
 		return
 	}
 
-	reason := r.FormValue("reason")
-	if reason == "" {
-		reason = "Manually ignored"
+	switch {
+	case action.Action == "create" && action.ExpenseID > 0:
+		if err := h.db.DeleteExpense(action.ExpenseID); err != nil {
+			l.Error("undo_delete_expense_error", "expense_id", action.ExpenseID, "error", err.Error())
+			http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d?%s", reconID, url.Values{"error": {"Failed to undo"}}.Encode()), http.StatusFound)
+			return
+		}
+	case action.Action == "unmatch" && action.PriorStatus == "created" && action.PriorExpenseID != nil:
+		if err := h.db.RestoreExpense(*action.PriorExpenseID); err != nil {
+			l.Error("undo_restore_expense_error", "expense_id", *action.PriorExpenseID, "error", err.Error())
+			http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d?%s", reconID, url.Values{"error": {"Failed to undo"}}.Encode()), http.StatusFound)
+			return
+		}
 	}
 
-	if err := h.db.IgnoreBankTransaction(txnID, reason); err != nil {
-		l.Error("ignore_error", "txn_id", txnID, "error", err.Error())
-	} else {
-		l.Info("transaction_ignored", "txn_id", txnID, "reason", reason)
+	if err := h.db.UndoReconciliationAction(*action); err != nil {
+		l.Error("undo_error", "action_id", action.ID, "error", err.Error())
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d?%s", reconID, url.Values{"error": {"Failed to undo"}}.Encode()), http.StatusFound)
+		return
 	}
 
-	http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
+	l.Info("reconciliation_action_undone", "id", reconID, "action", action.Action, "txn_id", action.TransactionID)
+	http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d?%s", reconID, url.Values{"undone": {action.Label()}}.Encode()), http.StatusFound)
+}
+
+// recordReviewAction adds an action to the statement's undo history. before is the
+// transaction as it was before the action
+func (h *Handler) recordReviewAction(r *http.Request, action string, before *models.BankTransaction, expenseID int64) {
+	if err := h.db.RecordReconciliationAction(action, before, expenseID); err != nil {
+		logger.FromContext(r.Context()).Error("record_review_action_error", "txn_id", before.ID, "action", action, "error", err.Error())
+	}
 }
 
 // ReconciliationsCreateExpense creates a new expense from a bank transaction
@@ -1485,53 +4329,26 @@ func (h *Handler) ReconciliationsCreateExpense(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// Create the expense (amount is negative in bank txn, so we use absolute value)
-	amount := txn.Amount
-	if amount < 0 {
-		amount = -amount
-	}
-
-	// Map bank transaction type to expense payment type
-	paymentType := ""
-	switch txn.TransactionType {
-	case "check":
-		paymentType = "check"
-	case "debit", "ach", "electronic":
-		paymentType = "debit"
-	case "credit":
-		paymentType = "credit"
-	default:
-		paymentType = "debit" // default for unknown types
-	}
-
-	expense := models.Expense{
-		Date:        txn.PostingDate,
-		VendorID:    vendorID,
-		Amount:      amount,
-		Status:      "paid",
-		PaymentType: paymentType,
-		CheckNumber: txn.CheckNumber,
-		DatePaid:    txn.PostingDate,
-		Notes:       fmt.Sprintf("Created from bank statement: %s", txn.Description),
-	}
+	expense := reconciliation.ExpenseFromTransaction(txn, vendorID)
 
 	// Handle receipt file upload
 	file, header, fileErr := r.FormFile("receipt")
 	if fileErr == nil {
 		defer file.Close()
-		storedPath, saveErr := h.files.Save(header.Filename, file)
+		storedPath, saveErr := h.saveReceipt(r, header.Filename, file)
 		if saveErr != nil {
 			l.Error("create_expense_receipt_save_error", "error", saveErr.Error())
-		} else {
-			expense.ReceiptPath = storedPath
+			http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d?", reconID)+url.Values{"error": {saveErr.Error()}}.Encode(), http.StatusFound)
+			return
 		}
+		expense.ReceiptPath = storedPath
 	}
 
 	expenseID, err := h.db.CreateExpense(expense)
 	if err != nil {
 		// Clean up uploaded file on error
 		if expense.ReceiptPath != "" {
-			h.files.Delete(expense.ReceiptPath)
+			h.deleteStoredFile(r, expense.ReceiptPath)
 		}
 		l.Error("create_expense_error", "error", err.Error())
 		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
@@ -1543,6 +4360,7 @@ func (h *Handler) ReconciliationsCreateExpense(w http.ResponseWriter, r *http.Re
 		l.Error("mark_txn_created_error", "txn_id", txnID, "expense_id", expenseID, "error", err.Error())
 	} else {
 		l.Info("expense_created_from_txn", "txn_id", txnID, "expense_id", expenseID)
+		h.recordReviewAction(r, "create", txn, expenseID)
 	}
 
 	http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
@@ -1581,6 +4399,52 @@ func (h *Handler) ReconciliationsUpdateType(w http.ResponseWriter, r *http.Reque
 	http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
 }
 
+// ReconciliationsUpdateCategory corrects a bank transaction's category, leaving its match status alone
+func (h *Handler) ReconciliationsUpdateCategory(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	reconID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Redirect(w, r, "/bank-statements", http.StatusFound)
+		return
+	}
+
+	txnID, err := strconv.ParseInt(r.FormValue("transaction_id"), 10, 64)
+	if err != nil {
+		l.Error("update_category_invalid_txn_id", "error", err.Error())
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
+		return
+	}
+
+	category := r.FormValue("category")
+	if !models.IsBankCategory(category) {
+		l.Warn("update_category_invalid", "txn_id", txnID, "category", category)
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
+		return
+	}
+
+	txn, err := h.db.GetBankTransaction(txnID)
+	if err != nil {
+		l.Error("update_category_get_txn_error", "txn_id", txnID, "error", err.Error())
+		http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
+		return
+	}
+
+	// Keep the delivery platform in step so payout checks follow the new category
+	platform := ""
+	if category == "income_delivery" {
+		platform = parser.DeliveryPlatform(txn.Description)
+	}
+
+	if err := h.db.UpdateBankTransactionCategory(txnID, category, platform); err != nil {
+		l.Error("update_category_error", "txn_id", txnID, "error", err.Error())
+	} else {
+		l.Info("transaction_category_updated", "txn_id", txnID, "category", category)
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/bank-statements/%d", reconID), http.StatusFound)
+}
+
 // JobStatus returns the status of a background job as JSON (for polling)
 func (h *Handler) JobStatus(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
@@ -1598,13 +4462,93 @@ func (h *Handler) JobStatus(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
-		"id":       job.ID,
-		"status":   job.Status,
-		"progress": job.Progress,
-		"result":   job.Result,
+		"id":               job.ID,
+		"status":           job.Status,
+		"progress":         job.Progress,
+		"result":           job.Result,
+		"cancel_requested": job.CancelRequested,
+	})
+}
+
+// JobCancel asks the worker to stop a pending or running job. It is marked failed with
+// "cancelled" once the worker notices, within a couple of seconds
+func (h *Handler) JobCancel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.RequestJobCancel(id); err != nil {
+		if errors.Is(err, database.ErrJobFinished) {
+			http.Error(w, "Job has already finished", http.StatusConflict)
+			return
+		}
+		logger.FromContext(r.Context()).Error("job_cancel_error", "job_id", id, "error", err.Error())
+		http.Error(w, "Failed to cancel job", http.StatusInternalServerError)
+		return
+	}
+
+	logger.FromContext(r.Context()).Info("job_cancel_requested", "job_id", id)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":               id,
+		"cancel_requested": true,
+	})
+}
+
+// jobsListLimit is how many recent jobs the jobs page shows
+const jobsListLimit = 100
+
+// JobsList shows recent background jobs and their outcomes
+func (h *Handler) JobsList(w http.ResponseWriter, r *http.Request) {
+	jobs, err := h.db.ListJobs(jobsListLimit)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("jobs_list_error", "error", err.Error())
+	}
+
+	var success string
+	if id := r.URL.Query().Get("retried"); id != "" {
+		success = "Job #" + id + " queued to run again"
+	}
+	h.render(w, r, "jobs_list.html", map[string]any{
+		"Title":   "Background Jobs",
+		"Active":  "settings",
+		"Jobs":    jobs,
+		"Success": success,
+		"Error":   r.URL.Query().Get("error"),
 	})
 }
 
+// JobsRetry queues a failed job to run again with a fresh set of attempts
+func (h *Handler) JobsRetry(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Redirect(w, r, "/jobs", http.StatusFound)
+		return
+	}
+
+	job, err := h.db.GetJob(id)
+	if err != nil {
+		http.Redirect(w, r, "/jobs?"+url.Values{"error": {"Job not found"}}.Encode(), http.StatusFound)
+		return
+	}
+	if job.Status != "failed" {
+		http.Redirect(w, r, "/jobs?"+url.Values{"error": {"Only failed jobs can be retried"}}.Encode(), http.StatusFound)
+		return
+	}
+
+	if err := h.db.RetryJob(id, time.Now()); err != nil {
+		l.Error("job_retry_error", "job_id", id, "error", err.Error())
+		http.Redirect(w, r, "/jobs?"+url.Values{"error": {"Failed to retry job"}}.Encode(), http.StatusFound)
+		return
+	}
+
+	l.Info("job_retried", "job_id", id, "job_type", job.JobType)
+	http.Redirect(w, r, fmt.Sprintf("/jobs?retried=%d", id), http.StatusFound)
+}
+
 // APIVersion returns the current application version
 func (h *Handler) APIVersion(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -1614,3 +4558,327 @@ func (h *Handler) APIVersion(w http.ResponseWriter, r *http.Request) {
 		"commit":     version.GitCommit,
 	})
 }
+
+// ReportsPL shows the profit and loss report for a month (?month=YYYY-MM, default current)
+func (h *Handler) ReportsPL(w http.ResponseWriter, r *http.Request) {
+	month, err := time.Parse("2006-01", r.URL.Query().Get("month"))
+	if err != nil {
+		now := time.Now()
+		month = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	}
+	startDate := month.Format("2006-01-02")
+	endDate := month.AddDate(0, 1, -1).Format("2006-01-02")
+
+	income, err := h.db.GetIncomeBreakdown(startDate, endDate)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("income_breakdown_error", "month", month.Format("2006-01"), "error", err.Error())
+	}
+	var totalIncome float64
+	for _, line := range income {
+		totalIncome += line.Amount
+	}
+
+	pl, err := h.db.GetMonthlyPL(month.Format("2006-01"))
+	if err != nil {
+		logger.FromContext(r.Context()).Error("monthly_pl_error", "month", month.Format("2006-01"), "error", err.Error())
+	}
+
+	h.render(w, r, "reports_pl.html", map[string]interface{}{
+		"Title":       "Profit & Loss",
+		"Active":      "dashboard",
+		"Month":       month.Format("2006-01"),
+		"MonthLabel":  month.Format("January 2006"),
+		"Income":      income,
+		"TotalIncome": totalIncome,
+		"PL":          pl,
+	})
+}
+
+// ReportsCashFlow shows a month's cash in against paid expenses and payroll (?month=YYYY-MM,
+// default this month)
+func (h *Handler) ReportsCashFlow(w http.ResponseWriter, r *http.Request) {
+	month, err := time.Parse("2006-01", r.URL.Query().Get("month"))
+	if err != nil {
+		now := time.Now()
+		month = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	}
+
+	cashFlow, err := h.db.GetCashFlow(month.Format("2006-01"))
+	if err != nil {
+		logger.FromContext(r.Context()).Error("cash_flow_error", "month", month.Format("2006-01"), "error", err.Error())
+	}
+
+	h.render(w, r, "reports_cashflow.html", map[string]interface{}{
+		"Title":      "Cash Flow",
+		"Active":     "dashboard",
+		"Month":      month.Format("2006-01"),
+		"MonthLabel": month.Format("January 2006"),
+		"CashFlow":   cashFlow,
+	})
+}
+
+// defaultVarianceThreshold is how far off a shift's cash must be, in dollars, before
+// the variance report lists it
+const defaultVarianceThreshold = 20.0
+
+// ReportsVariance lists shifts whose cash on hand missed the expected cash by more than
+// a threshold (?start=&end=&threshold=, default this month and $20), largest first
+func (h *Handler) ReportsVariance(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	now := time.Now()
+	start, end := q.Get("start"), q.Get("end")
+	if _, err := time.Parse("2006-01-02", start); err != nil {
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", end); err != nil {
+		end = now.Format("2006-01-02")
+	}
+	threshold, err := strconv.ParseFloat(q.Get("threshold"), 64)
+	if err != nil || threshold < 0 {
+		threshold = defaultVarianceThreshold
+	}
+
+	sales, err := h.db.ListSalesWithVariance(start, end, threshold)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("variance_report_error", "start", start, "end", end, "error", err.Error())
+	}
+	var over, short float64
+	for _, s := range sales {
+		if v := s.Variance(); v > 0 {
+			over += v
+		} else {
+			short += v
+		}
+	}
+
+	h.render(w, r, "reports_variance.html", map[string]interface{}{
+		"Title":     "Cash Variance",
+		"Active":    "dashboard",
+		"Start":     start,
+		"End":       end,
+		"Threshold": threshold,
+		"Sales":     sales,
+		"Over":      over,
+		"Short":     short,
+	})
+}
+
+// ReportsVendorSpend totals expenses by vendor and vendor category over a date range
+// (?start=&end=, default this quarter to date)
+func (h *Handler) ReportsVendorSpend(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	now := time.Now()
+	start, end := q.Get("start"), q.Get("end")
+	if _, err := time.Parse("2006-01-02", start); err != nil {
+		quarterMonth := time.Month((int(now.Month())-1)/3*3 + 1)
+		start = time.Date(now.Year(), quarterMonth, 1, 0, 0, 0, 0, time.Local).Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", end); err != nil {
+		end = now.Format("2006-01-02")
+	}
+
+	spend, err := h.db.GetVendorSpend(start, end)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("vendor_spend_error", "start", start, "end", end, "error", err.Error())
+	}
+
+	h.render(w, r, "reports_vendor_spend.html", map[string]interface{}{
+		"Title":  "Vendor Spend",
+		"Active": "dashboard",
+		"Start":  start,
+		"End":    end,
+		"Spend":  spend,
+	})
+}
+
+// ReportsSalesTax shows the sales tax collected by month with a running total, which
+// months a filing covers, and what's still outstanding. Defaults to quarter to date
+func (h *Handler) ReportsSalesTax(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	q := r.URL.Query()
+	now := time.Now()
+	start, end := q.Get("start"), q.Get("end")
+	if _, err := time.Parse("2006-01-02", start); err != nil {
+		quarterMonth := time.Month((int(now.Month())-1)/3*3 + 1)
+		start = time.Date(now.Year(), quarterMonth, 1, 0, 0, 0, 0, time.Local).Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", end); err != nil {
+		end = now.Format("2006-01-02")
+	}
+
+	errMsg := q.Get("error")
+	months, err := h.db.GetSalesTaxOwed(start, end)
+	if err != nil {
+		l.Error("sales_tax_report_error", "start", start, "end", end, "error", err.Error())
+		errMsg = err.Error()
+	}
+	filings, err := h.db.ListTaxFilings()
+	if err != nil {
+		l.Error("tax_filings_list_error", "error", err.Error())
+	}
+
+	var total, outstanding float64
+	for _, m := range months {
+		total += m.Taxes
+		if !m.Filed() {
+			outstanding += m.Taxes
+		}
+	}
+
+	h.render(w, r, "reports_sales_tax.html", map[string]interface{}{
+		"Title":       "Sales Tax",
+		"Active":      "dashboard",
+		"Start":       start,
+		"End":         end,
+		"Today":       now.Format("2006-01-02"),
+		"Months":      months,
+		"Total":       total,
+		"Outstanding": outstanding,
+		"Filings":     filings,
+		"Error":       errMsg,
+	})
+}
+
+// ReportsSalesTaxFile marks a period's sales tax as filed and paid
+func (h *Handler) ReportsSalesTaxFile(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	amount, amountErr := strconv.ParseFloat(r.FormValue("amount"), 64)
+	filing := models.TaxFiling{
+		PeriodStart: r.FormValue("period_start"),
+		PeriodEnd:   r.FormValue("period_end"),
+		Amount:      amount,
+		PaidDate:    r.FormValue("paid_date"),
+		Notes:       strings.TrimSpace(r.FormValue("notes")),
+	}
+	back := url.Values{"start": {filing.PeriodStart}, "end": {filing.PeriodEnd}}
+
+	var problem string
+	_, startErr := time.Parse("2006-01-02", filing.PeriodStart)
+	_, endErr := time.Parse("2006-01-02", filing.PeriodEnd)
+	_, paidErr := time.Parse("2006-01-02", filing.PaidDate)
+	switch {
+	case startErr != nil || endErr != nil:
+		problem = "Enter the period the filing covers"
+	case filing.PeriodEnd < filing.PeriodStart:
+		problem = "Period end is before its start"
+	case paidErr != nil:
+		problem = "Enter the date the tax was paid"
+	case amountErr != nil || amount < 0:
+		problem = "Enter the amount paid"
+	}
+	if problem != "" {
+		back.Set("error", problem)
+		http.Redirect(w, r, "/reports/sales-tax?"+back.Encode(), http.StatusFound)
+		return
+	}
+
+	id, err := h.db.CreateTaxFiling(filing)
+	if err != nil {
+		l.Error("tax_filing_create_error", "error", err.Error())
+		back.Set("error", "Error saving filing")
+	} else {
+		l.Info("tax_filing_created", "filing_id", id, "period_start", filing.PeriodStart, "period_end", filing.PeriodEnd, "amount", filing.Amount)
+	}
+
+	http.Redirect(w, r, "/reports/sales-tax?"+back.Encode(), http.StatusFound)
+}
+
+// ReportsSalesTaxUnfile removes a filing, putting its months back to outstanding
+func (h *Handler) ReportsSalesTaxUnfile(w http.ResponseWriter, r *http.Request) {
+	l := logger.FromContext(r.Context())
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err == nil {
+		if err := h.db.DeleteTaxFiling(id); err != nil {
+			l.Error("tax_filing_delete_error", "filing_id", id, "error", err.Error())
+		} else {
+			l.Info("tax_filing_deleted", "filing_id", id)
+		}
+	}
+
+	back := "/reports/sales-tax"
+	if start, end := r.FormValue("start"), r.FormValue("end"); start != "" && end != "" {
+		back += "?" + url.Values{"start": {start}, "end": {end}}.Encode()
+	}
+	http.Redirect(w, r, back, http.StatusFound)
+}
+
+// Trash handlers
+type trashSection struct {
+	Heading string
+	Items   []models.TrashItem
+}
+
+func (h *Handler) Trash(w http.ResponseWriter, r *http.Request) {
+	sales, _ := h.db.ListDeletedSales()
+	expenses, _ := h.db.ListDeletedExpenses()
+	payroll, _ := h.db.ListDeletedPayroll()
+	h.render(w, r, "trash.html", map[string]interface{}{
+		"Title":  "Trash",
+		"Active": "trash",
+		"Error":  r.URL.Query().Get("error"),
+		"Sections": []trashSection{
+			{Heading: "Sales", Items: sales},
+			{Heading: "Receipts", Items: expenses},
+			{Heading: "Payroll", Items: payroll},
+		},
+	})
+}
+
+// TrashRestore brings a soft-deleted record back
+func (h *Handler) TrashRestore(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	var err error
+	switch r.PathValue("type") {
+	case "sales":
+		err = h.db.RestoreSale(id)
+	case "expenses":
+		err = h.db.RestoreExpense(id)
+	case "payroll":
+		err = h.db.RestorePayroll(id)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if errors.Is(err, database.ErrSaleSlotTaken) || errors.Is(err, database.ErrPayrollSlotTaken) {
+		http.Redirect(w, r, "/trash?"+url.Values{"error": {"Not restored: " + err.Error()}}.Encode(), http.StatusFound)
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("trash_restore_failed", "type", r.PathValue("type"), "id", id, "error", err.Error())
+	}
+	http.Redirect(w, r, "/trash", http.StatusFound)
+}
+
+// TrashDelete permanently removes a soft-deleted record
+func (h *Handler) TrashDelete(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	var err error
+	switch r.PathValue("type") {
+	case "sales":
+		err = h.db.PurgeSale(id)
+	case "expenses":
+		receiptPath, _ := h.db.GetExpenseReceiptPath(id)
+		err = h.db.PurgeExpense(id)
+		if err == nil && receiptPath != "" {
+			h.deleteStoredFile(r, receiptPath)
+		}
+	case "payroll":
+		err = h.db.PurgePayroll(id)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	back := "/trash"
+	if r.FormValue("return") == "expenses" {
+		back = "/expenses/trash"
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("trash_delete_failed", "type", r.PathValue("type"), "id", id, "error", err.Error())
+		http.Redirect(w, r, back+"?"+url.Values{"error": {"Failed to delete it permanently"}}.Encode(), http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, back, http.StatusFound)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReconciliationsPreviewChecksUpload(t *testing.T) {
+	t.Setenv("HOMEBOOKS_MAX_UPLOAD_MB", "1")
+	mux, _ := newTestMux(t)
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+	tests := []struct {
+		name     string
+		filename string
+		content  []byte
+		want     int
+	}{
+		{"image renamed to .pdf", "statement.pdf", png, http.StatusBadRequest},
+		{"over the size limit", "statement.csv", bytes.Repeat([]byte("01/02/2026,COFFEE,-3.50\n"), 2<<20/24), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			mw := multipart.NewWriter(&body)
+			fw, err := mw.CreateFormFile("statement_file", tt.filename)
+			if err != nil {
+				t.Fatalf("CreateFormFile: %v", err)
+			}
+			fw.Write(tt.content)
+			mw.Close()
+
+			req := httptest.NewRequest(http.MethodPost, "/bank-statements/preview", &body)
+			req.Header.Set("Content-Type", mw.FormDataContentType())
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
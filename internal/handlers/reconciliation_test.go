@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"homebooks/internal/models"
+)
+
+func TestReconciliationsCompleteExplainsUnbalanced(t *testing.T) {
+	mux, db := newTestMux(t)
+	id, err := db.CreateReconciliation(models.BankReconciliation{
+		StatementDate: "2026-01-31", StartingBalance: 100, EndingBalance: 142.5, Status: "parsed",
+	})
+	if err != nil {
+		t.Fatalf("CreateReconciliation: %v", err)
+	}
+
+	rec := postForm(mux, fmt.Sprintf("/bank-statements/%d/complete", id), url.Values{"confirm": {"yes"}})
+	loc, _ := url.Parse(rec.Header().Get("Location"))
+	if rec.Code != http.StatusFound || !strings.Contains(loc.Query().Get("error"), "calculated balance is $42.50 below the statement") {
+		t.Fatalf("got %d to %q; want the statement with the outstanding difference", rec.Code, rec.Header().Get("Location"))
+	}
+	if got, _ := db.GetReconciliation(id); got.Status == "completed" {
+		t.Errorf("completed while unbalanced")
+	}
+}
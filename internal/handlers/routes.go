@@ -0,0 +1,165 @@
+package handlers
+
+import "net/http"
+
+// RegisterRoutes adds every page and action route to mux. Static files are left to the
+// caller since they come from the embedded web assets
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// Auth routes (no auth required)
+	mux.HandleFunc("GET /login", h.LoginPage)
+	mux.HandleFunc("POST /login", h.LoginSubmit)
+	mux.HandleFunc("POST /logout", h.Logout)
+
+	// Protected routes
+	mux.HandleFunc("GET /{$}", h.Dashboard)
+
+	// Sales
+	mux.HandleFunc("GET /sales", h.SalesList)
+	mux.HandleFunc("GET /sales/new", h.SalesNew)
+	mux.HandleFunc("GET /sales/export.csv", h.SalesExportCSV)
+	mux.HandleFunc("POST /sales", h.SalesCreate)
+	mux.HandleFunc("GET /sales/{id}/edit", h.SalesEdit)
+	mux.HandleFunc("POST /sales/{id}", h.SalesUpdate)
+	mux.HandleFunc("POST /sales/{id}/delete", h.SalesDelete)
+	mux.HandleFunc("GET /api/sales/shifts", h.SalesShiftsAPI)
+	mux.HandleFunc("GET /api/sales/trend", h.SalesTrendAPI)
+	mux.HandleFunc("GET /api/vendors", h.VendorsSearchAPI)
+	mux.HandleFunc("GET /api/vendors/{id}", h.VendorAPI)
+
+	// Cash
+	mux.HandleFunc("GET /sales/cash", h.CashLedger)
+	mux.HandleFunc("POST /sales/cash/deposits", h.CashDepositCreate)
+	mux.HandleFunc("POST /sales/cash/deposits/{id}/delete", h.CashDepositDelete)
+
+	// Delivery Sales
+	mux.HandleFunc("GET /sales/delivery/new", h.DeliveryNew)
+	mux.HandleFunc("GET /sales/delivery/{date}/edit", h.DeliveryEdit)
+	mux.HandleFunc("POST /sales/delivery", h.DeliverySave)
+
+	// Sales day notes
+	mux.HandleFunc("POST /sales/day-notes", h.SalesDayNoteSave)
+
+	// Expenses
+	mux.HandleFunc("GET /search", h.Search)
+	mux.HandleFunc("GET /expenses", h.ExpensesList)
+	mux.HandleFunc("GET /expenses/new", h.ExpensesNew)
+	mux.HandleFunc("GET /expenses/uncategorized", h.ExpensesUncategorized)
+	mux.HandleFunc("GET /expenses/export.csv", h.ExpensesExportCSV)
+	mux.HandleFunc("GET /expenses/trash", h.ExpensesTrash)
+	mux.HandleFunc("GET /expenses/recurring", h.ExpensesRecurring)
+	mux.HandleFunc("POST /expenses/recurring", h.ExpensesRecurringCreate)
+	mux.HandleFunc("POST /expenses/recurring/delete", h.ExpensesRecurringDelete)
+	mux.HandleFunc("POST /expenses/recurring/generate", h.ExpensesRecurringGenerate)
+	mux.HandleFunc("POST /expenses/bulk-update", h.ExpensesBulkUpdate)
+	mux.HandleFunc("POST /expenses", h.ExpensesCreate)
+	mux.HandleFunc("GET /expenses/{id}/edit", h.ExpensesEdit)
+	mux.HandleFunc("POST /expenses/{id}", h.ExpensesUpdate)
+	mux.HandleFunc("GET /expenses/{id}/pay", h.ExpensesPayForm)
+	mux.HandleFunc("POST /expenses/{id}/pay", h.ExpensesPay)
+	mux.HandleFunc("POST /expenses/{id}/quick-pay", h.ExpensesQuickPay)
+	mux.HandleFunc("POST /expenses/{id}/delete", h.ExpensesDelete)
+	mux.HandleFunc("POST /expenses/{id}/restore", h.ExpensesRestore)
+	mux.HandleFunc("GET /expenses/{id}/receipt", h.ExpensesDownloadReceipt)
+	mux.HandleFunc("GET /expenses/{id}/receipt/thumb", h.ExpensesReceiptThumb)
+	mux.HandleFunc("POST /expenses/{id}/receipt", h.ExpensesUploadReceipt)
+	mux.HandleFunc("POST /expenses/{id}/receipt/delete", h.ExpensesDeleteReceipt)
+
+	// Payroll
+	mux.HandleFunc("GET /payroll", h.PayrollList)
+	mux.HandleFunc("POST /payroll/save", h.PayrollSaveHours)
+	mux.HandleFunc("GET /payroll/weeks/new", h.PayrollWeekNew)
+	mux.HandleFunc("POST /payroll/weeks/copy", h.PayrollWeekCopy)
+	mux.HandleFunc("GET /payroll/weeks/{id}/edit", h.PayrollWeekEdit)
+	mux.HandleFunc("POST /payroll/weeks/{id}/pay-all", h.PayrollWeekPayAll)
+	mux.HandleFunc("GET /payroll/history/{id}", h.PayrollWeekDetail)
+	mux.HandleFunc("GET /payroll/new", h.PayrollNew)
+	mux.HandleFunc("POST /payroll", h.PayrollCreate)
+	mux.HandleFunc("GET /payroll/entry/{id}/edit", h.PayrollEdit)
+	mux.HandleFunc("GET /payroll/entry/{id}/stub", h.PayrollStub)
+	mux.HandleFunc("POST /payroll/entry/{id}", h.PayrollUpdate)
+	mux.HandleFunc("POST /payroll/entry/{id}/pay", h.PayrollPay)
+	mux.HandleFunc("POST /payroll/entry/{id}/reopen", h.PayrollReopen)
+	mux.HandleFunc("POST /payroll/entry/{id}/delete", h.PayrollDelete)
+
+	// Bank Statements
+	mux.HandleFunc("GET /bank-statements", h.ReconciliationsList)
+	mux.HandleFunc("POST /bank-statements/upload", h.ReconciliationsUpload)
+	mux.HandleFunc("POST /bank-statements/accounts", h.AccountsCreate)
+	mux.HandleFunc("POST /bank-statements/preview", h.ReconciliationsPreview)
+	mux.HandleFunc("GET /bank-statements/compare", h.ReconciliationsCompare)
+	mux.HandleFunc("GET /bank-statements/{id}", h.ReconciliationsReview)
+	mux.HandleFunc("GET /bank-statements/{id}/file", h.ReconciliationsFile)
+	mux.HandleFunc("POST /bank-statements/{id}/reparse", h.ReconciliationsReparse)
+	mux.HandleFunc("POST /bank-statements/{id}/rematch", h.ReconciliationsRematch)
+	mux.HandleFunc("GET /bank-statements/{id}/complete", h.ReconciliationsCompleteConfirm)
+	mux.HandleFunc("POST /bank-statements/{id}/complete", h.ReconciliationsComplete)
+	mux.HandleFunc("POST /bank-statements/{id}/match", h.ReconciliationsMatch)
+	mux.HandleFunc("POST /bank-statements/{id}/unmatch", h.ReconciliationsUnmatch)
+	mux.HandleFunc("POST /bank-statements/{id}/ignore", h.ReconciliationsIgnore)
+	mux.HandleFunc("POST /bank-statements/{id}/ignore-bulk", h.ReconciliationsIgnoreBulk)
+	mux.HandleFunc("POST /bank-statements/{id}/personal", h.ReconciliationsPersonal)
+	mux.HandleFunc("POST /bank-statements/{id}/undo", h.ReconciliationsUndo)
+	mux.HandleFunc("POST /bank-statements/{id}/create-expense", h.ReconciliationsCreateExpense)
+	mux.HandleFunc("POST /bank-statements/{id}/update-type", h.ReconciliationsUpdateType)
+	mux.HandleFunc("POST /bank-statements/{id}/update-category", h.ReconciliationsUpdateCategory)
+	mux.HandleFunc("POST /bank-statements/{id}/default-vendor", h.ReconciliationsSetDefaultVendor)
+	mux.HandleFunc("POST /bank-statements/{id}/balances", h.ReconciliationsBalances)
+	mux.HandleFunc("POST /bank-statements/{id}/transactions", h.ReconciliationsAddTransaction)
+	mux.HandleFunc("POST /bank-statements/{id}/transactions/{txnId}/edit", h.ReconciliationsEditTransaction)
+	mux.HandleFunc("POST /bank-statements/{id}/discrepancy", h.ReconciliationsDiscrepancy)
+	mux.HandleFunc("POST /bank-statements/{id}/delete", h.ReconciliationsDelete)
+
+	// Jobs API
+	mux.HandleFunc("GET /api/jobs/{id}", h.JobStatus)
+	mux.HandleFunc("POST /api/jobs/{id}/cancel", h.JobCancel)
+
+	// Version API
+	mux.HandleFunc("GET /api/version", h.APIVersion)
+
+	// Vendors
+	mux.HandleFunc("GET /vendors", h.VendorsList)
+	mux.HandleFunc("GET /vendors/new", h.VendorsNew)
+	mux.HandleFunc("GET /vendors/{id}", h.VendorsShow)
+	mux.HandleFunc("POST /vendors", h.VendorsCreate)
+	mux.HandleFunc("GET /vendors/{id}/edit", h.VendorsEdit)
+	mux.HandleFunc("POST /vendors/{id}", h.VendorsUpdate)
+	mux.HandleFunc("POST /vendors/{id}/delete", h.VendorsDelete)
+	mux.HandleFunc("POST /vendors/{id}/merge", h.VendorsMerge)
+	mux.HandleFunc("POST /vendors/{id}/rules", h.VendorsRuleCreate)
+	mux.HandleFunc("POST /vendors/{id}/rules/{ruleID}/delete", h.VendorsRuleDelete)
+
+	// Employees
+	mux.HandleFunc("GET /employees", h.EmployeesList)
+	mux.HandleFunc("POST /employees", h.EmployeesCreate)
+	mux.HandleFunc("GET /employees/{id}", h.EmployeesShow)
+	mux.HandleFunc("GET /employees/{id}/edit", h.EmployeesEdit)
+	mux.HandleFunc("POST /employees/{id}", h.EmployeesUpdate)
+	mux.HandleFunc("POST /employees/{id}/deactivate", h.EmployeesDeactivate)
+	mux.HandleFunc("POST /employees/{id}/reactivate", h.EmployeesReactivate)
+
+	// Reports
+	mux.HandleFunc("GET /reports/pl", h.ReportsPL)
+	mux.HandleFunc("GET /reports/cashflow", h.ReportsCashFlow)
+	mux.HandleFunc("GET /reports/variance", h.ReportsVariance)
+	mux.HandleFunc("GET /reports/vendor-spend", h.ReportsVendorSpend)
+	mux.HandleFunc("GET /reports/sales-tax", h.ReportsSalesTax)
+	mux.HandleFunc("POST /reports/sales-tax/filings", h.ReportsSalesTaxFile)
+	mux.HandleFunc("POST /reports/sales-tax/filings/{id}/delete", h.ReportsSalesTaxUnfile)
+
+	// Settings
+	mux.HandleFunc("GET /settings/password", h.SettingsPassword)
+	mux.HandleFunc("POST /settings/password", h.SettingsPasswordUpdate)
+	mux.HandleFunc("GET /settings/users", h.SettingsUsers)
+	mux.HandleFunc("POST /settings/users", h.SettingsUsersCreate)
+	mux.HandleFunc("POST /settings/users/delete", h.SettingsUsersDelete)
+	mux.HandleFunc("GET /settings/backup", h.SettingsBackup)
+
+	// Background jobs
+	mux.HandleFunc("GET /jobs", h.JobsList)
+	mux.HandleFunc("POST /jobs/{id}/retry", h.JobsRetry)
+
+	// Trash
+	mux.HandleFunc("GET /trash", h.Trash)
+	mux.HandleFunc("POST /trash/{type}/{id}/restore", h.TrashRestore)
+	mux.HandleFunc("POST /trash/{type}/{id}/delete", h.TrashDelete)
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"homebooks/internal/auth"
+	"homebooks/internal/database"
+	"homebooks/internal/filestore"
+	"homebooks/internal/models"
+	"homebooks/web"
+)
+
+// newTestMux returns the app's routes backed by a migrated temp database
+func newTestMux(t *testing.T) (*http.ServeMux, *database.DB) {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Init(); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	tmpl, err := template.New("").Funcs(TemplateFuncs).ParseFS(web.TemplatesFS, "templates/*.html")
+	if err != nil {
+		t.Fatalf("parse templates: %v", err)
+	}
+	files, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	New(db, auth.New(db.DB), tmpl, files).RegisterRoutes(mux)
+	return mux, db
+}
+
+func TestReconciliationActionsRedirectToRegisteredRoutes(t *testing.T) {
+	mux, db := newTestMux(t)
+
+	reconID, err := db.CreateReconciliation(models.BankReconciliation{StatementDate: "2026-01-31", Status: "parsed"})
+	if err != nil {
+		t.Fatalf("create reconciliation: %v", err)
+	}
+	var txnIDs []int64
+	for _, amount := range []float64{-42.50, -12} {
+		id, err := db.CreateBankTransaction(&models.BankTransaction{
+			ReconciliationID: reconID, PostingDate: "2026-01-15", Description: "WITHDRAWAL",
+			Amount: amount, TransactionType: "debit", Category: "expense",
+		})
+		if err != nil {
+			t.Fatalf("create transaction: %v", err)
+		}
+		txnIDs = append(txnIDs, id)
+	}
+	vendorID, err := db.CreateVendor("Jetro", "Food", "", "")
+	if err != nil {
+		t.Fatalf("create vendor: %v", err)
+	}
+	expenseID, err := db.CreateExpense(models.Expense{
+		Date: "2026-01-14", VendorID: vendorID, Amount: 42.50, Status: "not_paid",
+	})
+	if err != nil {
+		t.Fatalf("create expense: %v", err)
+	}
+
+	review := fmt.Sprintf("/bank-statements/%d", reconID)
+	txn := fmt.Sprint(txnIDs[0])
+	other := fmt.Sprint(txnIDs[1])
+
+	// Run in order, since later actions undo or build on earlier ones
+	steps := []struct {
+		action string
+		form   url.Values
+	}{
+		{"match", url.Values{"transaction_id": {txn}, "expense_id": {fmt.Sprint(expenseID)}}},
+		{"unmatch", url.Values{"transaction_id": {txn}}},
+		{"ignore", url.Values{"transaction_id": {txn}, "reason": {"duplicate"}}},
+		{"undo", nil},
+		{"personal", url.Values{"transaction_id": {txn}, "note": {"owner draw"}}},
+		{"undo", nil},
+		{"create-expense", url.Values{"transaction_id": {txn}, "vendor_id": {fmt.Sprint(vendorID)}}},
+		{"update-type", url.Values{"transaction_id": {other}, "transaction_type": {"debit"}}},
+		{"update-category", url.Values{"transaction_id": {other}, "category": {"expense"}}},
+		{"ignore-bulk", url.Values{"transaction_type": {"debit"}, "reason": {"fees"}}},
+		{"default-vendor", url.Values{"vendor_id": {fmt.Sprint(vendorID)}}},
+		{"discrepancy", url.Values{"amount": {"0.50"}, "notes": {"bank fee"}}},
+		{"balances", url.Values{"starting_balance": {"100"}, "ending_balance": {"45.50"}, "account_last_four": {"2609"}}},
+		{"transactions", url.Values{"posting_date": {"2026-01-20"}, "description": {"INTEREST"}, "transaction_type": {"deposit"}, "amount": {"0.05"}}},
+		{"transactions/" + other + "/edit", url.Values{"posting_date": {"2026-01-16"}, "description": {"FEE"}, "amount": {"-12"}}},
+		{"rematch", nil},
+	}
+	for _, step := range steps {
+		req := httptest.NewRequest(http.MethodPost, review+"/"+step.action, strings.NewReader(step.form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusFound {
+			t.Fatalf("POST %s: status %d, want %d", step.action, rec.Code, http.StatusFound)
+		}
+		loc, err := url.Parse(rec.Header().Get("Location"))
+		if err != nil {
+			t.Fatalf("POST %s: bad Location %q: %v", step.action, rec.Header().Get("Location"), err)
+		}
+		if loc.Path != review {
+			t.Errorf("POST %s redirected to %s, want %s", step.action, loc, review)
+		}
+		if _, pattern := mux.Handler(httptest.NewRequest(http.MethodGet, loc.String(), nil)); pattern == "" {
+			t.Errorf("POST %s redirected to %s, which has no route", step.action, loc)
+		}
+	}
+}
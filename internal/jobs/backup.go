@@ -0,0 +1,96 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"homebooks/internal/database"
+	"homebooks/internal/models"
+)
+
+// DefaultBackupKeep is how many daily backups are kept when HOMEBOOKS_BACKUP_KEEP is unset
+const DefaultBackupKeep = 7
+
+// BackupKeepFromEnv reads how many daily backups to keep from HOMEBOOKS_BACKUP_KEEP
+func BackupKeepFromEnv() int {
+	keep, err := strconv.Atoi(os.Getenv("HOMEBOOKS_BACKUP_KEEP"))
+	if err != nil || keep < 0 {
+		return DefaultBackupKeep
+	}
+	return keep
+}
+
+// backupPrefix starts every backup file name; the date follows, so names sort by age
+const backupPrefix = "homebooks-"
+
+// BackupHandler creates a job handler that writes today's copy of the database into
+// dir as homebooks-YYYY-MM-DD.db and removes all but the newest keep copies (zero
+// keeps them all). Running it again the same day replaces that day's copy
+func BackupHandler(dir string, keep int) JobHandler {
+	return func(ctx context.Context, job *models.Job, db *database.DB) error {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("create backup directory: %w", err)
+		}
+
+		// Back up to a temp name so a failed run never clobbers the last good copy
+		name := backupPrefix + time.Now().Format("2006-01-02") + ".db"
+		tmp := filepath.Join(dir, "."+name+".tmp")
+		os.Remove(tmp)
+		if err := db.BackupTo(tmp); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		if err := os.Rename(tmp, filepath.Join(dir, name)); err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("store backup: %w", err)
+		}
+		db.UpdateJobProgress(job.ID, 80)
+
+		removed, err := pruneBackups(dir, keep)
+		if err != nil {
+			return err
+		}
+
+		resultJSON, _ := json.Marshal(map[string]any{
+			"file":    name,
+			"removed": removed,
+		})
+		db.CompleteJob(job.ID, string(resultJSON))
+		return nil
+	}
+}
+
+// pruneBackups deletes the oldest backups in dir beyond the newest keep, returning
+// the names it removed
+func pruneBackups(dir string, keep int) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("list backups: %w", err)
+	}
+
+	var backups []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), backupPrefix) && strings.HasSuffix(e.Name(), ".db") {
+			backups = append(backups, e.Name())
+		}
+	}
+	if keep < 1 || len(backups) <= keep {
+		return nil, nil
+	}
+
+	slices.Sort(backups)
+	old := backups[:len(backups)-keep]
+	for _, name := range old {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return nil, fmt.Errorf("remove old backup: %w", err)
+		}
+	}
+	return old, nil
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 
 	"homebooks/internal/database"
+	"homebooks/internal/filestore"
 	"homebooks/internal/models"
 	"homebooks/internal/parser"
 	"homebooks/internal/reconciliation"
@@ -18,7 +20,7 @@ type ParseStatementPayload struct {
 }
 
 // ParseStatementHandler creates a job handler for parsing bank statements
-func ParseStatementHandler(fileStorePath string) JobHandler {
+func ParseStatementHandler(files filestore.Store, matchOpts reconciliation.MatchOptions) JobHandler {
 	return func(ctx context.Context, job *models.Job, db *database.DB) error {
 		// Parse payload
 		var payload ParseStatementPayload
@@ -26,24 +28,61 @@ func ParseStatementHandler(fileStorePath string) JobHandler {
 			return fmt.Errorf("unmarshal payload: %w", err)
 		}
 
+		// A cancelled parse leaves the statement pending, ready to be parsed again
+		cancelled := func() error {
+			db.UpdateReconciliationStatus(payload.ReconciliationID, "pending")
+			return ctx.Err()
+		}
+		if ctx.Err() != nil {
+			return cancelled()
+		}
+
 		// Update status to parsing
 		if err := db.UpdateReconciliationStatus(payload.ReconciliationID, "parsing"); err != nil {
 			return fmt.Errorf("update status: %w", err)
 		}
 		db.UpdateJobProgress(job.ID, 5)
 
-		// Build full file path
-		fullPath := fileStorePath + "/" + payload.FilePath
+		// The month picked at upload dates transactions if the statement period is unreadable
+		recon, err := db.GetReconciliation(payload.ReconciliationID)
+		if err != nil {
+			return fmt.Errorf("get reconciliation: %w", err)
+		}
+		opts := parser.Options{}
+		if len(recon.StatementDate) >= 7 {
+			opts.FallbackMonth = recon.StatementDate[:7]
+		}
+
+		// Parsers read from disk, so a statement kept in object storage is fetched first
+		fullPath, cleanup, err := files.LocalPath(payload.FilePath)
+		if err != nil {
+			db.UpdateReconciliationStatus(payload.ReconciliationID, "pending")
+			return fmt.Errorf("get statement file: %w", err)
+		}
+		defer cleanup()
 
-		// Parse the PDF
-		p := parser.NewTDBankParser()
+		// Parse the statement with the parser for its file type (PDF, CSV or OFX)
+		p, err := parser.ForFile(fullPath, opts)
+		if err != nil {
+			db.UpdateReconciliationStatus(payload.ReconciliationID, "pending")
+			return err
+		}
 		result, err := p.Parse(fullPath)
 		if err != nil {
 			db.UpdateReconciliationStatus(payload.ReconciliationID, "pending")
-			return fmt.Errorf("parse PDF: %w", err)
+			return fmt.Errorf("parse statement: %w", err)
+		}
+		if ctx.Err() != nil {
+			return cancelled()
 		}
 		db.UpdateJobProgress(job.ID, 40)
 
+		// Flag a statement uploaded to the wrong account; the review page shows it
+		recon.AccountLastFour = result.AccountLastFour
+		accountMismatch := recon.AccountMismatch()
+
+		calculatedEnding, balanceMatches := checkParsedBalance(result)
+
 		// Update reconciliation with parsed header info and summary values
 		if err := db.UpdateReconciliationParsed(
 			payload.ReconciliationID,
@@ -54,9 +93,14 @@ func ParseStatementHandler(fileStorePath string) JobHandler {
 			result.ElectronicPayments,
 			result.ChecksPaid,
 			result.ServiceFees,
+			calculatedEnding,
+			balanceMatches,
 		); err != nil {
 			return fmt.Errorf("update reconciliation parsed: %w", err)
 		}
+		if err := db.FillAccountLastFour(payload.ReconciliationID, result.AccountLastFour); err != nil {
+			return err
+		}
 		db.UpdateJobProgress(job.ID, 50)
 
 		// Delete any existing transactions (in case of re-parse)
@@ -74,6 +118,7 @@ func ParseStatementHandler(fileStorePath string) JobHandler {
 				Amount:           txn.Amount,
 				TransactionType:  txn.TransactionType,
 				Category:         txn.Category,
+				Platform:         txn.Platform,
 				CheckNumber:      txn.CheckNumber,
 				VendorHint:       txn.VendorHint,
 				ReferenceNumber:  txn.ReferenceNumber,
@@ -93,7 +138,7 @@ func ParseStatementHandler(fileStorePath string) JobHandler {
 			// Check for cancellation
 			select {
 			case <-ctx.Done():
-				return ctx.Err()
+				return cancelled()
 			default:
 			}
 		}
@@ -101,7 +146,10 @@ func ParseStatementHandler(fileStorePath string) JobHandler {
 		db.UpdateJobProgress(job.ID, 95)
 
 		// Run auto-matching
-		matched, _ := reconciliation.AutoMatch(db, payload.ReconciliationID)
+		matched, _ := reconciliation.AutoMatch(db, payload.ReconciliationID, matchOpts)
+
+		// Book recurring lines covered by auto-booking rules
+		booked, _ := reconciliation.ApplyAutoBookingRules(db, payload.ReconciliationID)
 
 		// Mark as parsed (not completed - user still needs to review)
 		if err := db.UpdateReconciliationStatus(payload.ReconciliationID, "parsed"); err != nil {
@@ -114,12 +162,35 @@ func ParseStatementHandler(fileStorePath string) JobHandler {
 		resultJSON, _ := json.Marshal(map[string]any{
 			"transactions_count": totalTxns,
 			"matched_count":      matched,
+			"auto_booked_count":  booked,
 			"beginning_balance":  result.BeginningBalance,
 			"ending_balance":     result.EndingBalance,
 			"account_last_four":  result.AccountLastFour,
+			"expected_last_four": recon.AccountExpectedLastFour,
+			"account_mismatch":   accountMismatch,
+			"calculated_ending":  calculatedEnding,
+			"balance_matches":    balanceMatches,
+			"warnings":           result.Warnings,
 		})
 		db.CompleteJob(job.ID, string(resultJSON))
 
 		return nil
 	}
 }
+
+// checkParsedBalance adds the parsed transactions to the beginning balance and reports
+// whether that lands on the statement ending balance. Statements without balances
+// (most CSV exports) have nothing to reconcile against, so they always match
+func checkParsedBalance(stmt *parser.ParsedStatement) (float64, bool) {
+	calculated := stmt.BeginningBalance
+	for _, txn := range stmt.Transactions {
+		calculated += txn.Amount
+	}
+	calculated = math.Round(calculated*100) / 100
+
+	if !stmt.HasBalances() {
+		return calculated, true
+	}
+	diff := math.Round((calculated-stmt.EndingBalance)*100) / 100
+	return calculated, math.Abs(diff) <= models.BalanceTolerance
+}
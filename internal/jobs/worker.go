@@ -20,6 +20,13 @@ type Worker struct {
 	done         chan struct{}
 	logger       *slog.Logger
 	pollInterval time.Duration
+	schedules    []schedule
+}
+
+// schedule is a job type queued on a fixed interval
+type schedule struct {
+	jobType  string
+	interval time.Duration
 }
 
 // NewWorker creates a new job worker
@@ -39,8 +46,18 @@ func (w *Worker) Register(jobType string, handler JobHandler) {
 	w.handlers[jobType] = handler
 }
 
+// Schedule queues a job of the given type when the worker starts and again every
+// interval after that, for housekeeping such as backups. Call before Start
+func (w *Worker) Schedule(jobType string, interval time.Duration) {
+	w.schedules = append(w.schedules, schedule{jobType: jobType, interval: interval})
+}
+
 // Start begins processing jobs in a background goroutine
 func (w *Worker) Start() {
+	for _, s := range w.schedules {
+		go w.runSchedule(s)
+	}
+
 	go func() {
 		defer close(w.done)
 		w.logger.Info("job_worker_started")
@@ -77,6 +94,25 @@ func (w *Worker) Stop() {
 	w.logger.Info("job_worker_stopped")
 }
 
+func (w *Worker) runSchedule(s schedule) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.db.CreateJob(s.jobType, map[string]any{}); err != nil {
+			w.logger.Error("job_schedule_error", "job_type", s.jobType, "error", err.Error())
+		} else {
+			w.logger.Info("job_scheduled", "job_type", s.jobType)
+		}
+
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func (w *Worker) processJob(job *models.Job) {
 	l := w.logger.With("job_id", job.ID, "job_type", job.JobType, "attempt", job.Attempts)
 	l.Info("job_processing_started")
@@ -88,25 +124,67 @@ func (w *Worker) processJob(job *models.Job) {
 		return
 	}
 
-	// Create context with timeout
+	// Create context with timeout, cut short if the job is cancelled from the UI
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
+	if job.CancelRequested {
+		cancel()
+	} else {
+		go w.watchCancel(ctx, job.ID, cancel)
+	}
 
 	// Run the handler
 	err := handler(ctx, job, w.db)
 
 	if err != nil {
+		if cancelled, _ := w.db.JobCancelRequested(job.ID); cancelled {
+			l.Info("job_cancelled")
+			w.db.FailJob(job.ID, "cancelled")
+			return
+		}
+
 		l.Error("job_processing_failed", "error", err.Error())
 
 		if job.Attempts >= job.MaxAttempts {
 			l.Warn("job_max_attempts_reached")
 			w.db.FailJob(job.ID, err.Error())
 		} else {
-			l.Info("job_retrying")
-			w.db.RetryJob(job.ID)
+			delay := backoff(job.Attempts)
+			l.Info("job_retrying", "delay", delay.String())
+			w.db.RetryJob(job.ID, time.Now().Add(delay))
 		}
 		return
 	}
 
 	l.Info("job_processing_completed")
 }
+
+// watchCancel polls for a cancellation request while a job runs and cancels its
+// context when one arrives. It returns once the job's context is done
+func (w *Worker) watchCancel(ctx context.Context, jobID int64, cancel context.CancelFunc) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if requested, _ := w.db.JobCancelRequested(jobID); requested {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// retryDelays is how long to wait before each retry of a failed job; attempts past
+// the end of the list wait as long as the last entry
+var retryDelays = []time.Duration{5 * time.Second, 30 * time.Second, 2 * time.Minute}
+
+// backoff returns how long to wait before retrying a job that has failed attempts
+// times, so a job that fails every time doesn't use up its attempts in a moment
+func backoff(attempts int) time.Duration {
+	i := min(max(attempts, 1), len(retryDelays)) - 1
+	return retryDelays[i]
+}
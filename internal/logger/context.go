@@ -12,6 +12,7 @@ type contextKey string
 const (
 	requestIDKey contextKey = "request_id"
 	loggerKey    contextKey = "logger"
+	userKey      contextKey = "user"
 )
 
 // GenerateRequestID creates a new unique request ID (16 hex chars)
@@ -39,6 +40,15 @@ func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
 	return context.WithValue(ctx, loggerKey, l)
 }
 
+// WithUser records the signed-in user for the request log written by HTTPMiddleware
+// and returns a context whose logger includes them
+func WithUser(ctx context.Context, name string) context.Context {
+	if u, ok := ctx.Value(userKey).(*string); ok {
+		*u = name
+	}
+	return WithLogger(ctx, FromContext(ctx).With("user", name))
+}
+
 // FromContext returns a logger from context, or the default logger.
 // The returned logger always includes the request ID if present.
 func FromContext(ctx context.Context) *slog.Logger {
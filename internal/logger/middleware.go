@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -52,6 +53,10 @@ func HTTPMiddleware(next http.Handler) http.Handler {
 		reqLogger := Default().With("request_id", requestID)
 		ctx = WithLogger(ctx, reqLogger)
 
+		// Filled in by the auth middleware once the session is validated
+		var user string
+		ctx = context.WithValue(ctx, userKey, &user)
+
 		// Wrap response writer to capture status
 		wrapped := newResponseWriter(w)
 
@@ -75,13 +80,17 @@ func HTTPMiddleware(next http.Handler) http.Handler {
 		}
 
 		// Log the completed request
-		reqLogger.Log(r.Context(), level, "http_request",
+		attrs := []interface{}{
 			"method", r.Method,
 			"path", r.URL.Path,
 			"status", wrapped.status,
 			"duration_ms", duration.Milliseconds(),
 			"remote_addr", r.RemoteAddr,
-		)
+		}
+		if user != "" {
+			attrs = append(attrs, "user", user)
+		}
+		reqLogger.Log(r.Context(), level, "http_request", attrs...)
 	})
 }
 
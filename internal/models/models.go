@@ -1,10 +1,22 @@
 package models
 
 import (
+	"fmt"
+	"math"
 	"strings"
 	"time"
 )
 
+// ValidationError reports a field that failed model validation
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
 // VendorCategories is the list of available vendor categories
 var VendorCategories = []string{
 	"Beverages",
@@ -29,12 +41,79 @@ var VendorCategories = []string{
 	"Utilities",
 }
 
+// Sources an income category can draw from
+const (
+	IncomeSourceCardSales = "card_sales" // card share of dine-in net sales
+	IncomeSourceCashSales = "cash_sales" // cash share of dine-in net sales
+	IncomeSourceDelivery  = "delivery"   // recorded delivery net payouts for a platform
+	IncomeSourceDeposits  = "deposits"   // bank deposits carrying a category
+)
+
+// IncomeCategory is a line in the P&L income breakdown
+type IncomeCategory struct {
+	Name         string
+	Source       string // one of the IncomeSource constants
+	Platform     string // delivery platform, for IncomeSourceDelivery
+	BankCategory string // bank transaction category, for IncomeSourceDeposits
+}
+
+// IncomeCategories is the list of income lines on the P&L, in display order.
+// Card and delivery deposits aren't counted here since they're the bank side of recorded sales.
+var IncomeCategories = []IncomeCategory{
+	{Name: "Card Sales", Source: IncomeSourceCardSales},
+	{Name: "Cash Sales", Source: IncomeSourceCashSales},
+	{Name: "Grubhub", Source: IncomeSourceDelivery, Platform: "grubhub"},
+	{Name: "DoorDash", Source: IncomeSourceDelivery, Platform: "doordash"},
+	{Name: "Uber Eats", Source: IncomeSourceDelivery, Platform: "ubereats"},
+	{Name: "Seamless", Source: IncomeSourceDelivery, Platform: "seamless"},
+	{Name: "ezCater", Source: IncomeSourceDelivery, Platform: "ezcater"},
+	{Name: "Slice", Source: IncomeSourceDelivery, Platform: "slice"},
+	{Name: "Catering", Source: IncomeSourceDeposits, BankCategory: "income_catering"},
+	{Name: "Other", Source: IncomeSourceDeposits, BankCategory: "income_other"},
+}
+
+// IncomeLine is one income category's total for a period
+type IncomeLine struct {
+	Category string
+	Amount   float64
+}
+
+// JoinVendorCategories validates submitted categories against VendorCategories and joins
+// them into the comma-separated form stored on a vendor. Values are trimmed, blanks and
+// duplicates dropped, and anything unknown (including values with embedded commas) rejected
+func JoinVendorCategories(values []string) (string, error) {
+	var cats []string
+	seen := make(map[string]bool)
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[v] {
+			continue
+		}
+		if strings.Contains(v, ",") || !isVendorCategory(v) {
+			return "", &ValidationError{Field: "category", Message: fmt.Sprintf("%q is not a vendor category", v)}
+		}
+		seen[v] = true
+		cats = append(cats, v)
+	}
+	return strings.Join(cats, ","), nil
+}
+
+func isVendorCategory(cat string) bool {
+	for _, c := range VendorCategories {
+		if c == cat {
+			return true
+		}
+	}
+	return false
+}
+
 type Vendor struct {
-	ID          int64
-	Name        string
-	Category    string // comma-separated list of categories
-	Description string
-	CreatedAt   time.Time
+	ID                 int64
+	Name               string
+	Category           string // comma-separated list of categories
+	Description        string
+	DefaultPaymentType string // prefilled on new receipts: cash, check, debit, credit or ""
+	CreatedAt          time.Time
 }
 
 // HasCategory checks if the vendor has a specific category
@@ -43,7 +122,7 @@ func (v Vendor) HasCategory(cat string) bool {
 		return false
 	}
 	for _, c := range strings.Split(v.Category, ",") {
-		if c == cat {
+		if strings.TrimSpace(c) == cat {
 			return true
 		}
 	}
@@ -58,6 +137,16 @@ func (v Vendor) CategoryList() []string {
 	return strings.Split(v.Category, ",")
 }
 
+// TrashItem is a soft-deleted record shown in the trash view
+type TrashItem struct {
+	Type        string // sales, expenses or payroll
+	ID          int64
+	Date        string
+	Description string
+	Amount      float64
+	DeletedAt   string
+}
+
 type Employee struct {
 	ID            int64
 	Name          string
@@ -67,6 +156,31 @@ type Employee struct {
 	CreatedAt     time.Time
 }
 
+// Validate checks the employee fields required before saving
+func (e Employee) Validate() error {
+	if strings.TrimSpace(e.Name) == "" {
+		return &ValidationError{Field: "name", Message: "is required"}
+	}
+	if e.HourlyRate <= 0 {
+		return &ValidationError{Field: "hourly_rate", Message: "must be greater than zero"}
+	}
+	if e.PaymentMethod != "cash" && e.PaymentMethod != "check" {
+		return &ValidationError{Field: "payment_method", Message: "must be cash or check"}
+	}
+	return nil
+}
+
+// EmployeeRateChange records an employee's hourly rate being changed
+type EmployeeRateChange struct {
+	ID            int64
+	EmployeeID    int64
+	OldRate       float64
+	NewRate       float64
+	EffectiveDate string // YYYY-MM-DD
+	ChangedBy     string // user name, empty for the shared password
+	ChangedAt     time.Time
+}
+
 type DailySale struct {
 	ID          int64
 	Date        string // YYYY-MM-DD
@@ -93,57 +207,229 @@ func (s DailySale) ExpectedCash() float64 {
 	return s.NetSales + s.Taxes - s.CreditCard
 }
 
+// DeliveryPlatform is a delivery or catering service whose sales are entered by day
+type DeliveryPlatform struct {
+	Key           string // stored on delivery_platform_sales rows and bank transaction tags
+	Name          string
+	SubtotalLabel string // what the platform's own reports call gross sales
+	NetLabel      string // and the payout after its fees
+	Color         string // header color on the delivery form
+}
+
+// DeliveryPlatforms is the list of platforms on the delivery form, in display order
+var DeliveryPlatforms = []DeliveryPlatform{
+	{Key: "grubhub", Name: "Grubhub", SubtotalLabel: "Subtotal (Gross)", NetLabel: "Net (After Fees)", Color: "#f63440"},
+	{Key: "doordash", Name: "DoorDash", SubtotalLabel: "Subtotal (Gross)", NetLabel: "Net (After Fees)", Color: "#ff3008"},
+	{Key: "ubereats", Name: "Uber Eats", SubtotalLabel: "Earnings (Gross)", NetLabel: "Payout (Net)", Color: "#06c167"},
+	{Key: "seamless", Name: "Seamless", SubtotalLabel: "Subtotal (Gross)", NetLabel: "Net (After Fees)", Color: "#2a5ec8"},
+	{Key: "ezcater", Name: "ezCater", SubtotalLabel: "Order Total (Gross)", NetLabel: "Payout (Net)", Color: "#00857c"},
+	{Key: "slice", Name: "Slice", SubtotalLabel: "Subtotal (Gross)", NetLabel: "Payout (Net)", Color: "#f26b21"},
+}
+
+// IsDeliveryPlatform reports whether key is one of DeliveryPlatforms
+func IsDeliveryPlatform(key string) bool {
+	for _, p := range DeliveryPlatforms {
+		if p.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliveryPlatformSales is one platform's sales for a day
+type DeliveryPlatformSales struct {
+	Platform string // DeliveryPlatform key
+	Subtotal float64
+	Net      float64
+}
+
+// Fees returns what the platform kept
+func (p DeliveryPlatformSales) Fees() float64 {
+	return p.Subtotal - p.Net
+}
+
 // DeliverySales represents delivery platform sales for a single day
 type DeliverySales struct {
-	ID               int64
-	Date             string // YYYY-MM-DD
-	GrubhubSubtotal  float64
-	GrubhubNet       float64
-	DoordashSubtotal float64
-	DoordashNet      float64
-	UberEatsEarnings float64
-	UberEatsPayout   float64
-	Notes            string
-	CreatedAt        time.Time
-	UpdatedAt        time.Time
+	ID        int64
+	Date      string                  // YYYY-MM-DD
+	Platforms []DeliveryPlatformSales // platforms with sales entered, in DeliveryPlatforms order
+	Notes     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Platform returns the day's sales for a platform, zero if none were entered
+func (d DeliverySales) Platform(key string) DeliveryPlatformSales {
+	for _, p := range d.Platforms {
+		if p.Platform == key {
+			return p
+		}
+	}
+	return DeliveryPlatformSales{Platform: key}
 }
 
 // TotalSubtotal returns the sum of all delivery subtotals/earnings
 func (d DeliverySales) TotalSubtotal() float64 {
-	return d.GrubhubSubtotal + d.DoordashSubtotal + d.UberEatsEarnings
+	var total float64
+	for _, p := range d.Platforms {
+		total += p.Subtotal
+	}
+	return total
 }
 
 // TotalNet returns the sum of all delivery net amounts/payouts
 func (d DeliverySales) TotalNet() float64 {
-	return d.GrubhubNet + d.DoordashNet + d.UberEatsPayout
+	var total float64
+	for _, p := range d.Platforms {
+		total += p.Net
+	}
+	return total
 }
 
 // HasData returns true if any delivery data has been entered
 func (d DeliverySales) HasData() bool {
-	return d.GrubhubSubtotal > 0 || d.GrubhubNet > 0 ||
-		d.DoordashSubtotal > 0 || d.DoordashNet > 0 ||
-		d.UberEatsEarnings > 0 || d.UberEatsPayout > 0
+	for _, p := range d.Platforms {
+		if p.Subtotal > 0 || p.Net > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// CashDeposit represents cash taken from the drawer/safe to the bank
+type CashDeposit struct {
+	ID        int64
+	Date      string // YYYY-MM-DD
+	Amount    float64
+	Notes     string
+	CreatedAt time.Time
+}
+
+// CashLedgerDay is one day of the undeposited cash roll-forward
+type CashLedgerDay struct {
+	Date            string // YYYY-MM-DD
+	DateDisplay     string // MM-DD-YYYY
+	Opening         float64
+	CashReceipts    float64 // sum of shift cash receipts
+	CashCounted     float64 // sum of shift cash on hand
+	Deposits        float64
+	ExpectedClosing float64 // Opening + CashReceipts - Deposits
+	ActualClosing   float64 // running balance using counted cash instead of receipts
+}
+
+// Difference returns counted cash minus receipted cash for the day
+func (d CashLedgerDay) Difference() float64 {
+	return d.CashCounted - d.CashReceipts
+}
+
+// Flagged reports whether the day's counted cash is more than a dollar off its receipts
+func (d CashLedgerDay) Flagged() bool {
+	diff := d.Difference()
+	return diff > 1 || diff < -1
 }
 
 type Expense struct {
 	ID            int64
 	Date          string // YYYY-MM-DD
-	VendorID      int64
-	VendorName    string // populated by JOIN
+	VendorID      int64  // 0 for an ad-hoc payee
+	VendorName    string // populated by JOIN, or PayeeName for ad-hoc payees
+	PayeeName     string // free-text payee used when there is no vendor
 	Amount        float64
 	InvoiceNumber string
-	Status        string // "paid" or "not_paid"
+	Status        string // "paid" or "not_paid"; see PaymentStatus for partly paid
 	PaymentType   string // "cash", "check", "debit", "credit"
 	CheckNumber   string
 	DateOpened    string // YYYY-MM-DD or empty
 	DueDate       string // YYYY-MM-DD or empty
 	DatePaid      string // YYYY-MM-DD or empty
 	Notes         string
-	ReceiptPath   string // stored filename in filestore
+	ReceiptPath   string  // stored filename in filestore
+	Category      string  // overrides the vendor's categories when set
+	Reconciled    bool    // matched on a completed bank statement; populated by ListExpenses
+	AmountPaid    float64 // Amount once paid, else the sum of its payments; populated by ListExpenses and GetExpense
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 }
 
+// Validate checks the expense fields required before saving
+func (e Expense) Validate() error {
+	if e.Date == "" {
+		return &ValidationError{Field: "date", Message: "is required"}
+	}
+	if e.VendorID <= 0 && strings.TrimSpace(e.PayeeName) == "" {
+		return &ValidationError{Field: "vendor_id", Message: "a vendor or payee is required"}
+	}
+	// A negative amount is a credit or refund from the vendor
+	if e.Amount == 0 {
+		return &ValidationError{Field: "amount", Message: "can't be zero"}
+	}
+	if e.Status != "paid" && e.Status != "not_paid" {
+		return &ValidationError{Field: "status", Message: "must be paid or not_paid"}
+	}
+	switch e.PaymentType {
+	case "", "cash", "check", "debit", "credit":
+	default:
+		return &ValidationError{Field: "payment_type", Message: "is not a valid payment type"}
+	}
+	return nil
+}
+
+// Balance returns what is still owed on the expense
+func (e Expense) Balance() float64 {
+	if e.Status == "paid" {
+		return 0
+	}
+	return math.Max(e.Amount-e.AmountPaid, 0)
+}
+
+// PaymentStatus returns "paid", "partial" when some but not all of it has been paid,
+// or "not_paid"
+func (e Expense) PaymentStatus() string {
+	switch {
+	case e.Status == "paid":
+		return "paid"
+	case e.AmountPaid >= 0.005:
+		return "partial"
+	default:
+		return "not_paid"
+	}
+}
+
+// ExpensePayment is one payment made against an expense, e.g. an installment
+type ExpensePayment struct {
+	ID          int64
+	ExpenseID   int64
+	Amount      float64 // 0 when adding one means the rest of the balance
+	Date        string  // YYYY-MM-DD; empty when adding one means today
+	PaymentType string  // "cash", "check", "debit", "credit"
+	CheckNumber string
+	CreatedAt   time.Time
+}
+
+// ExpenseCategoryVendorDefault clears an expense's own category so its vendor's categories apply
+const ExpenseCategoryVendorDefault = "vendor_default"
+
+// ExpenseBulkUpdate holds the fields to change on a set of expenses; empty fields are left alone
+type ExpenseBulkUpdate struct {
+	Category string // a VendorCategories entry, or ExpenseCategoryVendorDefault
+	Status   string
+	VendorID int64
+}
+
+// Validate checks the fields that are set
+func (u ExpenseBulkUpdate) Validate() error {
+	if u.Category != "" && u.Category != ExpenseCategoryVendorDefault && !isVendorCategory(u.Category) {
+		return &ValidationError{Field: "category", Message: fmt.Sprintf("%q is not a vendor category", u.Category)}
+	}
+	if u.Status != "" && u.Status != "paid" && u.Status != "not_paid" {
+		return &ValidationError{Field: "status", Message: "must be paid or not_paid"}
+	}
+	if u.VendorID < 0 {
+		return &ValidationError{Field: "vendor_id", Message: "is not valid"}
+	}
+	return nil
+}
+
 // PayrollWeek represents a payroll period (Monday-Sunday)
 type PayrollWeek struct {
 	ID          int64
@@ -161,7 +447,8 @@ type Payroll struct {
 	PeriodEnd     string // YYYY-MM-DD - populated by JOIN with payroll_weeks
 	TotalHours    float64
 	HourlyRate    float64
-	PaymentMethod string // "cash" or "check"
+	Withholding   float64 // taxes withheld from gross pay
+	PaymentMethod string  // "cash" or "check"
 	CheckNumber   string
 	Status        string // "paid" or "not_paid"
 	DatePaid      string // YYYY-MM-DD or empty
@@ -175,6 +462,34 @@ func (p Payroll) TotalPay() float64 {
 	return p.TotalHours * p.HourlyRate
 }
 
+// NetPay is gross pay less withholding
+func (p Payroll) NetPay() float64 {
+	return p.TotalPay() - p.Withholding
+}
+
+// OvertimeHours is the hours worked beyond a 40-hour week. Pay is straight time, so
+// these hours are already part of TotalPay
+func (p Payroll) OvertimeHours() float64 {
+	return max(p.TotalHours-40, 0)
+}
+
+// CheckUse is an expense or payroll entry already paid with a given check number
+type CheckUse struct {
+	Source string // "expense" or "payroll"
+	ID     int64
+	Payee  string
+	Date   string
+	Amount float64
+}
+
+// Link is the edit page of the record using the check
+func (c CheckUse) Link() string {
+	if c.Source == "payroll" {
+		return fmt.Sprintf("/payroll/entry/%d/edit", c.ID)
+	}
+	return fmt.Sprintf("/expenses/%d/edit", c.ID)
+}
+
 // WeeklyPayrollEntry combines an employee with their payroll for a specific week
 type WeeklyPayrollEntry struct {
 	Employee Employee
@@ -190,10 +505,16 @@ type PayrollWeekSummary struct {
 	PeriodEndDisplay   string
 	EmployeeCount      int
 	TotalHours         float64
-	TotalPay           float64
+	TotalPay           float64 // gross
+	TotalWithholding   float64
 	PaidCount          int
 }
 
+// TotalNetPay is the week's gross pay less withholding
+func (p PayrollWeekSummary) TotalNetPay() float64 {
+	return p.TotalPay - p.TotalWithholding
+}
+
 // AllPaid returns true if all employees are paid for this week
 func (p PayrollWeekSummary) AllPaid() bool {
 	return p.PaidCount == p.EmployeeCount
@@ -208,12 +529,174 @@ type Session struct {
 // Dashboard aggregates
 type DashboardData struct {
 	TodaySalesTotal     float64
+	WeekToDateSales     float64 // net sales and delivery net since Monday
+	MonthToDateSales    float64 // net sales and delivery net since the 1st
 	TodayExpensesTotal  float64
 	UnpaidExpensesTotal float64
 	UnpaidExpensesCount int
 	RecentSalesGrouped  []DateGroup
 	RecentSalesTotal    float64
 	UnpaidExpenses      []Expense
+	Setup               SetupProgress
+	FreshInstall        bool // nothing entered yet; the dashboard shows only onboarding
+	MonthToDate         MonthToDateSummary
+}
+
+// MonthToDateSummary holds the current month's totals behind the dashboard's profit estimate
+type MonthToDateSummary struct {
+	StartDate   string
+	EndDate     string
+	NetSales    float64
+	DeliveryNet float64
+	Expenses    float64
+	Payroll     float64
+}
+
+// SalesTrendPoint is one week or month on the sales trend chart
+type SalesTrendPoint struct {
+	Label     string // "Oct 12" for a week (its Monday), "Oct 2026" for a month
+	StartDate string // YYYY-MM-DD
+	EndDate   string // YYYY-MM-DD, inclusive
+	NetSales  float64
+}
+
+// Income returns dine-in net sales plus delivery net payouts
+func (s MonthToDateSummary) Income() float64 {
+	return s.NetSales + s.DeliveryNet
+}
+
+// ProfitEstimate returns income less expenses and payroll entered so far
+func (s MonthToDateSummary) ProfitEstimate() float64 {
+	return s.Income() - s.Expenses - s.Payroll
+}
+
+// ExpenseCategoryLine is one vendor category's expense total for a period
+type ExpenseCategoryLine struct {
+	Category string
+	Amount   float64
+}
+
+// MonthlyPL is a month's profit and loss: sales, expenses by vendor category and payroll
+type MonthlyPL struct {
+	Month         string // YYYY-MM
+	StartDate     string
+	EndDate       string
+	NetSales      float64               // dine-in net sales
+	DeliveryNet   float64               // delivery platform net payouts
+	Expenses      []ExpenseCategoryLine // one line per VendorCategories entry, in order
+	Uncategorized float64               // expenses with no known category
+	Payroll       float64               // weeks ending in the month
+}
+
+// VendorSpendLine is what was spent with one vendor, or one ad-hoc payee, over a period
+type VendorSpendLine struct {
+	VendorID int64 // 0 for a payee without a vendor record
+	Name     string
+	Count    int
+	Total    float64
+}
+
+// CategorySpendLine is what was spent under one vendor category over a period
+type CategorySpendLine struct {
+	Category string
+	Count    int
+	Total    float64
+}
+
+// VendorSpend breaks a period's expenses down by vendor and by vendor category.
+// A vendor with several categories counts in full toward each of them, so the
+// category totals can add up to more than Total
+type VendorSpend struct {
+	StartDate  string
+	EndDate    string
+	Vendors    []VendorSpendLine   // largest spend first
+	Categories []CategorySpendLine // largest spend first
+	Total      float64
+}
+
+// SalesTaxMonth is the sales tax collected in one calendar month of a report range
+type SalesTaxMonth struct {
+	Month        string // "Oct 2026"
+	StartDate    string // YYYY-MM-DD, the later of the month's first day and the range start
+	EndDate      string // YYYY-MM-DD, the earlier of the month's last day and the range end
+	Taxes        float64
+	RunningTotal float64 // taxes from the start of the range through this month
+	Filing       *TaxFiling
+}
+
+// Filed reports whether a filing covers the month
+func (m SalesTaxMonth) Filed() bool {
+	return m.Filing != nil
+}
+
+// TaxFiling records a sales tax return filed and paid for a period
+type TaxFiling struct {
+	ID          int64
+	PeriodStart string // YYYY-MM-DD
+	PeriodEnd   string // YYYY-MM-DD, inclusive
+	Amount      float64
+	PaidDate    string // YYYY-MM-DD
+	Notes       string
+	CreatedAt   time.Time
+}
+
+// TotalSales returns dine-in net sales plus delivery net payouts
+func (p MonthlyPL) TotalSales() float64 {
+	return p.NetSales + p.DeliveryNet
+}
+
+// TotalExpenses returns all expenses, categorized or not
+func (p MonthlyPL) TotalExpenses() float64 {
+	total := p.Uncategorized
+	for _, line := range p.Expenses {
+		total += line.Amount
+	}
+	return total
+}
+
+// NetProfit returns total sales less expenses and payroll
+func (p MonthlyPL) NetProfit() float64 {
+	return p.TotalSales() - p.TotalExpenses() - p.Payroll
+}
+
+// CashFlow is a month's money in against money out on a cash basis: only bills and
+// payroll actually paid during the month count as outflows
+type CashFlow struct {
+	Month       string // YYYY-MM
+	StartDate   string
+	EndDate     string
+	NetSales    float64 // dine-in net sales
+	DeliveryNet float64 // delivery platform net payouts
+	Expenses    float64 // expenses paid in the month
+	Payroll     float64 // payroll paid in the month
+}
+
+// In returns sales and delivery payouts
+func (c CashFlow) In() float64 {
+	return c.NetSales + c.DeliveryNet
+}
+
+// Out returns paid expenses and payroll
+func (c CashFlow) Out() float64 {
+	return c.Expenses + c.Payroll
+}
+
+// Net returns money in less money out
+func (c CashFlow) Net() float64 {
+	return c.In() - c.Out()
+}
+
+// SetupProgress tracks the first-run steps shown in the onboarding panel
+type SetupProgress struct {
+	HasVendors   bool
+	HasEmployees bool
+	HasSales     bool
+	PasswordSet  bool // HOMEBOOKS_PASSWORD is set instead of the default
+}
+
+// Complete reports whether every onboarding step is done
+func (s SetupProgress) Complete() bool {
+	return s.HasVendors && s.HasEmployees && s.HasSales && s.PasswordSet
 }
 
 // Filter structs for list queries
@@ -223,6 +706,11 @@ type ExpenseFilter struct {
 	Status     string
 	VendorID   int64
 	Categories []string // filter by vendor categories (multi-select)
+	Reconciled string   // "yes", "no" or "" for all
+	MinAmount  float64  // 0 means no lower bound
+	MaxAmount  float64  // 0 means no upper bound
+	SortBy     string   // date, amount, vendor or due_date; "" sorts by date
+	SortDir    string   // "asc" or "desc"; "" sorts descending
 }
 
 // HasCategory checks if a category is in the filter
@@ -247,6 +735,39 @@ type SalesFilter struct {
 	EndDate   string
 }
 
+// BankTransactionFilter narrows a statement review to one side, one match status and one page
+type BankTransactionFilter struct {
+	Direction   string // "credits", "debits" or "" for both
+	MatchStatus string // "" for any
+	Page        int    // 1-based
+	PerPage     int
+}
+
+// BankTransactionPage describes the page of a filtered statement review being shown
+type BankTransactionPage struct {
+	BankTransactionFilter
+	Total int // transactions matching the filter across all pages
+}
+
+// Pages returns the number of pages, at least 1
+func (p BankTransactionPage) Pages() int {
+	if p.PerPage <= 0 || p.Total <= p.PerPage {
+		return 1
+	}
+	return (p.Total + p.PerPage - 1) / p.PerPage
+}
+
+func (p BankTransactionPage) HasPrev() bool { return p.Page > 1 }
+func (p BankTransactionPage) HasNext() bool { return p.Page < p.Pages() }
+func (p BankTransactionPage) PrevPage() int { return p.Page - 1 }
+func (p BankTransactionPage) NextPage() int { return p.Page + 1 }
+
+// ShowCredits reports whether the deposits table is in view
+func (p BankTransactionPage) ShowCredits() bool { return p.Direction != "debits" }
+
+// ShowDebits reports whether the payments table is in view
+func (p BankTransactionPage) ShowDebits() bool { return p.Direction != "credits" }
+
 // DateGroup represents a single date's sales (for collapsing by date)
 type DateGroup struct {
 	Date      string         // Display date (MM-DD-YYYY)
@@ -254,9 +775,32 @@ type DateGroup struct {
 	Total     float64        // Sum of NetSales for this date
 	Sales     []DailySale    // Individual shift entries
 	Delivery  *DeliverySales // Delivery sales for this date (nil if none)
+	DayNote   string         // Context for the whole day, e.g. "snowstorm"
 	Collapsed bool           // Whether this date row is collapsed
 }
 
+// ShiftNetSales returns the net sales entered for one shift on this date
+func (g DateGroup) ShiftNetSales(shift string) float64 {
+	var total float64
+	for _, s := range g.Sales {
+		if s.Shift == shift {
+			total += s.NetSales
+		}
+	}
+	return total
+}
+
+// Totals sums taxes, credit card, cash on hand and variance across the date's shifts
+func (g DateGroup) Totals() (taxes, creditCard, cashOnHand, variance float64) {
+	for _, s := range g.Sales {
+		taxes += s.Taxes
+		creditCard += s.CreditCard
+		cashOnHand += s.CashOnHand
+		variance += s.Variance()
+	}
+	return taxes, creditCard, cashOnHand, variance
+}
+
 // SalesGroup represents a group of sales with a label and total
 type SalesGroup struct {
 	Label      string         // "Today", "This Week", "January 2025", "2024"
@@ -264,6 +808,7 @@ type SalesGroup struct {
 	Sales      []DailySale    // Individual entries (used for Today)
 	DateGroups []DateGroup    // Grouped by date (used for non-Today sections)
 	Delivery   *DeliverySales // Delivery data for Today section
+	DayNote    string         // Day note for Today section
 	Collapsed  bool           // Default collapsed state for UI
 }
 
@@ -276,6 +821,22 @@ type GroupedSalesData struct {
 }
 
 // BankReconciliation represents a bank statement reconciliation
+// Account is a bank account statements are uploaded for
+type Account struct {
+	ID        int64
+	Name      string
+	LastFour  string
+	CreatedAt time.Time
+}
+
+// Label returns the account name with its last four digits when known
+func (a Account) Label() string {
+	if a.LastFour == "" {
+		return a.Name
+	}
+	return fmt.Sprintf("%s (****%s)", a.Name, a.LastFour)
+}
+
 type BankReconciliation struct {
 	ID                   int64
 	StatementDate        string // YYYY-MM-DD
@@ -296,6 +857,116 @@ type BankReconciliation struct {
 	ElectronicPayments float64
 	ChecksPaid         float64
 	ServiceFees        float64
+	// Vendor prefilled when matching or creating receipts during review (0 = none)
+	DefaultVendorID int64
+	// Known difference from the statement ending balance (e.g. timing) and why it was accepted
+	DiscrepancyNotes    string
+	AcceptedDiscrepancy float64
+	// Account the statement was uploaded to (0 = unassigned) and the last four digits
+	// on file for it, which the parsed AccountLastFour is expected to match
+	AccountID               int64
+	AccountName             string
+	AccountExpectedLastFour string
+	// Starting balance plus the parsed transactions, and whether it landed on the
+	// statement ending balance when the statement was parsed
+	CalculatedEndingBalance float64
+	BalanceMatches          bool
+}
+
+// AccountMismatch reports whether the statement's account number differs from the
+// account it was uploaded to
+func (r BankReconciliation) AccountMismatch() bool {
+	return r.AccountLastFour != "" && r.AccountExpectedLastFour != "" && r.AccountLastFour != r.AccountExpectedLastFour
+}
+
+// ParsedShortfall returns how far the parsed transactions fall short of the statement
+// ending balance, i.e. the net amount of transactions the parser missed
+func (r BankReconciliation) ParsedShortfall() float64 {
+	return math.Round((r.EndingBalance-r.CalculatedEndingBalance)*100) / 100
+}
+
+// ParsedDiscrepancy returns the size of the parse-time difference regardless of direction
+func (r BankReconciliation) ParsedDiscrepancy() float64 {
+	return math.Abs(r.ParsedShortfall())
+}
+
+// BalanceTolerance is how far a reconciliation may be off the statement and still balance
+const BalanceTolerance = 0.01
+
+// BalanceCheck compares the balance computed from transactions with the statement ending balance
+type BalanceCheck struct {
+	CalculatedEnding float64
+	StatementEnding  float64
+	Accepted         float64 // accepted discrepancy recorded on the reconciliation
+}
+
+// BalanceCheck computes the ending balance from the starting balance and transaction totals
+// (debits as a positive amount)
+func (r BankReconciliation) BalanceCheck(credits, debits float64) BalanceCheck {
+	return BalanceCheck{
+		CalculatedEnding: r.StartingBalance + credits - debits,
+		StatementEnding:  r.EndingBalance,
+		Accepted:         r.AcceptedDiscrepancy,
+	}
+}
+
+// Difference returns the calculated ending balance minus the statement ending balance
+func (b BalanceCheck) Difference() float64 {
+	return math.Round((b.CalculatedEnding-b.StatementEnding)*100) / 100
+}
+
+// Remaining returns the difference not covered by the accepted discrepancy
+func (b BalanceCheck) Remaining() float64 {
+	return math.Round((b.Difference()-b.Accepted)*100) / 100
+}
+
+// Balanced reports whether the remaining difference is within tolerance
+func (b BalanceCheck) Balanced() bool {
+	return math.Abs(b.Remaining()) <= BalanceTolerance
+}
+
+// Bank transaction categories offered when correcting a parsed category, by direction
+var (
+	BankCreditCategories = []string{"income_cards", "income_delivery", "income_catering", "income_other", "refund", "transfer"}
+	BankDebitCategories  = []string{"expense", "expense_check", "fee", "transfer", "atm"}
+)
+
+// BankTransactionTypes are the transaction types a bank transaction can be given by hand
+var BankTransactionTypes = []string{"deposit", "ach", "refund", "credit", "check", "debit", "transfer", "fee", "withdrawal", "other"}
+
+// IsBankCategory reports whether cat is one of the bank transaction categories
+func IsBankCategory(cat string) bool {
+	for _, c := range BankCreditCategories {
+		if c == cat {
+			return true
+		}
+	}
+	for _, c := range BankDebitCategories {
+		if c == cat {
+			return true
+		}
+	}
+	return false
+}
+
+// StatComparison is one line of a side-by-side reconciliation comparison
+type StatComparison struct {
+	Label string
+	A     float64 // statement being compared
+	B     float64 // baseline statement
+}
+
+// Delta returns how much A moved from B
+func (c StatComparison) Delta() float64 {
+	return c.A - c.B
+}
+
+// DeltaPercent returns the change relative to B (0 when B is zero)
+func (c StatComparison) DeltaPercent() float64 {
+	if c.B == 0 {
+		return 0
+	}
+	return (c.A - c.B) / c.B * 100
 }
 
 // BankTransaction represents a single transaction from a bank statement
@@ -307,19 +978,126 @@ type BankTransaction struct {
 	Amount           float64 // negative for debits, positive for credits
 	TransactionType  string  // deposit, check, debit, ach, fee, transfer
 	Category         string  // income_cards, income_delivery, expense, fee, transfer
+	Platform         string  // delivery platform for income_delivery, a DeliveryPlatforms key
 	CheckNumber      string
 	VendorHint       string // extracted vendor name
 	ReferenceNumber  string
 	MatchedExpenseID *int64
-	MatchStatus      string // unmatched, matched, ignored, created
+	MatchStatus      string // unmatched, matched, ignored, created, personal
 	MatchConfidence  string // auto_exact, auto_fuzzy, manual
 	MatchedAt        *time.Time
 	Notes            string
 	CreatedAt        time.Time
 
 	// Joined fields for display
-	MatchedExpenseVendor string
-	MatchedExpenseDate   string
+	MatchedExpenseVendor  string
+	MatchedExpenseDate    string
+	MatchedExpenseDeleted bool // the matched expense is in the trash or gone, so the match is broken
+}
+
+// ReconciliationAction is a review action on a bank transaction along with the state it
+// replaced, so the most recent one can be undone
+type ReconciliationAction struct {
+	ID               int64
+	ReconciliationID int64
+	TransactionID    int64
+	Action           string // match, unmatch, ignore, personal, create
+	ExpenseID        int64  // expense matched or created by the action (0 = none)
+	PriorStatus      string
+	PriorExpenseID   *int64
+	PriorConfidence  string
+	PriorNotes       string
+	PriorMatchedAt   *time.Time
+	CreatedAt        time.Time
+
+	// Joined fields for display
+	TransactionDescription string
+	TransactionAmount      float64
+}
+
+// Label describes the action for the undo button, e.g. "ignore"
+func (a ReconciliationAction) Label() string {
+	switch a.Action {
+	case "create":
+		return "create expense"
+	case "personal":
+		return "mark personal"
+	default:
+		return a.Action
+	}
+}
+
+// AutoBookingRule turns recurring statement lines into expenses without review
+type AutoBookingRule struct {
+	ID         int64
+	VendorID   int64
+	VendorName string // joined for display
+	Category   string // expense category override (empty = vendor default)
+	Pattern    string // matched case-insensitively against vendor hint or description
+	CreatedAt  time.Time
+}
+
+// Matches reports whether the rule applies to a bank transaction
+func (r AutoBookingRule) Matches(txn BankTransaction) bool {
+	pattern := strings.ToLower(strings.TrimSpace(r.Pattern))
+	if pattern == "" {
+		return false
+	}
+	return strings.Contains(strings.ToLower(txn.VendorHint), pattern) ||
+		strings.Contains(strings.ToLower(txn.Description), pattern)
+}
+
+// RecurringExpense is a monthly bill that generates a not-paid expense each month
+type RecurringExpense struct {
+	ID          int64
+	VendorID    int64
+	VendorName  string // joined for display
+	Amount      float64
+	DayOfMonth  int    // 1-31, clamped to the month's last day
+	PaymentType string // cash, check, debit, credit or ""
+	CreatedAt   time.Time
+}
+
+// DueDate returns the date the expense falls due in the month containing t (YYYY-MM-DD).
+// A day past the end of the month, e.g. 31 in April, falls on the last day
+func (r RecurringExpense) DueDate(t time.Time) string {
+	lastDay := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	day := min(r.DayOfMonth, lastDay)
+	return time.Date(t.Year(), t.Month(), day, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+}
+
+// Expense builds the draft not-paid expense for the month containing t
+func (r RecurringExpense) Expense(t time.Time) Expense {
+	due := r.DueDate(t)
+	return Expense{
+		Date:        due,
+		VendorID:    r.VendorID,
+		Amount:      r.Amount,
+		Status:      "not_paid",
+		PaymentType: r.PaymentType,
+		DueDate:     due,
+		Notes:       "Generated from recurring expense",
+	}
+}
+
+// DeliveryPayoutCheck compares a delivery platform's bank deposit to the net sales
+// recorded for that platform over the payout period
+type DeliveryPayoutCheck struct {
+	Transaction BankTransaction
+	Platform    string
+	PeriodStart string // YYYY-MM-DD
+	PeriodEnd   string // YYYY-MM-DD
+	RecordedNet float64
+}
+
+// Difference returns the deposit minus the recorded net (positive = bank received more)
+func (c DeliveryPayoutCheck) Difference() float64 {
+	return c.Transaction.Amount - c.RecordedNet
+}
+
+// Matched reports whether the deposit agrees with recorded sales to within a dollar
+func (c DeliveryPayoutCheck) Matched() bool {
+	return c.RecordedNet > 0 && c.Difference() >= -1 && c.Difference() <= 1
 }
 
 // MonthOption represents a month available for reconciliation
@@ -342,4 +1120,7 @@ type Job struct {
 	CreatedAt   time.Time
 	StartedAt   *time.Time
 	CompletedAt *time.Time
+	NextRunAt   *time.Time // earliest time a pending job is claimed; nil means now
+
+	CancelRequested bool
 }
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestJoinVendorCategories(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    string
+		wantErr bool
+	}{
+		{"none", nil, "", false},
+		{"single", []string{"Food"}, "Food", false},
+		{"several in form order", []string{"Meat", "Food"}, "Meat,Food", false},
+		{"surrounding spaces trimmed", []string{"  Food ", "\tMeat"}, "Food,Meat", false},
+		{"duplicates dropped", []string{"Food", "Food", " Food"}, "Food", false},
+		{"blanks dropped", []string{"", " ", "Paper"}, "Paper", false},
+		{"embedded comma", []string{"Food,Meat"}, "", true},
+		{"comma with known parts", []string{"Food, Meat"}, "", true},
+		{"unknown category", []string{"Groceries"}, "", true},
+		{"unknown after known", []string{"Food", "Groceries"}, "", true},
+		{"wrong case", []string{"food"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := JoinVendorCategories(tt.values)
+			if tt.wantErr {
+				var ve *ValidationError
+				if !errors.As(err, &ve) || ve.Field != "category" {
+					t.Fatalf("JoinVendorCategories(%q) error = %v, want a category ValidationError", tt.values, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("JoinVendorCategories(%q): %v", tt.values, err)
+			}
+			if got != tt.want {
+				t.Errorf("JoinVendorCategories(%q) = %q, want %q", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVendorHasCategory(t *testing.T) {
+	v := Vendor{Category: "Food,Meat"}
+	for _, cat := range []string{"Food", "Meat"} {
+		if !v.HasCategory(cat) {
+			t.Errorf("HasCategory(%q) = false, want true", cat)
+		}
+	}
+	for _, cat := range []string{"Foo", "Food,Meat", ""} {
+		if v.HasCategory(cat) {
+			t.Errorf("HasCategory(%q) = true, want false", cat)
+		}
+	}
+}
+
+func TestExpenseValidate(t *testing.T) {
+	valid := Expense{Date: "2026-10-01", PayeeName: "Jetro", Amount: 25, Status: "not_paid"}
+	tests := []struct {
+		name      string
+		edit      func(e *Expense)
+		wantField string
+	}{
+		{"valid", func(e *Expense) {}, ""},
+		{"vendor instead of payee", func(e *Expense) { e.PayeeName, e.VendorID = "", 3 }, ""},
+		{"credit from the vendor", func(e *Expense) { e.Amount = -12.50 }, ""},
+		{"zero amount", func(e *Expense) { e.Amount = 0 }, "amount"},
+		{"no date", func(e *Expense) { e.Date = "" }, "date"},
+		{"no vendor or payee", func(e *Expense) { e.PayeeName = " " }, "vendor_id"},
+		{"unknown status", func(e *Expense) { e.Status = "partial" }, "status"},
+		{"unknown payment type", func(e *Expense) { e.PaymentType = "venmo" }, "payment_type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := valid
+			tt.edit(&e)
+			err := e.Validate()
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) || ve.Field != tt.wantField {
+				t.Fatalf("Validate() = %v, want a %s ValidationError", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	err := &ValidationError{Field: "vendor_id", Message: "a vendor or payee is required"}
+	if got, want := err.Error(), "vendor_id: a vendor or payee is required"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestExpenseBulkUpdateValidate(t *testing.T) {
+	tests := []struct {
+		update  ExpenseBulkUpdate
+		wantErr bool
+	}{
+		{ExpenseBulkUpdate{Category: "Food"}, false},
+		{ExpenseBulkUpdate{Category: ExpenseCategoryVendorDefault}, false},
+		{ExpenseBulkUpdate{Status: "not_paid", VendorID: 4}, false},
+		{ExpenseBulkUpdate{Category: "Groceries"}, true},
+		{ExpenseBulkUpdate{Status: "void"}, true},
+		{ExpenseBulkUpdate{VendorID: -1}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.update.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%+v.Validate() = %v, want error %v", tt.update, err, tt.wantErr)
+		}
+	}
+}
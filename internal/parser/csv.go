@@ -0,0 +1,321 @@
+package parser
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// CSVParser parses bank statements exported as CSV. Columns are mapped by header
+// name, so any export with a date, a description and either a signed amount or
+// separate debit/credit columns works
+type CSVParser struct {
+	debug bool
+}
+
+// NewCSVParser creates a new CSV statement parser
+func NewCSVParser() *CSVParser {
+	return &CSVParser{
+		debug: true, // Enable debug output
+	}
+}
+
+// csvColumns holds the index of each recognized column, -1 when absent
+type csvColumns struct {
+	date, description, amount, debit, credit, checkNumber, txnType, account int
+}
+
+// Header names recognized for each column, compared lower-cased and trimmed
+var csvHeaders = map[string][]string{
+	"date":        {"date", "posting date", "posted date", "transaction date", "post date"},
+	"description": {"description", "memo", "payee", "details", "transaction description", "name"},
+	"amount":      {"amount", "transaction amount"},
+	"debit":       {"debit", "debits", "withdrawal", "withdrawals", "debit amount"},
+	"credit":      {"credit", "credits", "deposit", "deposits", "credit amount"},
+	"checkNumber": {"check number", "check", "check #", "check no", "check no."},
+	"txnType":     {"transaction type", "type"},
+	"account":     {"account number", "account"},
+}
+
+// Date layouts tried in order when reading the date column
+var csvDateLayouts = []string{
+	"2006-01-02",
+	"01/02/2006",
+	"1/2/2006",
+	"01/02/06",
+	"1/2/06",
+	"01-02-2006",
+	"Jan 2, 2006",
+	"January 2, 2006",
+}
+
+// Parse reads a CSV statement export
+func (p *CSVParser) Parse(path string) (*ParsedStatement, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open csv: %w", err)
+	}
+	defer f.Close()
+
+	return p.ParseReader(f)
+}
+
+// ParseReader parses CSV statement content from r
+func (p *CSVParser) ParseReader(r io.Reader) (*ParsedStatement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read csv: %w", err)
+	}
+	text := strings.TrimPrefix(string(data), "\ufeff") // Excel adds a byte order mark
+
+	delim := sniffDelimiter(text)
+	p.debugLog("Detected delimiter %q", delim)
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = delim
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read csv: %w", err)
+	}
+
+	// Some exports put a title or account line above the header, so look for it
+	headerRow := -1
+	var cols csvColumns
+	for i, record := range records {
+		if c, ok := mapCSVHeader(record); ok {
+			headerRow, cols = i, c
+			break
+		}
+	}
+	if headerRow == -1 {
+		return nil, fmt.Errorf("no header row with date, description and amount columns")
+	}
+
+	stmt := &ParsedStatement{}
+	for i, record := range records[headerRow+1:] {
+		txn, ok := p.parseRecord(record, cols)
+		if !ok {
+			if strings.TrimSpace(strings.Join(record, "")) != "" {
+				p.debugLog("Skipping row %d: %q", headerRow+i+2, truncateString(strings.Join(record, string(delim)), 80))
+			}
+			continue
+		}
+		stmt.Transactions = append(stmt.Transactions, txn)
+
+		if stmt.AccountLastFour == "" && cols.account >= 0 {
+			stmt.AccountLastFour = lastFourDigits(csvField(record, cols.account))
+		}
+		if month := txn.PostingDate[:7]; month > stmt.StatementMonth {
+			stmt.StatementMonth = month
+		}
+	}
+
+	if len(stmt.Transactions) == 0 {
+		return nil, ErrNoTransactions
+	}
+
+	// CSV exports carry no summary section, so the totals come from the rows themselves
+	summarizeTransactions(stmt)
+
+	p.debugLog("Total transactions: %d", len(stmt.Transactions))
+	p.verifyTotals(stmt)
+
+	return stmt, nil
+}
+
+// parseRecord converts one data row, reporting false for rows that are not transactions
+func (p *CSVParser) parseRecord(record []string, cols csvColumns) (ParsedTransaction, bool) {
+	date := parseCSVDate(csvField(record, cols.date))
+	if date == "" {
+		return ParsedTransaction{}, false
+	}
+
+	var amount float64
+	var hasAmount bool
+	if cols.amount >= 0 {
+		amount, hasAmount = parseCSVAmount(csvField(record, cols.amount))
+	} else {
+		// Debit and credit columns are usually both unsigned
+		if debit, ok := parseCSVAmount(csvField(record, cols.debit)); ok && debit != 0 {
+			amount, hasAmount = -math.Abs(debit), true
+		}
+		if credit, ok := parseCSVAmount(csvField(record, cols.credit)); ok && credit != 0 {
+			amount, hasAmount = math.Abs(credit), true
+		}
+	}
+	if !hasAmount || amount == 0 {
+		return ParsedTransaction{}, false
+	}
+
+	txn := ParsedTransaction{
+		PostingDate: date,
+		Description: strings.Join(strings.Fields(csvField(record, cols.description)), " "),
+		Amount:      amount,
+		CheckNumber: strings.TrimLeft(csvField(record, cols.checkNumber), "0"),
+	}
+	txn.TransactionType = csvTransactionType(txn, csvField(record, cols.txnType))
+
+	txn.Category = categorizeTransaction(txn.TransactionType, txn.Description, txn.Amount)
+	txn.Platform = transactionPlatform(txn.Category, txn.Description)
+	txn.VendorHint = extractVendorHint(txn.Description)
+
+	return txn, true
+}
+
+// verifyTotals logs parsed totals by type. CSV exports have no beginning or ending
+// balance, so there is nothing to diff against
+func (p *CSVParser) verifyTotals(stmt *ParsedStatement) {
+	p.debugLog("Verification (parsed totals, no statement balances in CSV):")
+	p.debugLog("  Deposits: %.2f", stmt.ElectronicDeposits)
+	p.debugLog("  Payments: %.2f", stmt.ElectronicPayments)
+	p.debugLog("  Checks:   %.2f", stmt.ChecksPaid)
+	p.debugLog("  Fees:     %.2f", stmt.ServiceFees)
+}
+
+// debugLog prints debug output if debug mode is enabled
+func (p *CSVParser) debugLog(format string, args ...interface{}) {
+	if p.debug {
+		log.Printf("[CSVParser] "+format, args...)
+	}
+}
+
+// sniffDelimiter picks the delimiter that appears most often on the first non-empty
+// line, ignoring anything inside quotes
+func sniffDelimiter(text string) rune {
+	var line string
+	for _, l := range strings.Split(text, "\n") {
+		if strings.TrimSpace(l) != "" {
+			line = l
+			break
+		}
+	}
+
+	counts := map[rune]int{}
+	inQuotes := false
+	for _, c := range line {
+		switch c {
+		case '"':
+			inQuotes = !inQuotes
+		case ',', ';', '\t', '|':
+			if !inQuotes {
+				counts[c]++
+			}
+		}
+	}
+
+	best := ','
+	for _, c := range []rune{';', '\t', '|'} {
+		if counts[c] > counts[best] {
+			best = c
+		}
+	}
+	return best
+}
+
+// mapCSVHeader reports whether record is a header row and where each column is
+func mapCSVHeader(record []string) (csvColumns, bool) {
+	cols := csvColumns{-1, -1, -1, -1, -1, -1, -1, -1}
+	targets := map[string]*int{
+		"date":        &cols.date,
+		"description": &cols.description,
+		"amount":      &cols.amount,
+		"debit":       &cols.debit,
+		"credit":      &cols.credit,
+		"checkNumber": &cols.checkNumber,
+		"txnType":     &cols.txnType,
+		"account":     &cols.account,
+	}
+
+	for i, cell := range record {
+		name := strings.ToLower(strings.TrimSpace(cell))
+		for field, names := range csvHeaders {
+			if *targets[field] != -1 {
+				continue
+			}
+			for _, n := range names {
+				if name == n {
+					*targets[field] = i
+				}
+			}
+		}
+	}
+
+	hasAmount := cols.amount >= 0 || (cols.debit >= 0 && cols.credit >= 0)
+	return cols, cols.date >= 0 && cols.description >= 0 && hasAmount
+}
+
+// csvTransactionType infers the transaction type from the bank's type column when
+// present, then from the description and the sign of the amount
+func csvTransactionType(txn ParsedTransaction, bankType string) string {
+	descUpper := strings.ToUpper(txn.Description)
+	bankType = strings.ToUpper(bankType)
+
+	if txn.Amount > 0 {
+		return "deposit"
+	}
+	if txn.CheckNumber != "" || strings.Contains(bankType, "CHECK") || strings.HasPrefix(descUpper, "CHECK") {
+		return "check"
+	}
+	if strings.Contains(bankType, "FEE") || strings.Contains(descUpper, "SERVICE CHARGE") ||
+		strings.Contains(descUpper, "MAINTENANCE FEE") || strings.HasSuffix(descUpper, " FEE") {
+		return "fee"
+	}
+	return "debit"
+}
+
+func csvField(record []string, idx int) string {
+	if idx < 0 || idx >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[idx])
+}
+
+// parseCSVDate converts any of csvDateLayouts to YYYY-MM-DD, or "" if none match
+func parseCSVDate(s string) string {
+	for _, layout := range csvDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format("2006-01-02")
+		}
+	}
+	return ""
+}
+
+// parseCSVAmount reads "1,234.56", "-$1,234.56" or "(1,234.56)", reporting false when blank
+func parseCSVAmount(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = strings.Trim(s, "()")
+	}
+	amount := parseAmount(s)
+	if negative {
+		amount = -amount
+	}
+	return amount, true
+}
+
+func lastFourDigits(s string) string {
+	var digits []rune
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits) < 4 {
+		return ""
+	}
+	return string(digits[len(digits)-4:])
+}
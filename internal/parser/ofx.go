@@ -0,0 +1,201 @@
+package parser
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+)
+
+// OFXParser parses OFX and Quicken (.qfx) downloads. Both the SGML flavour (OFX 1.x,
+// where leaf elements have no closing tag) and the XML flavour (OFX 2.x) are read
+type OFXParser struct {
+	debug bool
+}
+
+// NewOFXParser creates a new OFX/QFX parser
+func NewOFXParser() *OFXParser {
+	return &OFXParser{
+		debug: true, // Enable debug output
+	}
+}
+
+// ofxTransactionTypes maps OFX TRNTYPE codes to our transaction types. Codes not
+// listed here (OTHER, and anything bank specific) fall back to the amount's sign
+var ofxTransactionTypes = map[string]string{
+	"CREDIT":      "deposit",
+	"DEP":         "deposit",
+	"DIRECTDEP":   "deposit",
+	"INT":         "credit",
+	"DIV":         "credit",
+	"DEBIT":       "debit",
+	"POS":         "debit",
+	"PAYMENT":     "debit",
+	"DIRECTDEBIT": "debit",
+	"REPEATPMT":   "debit",
+	"CHECK":       "check",
+	"FEE":         "fee",
+	"SRVCHG":      "fee",
+	"XFER":        "transfer",
+	"ATM":         "withdrawal",
+	"CASH":        "withdrawal",
+}
+
+// Parse reads an OFX or QFX file
+func (p *OFXParser) Parse(path string) (*ParsedStatement, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read ofx: %w", err)
+	}
+	return p.ParseText(string(data))
+}
+
+// ParseText parses OFX content, ignoring the SGML header block before <OFX>
+func (p *OFXParser) ParseText(text string) (*ParsedStatement, error) {
+	start := strings.Index(strings.ToUpper(text), "<OFX>")
+	if start == -1 {
+		return nil, fmt.Errorf("no <OFX> element found")
+	}
+
+	stmt := &ParsedStatement{}
+	var txn map[string]string
+	var ledgerBal, availBal string
+	var inLedger, inAvail bool
+
+	for _, el := range ofxElements(text[start:]) {
+		switch {
+		case el.tag == "STMTTRN":
+			txn = map[string]string{}
+		case el.tag == "/STMTTRN":
+			if t, ok := p.buildTransaction(txn); ok {
+				stmt.Transactions = append(stmt.Transactions, t)
+			}
+			txn = nil
+		case txn != nil:
+			txn[el.tag] = el.value
+		case el.tag == "LEDGERBAL", el.tag == "/LEDGERBAL":
+			inLedger = el.tag == "LEDGERBAL"
+		case el.tag == "AVAILBAL", el.tag == "/AVAILBAL":
+			inAvail = el.tag == "AVAILBAL"
+		case el.tag == "BALAMT" && inLedger:
+			ledgerBal = el.value
+		case el.tag == "BALAMT" && inAvail:
+			availBal = el.value
+		case el.tag == "ACCTID" && stmt.AccountLastFour == "":
+			stmt.AccountLastFour = lastFourDigits(el.value)
+		case el.tag == "DTEND":
+			if date := ofxDate(el.value); date != "" {
+				stmt.StatementMonth = date[:7]
+			}
+		}
+	}
+
+	if len(stmt.Transactions) == 0 {
+		return nil, ErrNoTransactions
+	}
+
+	// OFX only reports the balance at download time, so the beginning balance is
+	// worked back from it
+	balance := ledgerBal
+	if balance == "" {
+		balance = availBal
+	}
+	if balance != "" {
+		var net float64
+		for _, t := range stmt.Transactions {
+			net += t.Amount
+		}
+		stmt.EndingBalance = parseAmount(balance)
+		stmt.BeginningBalance = math.Round((stmt.EndingBalance-net)*100) / 100
+	}
+
+	summarizeTransactions(stmt)
+
+	p.debugLog("Header parsed: Account=%s, Beginning=%.2f, Ending=%.2f",
+		stmt.AccountLastFour, stmt.BeginningBalance, stmt.EndingBalance)
+	p.debugLog("Total transactions: %d", len(stmt.Transactions))
+
+	return stmt, nil
+}
+
+// buildTransaction converts the fields of one STMTTRN record
+func (p *OFXParser) buildTransaction(fields map[string]string) (ParsedTransaction, bool) {
+	date := ofxDate(fields["DTPOSTED"])
+	amount := parseAmount(fields["TRNAMT"])
+	if date == "" || amount == 0 {
+		p.debugLog("Skipping transaction %q: missing date or amount", fields["FITID"])
+		return ParsedTransaction{}, false
+	}
+
+	description := fields["NAME"]
+	if memo := fields["MEMO"]; memo != "" && memo != description {
+		description = strings.TrimSpace(description + " " + memo)
+	}
+
+	txn := ParsedTransaction{
+		PostingDate:     date,
+		Description:     strings.Join(strings.Fields(description), " "),
+		Amount:          amount,
+		CheckNumber:     strings.TrimLeft(fields["CHECKNUM"], "0"),
+		ReferenceNumber: fields["FITID"],
+	}
+
+	txn.TransactionType = ofxTransactionTypes[strings.ToUpper(fields["TRNTYPE"])]
+	if txn.TransactionType == "" {
+		txn.TransactionType = "debit"
+		if amount > 0 {
+			txn.TransactionType = "deposit"
+		}
+	}
+
+	txn.Category = categorizeTransaction(txn.TransactionType, txn.Description, txn.Amount)
+	if txn.TransactionType == "transfer" {
+		txn.Category = "transfer"
+	}
+	txn.Platform = transactionPlatform(txn.Category, txn.Description)
+	txn.VendorHint = extractVendorHint(txn.Description)
+
+	return txn, true
+}
+
+// debugLog prints debug output if debug mode is enabled
+func (p *OFXParser) debugLog(format string, args ...interface{}) {
+	if p.debug {
+		log.Printf("[OFXParser] "+format, args...)
+	}
+}
+
+// ofxElement is one tag and the text that follows it, e.g. <TRNAMT>-12.50
+type ofxElement struct {
+	tag   string
+	value string
+}
+
+// ofxElements splits OFX into its tags in document order. Closing tags come back
+// as "/TAG" so aggregates like STMTTRN can be tracked; values are unescaped
+func ofxElements(text string) []ofxElement {
+	var elements []ofxElement
+	for _, chunk := range strings.Split(text, "<")[1:] {
+		tag, value, ok := strings.Cut(chunk, ">")
+		if !ok {
+			continue
+		}
+		value = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">").Replace(strings.TrimSpace(value))
+		elements = append(elements, ofxElement{tag: strings.ToUpper(strings.TrimSpace(tag)), value: value})
+	}
+	return elements
+}
+
+// ofxDate converts an OFX datetime such as 20240115120000[-5:EST] to YYYY-MM-DD
+func ofxDate(s string) string {
+	if len(s) < 8 {
+		return ""
+	}
+	for _, c := range s[:8] {
+		if c < '0' || c > '9' {
+			return ""
+		}
+	}
+	return s[:4] + "-" + s[4:6] + "-" + s[6:8]
+}
@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoTransactions is returned when a statement parses without a single
+// transaction, which almost always means the layout wasn't recognized
+var ErrNoTransactions = errors.New("no transactions found")
+
+// StatementParser turns a statement file into a ParsedStatement
+type StatementParser interface {
+	Parse(path string) (*ParsedStatement, error)
+}
+
+// ForFile returns the parser for a statement file based on its extension
+func ForFile(path string, opts Options) (StatementParser, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf":
+		return NewTDBankParser(opts), nil
+	case ".csv":
+		return NewCSVParser(), nil
+	case ".ofx", ".qfx":
+		return NewOFXParser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported statement file type %q", filepath.Ext(path))
+	}
+}
+
+// HasBalances reports whether the statement carried beginning and ending balances.
+// CSV exports usually don't, so there is nothing to verify the transactions against
+func (s *ParsedStatement) HasBalances() bool {
+	return s.BeginningBalance != 0 || s.EndingBalance != 0
+}
+
+// summarizeTransactions fills the summary totals from the parsed transactions, for
+// formats that don't carry an account summary of their own
+func summarizeTransactions(stmt *ParsedStatement) {
+	for _, txn := range stmt.Transactions {
+		switch txn.TransactionType {
+		case "deposit":
+			stmt.ElectronicDeposits += txn.Amount
+		case "credit":
+			stmt.OtherCredits += txn.Amount
+		case "debit":
+			stmt.ElectronicPayments += -txn.Amount
+		case "check":
+			stmt.ChecksPaid += -txn.Amount
+		case "fee":
+			stmt.ServiceFees += -txn.Amount
+		case "withdrawal", "transfer":
+			if txn.Amount < 0 {
+				stmt.OtherWithdrawals += -txn.Amount
+			} else {
+				stmt.OtherCredits += txn.Amount
+			}
+		}
+	}
+}
+
+// checkBalance warns when the beginning balance plus the parsed transactions doesn't
+// land on the statement's ending balance
+func checkBalance(stmt *ParsedStatement) {
+	if !stmt.HasBalances() {
+		return
+	}
+	calculated := stmt.BeginningBalance
+	for _, txn := range stmt.Transactions {
+		calculated += txn.Amount
+	}
+	if offByMoreThanACent(calculated, stmt.EndingBalance) {
+		stmt.Warnings = append(stmt.Warnings, fmt.Sprintf("Calculated ending balance $%.2f does not match the statement ending balance $%.2f",
+			calculated, stmt.EndingBalance))
+	}
+}
+
+func offByMoreThanACent(a, b float64) bool {
+	return math.Abs(a-b) > 0.011
+}
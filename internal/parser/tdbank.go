@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // ParsedStatement represents a fully parsed bank statement
@@ -24,6 +25,9 @@ type ParsedStatement struct {
 	ServiceFees        float64
 	OtherCredits       float64
 	OtherWithdrawals   float64
+	// Warnings lists anything that suggests the parse is incomplete, e.g. section
+	// subtotals that don't match the statement summary
+	Warnings []string
 }
 
 // ParsedTransaction represents a single parsed transaction
@@ -33,22 +37,32 @@ type ParsedTransaction struct {
 	Amount          float64 // Negative for debits, positive for credits
 	TransactionType string  // deposit, credit, check, debit, ach, fee, transfer, withdrawal
 	Category        string  // income_cards, income_delivery, expense, fee, transfer, etc.
+	Platform        string  // Delivery platform for income_delivery (grubhub, doordash, ubereats, ...)
 	CheckNumber     string  // For checks only
 	VendorHint      string  // Extracted vendor name (best guess)
 	ReferenceNumber string  // Any reference/confirmation numbers
 }
 
+// Options configures a statement parser
+type Options struct {
+	// FallbackMonth (YYYY-MM) dates transactions when the statement period can't be
+	// read, usually the month picked at upload. Empty means no fallback
+	FallbackMonth string
+}
+
 // TDBankParser parses TD Bank PDF statements
 type TDBankParser struct {
 	statementYear  int
 	statementMonth int
+	fallbackMonth  string
 	debug          bool
 }
 
 // NewTDBankParser creates a new TD Bank parser
-func NewTDBankParser() *TDBankParser {
+func NewTDBankParser(opts Options) *TDBankParser {
 	return &TDBankParser{
-		debug: true, // Enable debug output
+		fallbackMonth: opts.FallbackMonth,
+		debug:         true, // Enable debug output
 	}
 }
 
@@ -62,6 +76,11 @@ func (p *TDBankParser) Parse(pdfPath string) (*ParsedStatement, error) {
 
 	p.debugLog("Extracted %d characters from PDF", len(text))
 
+	return p.ParseText(text)
+}
+
+// ParseText parses statement text as produced by `pdftotext -layout`
+func (p *TDBankParser) ParseText(text string) (*ParsedStatement, error) {
 	// Truncate at check images (pages 9+) - they start with #XXXX patterns
 	text = p.truncateAtCheckImages(text)
 	p.debugLog("After truncation: %d characters", len(text))
@@ -76,7 +95,7 @@ func (p *TDBankParser) Parse(pdfPath string) (*ParsedStatement, error) {
 		stmt.AccountLastFour, stmt.BeginningBalance, stmt.EndingBalance)
 
 	// Parse summary totals from ACCOUNT SUMMARY section
-	p.parseSummaryTotals(text, stmt)
+	hasSummary := p.parseSummaryTotals(text, stmt)
 
 	// Split into sections and parse each
 	sections := p.splitSections(text)
@@ -119,19 +138,28 @@ func (p *TDBankParser) Parse(pdfPath string) (*ParsedStatement, error) {
 	stmt.Transactions = append(stmt.Transactions, fees...)
 
 	p.debugLog("Total transactions: %d", len(stmt.Transactions))
+	if len(stmt.Transactions) == 0 {
+		return nil, ErrNoTransactions
+	}
 
 	// Verify parsed totals against summary
-	p.verifyTotals(stmt)
+	if hasSummary {
+		p.verifyTotals(stmt)
+	} else {
+		stmt.Warnings = append(stmt.Warnings, "Account summary not found; section totals were not verified")
+	}
+	checkBalance(stmt)
 
 	return stmt, nil
 }
 
-// parseSummaryTotals extracts the summary totals from ACCOUNT SUMMARY section
-func (p *TDBankParser) parseSummaryTotals(text string, stmt *ParsedStatement) {
+// parseSummaryTotals extracts the summary totals from ACCOUNT SUMMARY section,
+// reporting false when the section is missing
+func (p *TDBankParser) parseSummaryTotals(text string, stmt *ParsedStatement) bool {
 	// Find ACCOUNT SUMMARY section
 	summaryIdx := strings.Index(text, "ACCOUNT SUMMARY")
 	if summaryIdx == -1 {
-		return
+		return false
 	}
 
 	// Get a chunk of text after ACCOUNT SUMMARY (the summary table)
@@ -161,9 +189,11 @@ func (p *TDBankParser) parseSummaryTotals(text string, stmt *ParsedStatement) {
 
 	p.debugLog("Summary totals: Deposits=%.2f, Payments=%.2f, Checks=%.2f, Fees=%.2f",
 		stmt.ElectronicDeposits, stmt.ElectronicPayments, stmt.ChecksPaid, stmt.ServiceFees)
+	return true
 }
 
-// verifyTotals compares parsed transaction totals against summary totals
+// verifyTotals compares parsed transaction totals against summary totals, adding a
+// warning for each section that is off by more than a cent
 func (p *TDBankParser) verifyTotals(stmt *ParsedStatement) {
 	var depositSum, paymentSum, checkSum, feeSum, creditSum, withdrawalSum float64
 
@@ -191,6 +221,24 @@ func (p *TDBankParser) verifyTotals(stmt *ParsedStatement) {
 	p.debugLog("  Fees:        %.2f vs %.2f (diff: %.2f)", feeSum, stmt.ServiceFees, feeSum-stmt.ServiceFees)
 	p.debugLog("  Credits:     %.2f vs %.2f (diff: %.2f)", creditSum, stmt.OtherCredits, creditSum-stmt.OtherCredits)
 	p.debugLog("  Withdrawals: %.2f vs %.2f (diff: %.2f)", withdrawalSum, stmt.OtherWithdrawals, withdrawalSum-stmt.OtherWithdrawals)
+
+	sections := []struct {
+		name             string
+		parsed, expected float64
+	}{
+		{"Electronic Deposits", depositSum, stmt.ElectronicDeposits},
+		{"Electronic Payments", paymentSum, stmt.ElectronicPayments},
+		{"Checks Paid", checkSum, stmt.ChecksPaid},
+		{"Service Charges", feeSum, stmt.ServiceFees},
+		{"Other Credits", creditSum, stmt.OtherCredits},
+		{"Other Withdrawals", withdrawalSum, stmt.OtherWithdrawals},
+	}
+	for _, s := range sections {
+		if offByMoreThanACent(s.parsed, s.expected) {
+			stmt.Warnings = append(stmt.Warnings, fmt.Sprintf("%s: parsed lines total $%.2f but the statement summary shows $%.2f",
+				s.name, s.parsed, s.expected))
+		}
+	}
 }
 
 // debugLog prints debug output if debug mode is enabled
@@ -239,17 +287,36 @@ func (p *TDBankParser) truncateAtCheckImages(text string) string {
 // Regex patterns for parsing
 var (
 	periodPattern           = regexp.MustCompile(`Statement Period:\s+(\w+)\s+\d+\s+(\d{4})`)
-	accountPattern          = regexp.MustCompile(`Account\s*#[:\s]+[\d-]*(\d{4})`)
+	accountPattern          = regexp.MustCompile(`(?i)Account\s*(?:#|No\.?|Number)[:\s]*([\dX*•-]+(?: [\dX*•-]+)*)`)
 	beginningBalancePattern = regexp.MustCompile(`Beginning\s+Balance\s+\$?([\d,]+\.\d{2})`)
 	endingBalancePattern    = regexp.MustCompile(`Ending\s+Balance\s+\$?(-?[\d,]+\.\d{2})`)
 )
 
+// accountLastFour returns the last four visible digits of a possibly masked
+// account number (428-0712609, XXXXXX0712609, ****2609), or "" if there are fewer than four
+func accountLastFour(account string) string {
+	var digits []rune
+	for _, r := range account {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, r)
+		}
+	}
+	if len(digits) < 4 {
+		return ""
+	}
+	return string(digits[len(digits)-4:])
+}
+
 // parseHeader extracts account info and balances
 func (p *TDBankParser) parseHeader(text string) (*ParsedStatement, error) {
 	stmt := &ParsedStatement{}
 
-	if match := accountPattern.FindStringSubmatch(text); len(match) > 1 {
-		stmt.AccountLastFour = match[1]
+	// An earlier account line may be fully masked, so use the first that shows four digits
+	for _, match := range accountPattern.FindAllStringSubmatch(text, -1) {
+		if lastFour := accountLastFour(match[1]); lastFour != "" {
+			stmt.AccountLastFour = lastFour
+			break
+		}
 	}
 
 	if match := periodPattern.FindStringSubmatch(text); len(match) > 2 {
@@ -257,6 +324,14 @@ func (p *TDBankParser) parseHeader(text string) (*ParsedStatement, error) {
 		p.statementYear = year
 		p.statementMonth = monthNameToNumber(match[1])
 		stmt.StatementMonth = fmt.Sprintf("%d-%02d", year, p.statementMonth)
+	} else if t, err := time.Parse("2006-01", p.fallbackMonth); err == nil {
+		// Without a year every posting date would come out as 0-MM-DD
+		p.statementYear = t.Year()
+		p.statementMonth = int(t.Month())
+		stmt.StatementMonth = p.fallbackMonth
+		log.Printf("[TDBankParser] WARNING: statement period not found, falling back to %s", p.fallbackMonth)
+	} else {
+		log.Printf("[TDBankParser] WARNING: statement period not found and no fallback month, dates will be wrong")
 	}
 
 	if match := beginningBalancePattern.FindStringSubmatch(text); len(match) > 1 {
@@ -444,6 +519,26 @@ func (p *TDBankParser) cleanSectionContent(content string) string {
 // Transaction line pattern - date at start, amount at end
 var transactionLinePattern = regexp.MustCompile(`^(\d{2}/\d{2})\s+(.+?)\s{2,}([\d,]+\.\d{2})\s*$`)
 
+// Looser variant for the credits and fees sections, where small amounts (interest, refunds)
+// sometimes sit a single space after the description. The description must end in a letter
+// or closing paren so digits inside it aren't mistaken for the amount
+var singleSpaceAmountLinePattern = regexp.MustCompile(`^(\d{2}/\d{2})\s+(.*[A-Za-z)])\s([\d,]+\.\d{2})\s*$`)
+
+// Amounts inside a description, which rule out the looser match
+var embeddedAmountPattern = regexp.MustCompile(`\d\.\d{2}\b`)
+
+// matchAmountLine matches a credits or fees line, falling back to the single-space pattern
+func matchAmountLine(line string) []string {
+	if match := transactionLinePattern.FindStringSubmatch(line); len(match) > 3 {
+		return match
+	}
+	match := singleSpaceAmountLinePattern.FindStringSubmatch(line)
+	if len(match) > 3 && !embeddedAmountPattern.MatchString(match[2]) {
+		return match
+	}
+	return nil
+}
+
 // Continuation line - starts with significant whitespace, no date
 var continuationLinePattern = regexp.MustCompile(`^\s{6,}(\S.*)$`)
 
@@ -467,6 +562,7 @@ func (p *TDBankParser) parseDeposits(section string) []ParsedTransaction {
 				TransactionType: "deposit",
 			}
 			txn.Category = categorizeTransaction(txn.TransactionType, txn.Description, txn.Amount)
+			txn.Platform = transactionPlatform(txn.Category, txn.Description)
 			txn.VendorHint = extractVendorHint(txn.Description)
 			transactions = append(transactions, txn)
 		}
@@ -487,7 +583,7 @@ func (p *TDBankParser) parseCredits(section string) []ParsedTransaction {
 	for scanner.Scan() {
 		line := scanner.Text()
 
-		if match := transactionLinePattern.FindStringSubmatch(line); len(match) > 3 {
+		if match := matchAmountLine(line); match != nil {
 			txn := ParsedTransaction{
 				PostingDate:     p.formatDate(match[1]),
 				Description:     strings.TrimSpace(match[2]),
@@ -495,6 +591,7 @@ func (p *TDBankParser) parseCredits(section string) []ParsedTransaction {
 				TransactionType: "credit",
 			}
 			txn.Category = categorizeTransaction(txn.TransactionType, txn.Description, txn.Amount)
+			txn.Platform = transactionPlatform(txn.Category, txn.Description)
 			txn.VendorHint = extractVendorHint(txn.Description)
 			transactions = append(transactions, txn)
 		}
@@ -644,7 +741,7 @@ func (p *TDBankParser) parseFees(section string) []ParsedTransaction {
 	for scanner.Scan() {
 		line := scanner.Text()
 
-		if match := transactionLinePattern.FindStringSubmatch(line); len(match) > 3 {
+		if match := matchAmountLine(line); match != nil {
 			txn := ParsedTransaction{
 				PostingDate:     p.formatDate(match[1]),
 				Description:     strings.TrimSpace(match[2]),
@@ -663,6 +760,7 @@ func (p *TDBankParser) parseFees(section string) []ParsedTransaction {
 // finalizeTransaction sets category and vendor hint
 func (p *TDBankParser) finalizeTransaction(txn *ParsedTransaction) {
 	txn.Category = categorizeTransaction(txn.TransactionType, txn.Description, txn.Amount)
+	txn.Platform = transactionPlatform(txn.Category, txn.Description)
 	txn.VendorHint = extractVendorHint(txn.Description)
 }
 
@@ -757,6 +855,44 @@ func extractVendorHint(description string) string {
 	return ""
 }
 
+// Delivery platforms attributed on income_delivery transactions
+const (
+	PlatformGrubhub  = "grubhub"
+	PlatformDoorDash = "doordash"
+	PlatformUberEats = "ubereats"
+	PlatformSeamless = "seamless"
+	PlatformEzCater  = "ezcater"
+	PlatformSlice    = "slice"
+)
+
+// DeliveryPlatform returns the delivery platform named in a description, or "" if none
+func DeliveryPlatform(description string) string {
+	descUpper := strings.ToUpper(description)
+	switch {
+	case strings.Contains(descUpper, "UBER"):
+		return PlatformUberEats
+	case strings.Contains(descUpper, "GRUBHUB"):
+		return PlatformGrubhub
+	case strings.Contains(descUpper, "DOORDASH"):
+		return PlatformDoorDash
+	case strings.Contains(descUpper, "SEAMLESS"):
+		return PlatformSeamless
+	case strings.Contains(descUpper, "EZCATER"):
+		return PlatformEzCater
+	case strings.Contains(descUpper, "SLICE"):
+		return PlatformSlice
+	}
+	return ""
+}
+
+// transactionPlatform tags delivery income with its platform
+func transactionPlatform(category, description string) string {
+	if category != "income_delivery" {
+		return ""
+	}
+	return DeliveryPlatform(description)
+}
+
 func categorizeTransaction(txnType, description string, amount float64) string {
 	descUpper := strings.ToUpper(description)
 
@@ -764,13 +900,7 @@ func categorizeTransaction(txnType, description string, amount float64) string {
 		if strings.Contains(descUpper, "BANKCARD") || strings.Contains(descUpper, "MTOT DEP") {
 			return "income_cards"
 		}
-		if strings.Contains(descUpper, "UBER") {
-			return "income_delivery"
-		}
-		if strings.Contains(descUpper, "GRUBHUB") {
-			return "income_delivery"
-		}
-		if strings.Contains(descUpper, "DOORDASH") {
+		if DeliveryPlatform(description) != "" {
 			return "income_delivery"
 		}
 		if strings.Contains(descUpper, "REFUND") {
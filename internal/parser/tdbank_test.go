@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// parseFixture runs the TD Bank parser over a pdftotext -layout dump in testdata
+func parseFixture(t *testing.T, name string) *ParsedStatement {
+	t.Helper()
+	text, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	stmt, err := NewTDBankParser(Options{}).ParseText(string(text))
+	if err != nil {
+		t.Fatalf("parse %s: %v", name, err)
+	}
+	return stmt
+}
+
+func TestTDBankAccountLastFour(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    string
+	}{
+		{"account_x_masked.txt", "2609"},    // XXXXXX0712609
+		{"account_star_masked.txt", "2609"}, // Account # *** before Account # ****2609
+		{"account_primary.txt", "2609"},     // only Primary Account #: 428-0712609
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			if got := parseFixture(t, tt.fixture).AccountLastFour; got != tt.want {
+				t.Errorf("AccountLastFour = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccountLastFour(t *testing.T) {
+	tests := map[string]string{
+		"428-0712609":   "2609",
+		"XXXXXX0712609": "2609",
+		"****2609":      "2609",
+		"***":           "",
+		"**09":          "",
+	}
+	for account, want := range tests {
+		if got := accountLastFour(account); got != want {
+			t.Errorf("accountLastFour(%q) = %q, want %q", account, got, want)
+		}
+	}
+}
+
+func TestTDBankOtherCreditsInterest(t *testing.T) {
+	stmt := parseFixture(t, "other_credits_interest.txt")
+
+	// The interest line has its amount right after the description instead of in
+	// the amount column
+	var interest *ParsedTransaction
+	for i, txn := range stmt.Transactions {
+		if strings.Contains(txn.Description, "INTEREST PAID") {
+			interest = &stmt.Transactions[i]
+		}
+	}
+	if interest == nil {
+		t.Fatalf("no interest credit among %d transactions", len(stmt.Transactions))
+	}
+	if interest.PostingDate != "2026-01-31" || math.Abs(interest.Amount-0.05) > 0.001 {
+		t.Errorf("interest credit = %s %.2f, want 2026-01-31 0.05", interest.PostingDate, interest.Amount)
+	}
+	if math.Abs(stmt.OtherCredits-35.05) > 0.001 {
+		t.Errorf("OtherCredits = %.2f, want 35.05", stmt.OtherCredits)
+	}
+
+	if stmt.BeginningBalance != 1000 || math.Abs(stmt.EndingBalance-1005.05) > 0.001 {
+		t.Fatalf("balances = %.2f to %.2f, want 1000.00 to 1005.05", stmt.BeginningBalance, stmt.EndingBalance)
+	}
+	total := stmt.BeginningBalance
+	for _, txn := range stmt.Transactions {
+		total += txn.Amount
+	}
+	if math.Abs(total-stmt.EndingBalance) > 0.005 {
+		t.Errorf("beginning %.2f plus transactions = %.2f, want ending %.2f", stmt.BeginningBalance, total, stmt.EndingBalance)
+	}
+	if len(stmt.Warnings) > 0 {
+		t.Errorf("unexpected warnings: %q", stmt.Warnings)
+	}
+}
@@ -0,0 +1,86 @@
+package reconciliation
+
+import (
+	"fmt"
+	"math"
+
+	"homebooks/internal/database"
+	"homebooks/internal/models"
+)
+
+// ExpenseFromTransaction builds a paid expense for a vendor from a bank transaction
+func ExpenseFromTransaction(txn *models.BankTransaction, vendorID int64) models.Expense {
+	return models.Expense{
+		Date:        txn.PostingDate,
+		VendorID:    vendorID,
+		Amount:      math.Abs(txn.Amount), // amount is negative in bank txn
+		Status:      "paid",
+		PaymentType: paymentTypeFor(txn),
+		CheckNumber: txn.CheckNumber,
+		DatePaid:    txn.PostingDate,
+		Notes:       fmt.Sprintf("Created from bank statement: %s", txn.Description),
+	}
+}
+
+// paymentTypeFor maps a bank transaction type to an expense payment type
+func paymentTypeFor(txn *models.BankTransaction) string {
+	switch txn.TransactionType {
+	case "check":
+		return "check"
+	case "debit", "ach", "electronic":
+		return "debit"
+	case "credit":
+		return "credit"
+	default:
+		return "debit" // default for unknown types
+	}
+}
+
+// ApplyAutoBookingRules creates expenses for unmatched transactions that match an
+// auto-booking rule and marks them as created. Run after AutoMatch so existing
+// receipts win over rules. Returns the number of transactions booked
+func ApplyAutoBookingRules(db *database.DB, reconciliationID int64) (int, error) {
+	rules, err := db.ListAutoBookingRules(0)
+	if err != nil {
+		return 0, err
+	}
+	if len(rules) == 0 {
+		return 0, nil
+	}
+
+	transactions, err := db.GetUnmatchedBankTransactions(reconciliationID)
+	if err != nil {
+		return 0, err
+	}
+
+	booked := 0
+
+	for _, txn := range transactions {
+		// Only debits become expenses
+		if txn.Amount >= 0 {
+			continue
+		}
+
+		for _, rule := range rules {
+			if !rule.Matches(txn) {
+				continue
+			}
+
+			expense := ExpenseFromTransaction(&txn, rule.VendorID)
+			expense.Category = rule.Category
+			expense.Notes = fmt.Sprintf("Auto-booked from bank statement: %s", txn.Description)
+
+			expenseID, err := db.CreateExpense(expense)
+			if err != nil {
+				return booked, fmt.Errorf("create expense for transaction %d: %w", txn.ID, err)
+			}
+			if err := db.MarkBankTransactionCreated(txn.ID, expenseID); err != nil {
+				return booked, fmt.Errorf("mark transaction %d created: %w", txn.ID, err)
+			}
+			booked++
+			break
+		}
+	}
+
+	return booked, nil
+}
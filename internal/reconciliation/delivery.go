@@ -0,0 +1,55 @@
+package reconciliation
+
+import (
+	"time"
+
+	"homebooks/internal/database"
+	"homebooks/internal/models"
+	"homebooks/internal/parser"
+)
+
+// CheckDeliveryPayouts compares each delivery platform deposit on a statement against
+// that platform's recorded net over the periodDays days before the deposit
+func CheckDeliveryPayouts(db *database.DB, reconciliationID int64, periodDays int) ([]models.DeliveryPayoutCheck, error) {
+	transactions, err := db.GetBankTransactions(reconciliationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var checks []models.DeliveryPayoutCheck
+	for _, txn := range transactions {
+		if txn.Category != "income_delivery" || txn.Amount <= 0 {
+			continue
+		}
+
+		// Statements parsed before platforms were tagged only carry the description
+		platform := txn.Platform
+		if platform == "" {
+			platform = parser.DeliveryPlatform(txn.Description)
+		}
+		if platform == "" {
+			continue
+		}
+
+		depositDate, err := time.Parse("2006-01-02", txn.PostingDate)
+		if err != nil {
+			continue
+		}
+		periodStart := depositDate.AddDate(0, 0, -periodDays).Format("2006-01-02")
+		periodEnd := depositDate.AddDate(0, 0, -1).Format("2006-01-02")
+
+		net, err := db.SumDeliveryNet(platform, periodStart, periodEnd)
+		if err != nil {
+			return nil, err
+		}
+
+		checks = append(checks, models.DeliveryPayoutCheck{
+			Transaction: txn,
+			Platform:    platform,
+			PeriodStart: periodStart,
+			PeriodEnd:   periodEnd,
+			RecordedNet: net,
+		})
+	}
+	return checks, nil
+}
@@ -2,14 +2,61 @@ package reconciliation
 
 import (
 	"math"
+	"os"
+	"strconv"
 	"time"
 
 	"homebooks/internal/database"
 )
 
+// MatchOptions tunes AutoMatch
+type MatchOptions struct {
+	// IncludeUnpaid lets a high-confidence match (check number, or amount plus vendor hint)
+	// claim an unpaid expense and mark it paid as of the transaction's posting date
+	IncludeUnpaid bool
+	// AmountTolerance and AmountTolerancePercent let the amount-based strategies accept a
+	// transaction a few cents off the expense (delivery payouts, card settlements). The
+	// larger of the two applies; zero for both means amounts must match exactly.
+	// Check-number matches are never affected
+	AmountTolerance        float64
+	AmountTolerancePercent float64
+}
+
+// MatchOptionsFromEnv reads the matching options from the environment. Auto-matching
+// unpaid expenses marks them paid, so it is opt-in, and near-miss amounts are only
+// matched when a tolerance is configured
+func MatchOptionsFromEnv() MatchOptions {
+	includeUnpaid, _ := strconv.ParseBool(os.Getenv("HOMEBOOKS_AUTOMATCH_UNPAID"))
+	tolerance, _ := strconv.ParseFloat(os.Getenv("HOMEBOOKS_AUTOMATCH_TOLERANCE"), 64)
+	tolerancePercent, _ := strconv.ParseFloat(os.Getenv("HOMEBOOKS_AUTOMATCH_TOLERANCE_PERCENT"), 64)
+	return MatchOptions{
+		IncludeUnpaid:          includeUnpaid,
+		AmountTolerance:        tolerance,
+		AmountTolerancePercent: tolerancePercent,
+	}
+}
+
+// amountsMatch reports whether a transaction amount matches an expense amount, allowing
+// the configured tolerance only when tolerant is set
+func (o MatchOptions) amountsMatch(txnAmount, expenseAmount float64, tolerant bool) bool {
+	diff := math.Abs(txnAmount - expenseAmount)
+	if diff < 0.005 {
+		return true
+	}
+	if !tolerant {
+		return false
+	}
+	return diff <= math.Max(o.AmountTolerance, expenseAmount*o.AmountTolerancePercent/100)+0.000001
+}
+
+// hasTolerance reports whether near-miss amounts are allowed at all
+func (o MatchOptions) hasTolerance() bool {
+	return o.AmountTolerance > 0 || o.AmountTolerancePercent > 0
+}
+
 // AutoMatch attempts to automatically match bank transactions to expenses
 // Returns the number of transactions matched
-func AutoMatch(db *database.DB, reconciliationID int64) (int, error) {
+func AutoMatch(db *database.DB, reconciliationID int64, opts MatchOptions) (int, error) {
 	// Get the reconciliation to determine date range
 	recon, err := db.GetReconciliation(reconciliationID)
 	if err != nil {
@@ -43,6 +90,14 @@ func AutoMatch(db *database.DB, reconciliationID int64) (int, error) {
 
 	matched := 0
 
+	// Exact amounts get the first pass so a near miss can't take a transaction that
+	// belongs to another expense
+	passes := []bool{false}
+	if opts.hasTolerance() {
+		passes = append(passes, true)
+	}
+
+transactionLoop:
 	for _, txn := range transactions {
 		// Skip non-expense transactions (deposits, credits)
 		if txn.Amount >= 0 {
@@ -57,47 +112,74 @@ func AutoMatch(db *database.DB, reconciliationID int64) (int, error) {
 		txnAmount := math.Abs(txn.Amount)
 
 		// Try to find a matching expense
-		for _, exp := range expenses {
-			// Skip unpaid expenses
-			if exp.Status != "paid" {
-				continue
-			}
+		for _, tolerant := range passes {
+			for i := range expenses {
+				exp := &expenses[i]
+				amountMatches := opts.amountsMatch(txnAmount, exp.Amount, tolerant)
+
+				// Skip already matched expenses
+				if isExpenseMatched(db, reconciliationID, exp.ID) {
+					continue
+				}
 
-			// Skip already matched expenses
-			if isExpenseMatched(db, reconciliationID, exp.ID) {
-				continue
-			}
+				// The bank clearing an unpaid expense is the sign it got paid, but only
+				// trust that on a high-confidence match
+				if exp.Status != "paid" {
+					if !opts.IncludeUnpaid {
+						continue
+					}
+					confidence := ""
+					if txn.CheckNumber != "" && exp.CheckNumber != "" && txn.CheckNumber == exp.CheckNumber {
+						confidence = "auto_exact"
+					} else if amountMatches && txn.VendorHint != "" && vendorNameMatches(exp.VendorName, txn.VendorHint) {
+						confidence = "auto_fuzzy"
+					}
+					if confidence == "" {
+						continue
+					}
+					if err := db.MarkExpensePaidOn(exp.ID, txn.PostingDate, paymentTypeFor(&txn), txn.CheckNumber); err != nil {
+						continue
+					}
+					exp.Status = "paid"
+					exp.DatePaid = txn.PostingDate
+					if err := db.MatchBankTransaction(txn.ID, exp.ID, confidence); err == nil {
+						matched++
+						continue transactionLoop
+					}
+					continue
+				}
 
-			// Match strategy 1: Check number exact match (highest confidence)
-			if txn.CheckNumber != "" && exp.CheckNumber != "" && txn.CheckNumber == exp.CheckNumber {
-				if err := db.MatchBankTransaction(txn.ID, exp.ID, "auto_exact"); err == nil {
-					matched++
-					break
+				// Match strategy 1: Check number exact match (highest confidence)
+				if txn.CheckNumber != "" && exp.CheckNumber != "" && txn.CheckNumber == exp.CheckNumber {
+					if err := db.MatchBankTransaction(txn.ID, exp.ID, "auto_exact"); err == nil {
+						matched++
+						continue transactionLoop
+					}
 				}
-			}
 
-			// Match strategy 2: Exact amount + close date
-			if txnAmount == exp.Amount {
-				txnDate, _ := time.Parse("2006-01-02", txn.PostingDate)
-				expDate, _ := time.Parse("2006-01-02", exp.DatePaid)
+				// Match strategy 2: Amount (within tolerance on the second pass) + close date
+				if amountMatches {
+					txnDate, _ := time.Parse("2006-01-02", txn.PostingDate)
+					expDate, _ := time.Parse("2006-01-02", exp.DatePaid)
 
-				daysDiff := math.Abs(txnDate.Sub(expDate).Hours() / 24)
+					daysDiff := math.Abs(txnDate.Sub(expDate).Hours() / 24)
 
-				if daysDiff <= 3 {
-					if err := db.MatchBankTransaction(txn.ID, exp.ID, "auto_fuzzy"); err == nil {
-						matched++
-						break
+					if daysDiff <= 3 {
+						if err := db.MatchBankTransaction(txn.ID, exp.ID, "auto_fuzzy"); err == nil {
+							matched++
+							continue transactionLoop
+						}
 					}
 				}
-			}
 
-			// Match strategy 3: Amount match + vendor hint
-			if txnAmount == exp.Amount && txn.VendorHint != "" {
-				// Check if vendor hint matches expense vendor name (case insensitive)
-				if containsIgnoreCase(exp.VendorName, txn.VendorHint) {
-					if err := db.MatchBankTransaction(txn.ID, exp.ID, "auto_fuzzy"); err == nil {
-						matched++
-						break
+				// Match strategy 3: Amount match + vendor hint
+				if amountMatches && txn.VendorHint != "" {
+					// The statement's name for the vendor rarely matches ours exactly
+					if vendorNameMatches(exp.VendorName, txn.VendorHint) {
+						if err := db.MatchBankTransaction(txn.ID, exp.ID, "auto_fuzzy"); err == nil {
+							matched++
+							continue transactionLoop
+						}
 					}
 				}
 			}
@@ -117,31 +199,3 @@ func isExpenseMatched(db *database.DB, reconciliationID, expenseID int64) bool {
 	}
 	return false
 }
-
-// containsIgnoreCase checks if haystack contains needle (case insensitive)
-func containsIgnoreCase(haystack, needle string) bool {
-	return len(needle) > 0 && len(haystack) >= len(needle) &&
-		(haystack == needle ||
-			containsLower(toLower(haystack), toLower(needle)))
-}
-
-func toLower(s string) string {
-	b := make([]byte, len(s))
-	for i := range s {
-		c := s[i]
-		if c >= 'A' && c <= 'Z' {
-			c += 'a' - 'A'
-		}
-		b[i] = c
-	}
-	return string(b)
-}
-
-func containsLower(haystack, needle string) bool {
-	for i := 0; i <= len(haystack)-len(needle); i++ {
-		if haystack[i:i+len(needle)] == needle {
-			return true
-		}
-	}
-	return false
-}
@@ -0,0 +1,152 @@
+package reconciliation
+
+import (
+	"path/filepath"
+	"testing"
+
+	"homebooks/internal/database"
+	"homebooks/internal/models"
+)
+
+// matchFixture is a migrated temp database with one January statement to match against
+type matchFixture struct {
+	db      *database.DB
+	reconID int64
+}
+
+func newMatchFixture(t *testing.T) *matchFixture {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Init(); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	reconID, err := db.CreateReconciliation(models.BankReconciliation{StatementDate: "2026-01-31", Status: "parsed"})
+	if err != nil {
+		t.Fatalf("create reconciliation: %v", err)
+	}
+	return &matchFixture{db: db, reconID: reconID}
+}
+
+// paidExpense adds an expense paid on datePaid and returns its ID
+func (f *matchFixture) paidExpense(t *testing.T, date, datePaid string, amount float64, checkNumber string) int64 {
+	t.Helper()
+	paymentType := "debit"
+	if checkNumber != "" {
+		paymentType = "check"
+	}
+	id, err := f.db.CreateExpense(models.Expense{
+		Date: date, PayeeName: "Jetro", Amount: amount, Status: "paid",
+		PaymentType: paymentType, CheckNumber: checkNumber, DatePaid: datePaid,
+	})
+	if err != nil {
+		t.Fatalf("create expense: %v", err)
+	}
+	return id
+}
+
+// debit adds a withdrawal to the statement and returns its ID
+func (f *matchFixture) debit(t *testing.T, date string, amount float64, checkNumber string) int64 {
+	t.Helper()
+	txnType := "debit"
+	if checkNumber != "" {
+		txnType = "check"
+	}
+	id, err := f.db.CreateBankTransaction(&models.BankTransaction{
+		ReconciliationID: f.reconID, PostingDate: date, Description: "WITHDRAWAL",
+		Amount: -amount, TransactionType: txnType, Category: "expense", CheckNumber: checkNumber,
+	})
+	if err != nil {
+		t.Fatalf("create bank transaction: %v", err)
+	}
+	return id
+}
+
+// match returns the expense a transaction was matched to (0 if none) and the confidence
+func (f *matchFixture) match(t *testing.T, txnID int64) (int64, string) {
+	t.Helper()
+	txn, err := f.db.GetBankTransaction(txnID)
+	if err != nil {
+		t.Fatalf("get bank transaction: %v", err)
+	}
+	if txn.MatchedExpenseID == nil {
+		return 0, txn.MatchConfidence
+	}
+	return *txn.MatchedExpenseID, txn.MatchConfidence
+}
+
+var centsTolerance = MatchOptions{AmountTolerance: 0.05}
+
+func TestAutoMatchTolerance(t *testing.T) {
+	tests := []struct {
+		name string
+		opts MatchOptions
+		want bool
+	}{
+		{"no tolerance", MatchOptions{}, false},
+		{"fixed tolerance", centsTolerance, true},
+		{"percent tolerance", MatchOptions{AmountTolerancePercent: 0.05}, true},
+		{"tolerance too small", MatchOptions{AmountTolerance: 0.02}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newMatchFixture(t)
+			expenseID := f.paidExpense(t, "2026-01-09", "2026-01-10", 100.00, "")
+			txnID := f.debit(t, "2026-01-11", 99.97, "")
+
+			if _, err := AutoMatch(f.db, f.reconID, tt.opts); err != nil {
+				t.Fatalf("AutoMatch: %v", err)
+			}
+			got, confidence := f.match(t, txnID)
+			if !tt.want {
+				if got != 0 {
+					t.Errorf("99.97 matched expense %d, want unmatched", got)
+				}
+				return
+			}
+			if got != expenseID || confidence != "auto_fuzzy" {
+				t.Errorf("99.97 matched expense %d (%s), want %d (auto_fuzzy)", got, confidence, expenseID)
+			}
+		})
+	}
+}
+
+func TestAutoMatchPrefersExactAmount(t *testing.T) {
+	f := newMatchFixture(t)
+	// The 100.00 expense is newer so it is tried first, and is within tolerance of 99.97
+	near := f.paidExpense(t, "2026-01-09", "2026-01-10", 100.00, "")
+	exact := f.paidExpense(t, "2026-01-08", "2026-01-10", 99.97, "")
+	txnID := f.debit(t, "2026-01-10", 99.97, "")
+
+	if _, err := AutoMatch(f.db, f.reconID, centsTolerance); err != nil {
+		t.Fatalf("AutoMatch: %v", err)
+	}
+	if got, _ := f.match(t, txnID); got != exact {
+		t.Errorf("99.97 matched expense %d, want the exact %d over the near miss %d", got, exact, near)
+	}
+
+	// A second 99.97 can then only take the 100.00 expense, by tolerance
+	txnID = f.debit(t, "2026-01-11", 99.97, "")
+	if _, err := AutoMatch(f.db, f.reconID, centsTolerance); err != nil {
+		t.Fatalf("AutoMatch: %v", err)
+	}
+	if got, confidence := f.match(t, txnID); got != near || confidence != "auto_fuzzy" {
+		t.Errorf("second 99.97 matched expense %d (%s), want %d (auto_fuzzy)", got, confidence, near)
+	}
+}
+
+func TestAutoMatchCheckNumberStaysExact(t *testing.T) {
+	f := newMatchFixture(t)
+	expenseID := f.paidExpense(t, "2026-01-05", "2026-01-05", 250.00, "1234")
+	txnID := f.debit(t, "2026-01-20", 250.00, "1234")
+
+	if _, err := AutoMatch(f.db, f.reconID, centsTolerance); err != nil {
+		t.Fatalf("AutoMatch: %v", err)
+	}
+	if got, confidence := f.match(t, txnID); got != expenseID || confidence != "auto_exact" {
+		t.Errorf("check #1234 matched expense %d (%s), want %d (auto_exact)", got, confidence, expenseID)
+	}
+}
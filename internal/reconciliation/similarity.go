@@ -0,0 +1,127 @@
+package reconciliation
+
+import (
+	"strings"
+	"unicode"
+)
+
+// VendorSimilarityThreshold is the name similarity (0-1) a bank vendor hint needs with
+// an expense's vendor before an exact-amount match is accepted
+const VendorSimilarityThreshold = 0.8
+
+// Words that don't help tell vendors apart ("Jetro Inc" is "JETRO" on the statement)
+var vendorStopWords = map[string]bool{
+	"and": true, "the": true, "of": true,
+	"inc": true, "llc": true, "co": true, "corp": true, "company": true, "ltd": true,
+}
+
+// vendorNameMatches reports whether a bank vendor hint names the same vendor as name
+func vendorNameMatches(name, hint string) bool {
+	return nameSimilarity(name, hint) >= VendorSimilarityThreshold
+}
+
+// nameSimilarity scores how alike two vendor names are, from 0 to 1. Names are compared
+// squashed ("CONED" vs "Con Edison"), by edit distance, and word by word, so an
+// abbreviated or padded statement name ("JETRO CASH CARRY" vs "Jetro") still scores high
+func nameSimilarity(a, b string) float64 {
+	aTokens, bTokens := vendorTokens(a), vendorTokens(b)
+	if len(aTokens) == 0 || len(bTokens) == 0 {
+		return 0
+	}
+
+	aSquashed, bSquashed := strings.Join(aTokens, ""), strings.Join(bTokens, "")
+	if aSquashed == bSquashed {
+		return 1
+	}
+
+	// One name is an abbreviation or a prefix of the other
+	shorter, longer := aSquashed, bSquashed
+	if len(shorter) > len(longer) {
+		shorter, longer = longer, shorter
+	}
+	if len(shorter) >= 3 && strings.Contains(longer, shorter) {
+		return 1
+	}
+
+	return max(editSimilarity(aSquashed, bSquashed), tokenOverlap(aTokens, bTokens))
+}
+
+// vendorTokens lower-cases a name and splits it into words, dropping punctuation and
+// stop words. Apostrophes and ampersands are removed rather than split on, so
+// "Chef's" is "chefs" and "AT&T" is "att"
+func vendorTokens(name string) []string {
+	name = strings.NewReplacer("'", "", "&", "").Replace(strings.ToLower(name))
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	tokens := words[:0]
+	for _, w := range words {
+		if !vendorStopWords[w] {
+			tokens = append(tokens, w)
+		}
+	}
+	return tokens
+}
+
+// tokenOverlap is the share of the shorter name's words found in the other name. Words
+// match when one is a prefix of the other or they are within a typo of each other
+func tokenOverlap(a, b []string) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+
+	found := 0
+	for _, x := range a {
+		for _, y := range b {
+			if tokensMatch(x, y) {
+				found++
+				break
+			}
+		}
+	}
+	return float64(found) / float64(len(a))
+}
+
+func tokensMatch(x, y string) bool {
+	if x == y {
+		return true
+	}
+	if len(x) >= 3 && len(y) >= 3 && (strings.HasPrefix(x, y) || strings.HasPrefix(y, x) ||
+		strings.HasSuffix(x, y) || strings.HasSuffix(y, x)) {
+		return true
+	}
+	return editSimilarity(x, y) >= VendorSimilarityThreshold
+}
+
+// editSimilarity is 1 minus the Levenshtein distance over the longer string's length
+func editSimilarity(a, b string) float64 {
+	ar, br := []rune(a), []rune(b)
+	longest := max(len(ar), len(br))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ar, br))/float64(longest)
+}
+
+// levenshtein returns the number of single-character edits to turn a into b
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,100 @@
+package reconciliation
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestVendorTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Chef's Choice, Inc.", []string{"chefs", "choice"}},
+		{"AT&T Mobility LLC", []string{"att", "mobility"}},
+		{"The Home Depot", []string{"home", "depot"}},
+		{"JETRO CASH CARRY", []string{"jetro", "cash", "carry"}},
+		{"Inc.", []string{}},
+	}
+	for _, tt := range tests {
+		if got := vendorTokens(tt.name); !slices.Equal(got, tt.want) {
+			t.Errorf("vendorTokens(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+		{"jetro", "jetro", 0},
+		{"verizn", "verizon", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestTokenOverlap(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"Jetro", "JETRO CASH CARRY", 1},
+		{"Good Food", "Goodyear Tire", 0.5},
+		{"Cogent Waste", "Cogent Dental", 0.5},
+		{"INP Foods", "C&S Meats", 0},
+	}
+	for _, tt := range tests {
+		got := tokenOverlap(vendorTokens(tt.a), vendorTokens(tt.b))
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("tokenOverlap(%q, %q) = %.3f, want %.3f", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// The statement names on the left are what extractVendorHint's vendorPatterns and the
+// bank descriptions produce; the right is the vendor as entered in the app
+func TestVendorNameMatches(t *testing.T) {
+	tests := []struct {
+		name, hint string
+		want       bool
+	}{
+		// Abbreviated or padded statement names
+		{"Jetro", "JETRO CASH CARRY", true},
+		{"Con Edison", "CONED", true},
+		{"Chef's Choice", "CHEFS CHOICE", true},
+		{"National Grid Co", "NATIONAL GRID", true},
+		{"Sampar's", "SAMPARS", true},
+		{"Cintas Corp", "CINTAS", true},
+		{"AT&T", "ATT", true},
+		// A typo caught by edit distance
+		{"Verizon Wireless", "VERIZN WIRELESS", true},
+		// Unrelated vendors stay below the threshold
+		{"Con Edison", "VERIZON", false},
+		{"Wegmans", "JETRO", false},
+		{"Goodyear Tire", "GOOD FOOD", false},
+		{"Cogent Dental", "COGENT WASTE", false},
+		{"Restaurant Depot", "CARIBBEAN DEPOT", false},
+		{"C&S Meats", "INP FOODS", false},
+		{"Cintas", "CINTSA", false},
+		// Names that are only stop words don't match anything
+		{"The Co", "INC", false},
+	}
+	for _, tt := range tests {
+		score := nameSimilarity(tt.name, tt.hint)
+		if got := vendorNameMatches(tt.name, tt.hint); got != tt.want {
+			t.Errorf("vendorNameMatches(%q, %q) = %v (score %.3f, threshold %.2f), want %v",
+				tt.name, tt.hint, got, score, VendorSimilarityThreshold, tt.want)
+		}
+		if score < 0 || score > 1 {
+			t.Errorf("nameSimilarity(%q, %q) = %.3f, want 0-1", tt.name, tt.hint, score)
+		}
+	}
+}
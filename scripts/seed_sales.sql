@@ -588,3 +588,11 @@ INSERT INTO delivery_sales (date, grubhub_subtotal, grubhub_net, doordash_subtot
 ('2026-01-13', 78.60, 58.95, 95.40, 71.55, 65.80, 46.06, ''),
 ('2026-01-14', 85.40, 64.05, 102.60, 76.95, 72.40, 50.68, ''),
 ('2026-01-15', 82.60, 61.95, 98.40, 73.80, 68.60, 48.02, '');
+
+-- Per-platform rows, which is what the app reads
+INSERT OR IGNORE INTO delivery_platform_sales (date, platform, subtotal, net)
+SELECT date, 'grubhub', grubhub_subtotal, grubhub_net FROM delivery_sales WHERE grubhub_subtotal != 0 OR grubhub_net != 0;
+INSERT OR IGNORE INTO delivery_platform_sales (date, platform, subtotal, net)
+SELECT date, 'doordash', doordash_subtotal, doordash_net FROM delivery_sales WHERE doordash_subtotal != 0 OR doordash_net != 0;
+INSERT OR IGNORE INTO delivery_platform_sales (date, platform, subtotal, net)
+SELECT date, 'ubereats', ubereats_earnings, ubereats_payout FROM delivery_sales WHERE ubereats_earnings != 0 OR ubereats_payout != 0;